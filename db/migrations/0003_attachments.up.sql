@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS attachments (
+    id           uuid PRIMARY KEY,
+    email_id     uuid NOT NULL REFERENCES emails (id) ON DELETE CASCADE,
+    filename     text NOT NULL DEFAULT '',
+    content_type text NOT NULL DEFAULT '',
+    sniffed_type text NOT NULL DEFAULT '',
+    disposition  text NOT NULL DEFAULT '',
+    content_id   text NOT NULL DEFAULT '',
+    size         bigint NOT NULL DEFAULT 0,
+    sha256       text NOT NULL,
+    storage_key  text NOT NULL,
+    created_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_email_id ON attachments (email_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_sha256   ON attachments (sha256);
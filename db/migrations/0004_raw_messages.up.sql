@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS raw_messages (
+    email_id    uuid PRIMARY KEY REFERENCES emails (id) ON DELETE CASCADE,
+    storage_key text NOT NULL,
+    sha256      text NOT NULL,
+    size        bigint NOT NULL DEFAULT 0,
+    encoding    text NOT NULL DEFAULT 'gzip',
+    created_at  timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_emails_thread_subject;
+DROP INDEX IF EXISTS idx_emails_references_ids;
+DROP INDEX IF EXISTS idx_emails_in_reply_to;
+DROP INDEX IF EXISTS idx_emails_thread_id;
+
+ALTER TABLE emails
+    DROP COLUMN IF EXISTS thread_subject,
+    DROP COLUMN IF EXISTS thread_id,
+    DROP COLUMN IF EXISTS references_ids,
+    DROP COLUMN IF EXISTS in_reply_to;
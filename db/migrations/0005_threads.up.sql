@@ -0,0 +1,10 @@
+ALTER TABLE emails
+    ADD COLUMN IF NOT EXISTS in_reply_to    text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS references_ids text[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS thread_id      uuid NULL,
+    ADD COLUMN IF NOT EXISTS thread_subject text NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_emails_thread_id      ON emails (thread_id);
+CREATE INDEX IF NOT EXISTS idx_emails_in_reply_to    ON emails (in_reply_to) WHERE in_reply_to <> '';
+CREATE INDEX IF NOT EXISTS idx_emails_references_ids ON emails USING gin (references_ids);
+CREATE INDEX IF NOT EXISTS idx_emails_thread_subject ON emails (thread_subject, created_at DESC) WHERE thread_subject <> '';
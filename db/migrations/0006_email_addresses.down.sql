@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_email_addresses_domain;
+DROP INDEX IF EXISTS idx_email_addresses_address;
+DROP TABLE IF EXISTS email_addresses;
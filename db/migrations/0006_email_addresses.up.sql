@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS email_addresses (
+    email_id   uuid NOT NULL REFERENCES emails (id) ON DELETE CASCADE,
+    role       text NOT NULL CHECK (role IN ('from', 'sender', 'reply_to', 'to', 'cc', 'bcc')),
+    position   integer NOT NULL DEFAULT 0,
+    name       text NOT NULL DEFAULT '',
+    address    text NOT NULL,
+    local_part text NOT NULL DEFAULT '',
+    domain     text NOT NULL DEFAULT '',
+    PRIMARY KEY (email_id, role, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_addresses_address ON email_addresses (lower(address), role);
+CREATE INDEX IF NOT EXISTS idx_email_addresses_domain  ON email_addresses (domain, role);
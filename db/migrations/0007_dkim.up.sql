@@ -0,0 +1,2 @@
+ALTER TABLE emails
+    ADD COLUMN IF NOT EXISTS dkim_results jsonb NOT NULL DEFAULT '[]'::jsonb;
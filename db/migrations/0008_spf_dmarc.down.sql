@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_emails_dmarc_result;
+ALTER TABLE emails
+    DROP COLUMN IF EXISTS dmarc_policy,
+    DROP COLUMN IF EXISTS dmarc_result,
+    DROP COLUMN IF EXISTS dmarc,
+    DROP COLUMN IF EXISTS spf;
@@ -0,0 +1,9 @@
+ALTER TABLE emails
+    ADD COLUMN IF NOT EXISTS spf   jsonb NULL,
+    ADD COLUMN IF NOT EXISTS dmarc jsonb NULL;
+
+ALTER TABLE emails
+    ADD COLUMN IF NOT EXISTS dmarc_result text GENERATED ALWAYS AS (dmarc ->> 'result') STORED,
+    ADD COLUMN IF NOT EXISTS dmarc_policy text GENERATED ALWAYS AS (dmarc ->> 'applied_policy') STORED;
+
+CREATE INDEX IF NOT EXISTS idx_emails_dmarc_result ON emails (dmarc_result) WHERE dmarc_result IS NOT NULL;
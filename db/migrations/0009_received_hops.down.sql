@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_emails_origin_ip;
+ALTER TABLE emails
+    DROP COLUMN IF EXISTS origin_ip,
+    DROP COLUMN IF EXISTS transit_seconds,
+    DROP COLUMN IF EXISTS hops;
@@ -0,0 +1,6 @@
+ALTER TABLE emails
+    ADD COLUMN IF NOT EXISTS hops            jsonb NOT NULL DEFAULT '[]'::jsonb,
+    ADD COLUMN IF NOT EXISTS transit_seconds double precision NULL,
+    ADD COLUMN IF NOT EXISTS origin_ip       text NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_emails_origin_ip ON emails (origin_ip) WHERE origin_ip <> '';
@@ -0,0 +1,2 @@
+ALTER TABLE emails
+    DROP COLUMN IF EXISTS smime;
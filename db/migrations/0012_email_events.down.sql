@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_email_events_uid;
+DROP INDEX IF EXISTS idx_email_events_dtstart;
+DROP INDEX IF EXISTS idx_email_events_email_id;
+DROP TABLE IF EXISTS email_events;
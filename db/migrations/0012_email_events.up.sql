@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS email_events (
+    id            uuid PRIMARY KEY,
+    email_id      uuid NOT NULL REFERENCES emails (id) ON DELETE CASCADE,
+    method        text NOT NULL DEFAULT '',
+    uid           text NOT NULL DEFAULT '',
+    recurrence_id text NOT NULL DEFAULT '',
+    sequence      integer NOT NULL DEFAULT 0,
+    summary       text NOT NULL DEFAULT '',
+    description   text NOT NULL DEFAULT '',
+    location      text NOT NULL DEFAULT '',
+    status        text NOT NULL DEFAULT '',
+    organizer     jsonb NULL,
+    attendees     jsonb NOT NULL DEFAULT '[]'::jsonb,
+    dtstart       timestamptz NULL,
+    dtend         timestamptz NULL,
+    all_day       boolean NOT NULL DEFAULT false,
+    tzid          text NOT NULL DEFAULT '',
+    rrule         text NOT NULL DEFAULT '',
+    created_at    timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_events_email_id ON email_events (email_id);
+CREATE INDEX IF NOT EXISTS idx_email_events_dtstart  ON email_events (dtstart, dtend);
+CREATE INDEX IF NOT EXISTS idx_email_events_uid      ON email_events (uid);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_emails_parent_id;
+
+ALTER TABLE emails
+    DROP COLUMN IF EXISTS part_path,
+    DROP COLUMN IF EXISTS parent_id;
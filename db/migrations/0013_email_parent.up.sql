@@ -0,0 +1,5 @@
+ALTER TABLE emails
+    ADD COLUMN IF NOT EXISTS parent_id uuid NULL REFERENCES emails (id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS part_path text NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_emails_parent_id ON emails (parent_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_delivery_status_original_id;
+DROP INDEX IF EXISTS idx_delivery_status_recipient;
+DROP INDEX IF EXISTS idx_delivery_status_email_id;
+DROP TABLE IF EXISTS delivery_status;
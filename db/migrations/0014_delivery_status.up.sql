@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS delivery_status (
+    id                  uuid PRIMARY KEY,
+    email_id            uuid NOT NULL REFERENCES emails (id) ON DELETE CASCADE,
+    original_message_id text NOT NULL DEFAULT '',
+    recipient           text NOT NULL,
+    original_recipient  text NOT NULL DEFAULT '',
+    action              text NOT NULL DEFAULT '',
+    status              text NOT NULL DEFAULT '',
+    diagnostic_code     text NOT NULL DEFAULT '',
+    remote_mta          text NOT NULL DEFAULT '',
+    reporting_mta       text NOT NULL DEFAULT '',
+    bounce_type         text NOT NULL DEFAULT '',
+    standard            boolean NOT NULL DEFAULT false,
+    last_attempt        timestamptz NULL,
+    created_at          timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_delivery_status_email_id    ON delivery_status (email_id);
+CREATE INDEX IF NOT EXISTS idx_delivery_status_recipient   ON delivery_status (lower(recipient));
+CREATE INDEX IF NOT EXISTS idx_delivery_status_original_id ON delivery_status (original_message_id);
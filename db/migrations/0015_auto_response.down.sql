@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_emails_auto_response;
+
+ALTER TABLE emails
+    DROP COLUMN IF EXISTS auto_response;
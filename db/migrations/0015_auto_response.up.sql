@@ -0,0 +1,4 @@
+ALTER TABLE emails
+    ADD COLUMN IF NOT EXISTS auto_response text NOT NULL DEFAULT 'none';
+
+CREATE INDEX IF NOT EXISTS idx_emails_auto_response ON emails (auto_response);
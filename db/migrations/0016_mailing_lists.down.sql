@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_emails_list_id;
+
+ALTER TABLE emails
+    DROP COLUMN IF EXISTS list_id,
+    DROP COLUMN IF EXISTS list,
+    DROP COLUMN IF EXISTS category;
@@ -0,0 +1,6 @@
+ALTER TABLE emails
+    ADD COLUMN IF NOT EXISTS category text NOT NULL DEFAULT 'personal',
+    ADD COLUMN IF NOT EXISTS list jsonb NULL,
+    ADD COLUMN IF NOT EXISTS list_id text NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_emails_list_id ON emails (list_id) WHERE list IS NOT NULL;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_emails_parse_warnings;
+
+ALTER TABLE emails
+    DROP COLUMN IF EXISTS parse_warnings;
@@ -0,0 +1,4 @@
+ALTER TABLE emails
+    ADD COLUMN IF NOT EXISTS parse_warnings jsonb NOT NULL DEFAULT '[]'::jsonb;
+
+CREATE INDEX IF NOT EXISTS idx_emails_parse_warnings ON emails (created_at DESC) WHERE parse_warnings <> '[]'::jsonb;
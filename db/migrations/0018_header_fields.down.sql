@@ -0,0 +1,13 @@
+ALTER TABLE emails
+    ALTER COLUMN headers SET DEFAULT '{}'::jsonb;
+
+UPDATE emails
+SET headers = COALESCE((
+    SELECT jsonb_object_agg(name, vals)
+    FROM (
+        SELECT lower(f->>'name') AS name, string_agg(f->>'value', ', ' ORDER BY i) AS vals
+        FROM jsonb_array_elements(emails.headers) WITH ORDINALITY AS h(f, i)
+        GROUP BY lower(f->>'name')
+    ) g
+), '{}'::jsonb)
+WHERE jsonb_typeof(headers) = 'array';
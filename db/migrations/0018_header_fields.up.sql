@@ -0,0 +1,11 @@
+-- Headers become an ordered array of {name, raw, value}. Rows stored as the
+-- old lowercased name -> joined value object are converted as they are.
+UPDATE emails
+SET headers = COALESCE((
+    SELECT jsonb_agg(jsonb_build_object('name', key, 'raw', value, 'value', value) ORDER BY key)
+    FROM jsonb_each_text(emails.headers)
+), '[]'::jsonb)
+WHERE jsonb_typeof(headers) = 'object';
+
+ALTER TABLE emails
+    ALTER COLUMN headers SET DEFAULT '[]'::jsonb;
@@ -0,0 +1,3 @@
+ALTER TABLE emails
+    DROP COLUMN IF EXISTS date_source,
+    DROP COLUMN IF EXISTS date_tz_offset;
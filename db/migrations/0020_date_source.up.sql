@@ -0,0 +1,5 @@
+ALTER TABLE emails
+    ADD COLUMN IF NOT EXISTS date_tz_offset int NULL,
+    ADD COLUMN IF NOT EXISTS date_source text NOT NULL DEFAULT 'none';
+
+UPDATE emails SET date_source = 'header' WHERE date IS NOT NULL;
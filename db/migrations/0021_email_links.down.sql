@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_email_links_mismatch;
+DROP INDEX IF EXISTS idx_email_links_domain;
+DROP TABLE IF EXISTS email_links;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS email_links (
+    email_id    uuid NOT NULL REFERENCES emails (id) ON DELETE CASCADE,
+    position    integer NOT NULL,
+    url         text NOT NULL,
+    text        text NOT NULL DEFAULT '',
+    domain      text NOT NULL,
+    source      text NOT NULL CHECK (source IN ('text', 'html')),
+    text_domain text NOT NULL DEFAULT '',
+    mismatch    boolean NOT NULL DEFAULT false,
+    PRIMARY KEY (email_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_links_domain   ON email_links (domain);
+CREATE INDEX IF NOT EXISTS idx_email_links_mismatch ON email_links (email_id) WHERE mismatch;
@@ -0,0 +1,2 @@
+ALTER TABLE email_events
+    DROP COLUMN IF EXISTS series_end;
@@ -0,0 +1,4 @@
+-- Recurring events stored before this column was added keep a NULL
+-- series_end and are listed as unbounded, as before.
+ALTER TABLE email_events
+    ADD COLUMN IF NOT EXISTS series_end timestamptz NULL;
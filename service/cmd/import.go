@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Zifeldev/emailback/service/internal/config"
+	"github.com/Zifeldev/emailback/service/internal/db"
+	"github.com/Zifeldev/emailback/service/internal/importer"
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/Zifeldev/emailback/service/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const importUsage = `usage: emailback import [flags] <path>...
+
+Imports Maildir folders (cur/ and new/) and directory trees of .eml files
+directly into the database, using the same configuration environment as the
+server.
+
+flags:
+`
+
+// runImport implements "emailback import" and returns the process exit code.
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	concurrency := fs.Int("concurrency", 4, "number of messages parsed and saved in parallel")
+	checkpoint := fs.String("checkpoint", "", "file recording finished paths; rerun with the same file to resume")
+	dryRun := fs.Bool("dry-run", false, "parse messages without saving them")
+	timeout := fs.Duration("timeout", 30*time.Second, "per-message parse and save timeout")
+	maxSize := fs.Int64("max-size", importer.DefaultMaxSize, "largest message file imported, in bytes")
+	progress := fs.Duration("progress", 5*time.Second, "progress report interval (0 disables)")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), importUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg := config.MustLoad(context.Background())
+	log := logrus.New()
+	log.SetOutput(os.Stderr)
+	if lvl, err := logrus.ParseLevel(cfg.Logger.Level); err == nil {
+		log.SetLevel(lvl)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	im := &importer.Importer{
+		Parser:        newParser(cfg, newDetector(), log),
+		Concurrency:   *concurrency,
+		Timeout:       *timeout,
+		MaxSize:       *maxSize,
+		DryRun:        *dryRun,
+		ProgressEvery: *progress,
+		OnFailure: func(f importer.Failure) {
+			log.WithError(f.Err).WithField("path", f.Path).Warn("import failed")
+		},
+	}
+
+	if !*dryRun {
+		pool, err := db.New(ctx, cfg.Database)
+		if err != nil {
+			log.WithError(err).Error("failed to connect to database")
+			return 1
+		}
+		defer pool.Close()
+		blobs, err := storage.NewLocalStore(cfg.Storage.BlobDir)
+		if err != nil {
+			log.WithError(err).Error("failed to init blob store")
+			return 1
+		}
+		timeoutPool := &db.TimeoutPool{Pool: pool, QueryTimeout: cfg.Database.QueryTimeout}
+		im.Repo = repository.NewBlobEmailRepo(repository.NewPostgresEmailRepo(timeoutPool), blobs)
+	}
+
+	if *checkpoint != "" {
+		cp, err := importer.OpenCheckpoint(*checkpoint)
+		if err != nil {
+			log.WithError(err).Error("failed to open checkpoint")
+			return 1
+		}
+		defer func() {
+			if err := cp.Close(); err != nil {
+				log.WithError(err).Error("failed to write checkpoint")
+			}
+		}()
+		if n := cp.Len(); n > 0 {
+			log.WithField("done", n).Info("resuming from checkpoint")
+		}
+		im.Checkpoint = cp
+	}
+
+	start := time.Now()
+	im.OnProgress = func(s importer.Stats) {
+		printStats(os.Stderr, "progress", s, time.Since(start))
+	}
+
+	stats, err := im.Run(ctx, fs.Args()...)
+	printStats(os.Stderr, "done", stats, time.Since(start))
+	if err != nil {
+		log.WithError(err).Error("import stopped")
+		return 1
+	}
+	if stats.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func printStats(w *os.File, label string, s importer.Stats, elapsed time.Duration) {
+	rate := 0.0
+	if secs := elapsed.Seconds(); secs > 0 {
+		rate = float64(s.Imported+s.Failed) / secs
+	}
+	fmt.Fprintf(w, "%s: found=%d imported=%d failed=%d skipped=%d bytes=%d elapsed=%s rate=%.1f/s\n",
+		label, s.Found, s.Imported, s.Failed, s.Skipped, s.Bytes, elapsed.Round(time.Second), rate)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"net/http"
 	"os"
@@ -13,10 +14,14 @@ import (
 	"github.com/Zifeldev/emailback/service/internal/controllers"
 	"github.com/Zifeldev/emailback/service/internal/db"
 	"github.com/Zifeldev/emailback/service/internal/lang"
+	"github.com/Zifeldev/emailback/service/internal/mailauth"
 	"github.com/Zifeldev/emailback/service/internal/metrics"
 	"github.com/Zifeldev/emailback/service/internal/middleware"
+	"github.com/Zifeldev/emailback/service/internal/pgp"
 	"github.com/Zifeldev/emailback/service/internal/repository"
 	"github.com/Zifeldev/emailback/service/internal/service"
+	"github.com/Zifeldev/emailback/service/internal/smime"
+	"github.com/Zifeldev/emailback/service/internal/storage"
 	"github.com/gin-gonic/gin"
 	"github.com/pemistahl/lingua-go"
 	"github.com/redis/go-redis/v9"
@@ -33,6 +38,10 @@ import (
 // @description     Service for parsing and storing email messages
 // @BasePath        /
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Exit(runImport(os.Args[2:]))
+	}
+
 	cfg := config.MustLoad(context.Background())
 
 	log := logrus.New()
@@ -58,9 +67,15 @@ func main() {
 		QueryTimeout: cfg.Database.QueryTimeout,
 	}
 
-	ld := lang.NewDetector(lingua.English, lingua.Russian, lingua.German)
+	ld := newDetector()
 
-	var emailRepo repository.EmailRepository = repository.NewPostgresEmailRepo(timeoutPool)
+	blobs, err := storage.NewLocalStore(cfg.Storage.BlobDir)
+	if err != nil {
+		log.WithError(err).Fatal("failed to init blob store")
+	}
+
+	pgRepo := repository.NewPostgresEmailRepo(timeoutPool)
+	var emailRepo repository.EmailRepository = repository.NewBlobEmailRepo(pgRepo, blobs)
 
 	var rdb *redis.Client
 	if cfg.Redis.Enabled {
@@ -80,10 +95,7 @@ func main() {
 		cancel()
 	}
 
-	emailParser := service.NewEnmimeParser(service.Options{
-		HTMLToTextLimit: 1 << 20,
-		IncludeHTML:     false,
-	}, ld)
+	emailParser := newParser(cfg, ld, log)
 
 	baseEntry.WithFields(logrus.Fields{
 		"http_addr":        cfg.HTTP.Host,
@@ -106,20 +118,42 @@ func main() {
 		baseEntry.WithField("effective_req_timeout", reqTimeout.String()).
 			Warn("HTTP request timeout was 0; using default")
 	}
-	r.Use(middleware.TimeoutMiddleware(reqTimeout))
+	api := r.Group("", middleware.TimeoutMiddleware(reqTimeout))
 
 	// Swagger UI
-	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	api.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	pc := controllers.NewParserController(emailParser, emailRepo, baseEntry)
+	ac := controllers.NewAttachmentController(pgRepo, blobs, baseEntry)
+	mc := controllers.NewMessageController(pgRepo, blobs, baseEntry)
+	tc := controllers.NewThreadController(emailRepo, pgRepo, baseEntry)
+	ec := controllers.NewEventController(emailRepo, pgRepo, baseEntry)
+	bc := controllers.NewBounceController(emailRepo, pgRepo, baseEntry)
+	lc := controllers.NewListController(pgRepo, baseEntry)
+	rc := controllers.NewRenderController(emailRepo, pgRepo, cfg.Render.RemoteImages, cfg.Render.ImageProxy, baseEntry)
 	hc := controllers.NewHealthController(timeoutPool, rdb, baseEntry, time.Now(), "1.0.0")
 
-	r.GET("/health", middleware.TimeoutMiddleware(2*time.Second), hc.Handle)
+	api.GET("/health", middleware.TimeoutMiddleware(2*time.Second), hc.Handle)
 
-	r.POST("/parse", pc.ParseAndSave)
-	r.POST("/parse/batch", pc.BatchParseAndSave)
-	r.GET("/emails/:id", pc.GetByID)
-	r.GET("/emails", pc.GetAll)
+	api.POST("/parse", pc.ParseAndSave)
+	api.POST("/parse/batch", pc.BatchParseAndSave)
+	api.POST("/parse/preview", pc.Preview)
+	// Streaming upload: outside the per-request timeout group.
+	r.POST("/parse/mbox", middleware.TimeoutMiddleware(cfg.HTTP.StreamTimeout), pc.MboxParseAndSave)
+	api.GET("/emails/:id", pc.GetByID)
+	api.GET("/emails/:id/headers", pc.Headers)
+	api.GET("/emails", pc.GetAll)
+	api.GET("/emails/:id/attachments", ac.List)
+	api.GET("/emails/:id/attachments/:attId", ac.Download)
+	api.GET("/emails/:id/raw", mc.Raw)
+	api.GET("/emails/:id/render", rc.Render)
+	api.GET("/emails/:id/thread", tc.GetEmailThread)
+	api.GET("/threads/:id", tc.GetThread)
+	api.GET("/emails/:id/events", ec.ListEmailEvents)
+	api.GET("/events", ec.List)
+	api.GET("/emails/:id/delivery-status", bc.ListEmailDeliveryStatus)
+	api.GET("/bounces", bc.List)
+	api.GET("/lists", lc.List)
 
 	r.NoRoute(func(c *gin.Context) {
 		c.JSON(404, gin.H{"message": "Not Found"})
@@ -159,3 +193,82 @@ func main() {
 		baseEntry.Info("server exited properly")
 	}
 }
+
+func newDetector() lang.Detector {
+	return lang.NewDetector(lingua.English, lingua.Russian, lingua.German)
+}
+
+// newParser builds the message parser with the authentication and S/MIME
+// checks enabled in cfg.
+func newParser(cfg config.Config, ld lang.Detector, log *logrus.Logger) *service.EnmimeParser {
+	dns := mailauth.NewDNSResolver(nil, cfg.Auth.DNSTimeout)
+	var dkimKeys mailauth.KeyResolver
+	if cfg.Auth.DKIMEnabled {
+		if cfg.Auth.DKIMKeysFile != "" {
+			static, err := mailauth.LoadStaticKeyResolver(cfg.Auth.DKIMKeysFile)
+			if err != nil {
+				log.WithError(err).Fatal("failed to load dkim keys")
+			}
+			dkimKeys = static
+		} else {
+			dkimKeys = mailauth.NewDNSKeyResolver(dns)
+		}
+	}
+
+	var spfDNS mailauth.Resolver
+	if cfg.Auth.SPFEnabled {
+		spfDNS = dns
+	}
+	// The checks run inside the request, so lookups that outlast it leave
+	// nothing of the request's deadline for saving the email.
+	if (cfg.Auth.SPFEnabled || (cfg.Auth.DKIMEnabled && cfg.Auth.DKIMKeysFile == "")) &&
+		cfg.HTTP.RequestTimeout < 2*cfg.Auth.DNSTimeout {
+		log.WithFields(logrus.Fields{
+			"req_timeout": cfg.HTTP.RequestTimeout.String(),
+			"dns_timeout": cfg.Auth.DNSTimeout.String(),
+		}).Warn("DNS authentication enabled with a request timeout shorter than its lookups; raise HTTP_REQUEST_TIMEOUT and item_timeout")
+	}
+
+	var smimeProc *smime.Processor
+	if cfg.Crypto.SMIMEEnabled {
+		roots, err := smime.LoadTrustStore(cfg.Crypto.SMIMETrustStore)
+		if err != nil {
+			log.WithError(err).Fatal("failed to load s/mime trust store")
+		}
+		keys, err := smime.LoadKeys(cfg.Crypto.SMIMEKeysDir)
+		if err != nil {
+			log.WithError(err).Fatal("failed to load s/mime keys")
+		}
+		log.WithField("keys", len(keys)).Info("s/mime enabled")
+		smimeProc = smime.NewProcessor(roots, keys)
+	}
+
+	var pgpProc *pgp.Processor
+	if cfg.Crypto.PGPEnabled {
+		var passphrase []byte
+		if f := cfg.Crypto.PGPPassphraseFile; f != "" {
+			b, err := os.ReadFile(f)
+			if err != nil {
+				log.WithError(err).Fatal("failed to read pgp passphrase")
+			}
+			passphrase = bytes.TrimRight(b, "\r\n")
+		}
+		keyring, err := pgp.LoadKeyrings(cfg.Crypto.PGPKeyrings, passphrase)
+		if err != nil {
+			log.WithError(err).Fatal("failed to load pgp keyrings")
+		}
+		log.WithFields(logrus.Fields{"keys": len(keyring), "decryption_keys": len(keyring.DecryptionKeys())}).Info("pgp enabled")
+		pgpProc = pgp.NewProcessor(keyring)
+	}
+
+	return service.NewEnmimeParser(service.Options{
+		HTMLToTextLimit: 1 << 20,
+		IncludeHTML:     cfg.Parser.IncludeHTML,
+		DKIM:            dkimKeys,
+		DNS:             spfDNS,
+		SMIME:           smimeProc,
+		PGP:             pgpProc,
+		MaxDepth:        cfg.Parser.MaxDepth,
+		RepairCharset:   cfg.Parser.RepairCharset,
+	}, ld)
+}
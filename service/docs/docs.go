@@ -16,6 +16,76 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/bounces": {
+            "get": {
+                "description": "Failed and delayed deliveries reported by bounces, newest first, linked to the original email when it is stored.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "bounces"
+                ],
+                "summary": "List bounces",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Recipient address (case-insensitive)",
+                        "name": "recipient",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "hard",
+                            "soft"
+                        ],
+                        "type": "string",
+                        "description": "Bounce type",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "description": "Offset",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.BouncesListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
         "/emails": {
             "get": {
                 "produces": [
@@ -39,6 +109,56 @@ const docTemplate = `{
                         "description": "Offset",
                         "name": "offset",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Participant address",
+                        "name": "address",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Participant domain",
+                        "name": "domain",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "from",
+                            "sender",
+                            "reply_to",
+                            "to",
+                            "cc",
+                            "bcc"
+                        ],
+                        "type": "string",
+                        "description": "Participant role",
+                        "name": "role",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "none",
+                            "vacation",
+                            "auto-generated",
+                            "list"
+                        ],
+                        "type": "string",
+                        "description": "Automatic response class",
+                        "name": "auto_response",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only emails with (true) or without (false) MIME parse warnings",
+                        "name": "has_warnings",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Emails linking to this host or its subdomains",
+                        "name": "link_domain",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -48,6 +168,15 @@ const docTemplate = `{
                             "$ref": "#/definitions/controllers.EmailsListResponse"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -106,18 +235,83 @@ const docTemplate = `{
                 }
             }
         },
-        "/health": {
+        "/emails/{id}/attachments": {
             "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "health"
+                    "attachments"
+                ],
+                "summary": "List attachments of an email",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Liveness probe",
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.AttachmentsListResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/emails/{id}/attachments/{attId}": {
+            "get": {
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "attachments"
+                ],
+                "summary": "Download an attachment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Attachment ID",
+                        "name": "attId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -128,18 +322,33 @@ const docTemplate = `{
                 }
             }
         },
-        "/healthz": {
+        "/emails/{id}/delivery-status": {
             "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "health"
+                    "bounces"
+                ],
+                "summary": "List the per-recipient results of a bounce",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Database health check",
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.EmailDeliveryStatusResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -147,8 +356,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "503": {
-                        "description": "Service Unavailable",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -159,29 +368,33 @@ const docTemplate = `{
                 }
             }
         },
-        "/parse": {
-            "post": {
-                "description": "Accepts raw EML (text/plain or message/rfc822), parses it and persists to DB",
-                "consumes": [
-                    "text/plain",
-                    "message/rfc822"
-                ],
+        "/emails/{id}/events": {
+            "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "emails"
+                    "events"
+                ],
+                "summary": "List calendar events of an email",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Parse and save an email",
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/repository.EmailEntity"
+                            "$ref": "#/definitions/controllers.EmailEventsResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -200,80 +413,1881 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "controllers.EmailsListResponse": {
-            "type": "object",
-            "properties": {
-                "count": {
-                    "type": "integer"
-                },
-                "items": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/repository.EmailEntity"
+        },
+        "/emails/{id}/headers": {
+            "get": {
+                "description": "Every field in message order, repeated fields such as Received kept separate, with the raw value as sent and the unfolded value with RFC 2047 encoded words decoded.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "emails"
+                ],
+                "summary": "List the header fields of an email",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only fields with this name (case-insensitive)",
+                        "name": "name",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.EmailHeadersResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
-                },
-                "limit": {
-                    "type": "integer"
-                },
-                "offset": {
-                    "type": "integer"
+                }
+            }
+        },
+        "/emails/{id}/raw": {
+            "get": {
+                "description": "Returns the message exactly as it was received: RFC822, or the Outlook .msg file for .msg uploads",
+                "produces": [
+                    "message/rfc822",
+                    "application/vnd.ms-outlook"
+                ],
+                "tags": [
+                    "emails"
+                ],
+                "summary": "Download the original message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/emails/{id}/render": {
+            "get": {
+                "description": "The stored HTML body reduced to an allowlist of tags, attributes and inline styles, with cid: images pointing at the attachment download endpoint and remote images allowed, blocked or proxied. Emails stored without HTML are rendered from their text. A Content-Security-Policy header limits what the page may load.",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "emails"
+                ],
+                "summary": "Render an email as sanitized HTML",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "allow",
+                            "block",
+                            "proxy"
+                        ],
+                        "type": "string",
+                        "description": "Remote images",
+                        "name": "images",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/emails/{id}/thread": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "threads"
+                ],
+                "summary": "Get the conversation an email belongs to",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.ThreadResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/events": {
+            "get": {
+                "description": "Events overlapping [from, to). Recurring events are listed from their first occurrence until their RRULE ends.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "List calendar events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Range start (RFC 3339 or YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Range end, exclusive (RFC 3339 or YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "description": "Offset",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.EventsListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Returns detailed information about the EmailBack API service state, including database, Redis, memory usage, and uptime.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Service health check",
+                "responses": {
+                    "200": {
+                        "description": "Service is healthy",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.HealthResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service is degraded",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/lists": {
+            "get": {
+                "description": "Messages with List-* headers grouped by List-Id (or sender when there is none), most recently seen first, with the latest unsubscribe URIs.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "lists"
+                ],
+                "summary": "List mailing lists and newsletters",
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "description": "Offset",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.MailingListsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/parse": {
+            "post": {
+                "description": "Accepts raw EML (text/plain or message/rfc822), parses it and persists to DB",
+                "consumes": [
+                    "text/plain",
+                    "message/rfc822",
+                    "application/vnd.ms-outlook"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "emails"
+                ],
+                "summary": "Parse and save an email",
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/repository.EmailEntity"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/parse/batch": {
+            "post": {
+                "description": "batch emails parsing.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "emails"
+                ],
+                "summary": "Batch parse and save emails",
+                "parameters": [
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 5,
+                        "description": "Максимум параллельных воркеров (1..100)",
+                        "name": "max_workers",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "500ms",
+                        "description": "Таймаут на один элемент (напр. 500ms, 2s)",
+                        "name": "item_timeout",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Parse only and return previews instead of saving",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Список писем (RFC822 в поле raw)",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/controllers.BatchEmailInput"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.BatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/parse/mbox": {
+            "post": {
+                "description": "Streams an mbox file, splits it on \"From \" lines and parses/saves every message with the batch worker pool.",
+                "consumes": [
+                    "application/mbox",
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "emails"
+                ],
+                "summary": "Parse and save an mbox mailbox",
+                "parameters": [
+                    {
+                        "enum": [
+                            "mboxrd",
+                            "mboxo",
+                            "mboxcl",
+                            "mboxcl2"
+                        ],
+                        "type": "string",
+                        "default": "mboxrd",
+                        "description": "mbox variant",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 5,
+                        "description": "Max parallel workers (1..100)",
+                        "name": "max_workers",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "500ms",
+                        "description": "Per-message timeout (e.g. 500ms, 2s)",
+                        "name": "item_timeout",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.MboxResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/parse/preview": {
+            "post": {
+                "description": "Parses raw EML like POST /parse without saving anything. The response has the full entity including HTML and parse warnings, the MIME tree and what body cleaning removed.",
+                "consumes": [
+                    "text/plain",
+                    "message/rfc822",
+                    "application/vnd.ms-outlook"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "emails"
+                ],
+                "summary": "Preview how an email parses",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.Preview"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/threads/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "threads"
+                ],
+                "summary": "Get a conversation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Thread ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.ThreadResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "charset.Result": {
+            "type": "object",
+            "properties": {
+                "chosen": {
+                    "type": "string"
+                },
+                "declared": {
+                    "description": "from Content-Type, empty when missing",
+                    "type": "string"
+                },
+                "repair": {
+                    "description": "empty when the declared charset was kept",
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.AttachmentsListResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "email_id": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.AttachmentEntity"
+                    }
+                }
+            }
+        },
+        "controllers.BatchEmailInput": {
+            "type": "object",
+            "properties": {
+                "raw": {
+                    "type": "string",
+                    "example": "From: Alice \u003calice@example.com\u003e\r\nTo: Bob \u003cbob@example.com\u003e\r\nMessage-ID: \u003cmsg-1@example.com\u003e\r\nDate: Wed, 30 Oct 2025 18:00:00 +0000\r\nSubject: Test\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\nHello!"
+                }
+            }
+        },
+        "controllers.BatchItemResult": {
+            "type": "object",
+            "properties": {
+                "duration_ms": {
+                    "description": "ms",
+                    "type": "integer"
+                },
+                "email_id": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "message_id": {
+                    "type": "string"
+                },
+                "preview": {
+                    "description": "Preview is the parse result of a dry run; nothing was saved.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/service.Preview"
+                        }
+                    ]
+                },
+                "status": {
+                    "description": "ok|error",
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.BatchResponse": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer"
+                },
+                "processed": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controllers.BatchItemResult"
+                    }
+                },
+                "succeeded": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.BouncesListResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.DeliveryStatusEntity"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.EmailDeliveryStatusResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "email_id": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.DeliveryStatusEntity"
+                    }
+                }
+            }
+        },
+        "controllers.EmailEventsResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "email_id": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.EventEntity"
+                    }
+                }
+            }
+        },
+        "controllers.EmailHeadersResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "email_id": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/mailheader.Field"
+                    }
+                }
+            }
+        },
+        "controllers.EmailsListResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.EmailEntity"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.EventsListResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.EventEntity"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "go_version": {
+                    "type": "string",
+                    "example": "go1.23.2"
+                },
+                "hostname": {
+                    "type": "string",
+                    "example": "emailback-app-1"
+                },
+                "memory": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "num_goroutine": {
+                    "type": "integer",
+                    "example": 18
+                },
+                "service_name": {
+                    "type": "string",
+                    "example": "emailback"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                },
+                "timestamp": {
+                    "type": "string",
+                    "example": "2025-10-30T10:15:00Z"
+                },
+                "uptime": {
+                    "type": "string",
+                    "example": "5m42s"
+                },
+                "version": {
+                    "type": "string",
+                    "example": "v1.0.0"
+                }
+            }
+        },
+        "controllers.MailingListsResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.MailingListEntity"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.MboxResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "format": {
+                    "type": "string"
+                },
+                "processed": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controllers.BatchItemResult"
+                    }
+                },
+                "succeeded": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.ThreadResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "messages": {
+                    "description": "reading order",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/thread.Message"
+                    }
+                },
+                "thread_id": {
+                    "type": "string"
+                },
+                "tree": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/thread.Node"
+                    }
+                }
+            }
+        },
+        "ical.Attendee": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "partstat": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "rsvp": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "links.Link": {
+            "type": "object",
+            "properties": {
+                "domain": {
+                    "description": "host of URL, lower-cased",
+                    "type": "string"
+                },
+                "mismatch": {
+                    "type": "boolean"
+                },
+                "source": {
+                    "description": "SourceText or SourceHTML",
+                    "type": "string"
+                },
+                "text": {
+                    "description": "anchor text; empty for links in plain text",
+                    "type": "string"
+                },
+                "text_domain": {
+                    "description": "TextDomain is the domain shown in Text, if any; Mismatch is set when it\nbelongs to another organisation than Domain.",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "mailauth.DKIMResult": {
+            "type": "object",
+            "properties": {
+                "algorithm": {
+                    "type": "string"
+                },
+                "domain": {
+                    "type": "string"
+                },
+                "identity": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "result": {
+                    "type": "string"
+                },
+                "selector": {
+                    "type": "string"
+                }
+            }
+        },
+        "mailauth.DMARCResult": {
+            "type": "object",
+            "properties": {
+                "applied_policy": {
+                    "description": "disposition after pct sampling",
+                    "type": "string"
+                },
+                "dkim_aligned": {
+                    "type": "boolean"
+                },
+                "domain": {
+                    "description": "RFC5322.From domain",
+                    "type": "string"
+                },
+                "pct": {
+                    "type": "integer"
+                },
+                "policy": {
+                    "description": "published p= (or sp= for subdomains)",
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "record": {
+                    "description": "domain the policy was found at",
+                    "type": "string"
+                },
+                "result": {
+                    "description": "pass, fail, none, temperror, permerror",
+                    "type": "string"
+                },
+                "spf_aligned": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "mailauth.SPFResult": {
+            "type": "object",
+            "properties": {
+                "domain": {
+                    "description": "domain whose policy was evaluated",
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "mail_from": {
+                    "description": "envelope sender, or postmaster@HELO",
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "result": {
+                    "type": "string"
+                }
+            }
+        },
+        "mailheader.Field": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "raw": {
+                    "description": "value as sent, folding included",
+                    "type": "string"
+                },
+                "value": {
+                    "description": "unfolded, encoded words decoded",
+                    "type": "string"
+                }
+            }
+        },
+        "mailinglist.Info": {
+            "type": "object",
+            "properties": {
+                "archive": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "description": "ID is the List-Id identifier without angle brackets, e.g.\n\"dev.lists.example.com\"; Name is its optional description.",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "one_click_unsubscribe": {
+                    "description": "OneClick is set when List-Unsubscribe-Post allows unsubscribing with a\nsingle POST to the https URI in Unsubscribe (RFC 8058).",
+                    "type": "boolean"
+                },
+                "post": {
+                    "description": "empty when List-Post is \"NO\"",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "unsubscribe": {
+                    "description": "mailto: and http(s): URIs in header order",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "pgp.Result": {
+            "type": "object",
+            "properties": {
+                "decrypted": {
+                    "type": "boolean"
+                },
+                "encrypted": {
+                    "type": "boolean"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "inline": {
+                    "type": "boolean"
+                },
+                "recipients": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "signatures": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pgp.Signature"
+                    }
+                },
+                "signed": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "pgp.Signature": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "string"
+                },
+                "fingerprint": {
+                    "type": "string"
+                },
+                "key_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                },
+                "validity": {
+                    "type": "string"
+                }
+            }
+        },
+        "received.Hop": {
+            "type": "object",
+            "properties": {
+                "by": {
+                    "type": "string"
+                },
+                "delay_seconds": {
+                    "description": "Delay is the time in seconds since the previous hop (or the Date header\nfor the first hop). It can be negative when server clocks disagree.",
+                    "type": "number"
+                },
+                "for": {
+                    "type": "string"
+                },
+                "from": {
+                    "description": "name announced in HELO/EHLO",
+                    "type": "string"
+                },
+                "from_rdns": {
+                    "description": "reverse DNS name recorded by the receiver",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "protocol": {
+                    "description": "SMTP, ESMTPS, LMTP, ...",
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "tls": {
+                    "description": "protocol version and cipher, when recorded",
+                    "type": "string"
+                },
+                "via": {
+                    "type": "string"
+                }
+            }
+        },
+        "repository.Address": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "domain": {
+                    "type": "string"
+                },
+                "local_part": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "repository.AttachmentEntity": {
+            "type": "object",
+            "properties": {
+                "content_id": {
+                    "type": "string"
+                },
+                "content_type": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "disposition": {
+                    "type": "string"
+                },
+                "email_id": {
+                    "type": "string"
+                },
+                "filename": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "sha256": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "sniffed_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "repository.ChildEmail": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "message_id": {
+                    "type": "string"
+                },
+                "part_path": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                }
+            }
+        },
+        "repository.DeliveryStatusEntity": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "bounce_type": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "diagnostic_code": {
+                    "type": "string"
+                },
+                "email_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_attempt": {
+                    "type": "string"
+                },
+                "original_email_id": {
+                    "type": "string"
+                },
+                "original_message_id": {
+                    "type": "string"
+                },
+                "original_recipient": {
+                    "type": "string"
+                },
+                "recipient": {
+                    "type": "string"
+                },
+                "remote_mta": {
+                    "type": "string"
+                },
+                "reporting_mta": {
+                    "type": "string"
+                },
+                "standard": {
+                    "type": "boolean"
+                },
+                "status": {
+                    "type": "string"
                 }
             }
         },
         "repository.EmailEntity": {
             "type": "object",
             "properties": {
+                "attachments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.AttachmentEntity"
+                    }
+                },
+                "auto_response": {
+                    "description": "AutoResponse is one of AutoResponses: vacation notices and other\nmachine-generated mail are told apart from mail written by a person.",
+                    "type": "string"
+                },
+                "bcc": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.Address"
+                    }
+                },
+                "category": {
+                    "description": "Category is one of Categories; List holds the parsed List-* fields.",
+                    "type": "string"
+                },
+                "cc": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.Address"
+                    }
+                },
+                "charset": {
+                    "description": "Charset records the body's declared charset and the one its text was\nfinally decoded with.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/charset.Result"
+                        }
+                    ]
+                },
+                "children": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.ChildEmail"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "date_source": {
+                    "type": "string"
+                },
+                "date_tz_offset": {
+                    "description": "DateTZOffset is the sender's UTC offset in minutes, from the Date\nheader; Date is returned in that zone. DateSource is one of DateSources.",
+                    "type": "integer"
+                },
+                "delivery_status": {
+                    "description": "DeliveryStatus holds the per-recipient results when the message is a bounce.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.DeliveryStatusEntity"
+                    }
+                },
+                "dkim": {
+                    "description": "DKIM holds one verification result per DKIM-Signature header.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/mailauth.DKIMResult"
+                    }
+                },
+                "dmarc": {
+                    "$ref": "#/definitions/mailauth.DMARCResult"
+                },
+                "events": {
+                    "description": "Events are the calendar invitations carried by the message.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.EventEntity"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "from_address": {
+                    "description": "Structured participants; From/To above keep the bare addresses for compatibility.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/repository.Address"
+                        }
+                    ]
+                },
+                "headers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/mailheader.Field"
+                    }
+                },
+                "hops": {
+                    "description": "Hops is the Received chain, oldest first.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/received.Hop"
+                    }
+                },
+                "html": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "in_reply_to": {
+                    "type": "string"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "language_confidence": {
+                    "type": "number"
+                },
+                "links": {
+                    "description": "Links are the hyperlinks of the text and HTML bodies, which cleaning\nremoves from Text.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/links.Link"
+                    }
+                },
+                "list": {
+                    "$ref": "#/definitions/mailinglist.Info"
+                },
+                "message_id": {
+                    "type": "string"
+                },
+                "metrics": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "origin_ip": {
+                    "type": "string"
+                },
+                "parent_id": {
+                    "description": "ParentID is set on messages that arrived attached (message/rfc822) to\nanother email; PartPath is the MIME part they were found in.",
+                    "type": "string"
+                },
+                "parse_warnings": {
+                    "description": "ParseWarnings are the defects the MIME parser recovered from; mail with\nany is likely to be mis-decoded.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.ParseWarning"
+                    }
+                },
+                "part_path": {
+                    "type": "string"
+                },
+                "pgp": {
+                    "description": "PGP does the same for OpenPGP/MIME layers and inline armored blocks.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/pgp.Result"
+                        }
+                    ]
+                },
+                "raw_size": {
+                    "type": "integer"
+                },
+                "references": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "reply_to": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.Address"
+                    }
+                },
+                "sender": {
+                    "$ref": "#/definitions/repository.Address"
+                },
+                "smime": {
+                    "description": "SMIME describes the signature and encryption layers that were removed\nbefore parsing; nil for ordinary mail.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/smime.Result"
+                        }
+                    ]
+                },
+                "spf": {
+                    "$ref": "#/definitions/mailauth.SPFResult"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "thread_id": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "to_addresses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.Address"
+                    }
+                },
+                "transit_seconds": {
+                    "type": "number"
+                }
+            }
+        },
+        "repository.EventEntity": {
+            "type": "object",
+            "properties": {
+                "all_day": {
+                    "type": "boolean"
+                },
+                "attendees": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/ical.Attendee"
+                    }
+                },
                 "created_at": {
                     "type": "string"
                 },
-                "date": {
+                "description": {
                     "type": "string"
                 },
-                "from": {
+                "email_id": {
                     "type": "string"
                 },
-                "headers": {
-                    "type": "object",
-                    "additionalProperties": {
+                "end": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "location": {
+                    "type": "string"
+                },
+                "method": {
+                    "type": "string"
+                },
+                "organizer": {
+                    "$ref": "#/definitions/ical.Attendee"
+                },
+                "recurrence_id": {
+                    "type": "string"
+                },
+                "rrule": {
+                    "type": "string"
+                },
+                "sequence": {
+                    "type": "integer"
+                },
+                "start": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "summary": {
+                    "type": "string"
+                },
+                "tzid": {
+                    "type": "string"
+                },
+                "uid": {
+                    "type": "string"
+                }
+            }
+        },
+        "repository.MailingListEntity": {
+            "type": "object",
+            "properties": {
+                "archive": {
+                    "type": "array",
+                    "items": {
                         "type": "string"
                     }
                 },
-                "html": {
+                "first_seen": {
                     "type": "string"
                 },
-                "id": {
+                "last_seen": {
                     "type": "string"
                 },
-                "language": {
+                "list_id": {
                     "type": "string"
                 },
-                "language_confidence": {
-                    "type": "number"
+                "messages": {
+                    "type": "integer"
                 },
-                "message_id": {
+                "name": {
                     "type": "string"
                 },
-                "metrics": {
-                    "type": "object",
-                    "additionalProperties": true
+                "one_click_uri": {
+                    "description": "OneClickURI is set when the latest message allows RFC 8058 one-click\nunsubscribe: a POST with body \"List-Unsubscribe=One-Click\".",
+                    "type": "string"
                 },
-                "raw_size": {
+                "senders": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "unsubscribe": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "repository.ParseWarning": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string"
+                },
+                "part_path": {
+                    "type": "string"
+                },
+                "severity": {
+                    "description": "SeverityError or SeverityWarning",
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.Cleaning": {
+            "type": "object",
+            "properties": {
+                "body_source": {
+                    "description": "text, html or empty",
+                    "type": "string"
+                },
+                "emails": {
+                    "type": "integer"
+                },
+                "forward_headers": {
+                    "type": "integer"
+                },
+                "header_lines": {
+                    "type": "integer"
+                },
+                "html": {
+                    "description": "body was converted from HTML",
+                    "type": "boolean"
+                },
+                "input_chars": {
+                    "type": "integer"
+                },
+                "output_chars": {
+                    "type": "integer"
+                },
+                "quoted_lines": {
+                    "type": "integer"
+                },
+                "reply_headers": {
+                    "description": "\"On ... wrote:\" style lines",
+                    "type": "integer"
+                },
+                "signature_chars": {
+                    "description": "SignatureChars is how much trailing text was cut at a sign-off or\nsignature delimiter.",
+                    "type": "integer"
+                },
+                "tags_stripped": {
+                    "description": "HTML parsing failed; tags were cut out instead",
+                    "type": "boolean"
+                },
+                "urls": {
+                    "type": "integer"
+                }
+            }
+        },
+        "service.MIMEPart": {
+            "type": "object",
+            "properties": {
+                "charset": {
+                    "type": "string"
+                },
+                "children": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.MIMEPart"
+                    }
+                },
+                "content_type": {
+                    "type": "string"
+                },
+                "disposition": {
+                    "type": "string"
+                },
+                "filename": {
+                    "type": "string"
+                },
+                "part_id": {
+                    "type": "string"
+                },
+                "size": {
                     "type": "integer"
+                }
+            }
+        },
+        "service.Preview": {
+            "type": "object",
+            "properties": {
+                "cleaning": {
+                    "$ref": "#/definitions/service.Cleaning"
+                },
+                "email": {
+                    "$ref": "#/definitions/repository.EmailEntity"
+                },
+                "embedded": {
+                    "description": "Embedded are the attached messages that would be stored as children.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.EmailEntity"
+                    }
+                },
+                "mime_tree": {
+                    "$ref": "#/definitions/service.MIMEPart"
+                }
+            }
+        },
+        "smime.Result": {
+            "type": "object",
+            "properties": {
+                "decrypted": {
+                    "type": "boolean"
+                },
+                "encrypted": {
+                    "type": "boolean"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "signed": {
+                    "type": "boolean"
+                },
+                "signers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/smime.Signer"
+                    }
+                }
+            }
+        },
+        "smime.Signer": {
+            "type": "object",
+            "properties": {
+                "emails": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "fingerprint_sha256": {
+                    "type": "string"
+                },
+                "issuer": {
+                    "type": "string"
+                },
+                "not_after": {
+                    "type": "string"
+                },
+                "not_before": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "result": {
+                    "type": "string"
+                },
+                "serial": {
+                    "type": "string"
+                },
+                "signing_time": {
+                    "type": "string"
                 },
                 "subject": {
                     "type": "string"
+                }
+            }
+        },
+        "thread.Message": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "type": "string"
                 },
-                "text": {
+                "from": {
                     "type": "string"
                 },
-                "to": {
+                "id": {
+                    "type": "string"
+                },
+                "in_reply_to": {
+                    "type": "string"
+                },
+                "message_id": {
+                    "type": "string"
+                },
+                "references": {
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
+                },
+                "subject": {
+                    "type": "string"
+                }
+            }
+        },
+        "thread.Node": {
+            "type": "object",
+            "properties": {
+                "children": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/thread.Node"
+                    }
+                },
+                "message": {
+                    "$ref": "#/definitions/thread.Message"
                 }
             }
         }
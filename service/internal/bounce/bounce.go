@@ -0,0 +1,318 @@
+// Package bounce extracts per-recipient delivery results from RFC 3464
+// delivery status notifications and from the plain-text bounces of MTAs that
+// do not send them.
+package bounce
+
+import (
+	"bufio"
+	"bytes"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jhillyerd/enmime"
+)
+
+// Bounce types. Hard bounces will not succeed on retry; soft bounces are
+// temporary (full mailbox, greylisting, delivery still being attempted).
+const (
+	TypeHard = "hard"
+	TypeSoft = "soft"
+)
+
+// Recipient is the delivery result for one recipient of the original message.
+type Recipient struct {
+	Recipient         string     `json:"recipient"`
+	OriginalRecipient string     `json:"original_recipient,omitempty"`
+	Action            string     `json:"action"` // failed, delayed, delivered, relayed, expanded
+	Status            string     `json:"status,omitempty"`
+	DiagnosticCode    string     `json:"diagnostic_code,omitempty"`
+	RemoteMTA         string     `json:"remote_mta,omitempty"`
+	LastAttempt       *time.Time `json:"last_attempt,omitempty"`
+	Type              string     `json:"type,omitempty"` // TypeHard, TypeSoft or empty for successful delivery
+}
+
+// Report is a parsed bounce.
+type Report struct {
+	// Standard is true for a message/delivery-status report and false for a
+	// bounce recognised from its text.
+	Standard          bool        `json:"standard"`
+	ReportingMTA      string      `json:"reporting_mta,omitempty"`
+	OriginalMessageID string      `json:"original_message_id,omitempty"`
+	Recipients        []Recipient `json:"recipients"`
+}
+
+var (
+	reStatus      = regexp.MustCompile(`\b([245])\.(\d{1,3})\.(\d{1,3})\b`)
+	reSMTPCode    = regexp.MustCompile(`(?:^|[\s:(])([45]\d\d)(?:[\s-]|$)`)
+	reMessageID   = regexp.MustCompile(`(?im)^[ \t]*Message-Id:[ \t]*<?([^<>\s]+@[^<>\s]+)>?`)
+	reAddress     = regexp.MustCompile(`<?([A-Za-z0-9.!#$%&'*+/=?^_{|}~-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)+)>?`)
+	reRemoteHost  = regexp.MustCompile(`(?i)\bhost ([a-z0-9][a-z0-9.-]*\.[a-z]{2,}|\d{1,3}(?:\.\d{1,3}){3})\b`)
+	reQmailRemote = regexp.MustCompile(`(?m)^(\d{1,3}(?:\.\d{1,3}){3}) does not like recipient`)
+
+	reBounceSender  = regexp.MustCompile(`(?i)^(mailer-daemon|postmaster|mail-daemon|mailerdaemon)@`)
+	reBounceSubject = regexp.MustCompile(`(?i)undeliver|delivery status notification|delivery (failure|has failed|problem|incomplete)|mail delivery (failed|failure|subsystem)|failure notice|returned mail|could not be delivered`)
+	reDelayed       = regexp.MustCompile(`(?i)delayed|still undelivered|not yet been delivered|will (keep|continue) (trying|to try)`)
+	// Bounce texts quote the original message after one of these lines;
+	// addresses below them belong to the copy, not to the failure report.
+	reOriginalCopy = regexp.MustCompile(`(?im)^.*(below this line is a copy|this is a copy of the message|original message follows|original message ---|message headers follow|returned message).*$`)
+)
+
+// Parse returns the bounce carried by env, or nil when it is not one.
+func Parse(env *enmime.Envelope) *Report {
+	if env == nil {
+		return nil
+	}
+	if rep := parseDSN(env); rep != nil {
+		return rep
+	}
+	return parseText(env)
+}
+
+// parseDSN reads the first message/delivery-status part: a block of
+// per-message fields followed by one block per recipient.
+func parseDSN(env *enmime.Envelope) *Report {
+	status := findPart(env.Root, "message/delivery-status", "message/global-delivery-status")
+	if status == nil {
+		return nil
+	}
+	blocks := fieldBlocks(status.Content)
+	if len(blocks) == 0 {
+		return nil
+	}
+	rep := &Report{Standard: true, OriginalMessageID: originalMessageID(env)}
+	if blocks[0].Get("Final-Recipient") == "" {
+		rep.ReportingMTA = typedValue(blocks[0].Get("Reporting-MTA"))
+		blocks = blocks[1:]
+	}
+	for _, b := range blocks {
+		r := Recipient{
+			Recipient:         typedValue(b.Get("Final-Recipient")),
+			OriginalRecipient: typedValue(b.Get("Original-Recipient")),
+			Action:            strings.ToLower(strings.TrimSpace(b.Get("Action"))),
+			Status:            reStatus.FindString(b.Get("Status")),
+			DiagnosticCode:    typedValue(b.Get("Diagnostic-Code")),
+			RemoteMTA:         typedValue(b.Get("Remote-MTA")),
+		}
+		if r.Recipient == "" {
+			continue
+		}
+		if t, err := mail.ParseDate(b.Get("Last-Attempt-Date")); err == nil {
+			t = t.UTC()
+			r.LastAttempt = &t
+		}
+		r.Type = Classify(r.Action, r.Status, r.DiagnosticCode)
+		rep.Recipients = append(rep.Recipients, r)
+	}
+	if len(rep.Recipients) == 0 {
+		return nil
+	}
+	return rep
+}
+
+// parseText recognises a bounce by its sender or subject and takes each
+// paragraph of the explanation that names an address together with an SMTP
+// reply as a failed recipient (Exim, qmail, Postfix without DSN support).
+func parseText(env *enmime.Envelope) *Report {
+	from := env.GetHeader("From")
+	if a, err := mail.ParseAddress(from); err == nil {
+		from = a.Address
+	}
+	failed := env.GetHeader("X-Failed-Recipients")
+	if !reBounceSender.MatchString(from) && !reBounceSubject.MatchString(env.GetHeader("Subject")) && failed == "" {
+		return nil
+	}
+
+	text := env.Text
+	if loc := reOriginalCopy.FindStringIndex(text); loc != nil {
+		text = text[:loc[0]]
+	}
+	action := "failed"
+	if reDelayed.MatchString(env.GetHeader("Subject")) || reDelayed.MatchString(text) {
+		action = "delayed"
+	}
+
+	rep := &Report{OriginalMessageID: originalMessageID(env)}
+	seen := make(map[string]bool)
+	for _, para := range paragraphs(text) {
+		m := reAddress.FindStringSubmatch(para)
+		if m == nil || seen[strings.ToLower(m[1])] {
+			continue
+		}
+		status := reStatus.FindString(para)
+		if status == "" && reSMTPCode.FindStringSubmatch(para) == nil {
+			continue
+		}
+		seen[strings.ToLower(m[1])] = true
+		rep.Recipients = append(rep.Recipients, textRecipient(m[1], action, status, para))
+	}
+	// Exim lists the failed addresses in a header even when its text is
+	// localised beyond recognition.
+	for _, addr := range strings.Split(failed, ",") {
+		addr = strings.Trim(strings.TrimSpace(addr), "<>")
+		if addr == "" || seen[strings.ToLower(addr)] {
+			continue
+		}
+		seen[strings.ToLower(addr)] = true
+		rep.Recipients = append(rep.Recipients, textRecipient(addr, action, "", ""))
+	}
+	if len(rep.Recipients) == 0 {
+		return nil
+	}
+	return rep
+}
+
+func textRecipient(addr, action, status, para string) Recipient {
+	r := Recipient{
+		Recipient:      addr,
+		Action:         action,
+		Status:         status,
+		DiagnosticCode: diagnosticLine(para),
+	}
+	if m := reRemoteHost.FindStringSubmatch(para); m != nil {
+		r.RemoteMTA = m[1]
+	} else if m := reQmailRemote.FindStringSubmatch(para); m != nil {
+		r.RemoteMTA = m[1]
+	}
+	r.Type = Classify(r.Action, r.Status, r.DiagnosticCode)
+	return r
+}
+
+// Classify maps a recipient's result to TypeHard, TypeSoft or "" for a
+// successful delivery. The enhanced status code wins over the SMTP reply in
+// the diagnostic; a full mailbox is soft even when reported as permanent.
+func Classify(action, status, diagnostic string) string {
+	switch strings.ToLower(action) {
+	case "delivered", "relayed", "expanded":
+		return ""
+	case "delayed":
+		return TypeSoft
+	}
+	if status == "" {
+		status = reStatus.FindString(diagnostic)
+	}
+	switch {
+	case status == "5.2.2":
+		return TypeSoft
+	case strings.HasPrefix(status, "5."):
+		return TypeHard
+	case strings.HasPrefix(status, "4."):
+		return TypeSoft
+	}
+	if m := reSMTPCode.FindStringSubmatch(diagnostic); m != nil {
+		switch {
+		case m[1] == "552":
+			return TypeSoft
+		case m[1][0] == '5':
+			return TypeHard
+		default:
+			return TypeSoft
+		}
+	}
+	if strings.EqualFold(action, "failed") {
+		return TypeHard
+	}
+	return ""
+}
+
+// originalMessageID finds the Message-ID of the bounced message in the
+// returned copy or headers, or failing that in the quoted text.
+func originalMessageID(env *enmime.Envelope) string {
+	if p := findPart(env.Root, "message/rfc822", "message/global", "text/rfc822-headers",
+		"message/rfc822-headers", "message/global-headers"); p != nil {
+		if h, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(withBlankLine(p.Content)))).ReadMIMEHeader(); err == nil || len(h) > 0 {
+			if id := strings.Trim(h.Get("Message-Id"), " <>"); id != "" {
+				return id
+			}
+		}
+	}
+	if m := reMessageID.FindStringSubmatch(env.Text); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func findPart(p *enmime.Part, types ...string) *enmime.Part {
+	for ; p != nil; p = p.NextSibling {
+		for _, t := range types {
+			if strings.EqualFold(p.ContentType, t) {
+				return p
+			}
+		}
+		if found := findPart(p.FirstChild, types...); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// fieldBlocks splits a delivery-status body into its header-style blocks.
+func fieldBlocks(data []byte) []textproto.MIMEHeader {
+	r := textproto.NewReader(bufio.NewReader(bytes.NewReader(withBlankLine(bytes.TrimSpace(data)))))
+	var out []textproto.MIMEHeader
+	for {
+		h, err := r.ReadMIMEHeader()
+		if len(h) > 0 {
+			out = append(out, h)
+		}
+		if err != nil {
+			return out
+		}
+		// Tolerate extra blank lines between blocks.
+		for {
+			b, err := r.R.Peek(1)
+			if err != nil {
+				return out
+			}
+			if b[0] != '\r' && b[0] != '\n' {
+				break
+			}
+			_, _ = r.R.ReadByte()
+		}
+	}
+}
+
+// withBlankLine terminates data with an empty line, which ReadMIMEHeader
+// needs to see the end of the last block.
+func withBlankLine(data []byte) []byte {
+	out := make([]byte, 0, len(data)+4)
+	out = append(out, data...)
+	return append(out, "\r\n\r\n"...)
+}
+
+// typedValue strips the address or diagnostic type of a DSN field such as
+// "rfc822; bob@example.org" or "smtp; 550 5.1.1 User unknown".
+func typedValue(v string) string {
+	v = strings.TrimSpace(v)
+	if i := strings.IndexByte(v, ';'); i >= 0 && !strings.ContainsAny(v[:i], " <@") {
+		v = strings.TrimSpace(v[i+1:])
+	}
+	return strings.Join(strings.Fields(v), " ")
+}
+
+func paragraphs(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var out []string
+	for _, p := range strings.Split(text, "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// diagnosticLine returns the part of a bounce paragraph that carries the
+// remote server's reply, starting at the SMTP code.
+func diagnosticLine(para string) string {
+	flat := strings.Join(strings.Fields(para), " ")
+	loc := reSMTPCode.FindStringSubmatchIndex(flat)
+	if loc == nil {
+		if loc = reStatus.FindStringIndex(flat); loc == nil {
+			return ""
+		}
+		return flat[loc[0]:]
+	}
+	return flat[loc[2]:]
+}
@@ -0,0 +1,176 @@
+package bounce
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jhillyerd/enmime"
+)
+
+func envelope(t *testing.T, raw string) *enmime.Envelope {
+	t.Helper()
+	env, err := enmime.ReadEnvelope(bytes.NewReader([]byte(strings.ReplaceAll(raw, "\n", "\r\n"))))
+	if err != nil {
+		t.Fatalf("read envelope: %v", err)
+	}
+	return env
+}
+
+func TestParse_DeliveryStatusReport(t *testing.T) {
+	env := envelope(t, `From: MAILER-DAEMON@mail.example.com (Mail Delivery System)
+To: news@example.com
+Subject: Undelivered Mail Returned to Sender
+MIME-Version: 1.0
+Content-Type: multipart/report; report-type=delivery-status; boundary="b"
+
+--b
+Content-Type: text/plain
+
+I'm sorry to have to inform you that your message could not
+be delivered to one or more recipients.
+
+--b
+Content-Type: message/delivery-status
+
+Reporting-MTA: dns; mail.example.com
+Arrival-Date: Mon, 15 Jul 2024 10:00:00 +0200
+
+Final-Recipient: rfc822; bob@example.org
+Original-Recipient: rfc822;Bob@Example.org
+Action: failed
+Status: 5.1.1
+Remote-MTA: dns; mx.example.org
+Diagnostic-Code: smtp; 550 5.1.1 <bob@example.org>: Recipient address
+    rejected: User unknown
+
+Final-Recipient: rfc822; carol@example.net
+Action: delayed
+Status: 4.4.1
+Last-Attempt-Date: Mon, 15 Jul 2024 10:05:00 +0200
+
+--b
+Content-Type: text/rfc822-headers
+
+From: news@example.com
+Subject: July newsletter
+Message-ID: <nl-42@example.com>
+
+--b--
+`)
+	rep := Parse(env)
+	if rep == nil || !rep.Standard {
+		t.Fatalf("expected standard report, got %+v", rep)
+	}
+	if rep.ReportingMTA != "mail.example.com" || rep.OriginalMessageID != "nl-42@example.com" {
+		t.Fatalf("unexpected report fields %+v", rep)
+	}
+	if len(rep.Recipients) != 2 {
+		t.Fatalf("expected 2 recipients, got %+v", rep.Recipients)
+	}
+	bob := rep.Recipients[0]
+	if bob.Recipient != "bob@example.org" || bob.OriginalRecipient != "Bob@Example.org" || bob.Action != "failed" ||
+		bob.Status != "5.1.1" || bob.RemoteMTA != "mx.example.org" || bob.Type != TypeHard {
+		t.Fatalf("unexpected recipient %+v", bob)
+	}
+	if !strings.HasPrefix(bob.DiagnosticCode, "550 5.1.1") || !strings.HasSuffix(bob.DiagnosticCode, "User unknown") {
+		t.Fatalf("unexpected diagnostic %q", bob.DiagnosticCode)
+	}
+	carol := rep.Recipients[1]
+	if carol.Type != TypeSoft || carol.LastAttempt == nil || carol.LastAttempt.Hour() != 8 {
+		t.Fatalf("unexpected delayed recipient %+v", carol)
+	}
+}
+
+func TestParse_EximText(t *testing.T) {
+	env := envelope(t, `From: Mail Delivery System <Mailer-Daemon@mx.example.com>
+To: news@example.com
+Subject: Mail delivery failed: returning message to sender
+X-Failed-Recipients: bob@example.org
+
+This message was created automatically by mail delivery software.
+
+A message that you sent could not be delivered to one or more of its
+recipients. This is a permanent error. The following address(es) failed:
+
+  bob@example.org
+    host mx.example.org [192.0.2.1]
+    SMTP error from remote mail server after RCPT TO:<bob@example.org>:
+    550 5.1.1 <bob@example.org>: Recipient address rejected: User unknown
+
+------ This is a copy of the message, including all the headers. ------
+
+Message-ID: <nl-42@example.com>
+From: news@example.com
+To: alice@example.net
+`)
+	rep := Parse(env)
+	if rep == nil || rep.Standard {
+		t.Fatalf("expected text bounce, got %+v", rep)
+	}
+	if rep.OriginalMessageID != "nl-42@example.com" || len(rep.Recipients) != 1 {
+		t.Fatalf("unexpected report %+v", rep)
+	}
+	r := rep.Recipients[0]
+	if r.Recipient != "bob@example.org" || r.Status != "5.1.1" || r.RemoteMTA != "mx.example.org" || r.Type != TypeHard {
+		t.Fatalf("unexpected recipient %+v", r)
+	}
+	if !strings.HasPrefix(r.DiagnosticCode, "550 5.1.1") {
+		t.Fatalf("unexpected diagnostic %q", r.DiagnosticCode)
+	}
+}
+
+func TestParse_QmailText(t *testing.T) {
+	env := envelope(t, `From: MAILER-DAEMON@example.com
+To: news@example.com
+Subject: failure notice
+
+Hi. This is the qmail-send program at example.com.
+I'm afraid I wasn't able to deliver your message to the following addresses.
+This is a permanent error; I've given up. Sorry it didn't work out.
+
+<dave@example.org>:
+192.0.2.7 does not like recipient.
+Remote host said: 552 Mailbox full
+Giving up on 192.0.2.7.
+
+--- Below this line is a copy of the message.
+
+To: eve@example.net
+`)
+	rep := Parse(env)
+	if rep == nil || len(rep.Recipients) != 1 {
+		t.Fatalf("expected one recipient, got %+v", rep)
+	}
+	r := rep.Recipients[0]
+	if r.Recipient != "dave@example.org" || r.RemoteMTA != "192.0.2.7" || r.Type != TypeSoft || r.DiagnosticCode != "552 Mailbox full Giving up on 192.0.2.7." {
+		t.Fatalf("unexpected recipient %+v", r)
+	}
+}
+
+func TestParse_NotABounce(t *testing.T) {
+	env := envelope(t, "From: bob@example.org\nSubject: Lunch\n\nCall me at 555 1234, bob@example.org\n")
+	if rep := Parse(env); rep != nil {
+		t.Fatalf("expected nil, got %+v", rep)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		action, status, diag, want string
+	}{
+		{"failed", "5.1.1", "", TypeHard},
+		{"failed", "5.2.2", "", TypeSoft},
+		{"failed", "4.4.7", "", TypeSoft},
+		{"failed", "", "smtp; 421 try again later", TypeSoft},
+		{"failed", "", "550 no such user", TypeHard},
+		{"failed", "", "", TypeHard},
+		{"delayed", "5.0.0", "", TypeSoft},
+		{"delivered", "2.0.0", "", ""},
+	}
+	for _, c := range cases {
+		if got := Classify(c.action, c.status, c.diag); got != c.want {
+			t.Errorf("Classify(%q, %q, %q) = %q, want %q", c.action, c.status, c.diag, got, c.want)
+		}
+	}
+}
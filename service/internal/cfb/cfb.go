@@ -0,0 +1,341 @@
+// Package cfb reads Microsoft Compound File Binary containers (MS-CFB), the
+// OLE "structured storage" format behind Outlook .msg files.
+package cfb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// Signature is the 8-byte magic every compound file starts with.
+var Signature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// Special sector numbers.
+const (
+	maxRegSect = 0xFFFFFFFA
+	endOfChain = 0xFFFFFFFE
+	noStream   = 0xFFFFFFFF
+)
+
+// Directory entry object types.
+const (
+	typeStorage = 1
+	typeStream  = 2
+	typeRoot    = 5
+)
+
+const (
+	headerSize   = 512
+	dirEntrySize = 128
+	headerDIFAT  = 109
+	// miniStreamCutoff is the only value MS-CFB allows in the header.
+	miniStreamCutoff = 4096
+)
+
+var (
+	ErrNotCFB = errors.New("cfb: bad signature")
+	errShort  = errors.New("cfb: truncated file")
+	errChain  = errors.New("cfb: corrupt sector chain")
+)
+
+// IsCFB reports whether data starts with the compound file signature.
+func IsCFB(data []byte) bool {
+	return bytes.HasPrefix(data, Signature)
+}
+
+// Entry is a storage or stream in the directory tree.
+type Entry struct {
+	Name     string
+	children []*Entry
+	storage  bool
+	start    uint32
+	size     uint64
+}
+
+// IsStorage reports whether the entry can have children.
+func (e *Entry) IsStorage() bool { return e.storage }
+
+// Size is the stream length in bytes.
+func (e *Entry) Size() int64 { return int64(e.size) }
+
+// Children lists the direct children of a storage.
+func (e *Entry) Children() []*Entry { return e.children }
+
+// Child finds a direct child by name. Names compare case-insensitively, as
+// they do in the format.
+func (e *Entry) Child(name string) *Entry {
+	for _, c := range e.children {
+		if strings.EqualFold(c.Name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// File is a parsed compound file. The whole container is held in memory.
+type File struct {
+	data       []byte
+	sectorSize int
+	miniSize   int
+	cutoff     uint64
+	fat        []uint32
+	miniFAT    []uint32
+	miniStream []byte
+	root       *Entry
+}
+
+// Open parses the header, allocation tables and directory of data.
+func Open(data []byte) (*File, error) {
+	if !IsCFB(data) {
+		return nil, ErrNotCFB
+	}
+	if len(data) < headerSize {
+		return nil, errShort
+	}
+	le := binary.LittleEndian
+	sectorShift := le.Uint16(data[0x1E:])
+	miniShift := le.Uint16(data[0x20:])
+	if sectorShift != 9 && sectorShift != 12 || miniShift != 6 {
+		return nil, fmt.Errorf("cfb: unsupported sector shift %d/%d", sectorShift, miniShift)
+	}
+	f := &File{
+		data:       data,
+		sectorSize: 1 << sectorShift,
+		miniSize:   1 << miniShift,
+		cutoff:     uint64(le.Uint32(data[0x38:])),
+	}
+	if f.cutoff != miniStreamCutoff {
+		return nil, fmt.Errorf("cfb: unsupported mini stream cutoff %d", f.cutoff)
+	}
+
+	if err := f.readFAT(); err != nil {
+		return nil, err
+	}
+
+	dir, err := f.chain(le.Uint32(data[0x30:]), -1)
+	if err != nil {
+		return nil, fmt.Errorf("cfb: directory: %w", err)
+	}
+	entries, err := parseDirectory(dir)
+	if err != nil {
+		return nil, err
+	}
+	f.root = entries[0]
+
+	if first := le.Uint32(data[0x3C:]); le.Uint32(data[0x40:]) > 0 && first <= maxRegSect {
+		b, err := f.chain(first, -1)
+		if err != nil {
+			return nil, fmt.Errorf("cfb: mini fat: %w", err)
+		}
+		f.miniFAT = u32s(b)
+	}
+	if f.root.size > 0 {
+		f.miniStream, err = f.chain(f.root.start, int64(f.root.size))
+		if err != nil {
+			return nil, fmt.Errorf("cfb: mini stream: %w", err)
+		}
+	}
+	return f, nil
+}
+
+// Root returns the root storage.
+func (f *File) Root() *Entry { return f.root }
+
+// ReadStream returns the contents of a stream entry.
+func (f *File) ReadStream(e *Entry) ([]byte, error) {
+	if e == nil || e.storage {
+		return nil, errors.New("cfb: not a stream")
+	}
+	if e.size == 0 {
+		return nil, nil
+	}
+	if e.size < f.cutoff {
+		return f.miniChain(e.start, int64(e.size))
+	}
+	return f.chain(e.start, int64(e.size))
+}
+
+// readFAT collects the FAT sector list from the header and DIFAT chain and
+// loads the table.
+func (f *File) readFAT() error {
+	le := binary.LittleEndian
+	numFAT := int(le.Uint32(f.data[0x2C:]))
+	if numFAT > len(f.data)/f.sectorSize {
+		return errShort
+	}
+	sectors := make([]uint32, 0, numFAT)
+	for i := 0; i < headerDIFAT && len(sectors) < numFAT; i++ {
+		sectors = append(sectors, le.Uint32(f.data[0x4C+4*i:]))
+	}
+	next := le.Uint32(f.data[0x44:])
+	perSector := f.sectorSize/4 - 1
+	for hops := 0; len(sectors) < numFAT && next <= maxRegSect; hops++ {
+		if hops > len(f.data)/f.sectorSize {
+			return errChain
+		}
+		b, err := f.sector(next)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < perSector && len(sectors) < numFAT; i++ {
+			sectors = append(sectors, le.Uint32(b[4*i:]))
+		}
+		next = le.Uint32(b[4*perSector:])
+	}
+	if len(sectors) < numFAT {
+		return errChain
+	}
+
+	f.fat = make([]uint32, 0, numFAT*f.sectorSize/4)
+	for _, s := range sectors {
+		b, err := f.sector(s)
+		if err != nil {
+			return err
+		}
+		f.fat = append(f.fat, u32s(b)...)
+	}
+	return nil
+}
+
+func (f *File) sector(n uint32) ([]byte, error) {
+	off := (int64(n) + 1) * int64(f.sectorSize)
+	if n > maxRegSect || off+int64(f.sectorSize) > int64(len(f.data)) {
+		return nil, errShort
+	}
+	return f.data[off : off+int64(f.sectorSize)], nil
+}
+
+// chain follows the FAT from start and concatenates the sectors, truncated
+// to size when size >= 0.
+func (f *File) chain(start uint32, size int64) ([]byte, error) {
+	if size > int64(len(f.data)) {
+		return nil, errShort
+	}
+	var out []byte
+	for n, hops := start, 0; n != endOfChain; hops++ {
+		if int(n) >= len(f.fat) || hops > len(f.fat) {
+			return nil, errChain
+		}
+		b, err := f.sector(n)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b...)
+		if size >= 0 && int64(len(out)) >= size {
+			return out[:size], nil
+		}
+		n = f.fat[n]
+	}
+	if size > int64(len(out)) {
+		return nil, errShort
+	}
+	return out, nil
+}
+
+func (f *File) miniChain(start uint32, size int64) ([]byte, error) {
+	if size > int64(len(f.miniStream)) {
+		return nil, errShort
+	}
+	out := make([]byte, 0, size)
+	for n, hops := start, 0; int64(len(out)) < size; hops++ {
+		if int(n) >= len(f.miniFAT) || hops > len(f.miniFAT) {
+			return nil, errChain
+		}
+		off := int(n) * f.miniSize
+		if off+f.miniSize > len(f.miniStream) {
+			return nil, errShort
+		}
+		out = append(out, f.miniStream[off:off+f.miniSize]...)
+		n = f.miniFAT[n]
+	}
+	return out[:size], nil
+}
+
+type dirEntry struct {
+	entry              *Entry
+	typ                byte
+	left, right, child uint32
+}
+
+// parseDirectory decodes the directory entries and links each storage to its
+// children, which the format keeps in a red-black tree of siblings.
+func parseDirectory(dir []byte) ([]*Entry, error) {
+	le := binary.LittleEndian
+	raw := make([]dirEntry, 0, len(dir)/dirEntrySize)
+	for off := 0; off+dirEntrySize <= len(dir); off += dirEntrySize {
+		b := dir[off : off+dirEntrySize]
+		nameLen := int(le.Uint16(b[0x40:]))
+		if nameLen > 64 {
+			nameLen = 64
+		}
+		u := make([]uint16, 0, nameLen/2)
+		for i := 0; i+1 < nameLen; i += 2 {
+			if c := le.Uint16(b[i:]); c != 0 {
+				u = append(u, c)
+			}
+		}
+		typ := b[0x42]
+		raw = append(raw, dirEntry{
+			entry: &Entry{
+				Name:    string(utf16.Decode(u)),
+				storage: typ == typeStorage || typ == typeRoot,
+				start:   le.Uint32(b[0x74:]),
+				size:    le.Uint64(b[0x78:]),
+			},
+			typ:   typ,
+			left:  le.Uint32(b[0x44:]),
+			right: le.Uint32(b[0x48:]),
+			child: le.Uint32(b[0x4C:]),
+		})
+	}
+	if len(raw) == 0 || raw[0].typ != typeRoot {
+		return nil, errors.New("cfb: missing root entry")
+	}
+
+	visited := make([]bool, len(raw))
+	var collect func(parent *Entry, id uint32) error
+	collect = func(parent *Entry, id uint32) error {
+		if id == noStream {
+			return nil
+		}
+		if int(id) >= len(raw) || visited[id] {
+			return errors.New("cfb: corrupt directory tree")
+		}
+		visited[id] = true
+		d := raw[id]
+		if err := collect(parent, d.left); err != nil {
+			return err
+		}
+		if d.typ == typeStorage || d.typ == typeStream {
+			parent.children = append(parent.children, d.entry)
+			if d.typ == typeStorage {
+				if err := collect(d.entry, d.child); err != nil {
+					return err
+				}
+			}
+		}
+		return collect(parent, d.right)
+	}
+	visited[0] = true
+	if err := collect(raw[0].entry, raw[0].child); err != nil {
+		return nil, err
+	}
+
+	out := make([]*Entry, len(raw))
+	for i, d := range raw {
+		out[i] = d.entry
+	}
+	return out, nil
+}
+
+func u32s(b []byte) []uint32 {
+	out := make([]uint32, len(b)/4)
+	for i := range out {
+		out[i] = binary.LittleEndian.Uint32(b[4*i:])
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+package cfb_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/Zifeldev/emailback/service/internal/cfb"
+	"github.com/Zifeldev/emailback/service/internal/cfb/cfbtest"
+)
+
+func TestOpen(t *testing.T) {
+	small := []byte("mini stream payload")
+	large := bytes.Repeat([]byte("0123456789abcdef"), 700) // over the 4096 cutoff
+	data := cfbtest.Build(
+		cfbtest.Stream("small", small),
+		cfbtest.Storage("dir",
+			cfbtest.Stream("large", large),
+			cfbtest.Stream("empty", nil),
+		),
+	)
+	if !cfb.IsCFB(data) {
+		t.Fatalf("signature not detected")
+	}
+	f, err := cfb.Open(data)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	root := f.Root()
+	if got := len(root.Children()); got != 2 {
+		t.Fatalf("want 2 root children, got %d", got)
+	}
+	b, err := f.ReadStream(root.Child("SMALL"))
+	if err != nil || !bytes.Equal(b, small) {
+		t.Fatalf("small stream: %q, %v", b, err)
+	}
+	dir := root.Child("dir")
+	if dir == nil || !dir.IsStorage() {
+		t.Fatalf("missing storage")
+	}
+	b, err = f.ReadStream(dir.Child("large"))
+	if err != nil || !bytes.Equal(b, large) {
+		t.Fatalf("large stream: len=%d, %v", len(b), err)
+	}
+	if b, err := f.ReadStream(dir.Child("empty")); err != nil || len(b) != 0 {
+		t.Fatalf("empty stream: %q, %v", b, err)
+	}
+	if _, err := f.ReadStream(dir); err == nil {
+		t.Fatalf("reading a storage should fail")
+	}
+}
+
+func TestOpen_Errors(t *testing.T) {
+	if _, err := cfb.Open([]byte("PK\x03\x04")); err != cfb.ErrNotCFB {
+		t.Fatalf("want ErrNotCFB, got %v", err)
+	}
+	data := cfbtest.Build(cfbtest.Stream("s", bytes.Repeat([]byte{1}, 5000)))
+	f, err := cfb.Open(data[:len(data)-1024])
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := f.ReadStream(f.Root().Child("s")); err == nil {
+		t.Fatalf("expected error for truncated stream")
+	}
+}
+
+func TestOpen_MalformedHeader(t *testing.T) {
+	le := binary.LittleEndian
+	build := func() []byte {
+		return cfbtest.Build(cfbtest.Stream("small", []byte("tiny")), cfbtest.Stream("large", bytes.Repeat([]byte{1}, 5000)))
+	}
+
+	data := build()
+	le.PutUint32(data[0x38:], 0xFFFFFFFF)
+	if _, err := cfb.Open(data); err == nil {
+		t.Fatalf("expected error for a mini stream cutoff other than 4096")
+	}
+
+	// Directory entries claiming sizes far beyond the file must fail
+	// before anything of that size is allocated.
+	for _, tc := range []struct {
+		name string
+		size uint32
+	}{{"small", 4095}, {"large", 0xFFFFFFF0}} {
+		data := build()
+		dirOff := (int(le.Uint32(data[0x30:])) + 1) * 512
+		f, err := cfb.Open(data)
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		idx := -1
+		for i, c := range f.Root().Children() {
+			if c.Name == tc.name {
+				idx = i + 1 // entry 0 is the root
+			}
+		}
+		le.PutUint32(data[dirOff+idx*128+0x78:], tc.size)
+		f, err = cfb.Open(data)
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		e := f.Root().Child(tc.name)
+		if e.Size() != int64(tc.size) {
+			t.Fatalf("%s: size not patched: %d", tc.name, e.Size())
+		}
+		if _, err := f.ReadStream(e); err == nil {
+			t.Fatalf("%s: expected error for a stream size of %d", tc.name, tc.size)
+		}
+	}
+}
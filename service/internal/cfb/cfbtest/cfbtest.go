@@ -0,0 +1,223 @@
+// Package cfbtest builds small compound files for tests.
+package cfbtest
+
+import (
+	"encoding/binary"
+	"unicode/utf16"
+)
+
+const (
+	sectorSize = 512
+	miniSize   = 64
+	cutoff     = 4096
+	endOfChain = 0xFFFFFFFE
+	fatSect    = 0xFFFFFFFD
+	freeSect   = 0xFFFFFFFF
+	noStream   = 0xFFFFFFFF
+)
+
+// Node is a storage (Children may be empty) or a stream (Data).
+type Node struct {
+	Name     string
+	Data     []byte
+	Children []*Node
+	storage  bool
+}
+
+// Storage returns a storage node.
+func Storage(name string, children ...*Node) *Node {
+	return &Node{Name: name, Children: children, storage: true}
+}
+
+// Stream returns a stream node.
+func Stream(name string, data []byte) *Node {
+	return &Node{Name: name, Data: data}
+}
+
+type entry struct {
+	node               *Node
+	typ                byte
+	left, right, child uint32
+	start              uint32
+	size               uint64
+}
+
+// Build lays out the given nodes under the root storage as a version 3
+// compound file. Streams under the cutoff go to the mini stream, the rest to
+// regular sectors.
+func Build(children ...*Node) []byte {
+	entries := []*entry{{node: &Node{Name: "Root Entry"}, typ: 5, left: noStream, right: noStream, child: noStream}}
+	var link func(parent *entry, kids []*Node)
+	link = func(parent *entry, kids []*Node) {
+		var prev *entry
+		for _, k := range kids {
+			e := &entry{node: k, typ: 2, left: noStream, right: noStream, child: noStream}
+			if k.storage {
+				e.typ = 1
+			}
+			id := uint32(len(entries))
+			entries = append(entries, e)
+			if prev == nil {
+				parent.child = id
+			} else {
+				prev.right = id
+			}
+			prev = e
+			if k.storage {
+				link(e, k.Children)
+			}
+		}
+	}
+	link(entries[0], children)
+
+	// Mini stream allocation.
+	var mini []byte
+	var miniFAT []uint32
+	var big []*entry
+	for _, e := range entries[1:] {
+		if e.typ != 2 {
+			continue
+		}
+		e.size = uint64(len(e.node.Data))
+		if e.size == 0 {
+			e.start = endOfChain
+			continue
+		}
+		if e.size >= cutoff {
+			big = append(big, e)
+			continue
+		}
+		e.start = uint32(len(miniFAT))
+		n := (len(e.node.Data) + miniSize - 1) / miniSize
+		for i := 0; i < n; i++ {
+			next := uint32(len(miniFAT) + 1)
+			if i == n-1 {
+				next = endOfChain
+			}
+			miniFAT = append(miniFAT, next)
+		}
+		mini = append(mini, pad(e.node.Data, miniSize)...)
+	}
+
+	var dir []byte
+	for len(entries)%(sectorSize/128) != 0 {
+		entries = append(entries, &entry{node: &Node{}, left: noStream, right: noStream, child: noStream})
+	}
+	sectors := func(n int) int { return (n + sectorSize - 1) / sectorSize }
+	dirSectors := len(entries) * 128 / sectorSize
+	miniFATSectors := sectors(len(miniFAT) * 4)
+	miniSectors := sectors(len(mini))
+	bigSectors := 0
+	for _, e := range big {
+		bigSectors += sectors(int(e.size))
+	}
+	rest := dirSectors + miniFATSectors + miniSectors + bigSectors
+	fatSectors := 1
+	for fatSectors*sectorSize/4 < rest+fatSectors {
+		fatSectors++
+	}
+
+	fat := make([]uint32, 0, fatSectors*sectorSize/4)
+	for i := 0; i < fatSectors; i++ {
+		fat = append(fat, fatSect)
+	}
+	alloc := func(n int) uint32 {
+		if n == 0 {
+			return endOfChain
+		}
+		start := uint32(len(fat))
+		for i := 0; i < n; i++ {
+			next := uint32(len(fat) + 1)
+			if i == n-1 {
+				next = endOfChain
+			}
+			fat = append(fat, next)
+		}
+		return start
+	}
+	dirStart := alloc(dirSectors)
+	miniFATStart := alloc(miniFATSectors)
+	entries[0].start = alloc(miniSectors)
+	entries[0].size = uint64(len(mini))
+	for _, e := range big {
+		e.start = alloc(sectors(int(e.size)))
+	}
+	for len(fat) < fatSectors*sectorSize/4 {
+		fat = append(fat, freeSect)
+	}
+
+	for _, e := range entries {
+		dir = append(dir, dirEntry(e)...)
+	}
+
+	le := binary.LittleEndian
+	hdr := make([]byte, sectorSize)
+	copy(hdr, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1})
+	le.PutUint16(hdr[0x18:], 0x3E)
+	le.PutUint16(hdr[0x1A:], 3)
+	le.PutUint16(hdr[0x1C:], 0xFFFE)
+	le.PutUint16(hdr[0x1E:], 9)
+	le.PutUint16(hdr[0x20:], 6)
+	le.PutUint32(hdr[0x2C:], uint32(fatSectors))
+	le.PutUint32(hdr[0x30:], dirStart)
+	le.PutUint32(hdr[0x38:], cutoff)
+	le.PutUint32(hdr[0x3C:], miniFATStart)
+	le.PutUint32(hdr[0x40:], uint32(miniFATSectors))
+	le.PutUint32(hdr[0x44:], endOfChain)
+	for i := 0; i < 109; i++ {
+		v := uint32(freeSect)
+		if i < fatSectors {
+			v = uint32(i)
+		}
+		le.PutUint32(hdr[0x4C+4*i:], v)
+	}
+
+	out := hdr
+	out = append(out, u32s(fat)...)
+	out = append(out, dir...)
+	for len(miniFAT)%(sectorSize/4) != 0 {
+		miniFAT = append(miniFAT, freeSect)
+	}
+	out = append(out, u32s(miniFAT)...)
+	out = append(out, pad(mini, sectorSize)...)
+	for _, e := range big {
+		out = append(out, pad(e.node.Data, sectorSize)...)
+	}
+	return out
+}
+
+func dirEntry(e *entry) []byte {
+	le := binary.LittleEndian
+	b := make([]byte, 128)
+	if e.typ != 0 {
+		name := utf16.Encode([]rune(e.node.Name))
+		for i, c := range name {
+			le.PutUint16(b[2*i:], c)
+		}
+		le.PutUint16(b[0x40:], uint16(2*len(name)+2))
+	}
+	b[0x42] = e.typ
+	b[0x43] = 1 // black
+	le.PutUint32(b[0x44:], e.left)
+	le.PutUint32(b[0x48:], e.right)
+	le.PutUint32(b[0x4C:], e.child)
+	le.PutUint32(b[0x74:], e.start)
+	le.PutUint64(b[0x78:], e.size)
+	return b
+}
+
+func pad(b []byte, n int) []byte {
+	out := append([]byte(nil), b...)
+	for len(out)%n != 0 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+func u32s(v []uint32) []byte {
+	out := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(out[4*i:], x)
+	}
+	return out
+}
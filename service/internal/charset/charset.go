@@ -0,0 +1,222 @@
+// Package charset repairs message bodies whose declared charset does not
+// match their bytes: undeclared or us-ascii parts carrying CP1251 or KOI8-R,
+// Cyrillic labelled as Latin-1, and UTF-8 that was decoded twice.
+package charset
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	// RepairGuessed: the part had no usable charset for its bytes.
+	RepairGuessed = "guessed"
+	// RepairRedecoded: the declared charset decoded, but another one reads better.
+	RepairRedecoded = "redecoded"
+	// RepairDoubleEncoded: UTF-8 bytes had been decoded as Latin-1/CP1252.
+	RepairDoubleEncoded = "double-encoded"
+)
+
+// Result records how the charset of a body was settled.
+type Result struct {
+	Declared string `json:"declared"` // from Content-Type, empty when missing
+	Chosen   string `json:"chosen"`
+	Repair   string `json:"repair,omitempty"` // empty when the declared charset was kept
+}
+
+// candidates are tried in order; earlier ones win ties.
+var candidates = []struct {
+	name string
+	enc  encoding.Encoding
+}{
+	{"windows-1251", charmap.Windows1251},
+	{"koi8-r", charmap.KOI8R},
+	{"windows-1252", charmap.Windows1252},
+	{"iso-8859-5", charmap.ISO8859_5},
+	{"ibm866", charmap.CodePage866},
+}
+
+// Fix checks text, the decoding of a body part under its declared charset,
+// and returns it re-decoded when the declaration is evidently wrong. Parts
+// without a charset arrive as raw bytes and us-ascii parts as Latin-1, so
+// both are always guessed. With repair set, text that decoded cleanly is
+// also re-read when a Cyrillic charset or a second UTF-8 pass fits better.
+func Fix(text, declared string, repair bool) (string, Result) {
+	declared = strings.ToLower(strings.TrimSpace(declared))
+	res := Result{Declared: declared, Chosen: declared}
+	if res.Chosen == "" {
+		res.Chosen = "us-ascii"
+	}
+	if !hasNonASCII(text) {
+		return text, res
+	}
+
+	switch {
+	case !utf8.ValidString(text):
+		out, name := guess([]byte(text))
+		res.Chosen, res.Repair = name, RepairGuessed
+		return out, res
+	case declared == "" || declared == "us-ascii" || declared == "ascii":
+		// enmime passes valid UTF-8 through and reads 8-bit bytes as Latin-1.
+		raw, ok := reencode(text)
+		if !ok {
+			res.Chosen = "utf-8"
+			return text, res
+		}
+		out, name := guess(raw)
+		if out == text {
+			// Latin text reads the same either way; valid UTF-8 needs no repair.
+			res.Chosen = "utf-8"
+			return text, res
+		}
+		res.Chosen, res.Repair = name, RepairGuessed
+		return out, res
+	case !repair:
+		return text, res
+	}
+
+	best, bestScore := text, score(text)
+	if raw, ok := reencode(text); ok {
+		if utf8.Valid(raw) {
+			if s := string(raw); score(s) > bestScore {
+				best, bestScore = s, score(s)
+				res.Chosen, res.Repair = "utf-8", RepairDoubleEncoded
+			}
+		} else if isLatin(declared) {
+			if s, name := guess(raw); score(s) > bestScore {
+				best = s
+				res.Chosen, res.Repair = name, RepairRedecoded
+			}
+		}
+	}
+	return best, res
+}
+
+// guess decodes raw with the candidate that reads most like real text.
+// Non-ASCII bytes that form valid UTF-8 are almost never anything else.
+func guess(raw []byte) (string, string) {
+	if utf8.Valid(raw) {
+		return string(raw), "utf-8"
+	}
+	best, bestName, bestScore := "", "", 0
+	for i, c := range candidates {
+		s, err := c.enc.NewDecoder().Bytes(raw)
+		if err != nil {
+			continue
+		}
+		if sc := score(string(s)); i == 0 || sc > bestScore {
+			best, bestName, bestScore = string(s), c.name, sc
+		}
+	}
+	return best, bestName
+}
+
+// reencode turns text decoded as Latin-1 or CP1252 back into its bytes.
+// It fails for runes neither charset has, which rules out a mis-decoding.
+func reencode(text string) ([]byte, bool) {
+	enc := charmap.Windows1252.NewEncoder()
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		if r < 0x100 {
+			out = append(out, byte(r))
+			continue
+		}
+		b, err := enc.String(string(r))
+		if err != nil || len(b) != 1 {
+			return nil, false
+		}
+		out = append(out, b[0])
+	}
+	return out, true
+}
+
+func isLatin(cs string) bool {
+	switch cs {
+	case "iso-8859-1", "latin1", "iso-8859-15", "windows-1252", "cp1252":
+		return true
+	}
+	return false
+}
+
+func hasNonASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return true
+		}
+	}
+	return false
+}
+
+// russianBonus weighs lowercase Russian letters by how common they are:
+// 3 for the most frequent third, 1 for the rarest.
+var russianBonus = func() map[rune]int {
+	letters := []rune("оеаинтсрвлкмдпуяыьгзбчйхжшюцщэфъё")
+	m := make(map[rune]int, len(letters))
+	for i, r := range letters {
+		m[r] = 3 - i*3/len(letters)
+	}
+	return m
+}()
+
+// score rates how much s reads like natural text. Only non-ASCII runes are
+// scored, since every candidate decodes ASCII the same way: common Russian
+// letters in Cyrillic words count up; control characters, mid-word capitals,
+// mixed-script words and runs of accented Latin letters, the marks of
+// mojibake, count down.
+func score(s string) int {
+	rs := []rune(s)
+	total := 0
+	for i, r := range rs {
+		if r < 0x80 {
+			continue
+		}
+		var prev, next rune
+		if i > 0 {
+			prev = rs[i-1]
+		}
+		if i+1 < len(rs) {
+			next = rs[i+1]
+		}
+		switch {
+		case r == utf8.RuneError || unicode.IsControl(r):
+			total -= 10
+		case unicode.Is(unicode.Cyrillic, r):
+			switch {
+			case isASCIILetter(prev) || isASCIILetter(next):
+				total -= 2
+			case unicode.IsUpper(r) && unicode.IsLower(prev):
+				total -= 3
+			case unicode.IsUpper(r) && unicode.IsLetter(prev):
+				// all-caps word: neutral
+			default:
+				if bonus, ok := russianBonus[unicode.ToLower(r)]; ok {
+					total += bonus
+				} else {
+					total-- // not Russian: Serbian, Ukrainian or mojibake
+				}
+			}
+		case unicode.Is(unicode.Latin, r):
+			if isNonASCIILetter(prev) || isNonASCIILetter(next) {
+				total--
+			} else {
+				total++
+			}
+		case unicode.IsSpace(r) || strings.ContainsRune("«»—–“”„‘’…№€•", r):
+		default:
+			total -= 2
+		}
+	}
+	return total
+}
+
+func isASCIILetter(r rune) bool {
+	return r < 0x80 && unicode.IsLetter(r)
+}
+
+func isNonASCIILetter(r rune) bool {
+	return r >= 0x80 && unicode.IsLetter(r)
+}
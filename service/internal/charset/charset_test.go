@@ -0,0 +1,108 @@
+package charset
+
+import (
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+const russian = "Привет! Как дела? Высылаю отчёт за прошлую неделю, посмотри, пожалуйста."
+
+func encode(t *testing.T, cm *charmap.Charmap, s string) string {
+	t.Helper()
+	b, err := cm.NewEncoder().String(s)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return b
+}
+
+// latin1 decodes b the way enmime reads 8-bit bytes in a us-ascii part.
+func latin1(b string) string {
+	s, _ := charmap.ISO8859_1.NewDecoder().String(b)
+	return s
+}
+
+func TestFix_UndeclaredBytes(t *testing.T) {
+	for _, tc := range []struct {
+		cm   *charmap.Charmap
+		name string
+	}{
+		{charmap.Windows1251, "windows-1251"},
+		{charmap.KOI8R, "koi8-r"},
+		{charmap.CodePage866, "ibm866"},
+	} {
+		got, res := Fix(encode(t, tc.cm, russian), "", false)
+		if got != russian {
+			t.Fatalf("%s: got %q", tc.name, got)
+		}
+		if res.Declared != "" || res.Chosen != tc.name || res.Repair != RepairGuessed {
+			t.Fatalf("%s: unexpected result %+v", tc.name, res)
+		}
+	}
+}
+
+func TestFix_MislabeledUSASCII(t *testing.T) {
+	got, res := Fix(latin1(encode(t, charmap.KOI8R, russian)), "US-ASCII", false)
+	if got != russian || res.Declared != "us-ascii" || res.Chosen != "koi8-r" || res.Repair != RepairGuessed {
+		t.Fatalf("got %q %+v", got, res)
+	}
+
+	// UTF-8 under a us-ascii label is kept as UTF-8.
+	got, res = Fix(latin1(russian), "us-ascii", false)
+	if got != russian || res.Chosen != "utf-8" {
+		t.Fatalf("got %q %+v", got, res)
+	}
+
+	// So is undeclared Latin text that is already valid UTF-8.
+	german := "Schöne Grüße aus München"
+	for _, declared := range []string{"", "us-ascii"} {
+		if got, res := Fix(german, declared, true); got != german || res.Chosen != "utf-8" || res.Repair != "" {
+			t.Fatalf("%q: got %q %+v", declared, got, res)
+		}
+	}
+}
+
+func TestFix_RepairLatinLabel(t *testing.T) {
+	mojibake := latin1(encode(t, charmap.Windows1251, russian))
+	if got, res := Fix(mojibake, "iso-8859-1", false); got != mojibake || res.Repair != "" {
+		t.Fatalf("without repair the declared charset must be kept: %q %+v", got, res)
+	}
+	got, res := Fix(mojibake, "iso-8859-1", true)
+	if got != russian || res.Chosen != "windows-1251" || res.Repair != RepairRedecoded {
+		t.Fatalf("got %q %+v", got, res)
+	}
+
+	// Genuine Latin-1 text stays as it is.
+	german := "Schöne Grüße aus München, bis nächste Woche."
+	if got, res := Fix(german, "iso-8859-1", true); got != german || res.Repair != "" {
+		t.Fatalf("german text changed: %q %+v", got, res)
+	}
+}
+
+func TestFix_DoubleEncodedUTF8(t *testing.T) {
+	for _, tc := range []struct {
+		want string
+		cm   *charmap.Charmap
+	}{
+		{russian, charmap.ISO8859_1},
+		{"Café crème, naïve résumé", charmap.Windows1252},
+	} {
+		want := tc.want
+		twice, _ := tc.cm.NewDecoder().String(want)
+		got, res := Fix(twice, "utf-8", true)
+		if got != want || res.Chosen != "utf-8" || res.Repair != RepairDoubleEncoded {
+			t.Fatalf("got %q %+v", got, res)
+		}
+		if got, _ := Fix(want, "utf-8", true); got != want {
+			t.Fatalf("correct utf-8 changed: %q", got)
+		}
+	}
+}
+
+func TestFix_ASCII(t *testing.T) {
+	got, res := Fix("plain text", "", true)
+	if got != "plain text" || res.Chosen != "us-ascii" || res.Repair != "" {
+		t.Fatalf("got %q %+v", got, res)
+	}
+}
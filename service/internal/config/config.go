@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -28,6 +29,7 @@ type HTTPConfig struct {
 	Host            string
 	ShutdownTimeout time.Duration
 	RequestTimeout  time.Duration
+	StreamTimeout   time.Duration // streaming uploads such as /parse/mbox
 }
 
 type LoggerConfig struct {
@@ -43,12 +45,50 @@ type RedisConfig struct {
 	TTL      time.Duration
 }
 
+type StorageConfig struct {
+	BlobDir string
+}
+
+type AuthConfig struct {
+	DKIMEnabled  bool
+	DKIMKeysFile string // static key records; empty means DNS
+	SPFEnabled   bool   // SPF and DMARC
+	DNSTimeout   time.Duration
+}
+
+type CryptoConfig struct {
+	SMIMEEnabled    bool
+	SMIMETrustStore string // PEM bundle or directory; empty means system roots
+	SMIMEKeysDir    string // recipient certificates and private keys (PEM)
+
+	PGPEnabled        bool
+	PGPKeyrings       []string // keyring files or directories, armored or binary
+	PGPPassphraseFile string   // unlocks encrypted private keys; empty means none
+}
+
+type ParserConfig struct {
+	MaxDepth int // levels of attached messages parsed into child emails
+	// RepairCharset re-decodes Latin-1-labelled Cyrillic and double-encoded UTF-8.
+	RepairCharset bool
+	IncludeHTML   bool // store the sanitized HTML body, needed by /render
+}
+
+type RenderConfig struct {
+	RemoteImages string // allow, block or proxy
+	ImageProxy   string // prefix for proxied image URLs; empty blocks them
+}
+
 type Config struct {
 	Strict   bool
 	Database DatabaseConfig
 	HTTP     HTTPConfig
 	Logger   LoggerConfig
 	Redis    RedisConfig
+	Storage  StorageConfig
+	Auth     AuthConfig
+	Crypto   CryptoConfig
+	Parser   ParserConfig
+	Render   RenderConfig
 }
 
 func MustLoad(_ context.Context) Config {
@@ -58,6 +98,7 @@ func MustLoad(_ context.Context) Config {
 	cfg.HTTP = HTTPConfig{
 		Host:            getEnv("HTTP_HOST", ":8080"),
 		ShutdownTimeout: getEnvDuration("HTTP_SHUTDOWN_TIMEOUT", 10*time.Second),
+		StreamTimeout:   getEnvDuration("HTTP_STREAM_TIMEOUT", 30*time.Minute),
 	}
 	if cfg.Strict {
 		cfg.Database = DatabaseConfig{
@@ -115,6 +156,33 @@ func MustLoad(_ context.Context) Config {
 	cfg.Logger = LoggerConfig{
 		Level: getEnv("LOGGER_LEVEL", "info"),
 	}
+	cfg.Storage = StorageConfig{
+		BlobDir: getEnv("BLOB_DIR", "./data/blobs"),
+	}
+	cfg.Auth = AuthConfig{
+		DKIMEnabled:  getEnvBool("DKIM_ENABLED", false),
+		DKIMKeysFile: getEnv("DKIM_KEYS_FILE", ""),
+		SPFEnabled:   getEnvBool("SPF_DMARC_ENABLED", false),
+		DNSTimeout:   getEnvDuration("DNS_TIMEOUT", 3*time.Second),
+	}
+	cfg.Crypto = CryptoConfig{
+		SMIMEEnabled:    getEnvBool("SMIME_ENABLED", true),
+		SMIMETrustStore: getEnv("SMIME_TRUST_STORE", ""),
+		SMIMEKeysDir:    getEnv("SMIME_KEYS_DIR", "./secrets/smime"),
+
+		PGPEnabled:        getEnvBool("PGP_ENABLED", true),
+		PGPKeyrings:       getEnvList("PGP_KEYRINGS", []string{"./secrets/pgp"}),
+		PGPPassphraseFile: getEnv("PGP_PASSPHRASE_FILE", ""),
+	}
+	cfg.Parser = ParserConfig{
+		MaxDepth:      getEnvInt("PARSE_MAX_DEPTH", 3),
+		RepairCharset: getEnvBool("PARSE_CHARSET_REPAIR", false),
+		IncludeHTML:   getEnvBool("PARSE_INCLUDE_HTML", false),
+	}
+	cfg.Render = RenderConfig{
+		RemoteImages: getEnv("RENDER_REMOTE_IMAGES", "block"),
+		ImageProxy:   getEnv("RENDER_IMAGE_PROXY", ""),
+	}
 	return cfg
 }
 
@@ -141,6 +209,18 @@ func getEnvInt(key string, def int) int {
 	}
 	return def
 }
+func getEnvList(key string, def []string) []string {
+	if v, ok := os.LookupEnv(key); ok {
+		var out []string
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return def
+}
 func getEnvDuration(key string, def time.Duration) time.Duration {
 	if v, ok := os.LookupEnv(key); ok {
 		if d, err := time.ParseDuration(v); err == nil {
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/Zifeldev/emailback/service/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type AttachmentsListResponse struct {
+	EmailID string                        `json:"email_id"`
+	Count   int                           `json:"count"`
+	Items   []repository.AttachmentEntity `json:"items"`
+}
+
+type AttachmentController struct {
+	repo  repository.AttachmentRepository
+	blobs storage.BlobStore
+	log   *logrus.Entry
+}
+
+func NewAttachmentController(r repository.AttachmentRepository, blobs storage.BlobStore, log *logrus.Entry) *AttachmentController {
+	return &AttachmentController{
+		repo:  r,
+		blobs: blobs,
+		log:   log,
+	}
+}
+
+// List
+// @Summary      List attachments of an email
+// @Tags         attachments
+// @Produce      json
+// @Param        id   path      string  true  "Email ID"
+// @Success      200  {object}  AttachmentsListResponse
+// @Failure      500  {object}  map[string]string
+// @Router       /emails/{id}/attachments [get]
+func (ac *AttachmentController) List(c *gin.Context) {
+	log := ac.log.WithField("handler", "ListAttachments")
+	id := c.Param("id")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Second)
+	defer cancel()
+
+	items, err := ac.repo.ListAttachments(ctx, id)
+	if err != nil {
+		log.WithError(err).WithField("id", id).Error("repo.ListAttachments failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	out := make([]repository.AttachmentEntity, 0, len(items))
+	for _, a := range items {
+		out = append(out, *a)
+	}
+	c.JSON(http.StatusOK, AttachmentsListResponse{EmailID: id, Count: len(out), Items: out})
+}
+
+// Download
+// @Summary      Download an attachment
+// @Tags         attachments
+// @Produce      octet-stream
+// @Param        id     path  string  true  "Email ID"
+// @Param        attId  path  string  true  "Attachment ID"
+// @Success      200
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /emails/{id}/attachments/{attId} [get]
+func (ac *AttachmentController) Download(c *gin.Context) {
+	log := ac.log.WithField("handler", "DownloadAttachment")
+	id, attID := c.Param("id"), c.Param("attId")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Second)
+	defer cancel()
+
+	att, err := ac.repo.GetAttachment(ctx, id, attID)
+	if err != nil {
+		if errors.Is(err, repository.ErrAttachmentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+			return
+		}
+		log.WithError(err).Error("repo.GetAttachment failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	rc, err := ac.blobs.Get(c.Request.Context(), att.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			log.WithField("storage_key", att.StorageKey).Error("attachment blob missing")
+			c.JSON(http.StatusNotFound, gin.H{"error": "blob_not_found"})
+			return
+		}
+		log.WithError(err).Error("blob get failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "storage error"})
+		return
+	}
+	defer rc.Close()
+
+	ctype := att.ContentType
+	if ctype == "" {
+		ctype = att.SniffedType
+	}
+	if ctype == "" {
+		ctype = "application/octet-stream"
+	}
+	filename := att.Filename
+	if filename == "" {
+		filename = fmt.Sprintf("attachment-%s", att.ID)
+	}
+	c.DataFromReader(http.StatusOK, att.Size, ctype, rc, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": filename}),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/Zifeldev/emailback/service/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type memAttachmentRepo struct {
+	items []*repository.AttachmentEntity
+}
+
+func (m *memAttachmentRepo) ListAttachments(ctx context.Context, emailID string) ([]*repository.AttachmentEntity, error) {
+	out := []*repository.AttachmentEntity{}
+	for _, a := range m.items {
+		if a.EmailID == emailID {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func (m *memAttachmentRepo) GetAttachment(ctx context.Context, emailID, attID string) (*repository.AttachmentEntity, error) {
+	for _, a := range m.items {
+		if a.EmailID == emailID && a.ID == attID {
+			return a, nil
+		}
+	}
+	return nil, repository.ErrAttachmentNotFound
+}
+
+func setupAttachmentRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	store, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	_ = store.Put(context.Background(), "attachments/ab/abc", strings.NewReader("%PDF-1.4"))
+	repo := &memAttachmentRepo{items: []*repository.AttachmentEntity{
+		{ID: "a1", EmailID: "e1", Filename: "invoice.pdf", ContentType: "application/pdf", Size: 8, SHA256: "abc", StorageKey: "attachments/ab/abc"},
+	}}
+
+	gin.SetMode(gin.TestMode)
+	ac := NewAttachmentController(repo, store, logrus.New().WithField("t", "test"))
+	r := gin.New()
+	r.GET("/emails/:id/attachments", ac.List)
+	r.GET("/emails/:id/attachments/:attId", ac.Download)
+	return r
+}
+
+func TestAttachmentController_List(t *testing.T) {
+	r := setupAttachmentRouter(t)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/emails/e1/attachments", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var resp AttachmentsListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("bad json: %v", err)
+	}
+	if resp.Count != 1 || resp.Items[0].Filename != "invoice.pdf" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestAttachmentController_Download(t *testing.T) {
+	r := setupAttachmentRouter(t)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/emails/e1/attachments/a1", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if w.Body.String() != "%PDF-1.4" {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/pdf" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, "invoice.pdf") {
+		t.Fatalf("unexpected disposition %q", cd)
+	}
+}
+
+func TestAttachmentController_Download_NotFound(t *testing.T) {
+	r := setupAttachmentRouter(t)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/emails/e1/attachments/nope", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Zifeldev/emailback/service/internal/bounce"
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type EmailDeliveryStatusResponse struct {
+	EmailID string                            `json:"email_id"`
+	Count   int                               `json:"count"`
+	Items   []repository.DeliveryStatusEntity `json:"items"`
+}
+
+type BouncesListResponse struct {
+	Limit  int                               `json:"limit"`
+	Offset int                               `json:"offset"`
+	Count  int                               `json:"count"`
+	Items  []repository.DeliveryStatusEntity `json:"items"`
+}
+
+type BounceController struct {
+	emails   repository.EmailRepository
+	statuses repository.DeliveryStatusRepository
+	log      *logrus.Entry
+}
+
+func NewBounceController(emails repository.EmailRepository, statuses repository.DeliveryStatusRepository, log *logrus.Entry) *BounceController {
+	return &BounceController{
+		emails:   emails,
+		statuses: statuses,
+		log:      log,
+	}
+}
+
+// ListEmailDeliveryStatus
+// @Summary      List the per-recipient results of a bounce
+// @Tags         bounces
+// @Produce      json
+// @Param        id   path      string  true  "Email ID"
+// @Success      200  {object}  EmailDeliveryStatusResponse
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /emails/{id}/delivery-status [get]
+func (bc *BounceController) ListEmailDeliveryStatus(c *gin.Context) {
+	log := bc.log.WithField("handler", "ListEmailDeliveryStatus")
+	id := c.Param("id")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Second)
+	defer cancel()
+
+	if _, err := bc.emails.GetByID(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrEmailNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+			return
+		}
+		log.WithError(err).WithField("id", id).Error("repo.GetByID failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	items, err := bc.statuses.ListEmailDeliveryStatus(ctx, id)
+	if err != nil {
+		log.WithError(err).WithField("id", id).Error("repo.ListEmailDeliveryStatus failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	c.JSON(http.StatusOK, EmailDeliveryStatusResponse{EmailID: id, Count: len(items), Items: derefDeliveryStatus(items)})
+}
+
+// List
+// @Summary      List bounces
+// @Description  Failed and delayed deliveries reported by bounces, newest first, linked to the original email when it is stored.
+// @Tags         bounces
+// @Produce      json
+// @Param        recipient  query     string  false  "Recipient address (case-insensitive)"
+// @Param        type       query     string  false  "Bounce type"  Enums(hard, soft)
+// @Param        limit      query     int     false  "Limit"   minimum(1)
+// @Param        offset     query     int     false  "Offset"  minimum(0)
+// @Success      200  {object}  BouncesListResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /bounces [get]
+func (bc *BounceController) List(c *gin.Context) {
+	log := bc.log.WithField("handler", "ListBounces")
+
+	limit := 50
+	offset := 0
+	if l := c.Query("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 {
+			limit = v
+		}
+	}
+	if o := c.Query("offset"); o != "" {
+		if v, err := strconv.Atoi(o); err == nil && v >= 0 {
+			offset = v
+		}
+	}
+
+	filter := repository.BounceFilter{
+		Recipient: c.Query("recipient"),
+		Type:      c.Query("type"),
+	}
+	switch filter.Type {
+	case "", bounce.TypeHard, bounce.TypeSoft:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid type"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	items, err := bc.statuses.ListBounces(ctx, limit, offset, filter)
+	if err != nil {
+		log.WithError(err).Error("repo.ListBounces failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	c.JSON(http.StatusOK, BouncesListResponse{Limit: limit, Offset: offset, Count: len(items), Items: derefDeliveryStatus(items)})
+}
+
+func derefDeliveryStatus(items []*repository.DeliveryStatusEntity) []repository.DeliveryStatusEntity {
+	out := make([]repository.DeliveryStatusEntity, 0, len(items))
+	for _, d := range items {
+		out = append(out, *d)
+	}
+	return out
+}
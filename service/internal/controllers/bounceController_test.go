@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type memDeliveryStatusRepo struct {
+	byEmail    map[string][]*repository.DeliveryStatusEntity
+	lastFilter repository.BounceFilter
+}
+
+func (m *memDeliveryStatusRepo) ListEmailDeliveryStatus(ctx context.Context, emailID string) ([]*repository.DeliveryStatusEntity, error) {
+	return m.byEmail[emailID], nil
+}
+
+func (m *memDeliveryStatusRepo) ListBounces(ctx context.Context, limit, offset int, filter repository.BounceFilter) ([]*repository.DeliveryStatusEntity, error) {
+	m.lastFilter = filter
+	var out []*repository.DeliveryStatusEntity
+	for _, ds := range m.byEmail {
+		out = append(out, ds...)
+	}
+	return out, nil
+}
+
+func setupBounceRouter(t *testing.T) (*gin.Engine, *memDeliveryStatusRepo) {
+	t.Helper()
+	emails := newMemRepo()
+	_ = emails.SaveEmail(context.Background(), &repository.EmailEntity{ID: "b1"})
+	statuses := &memDeliveryStatusRepo{byEmail: map[string][]*repository.DeliveryStatusEntity{
+		"b1": {{ID: "ds1", EmailID: "b1", Recipient: "bob@example.org", Action: "failed", Status: "5.1.1", BounceType: "hard"}},
+	}}
+
+	gin.SetMode(gin.TestMode)
+	bc := NewBounceController(emails, statuses, logrus.New().WithField("t", "test"))
+	r := gin.New()
+	r.GET("/emails/:id/delivery-status", bc.ListEmailDeliveryStatus)
+	r.GET("/bounces", bc.List)
+	return r, statuses
+}
+
+func TestBounceController_ListEmailDeliveryStatus(t *testing.T) {
+	r, _ := setupBounceRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/emails/b1/delivery-status", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var resp EmailDeliveryStatusResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("bad json: %v", err)
+	}
+	if resp.Count != 1 || resp.Items[0].Recipient != "bob@example.org" {
+		t.Fatalf("unexpected items: %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/emails/missing/delivery-status", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestBounceController_List(t *testing.T) {
+	r, statuses := setupBounceRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/bounces?recipient=bob@example.org&type=hard", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if f := statuses.lastFilter; f.Recipient != "bob@example.org" || f.Type != "hard" {
+		t.Fatalf("unexpected filter: %+v", f)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/bounces?type=bogus", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
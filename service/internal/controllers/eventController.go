@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type EmailEventsResponse struct {
+	EmailID string                   `json:"email_id"`
+	Count   int                      `json:"count"`
+	Items   []repository.EventEntity `json:"items"`
+}
+
+type EventsListResponse struct {
+	Limit  int                      `json:"limit"`
+	Offset int                      `json:"offset"`
+	Count  int                      `json:"count"`
+	Items  []repository.EventEntity `json:"items"`
+}
+
+type EventController struct {
+	emails repository.EmailRepository
+	events repository.EventRepository
+	log    *logrus.Entry
+}
+
+func NewEventController(emails repository.EmailRepository, events repository.EventRepository, log *logrus.Entry) *EventController {
+	return &EventController{
+		emails: emails,
+		events: events,
+		log:    log,
+	}
+}
+
+// ListEmailEvents
+// @Summary      List calendar events of an email
+// @Tags         events
+// @Produce      json
+// @Param        id   path      string  true  "Email ID"
+// @Success      200  {object}  EmailEventsResponse
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /emails/{id}/events [get]
+func (ec *EventController) ListEmailEvents(c *gin.Context) {
+	log := ec.log.WithField("handler", "ListEmailEvents")
+	id := c.Param("id")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Second)
+	defer cancel()
+
+	if _, err := ec.emails.GetByID(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrEmailNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+			return
+		}
+		log.WithError(err).WithField("id", id).Error("repo.GetByID failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	items, err := ec.events.ListEmailEvents(ctx, id)
+	if err != nil {
+		log.WithError(err).WithField("id", id).Error("repo.ListEmailEvents failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	c.JSON(http.StatusOK, EmailEventsResponse{EmailID: id, Count: len(items), Items: derefEvents(items)})
+}
+
+// List
+// @Summary      List calendar events
+// @Description  Events overlapping [from, to). Recurring events are listed from their first occurrence until their RRULE ends.
+// @Tags         events
+// @Produce      json
+// @Param        from    query     string  false  "Range start (RFC 3339 or YYYY-MM-DD)"
+// @Param        to      query     string  false  "Range end, exclusive (RFC 3339 or YYYY-MM-DD)"
+// @Param        limit   query     int     false  "Limit"   minimum(1)
+// @Param        offset  query     int     false  "Offset"  minimum(0)
+// @Success      200  {object}  EventsListResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /events [get]
+func (ec *EventController) List(c *gin.Context) {
+	log := ec.log.WithField("handler", "ListEvents")
+
+	limit := 50
+	offset := 0
+	if l := c.Query("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 {
+			limit = v
+		}
+	}
+	if o := c.Query("offset"); o != "" {
+		if v, err := strconv.Atoi(o); err == nil && v >= 0 {
+			offset = v
+		}
+	}
+
+	var filter repository.EventFilter
+	var ok bool
+	if filter.From, ok = timeQuery(c, "from"); !ok {
+		return
+	}
+	if filter.To, ok = timeQuery(c, "to"); !ok {
+		return
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	items, err := ec.events.ListEvents(ctx, limit, offset, filter)
+	if err != nil {
+		log.WithError(err).Error("repo.ListEvents failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	c.JSON(http.StatusOK, EventsListResponse{Limit: limit, Offset: offset, Count: len(items), Items: derefEvents(items)})
+}
+
+// timeQuery reads an optional RFC 3339 timestamp or plain date (midnight
+// UTC) from the query string, answering 400 when it is malformed.
+func timeQuery(c *gin.Context, name string) (*time.Time, bool) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		if t, err = time.Parse(time.DateOnly, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+			return nil, false
+		}
+	}
+	t = t.UTC()
+	return &t, true
+}
+
+func derefEvents(items []*repository.EventEntity) []repository.EventEntity {
+	out := make([]repository.EventEntity, 0, len(items))
+	for _, e := range items {
+		out = append(out, *e)
+	}
+	return out
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type memEventRepo struct {
+	byEmail    map[string][]*repository.EventEntity
+	lastFilter repository.EventFilter
+}
+
+func (m *memEventRepo) ListEmailEvents(ctx context.Context, emailID string) ([]*repository.EventEntity, error) {
+	return m.byEmail[emailID], nil
+}
+
+func (m *memEventRepo) ListEvents(ctx context.Context, limit, offset int, filter repository.EventFilter) ([]*repository.EventEntity, error) {
+	m.lastFilter = filter
+	var out []*repository.EventEntity
+	for _, evs := range m.byEmail {
+		out = append(out, evs...)
+	}
+	return out, nil
+}
+
+func setupEventRouter(t *testing.T) (*gin.Engine, *memEventRepo) {
+	t.Helper()
+	emails := newMemRepo()
+	_ = emails.SaveEmail(context.Background(), &repository.EmailEntity{ID: "e1"})
+	start := time.Date(2024, 7, 15, 8, 0, 0, 0, time.UTC)
+	events := &memEventRepo{byEmail: map[string][]*repository.EventEntity{
+		"e1": {{ID: "ev1", EmailID: "e1", UID: "standup-1", Method: "REQUEST", Start: &start}},
+	}}
+
+	gin.SetMode(gin.TestMode)
+	ec := NewEventController(emails, events, logrus.New().WithField("t", "test"))
+	r := gin.New()
+	r.GET("/emails/:id/events", ec.ListEmailEvents)
+	r.GET("/events", ec.List)
+	return r, events
+}
+
+func TestEventController_ListEmailEvents(t *testing.T) {
+	r, _ := setupEventRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/emails/e1/events", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var resp EmailEventsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("bad json: %v", err)
+	}
+	if resp.Count != 1 || resp.Items[0].UID != "standup-1" {
+		t.Fatalf("unexpected events: %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/emails/missing/events", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestEventController_List_TimeRange(t *testing.T) {
+	r, events := setupEventRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/events?from=2024-07-01&to=2024-08-01T00:00:00%2B02:00", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	f := events.lastFilter
+	if f.From == nil || !f.From.Equal(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected from: %v", f.From)
+	}
+	if f.To == nil || !f.To.Equal(time.Date(2024, 7, 31, 22, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected to: %v", f.To)
+	}
+
+	for _, q := range []string{"from=yesterday", "from=2024-08-01&to=2024-07-01"} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/events?"+q, nil)
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", q, w.Code)
+		}
+	}
+}
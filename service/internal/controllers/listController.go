@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type MailingListsResponse struct {
+	Limit  int                            `json:"limit"`
+	Offset int                            `json:"offset"`
+	Count  int                            `json:"count"`
+	Items  []repository.MailingListEntity `json:"items"`
+}
+
+type ListController struct {
+	lists repository.MailingListRepository
+	log   *logrus.Entry
+}
+
+func NewListController(lists repository.MailingListRepository, log *logrus.Entry) *ListController {
+	return &ListController{
+		lists: lists,
+		log:   log,
+	}
+}
+
+// List
+// @Summary      List mailing lists and newsletters
+// @Description  Messages with List-* headers grouped by List-Id (or sender when there is none), most recently seen first, with the latest unsubscribe URIs.
+// @Tags         lists
+// @Produce      json
+// @Param        limit   query     int  false  "Limit"   minimum(1)
+// @Param        offset  query     int  false  "Offset"  minimum(0)
+// @Success      200  {object}  MailingListsResponse
+// @Failure      500  {object}  map[string]string
+// @Router       /lists [get]
+func (lc *ListController) List(c *gin.Context) {
+	log := lc.log.WithField("handler", "ListMailingLists")
+
+	limit := 50
+	offset := 0
+	if l := c.Query("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 {
+			limit = v
+		}
+	}
+	if o := c.Query("offset"); o != "" {
+		if v, err := strconv.Atoi(o); err == nil && v >= 0 {
+			offset = v
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	items, err := lc.lists.ListMailingLists(ctx, limit, offset)
+	if err != nil {
+		log.WithError(err).Error("repo.ListMailingLists failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	out := make([]repository.MailingListEntity, 0, len(items))
+	for _, m := range items {
+		out = append(out, *m)
+	}
+	c.JSON(http.StatusOK, MailingListsResponse{Limit: limit, Offset: offset, Count: len(out), Items: out})
+}
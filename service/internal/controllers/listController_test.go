@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type memListRepo struct {
+	items         []*repository.MailingListEntity
+	limit, offset int
+}
+
+func (m *memListRepo) ListMailingLists(ctx context.Context, limit, offset int) ([]*repository.MailingListEntity, error) {
+	m.limit, m.offset = limit, offset
+	return m.items, nil
+}
+
+func TestListController_List(t *testing.T) {
+	lists := &memListRepo{items: []*repository.MailingListEntity{{
+		ListID: "news.acme.example", Senders: []string{"news@acme.example"}, Messages: 3,
+		Unsubscribe: []string{"https://acme.example/u"}, OneClickURI: "https://acme.example/u",
+	}}}
+	gin.SetMode(gin.TestMode)
+	lc := NewListController(lists, logrus.New().WithField("t", "test"))
+	r := gin.New()
+	r.GET("/lists", lc.List)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/lists?limit=5&offset=10", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var resp MailingListsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("bad json: %v", err)
+	}
+	if resp.Count != 1 || resp.Items[0].OneClickURI == "" || lists.limit != 5 || lists.offset != 10 {
+		t.Fatalf("unexpected response %+v (limit=%d offset=%d)", resp, lists.limit, lists.offset)
+	}
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Zifeldev/emailback/service/internal/outlook"
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/Zifeldev/emailback/service/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// MessageController serves stored representations of a message other than the parsed entity.
+type MessageController struct {
+	raws  repository.RawMessageRepository
+	blobs storage.BlobStore
+	log   *logrus.Entry
+}
+
+func NewMessageController(raws repository.RawMessageRepository, blobs storage.BlobStore, log *logrus.Entry) *MessageController {
+	return &MessageController{
+		raws:  raws,
+		blobs: blobs,
+		log:   log,
+	}
+}
+
+// Raw
+// @Summary      Download the original message
+// @Description  Returns the message exactly as it was received: RFC822, or the Outlook .msg file for .msg uploads
+// @Tags         emails
+// @Produce      message/rfc822,application/vnd.ms-outlook
+// @Param        id   path  string  true  "Email ID"
+// @Success      200
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /emails/{id}/raw [get]
+func (mc *MessageController) Raw(c *gin.Context) {
+	log := mc.log.WithField("handler", "Raw")
+	id := c.Param("id")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Second)
+	defer cancel()
+
+	meta, err := mc.raws.GetRawMessage(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRawMessageNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+			return
+		}
+		log.WithError(err).Error("repo.GetRawMessage failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	rc, err := mc.blobs.Get(c.Request.Context(), meta.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			log.WithField("storage_key", meta.StorageKey).Error("raw message blob missing")
+			c.JSON(http.StatusNotFound, gin.H{"error": "blob_not_found"})
+			return
+		}
+		log.WithError(err).Error("blob get failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "storage error"})
+		return
+	}
+	defer rc.Close()
+
+	var body io.Reader = rc
+	if meta.Encoding == "gzip" {
+		zr, err := gzip.NewReader(rc)
+		if err != nil {
+			log.WithError(err).Error("raw message blob is not valid gzip")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "storage error"})
+			return
+		}
+		defer zr.Close()
+		body = zr
+	}
+
+	// Outlook uploads are kept as the .msg file that was sent to /parse.
+	br := bufio.NewReader(body)
+	contentType, ext := "message/rfc822", "eml"
+	if head, _ := br.Peek(8); outlook.IsMSG(head) {
+		contentType, ext = "application/vnd.ms-outlook", "msg"
+	}
+	c.DataFromReader(http.StatusOK, meta.Size, contentType, br, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s.%s"`, id, ext),
+	})
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Zifeldev/emailback/service/internal/cfb"
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/Zifeldev/emailback/service/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type memRawRepo struct {
+	byEmail map[string]*repository.RawMessageEntity
+}
+
+func (m *memRawRepo) GetRawMessage(ctx context.Context, emailID string) (*repository.RawMessageEntity, error) {
+	if v, ok := m.byEmail[emailID]; ok {
+		return v, nil
+	}
+	return nil, repository.ErrRawMessageNotFound
+}
+
+func TestMessageController_Raw(t *testing.T) {
+	const raw = "From: a@example.com\r\nSubject: hi\r\n\r\nbody\r\n"
+	store, _ := storage.NewLocalStore(t.TempDir())
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write([]byte(raw))
+	_ = zw.Close()
+	_ = store.Put(context.Background(), "raw/ab/abc.eml.gz", &buf)
+
+	repo := &memRawRepo{byEmail: map[string]*repository.RawMessageEntity{
+		"e1": {EmailID: "e1", StorageKey: "raw/ab/abc.eml.gz", Size: int64(len(raw)), Encoding: "gzip"},
+	}}
+	gin.SetMode(gin.TestMode)
+	mc := NewMessageController(repo, store, logrus.New().WithField("t", "test"))
+	r := gin.New()
+	r.GET("/emails/:id/raw", mc.Raw)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/emails/e1/raw", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if w.Body.String() != raw {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "message/rfc822" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/emails/missing/raw", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestMessageController_Raw_OutlookMSG(t *testing.T) {
+	msg := append(append([]byte{}, cfb.Signature...), "rest of the compound file"...)
+	store, _ := storage.NewLocalStore(t.TempDir())
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write(msg)
+	_ = zw.Close()
+	_ = store.Put(context.Background(), "raw/cd/cde.eml.gz", &buf)
+
+	repo := &memRawRepo{byEmail: map[string]*repository.RawMessageEntity{
+		"e2": {EmailID: "e2", StorageKey: "raw/cd/cde.eml.gz", Size: int64(len(msg)), Encoding: "gzip"},
+	}}
+	gin.SetMode(gin.TestMode)
+	mc := NewMessageController(repo, store, logrus.New().WithField("t", "test"))
+	r := gin.New()
+	r.GET("/emails/:id/raw", mc.Raw)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/emails/e2/raw", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), msg) {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/vnd.ms-outlook" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="e2.msg"` {
+		t.Fatalf("unexpected disposition %q", cd)
+	}
+}
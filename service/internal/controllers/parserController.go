@@ -2,19 +2,26 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/Zifeldev/emailback/service/internal/mailheader"
 	"github.com/Zifeldev/emailback/service/internal/middleware"
+	"github.com/Zifeldev/emailback/service/internal/outlook"
 	"github.com/Zifeldev/emailback/service/internal/repository"
 	"github.com/Zifeldev/emailback/service/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
+const contentTypeOutlookMSG = "application/vnd.ms-outlook"
+
 type EmailsListResponse struct {
 	Limit  int                      `json:"limit"`
 	Offset int                      `json:"offset"`
@@ -23,6 +30,12 @@ type EmailsListResponse struct {
 }
 
 
+type EmailHeadersResponse struct {
+	EmailID string             `json:"email_id"`
+	Count   int                `json:"count"`
+	Items   []mailheader.Field `json:"items"`
+}
+
 type ParserController struct {
 	parser service.Parser
 	repo   repository.EmailRepository
@@ -62,8 +75,12 @@ type BatchItemResult struct {
 	Index      int    `json:"index"`
 	Status     string `json:"status"` // ok|error
 	EmailID    string `json:"email_id,omitempty"`
+	MessageID  string `json:"message_id,omitempty"`
+	Subject    string `json:"subject,omitempty"`
 	Error      string `json:"error,omitempty"`
 	DurationMS int64  `json:"duration_ms,omitempty"` // ms
+	// Preview is the parse result of a dry run; nothing was saved.
+	Preview *service.Preview `json:"preview,omitempty"`
 }
 
 // BatchResponse 
@@ -82,6 +99,7 @@ type BatchResponse struct {
 // @Produce      json
 // @Param        max_workers   query   int     false  "Максимум параллельных воркеров (1..100)" minimum(1) maximum(100) default(5)
 // @Param        item_timeout  query   string  false  "Таймаут на один элемент (напр. 500ms, 2s)" default(500ms)
+// @Param        dry_run       query   bool    false  "Parse only and return previews instead of saving"
 // @Param        body          body    []BatchEmailInput  true  "Список писем (RFC822 в поле raw)"
 // @Success      200  {object}  BatchResponse
 // @Failure      400  {object}  map[string]string
@@ -101,76 +119,23 @@ func (pc *ParserController) BatchParseAndSave(c *gin.Context) {
 		return
 	}
 
-	maxWorkers := 5
-	if s := c.Query("max_workers"); s != "" {
-		if v, err := strconv.Atoi(s); err == nil && v >= 1 && v <= 100 {
-			maxWorkers = v
-		}
-	}
-	itemTimeout := 500 * time.Millisecond
-	if s := c.Query("item_timeout"); s != "" {
-		if d, err := time.ParseDuration(s); err == nil && d > 0 {
-			itemTimeout = d
-		}
-	}
+	maxWorkers, itemTimeout := poolParams(c)
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
 
 	log = log.WithFields(logrus.Fields{
 		"items":        len(inputs),
 		"max_workers":  maxWorkers,
 		"item_timeout": itemTimeout.String(),
+		"dry_run":      dryRun,
 	})
 	log.Info("batch started")
 
-	type job struct {
-		i   int
-		raw string
-	}
-	jobs := make(chan job, len(inputs))
-	results := make(chan BatchItemResult, len(inputs))
-
-	ctx := c.Request.Context()
-
-	worker := func(id int) {
-		for j := range jobs {
-			start := time.Now()
-			ictx, cancel := context.WithTimeout(ctx, itemTimeout)
-			ent, err := pc.parser.Parse(ictx, []byte(j.raw)) 
-			if err == nil {
-				err = pc.repo.SaveEmail(ictx, ent) 
-			}
-			cancel()
-
-			dur := time.Since(start).Milliseconds()
-			if err != nil {
-				log.WithFields(logrus.Fields{"idx": j.i, "dur": dur, "err": err.Error()}).Warn("item parse failed")
-				results <- BatchItemResult{Index: j.i, Status: "error", Error: err.Error(), DurationMS: dur}
-				continue
-			}
-			results <- BatchItemResult{Index: j.i, Status: "ok", EmailID: ent.ID, DurationMS: dur}
-		}
-	}
-
-	wc := maxWorkers
-	if wc > len(inputs) {
-		wc = len(inputs)
-	}
-	var wg sync.WaitGroup
-	wg.Add(wc)
-	for i := 0; i < wc; i++ {
-		go func(id int) {
-			defer wg.Done()
-			worker(id)
-		}(i + 1)
-	}
+	jobs := make(chan parseJob, len(inputs))
 	for i, in := range inputs {
-		jobs <- job{i: i, raw: in.Raw}
+		jobs <- parseJob{index: i, raw: []byte(in.Raw)}
 	}
 	close(jobs)
-
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
+	results := pc.runWorkers(c.Request.Context(), log, jobs, min(maxWorkers, len(inputs)), itemTimeout, dryRun)
 
 	out := make([]BatchItemResult, len(inputs))
 	ok, fail := 0, 0
@@ -197,12 +162,89 @@ func (pc *ParserController) BatchParseAndSave(c *gin.Context) {
 	})
 }
 
+type parseJob struct {
+	index int
+	raw   []byte
+}
+
+// poolParams reads the max_workers and item_timeout query parameters shared
+// by the bulk endpoints.
+func poolParams(c *gin.Context) (int, time.Duration) {
+	maxWorkers := 5
+	if s := c.Query("max_workers"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 1 && v <= 100 {
+			maxWorkers = v
+		}
+	}
+	itemTimeout := 500 * time.Millisecond
+	if s := c.Query("item_timeout"); s != "" {
+		if d, err := time.ParseDuration(s); err == nil && d > 0 {
+			itemTimeout = d
+		}
+	}
+	return maxWorkers, itemTimeout
+}
+
+// runWorkers parses and saves jobs with n workers. In a dry run nothing is
+// saved and each result carries its preview instead. The returned channel is
+// closed once jobs is closed and drained.
+func (pc *ParserController) runWorkers(ctx context.Context, log *logrus.Entry, jobs <-chan parseJob, n int, itemTimeout time.Duration, dryRun bool) <-chan BatchItemResult {
+	results := make(chan BatchItemResult, n)
+	worker := func() {
+		for j := range jobs {
+			start := time.Now()
+			ictx, cancel := context.WithTimeout(ctx, itemTimeout)
+			var ent *repository.EmailEntity
+			var pv *service.Preview
+			var err error
+			if dryRun {
+				if pv, err = pc.preview(ictx, j.raw); err == nil {
+					ent = pv.Email
+				}
+			} else if ent, err = pc.parser.Parse(ictx, j.raw); err == nil {
+				err = pc.repo.SaveEmail(ictx, ent)
+			}
+			cancel()
+
+			dur := time.Since(start).Milliseconds()
+			if err != nil {
+				log.WithFields(logrus.Fields{"idx": j.index, "dur": dur, "err": err.Error()}).Warn("item parse failed")
+				results <- BatchItemResult{Index: j.index, Status: "error", Error: err.Error(), DurationMS: dur}
+				continue
+			}
+			res := BatchItemResult{Index: j.index, Status: "ok", EmailID: ent.ID, MessageID: ent.MessageID, Subject: ent.Subject, DurationMS: dur, Preview: pv}
+			if dryRun {
+				res.EmailID = ""
+			}
+			results <- res
+		}
+	}
+
+	if n < 1 {
+		n = 1
+	}
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			worker()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
 // ParseAndSave
 // @Summary      Parse and save an email
 // @Description  Accepts raw EML (text/plain or message/rfc822), parses it and persists to DB
 // @Tags         emails
 // @Accept       plain
 // @Accept       message/rfc822
+// @Accept       application/vnd.ms-outlook
 // @Produce      json
 // @Success      201  {object}  repository.EmailEntity
 // @Failure      400  {object}  map[string]string
@@ -217,6 +259,13 @@ func (pc *ParserController) ParseAndSave(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
 		return
 	}
+	// The parser picks the format from the magic bytes; a declared .msg
+	// upload that is not one is rejected here rather than read as RFC822.
+	if c.ContentType() == contentTypeOutlookMSG && !outlook.IsMSG(raw) {
+		log.Warn("body is not an outlook .msg file")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "body is not an Outlook .msg file"})
+		return
+	}
 
 	ent, err := pc.parser.Parse(c.Request.Context(), raw)
 	if err != nil {
@@ -246,6 +295,55 @@ func (pc *ParserController) ParseAndSave(c *gin.Context) {
 	c.JSON(http.StatusCreated, saved)
 }
 
+// preview parses raw without saving it. Parsers that cannot report
+// diagnostics still return the parsed email.
+func (pc *ParserController) preview(ctx context.Context, raw []byte) (*service.Preview, error) {
+	if p, ok := pc.parser.(service.Previewer); ok {
+		return p.Preview(ctx, raw)
+	}
+	ent, err := pc.parser.Parse(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	return &service.Preview{Email: ent}, nil
+}
+
+// Preview
+// @Summary      Preview how an email parses
+// @Description  Parses raw EML like POST /parse without saving anything. The response has the full entity including HTML and parse warnings, the MIME tree and what body cleaning removed.
+// @Tags         emails
+// @Accept       plain
+// @Accept       message/rfc822
+// @Accept       application/vnd.ms-outlook
+// @Produce      json
+// @Success      200  {object}  service.Preview
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /parse/preview [post]
+func (pc *ParserController) Preview(c *gin.Context) {
+	log := pc.reqLogger(c).WithField("handler", "Preview")
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 10<<20) // 10 MB limit
+	raw, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.WithError(err).Warn("bad request body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if c.ContentType() == contentTypeOutlookMSG && !outlook.IsMSG(raw) {
+		log.Warn("body is not an outlook .msg file")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "body is not an Outlook .msg file"})
+		return
+	}
+
+	pv, err := pc.preview(c.Request.Context(), raw)
+	if err != nil {
+		log.WithError(err).Error("failed to preview email")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "processing failed"})
+		return
+	}
+	c.JSON(http.StatusOK, pv)
+}
+
 // GetByID
 // @Summary      Get email by ID
 // @Tags         emails
@@ -282,13 +380,59 @@ func (pc *ParserController) GetByID(c *gin.Context) {
 	c.JSON(http.StatusOK, ent)
 }
 
+// Headers
+// @Summary      List the header fields of an email
+// @Description  Every field in message order, repeated fields such as Received kept separate, with the raw value as sent and the unfolded value with RFC 2047 encoded words decoded.
+// @Tags         emails
+// @Produce      json
+// @Param        id    path      string  true   "Email ID"
+// @Param        name  query     string  false  "Only fields with this name (case-insensitive)"
+// @Success      200  {object}  EmailHeadersResponse
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /emails/{id}/headers [get]
+func (pc *ParserController) Headers(c *gin.Context) {
+	log := pc.reqLogger(c).WithField("handler", "Headers")
+	id := c.Param("id")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Second)
+	defer cancel()
+
+	ent, err := pc.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrEmailNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+			return
+		}
+		log.WithError(err).WithField("id", id).Error("repo.GetByID failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	items := make([]mailheader.Field, 0, len(ent.Headers))
+	name := strings.TrimSpace(c.Query("name"))
+	for _, f := range ent.Headers {
+		if name == "" || strings.EqualFold(f.Name, name) {
+			items = append(items, f)
+		}
+	}
+	c.JSON(http.StatusOK, EmailHeadersResponse{EmailID: ent.ID, Count: len(items), Items: items})
+}
+
 // GetAll
 // @Summary      List emails
 // @Tags         emails
 // @Produce      json
-// @Param        limit   query   int  false  "Limit"   minimum(1)
-// @Param        offset  query   int  false  "Offset"  minimum(0)
+// @Param        limit          query   int     false  "Limit"   minimum(1)
+// @Param        offset         query   int     false  "Offset"  minimum(0)
+// @Param        address        query   string  false  "Participant address"
+// @Param        domain         query   string  false  "Participant domain"
+// @Param        role           query   string  false  "Participant role" Enums(from, sender, reply_to, to, cc, bcc)
+// @Param        auto_response  query   string  false  "Automatic response class" Enums(none, vacation, auto-generated, list)
+// @Param        has_warnings   query   bool    false  "Only emails with (true) or without (false) MIME parse warnings"
+// @Param        link_domain    query   string  false  "Emails linking to this host or its subdomains"
 // @Success      200  {object}  EmailsListResponse
+// @Failure      400  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /emails [get]
 func (pc *ParserController) GetAll(c *gin.Context) {
@@ -307,10 +451,34 @@ func (pc *ParserController) GetAll(c *gin.Context) {
 		}
 	}
 
+	filter := repository.EmailFilter{
+		Address:      strings.TrimSpace(c.Query("address")),
+		Domain:       strings.TrimSpace(c.Query("domain")),
+		Role:         c.Query("role"),
+		AutoResponse: c.Query("auto_response"),
+		LinkDomain:   strings.TrimSpace(c.Query("link_domain")),
+	}
+	if filter.Role != "" && !slices.Contains(repository.AddressRoles, filter.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+		return
+	}
+	if filter.AutoResponse != "" && !slices.Contains(repository.AutoResponses, filter.AutoResponse) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid auto_response"})
+		return
+	}
+	if s := c.Query("has_warnings"); s != "" {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid has_warnings"})
+			return
+		}
+		filter.HasWarnings = &v
+	}
+
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	items, err := pc.repo.GetAll(ctx, limit, offset)
+	items, err := pc.repo.GetAll(ctx, limit, offset, filter)
 	if err != nil {
 		log.WithError(err).Error("repo.GetAll failed")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
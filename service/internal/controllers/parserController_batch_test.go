@@ -63,3 +63,36 @@ func TestBatchParseAndSave_ParseErrors(t *testing.T) {
 		t.Fatalf("expected 200 with error items, got %d", w.Code)
 	}
 }
+
+type failSaveRepo struct{ memRepo }
+
+func (failSaveRepo) SaveEmail(context.Context, *repository.EmailEntity) error {
+	return errors.New("save must not be called")
+}
+
+func TestBatchParseAndSave_DryRun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pc := NewParserController(okParser{}, &failSaveRepo{*newMemRepo()}, logrus.New().WithField("t", "test"))
+	r := gin.New()
+	r.POST("/parse/batch", pc.BatchParseAndSave)
+
+	b, _ := json.Marshal([]BatchEmailInput{{Raw: "From: a\nTo: b\n\nhello"}})
+	req, _ := http.NewRequest("POST", "/parse/batch?dry_run=true", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var resp BatchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Succeeded != 1 || len(resp.Results) != 1 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	res := resp.Results[0]
+	if res.EmailID != "" || res.Preview == nil || res.Preview.Email.MessageID != "m-x" {
+		t.Fatalf("unexpected dry-run result %+v", res)
+	}
+}
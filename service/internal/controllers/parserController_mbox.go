@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Zifeldev/emailback/service/internal/mbox"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// MboxResponse summarizes an mbox import. Error is set when the stream broke
+// off part way; the results cover the messages read up to that point.
+type MboxResponse struct {
+	Format string `json:"format"`
+	BatchResponse
+	Error string `json:"error,omitempty"`
+}
+
+// MboxParseAndSave
+// @Summary      Parse and save an mbox mailbox
+// @Description  Streams an mbox file, splits it on "From " lines and parses/saves every message with the batch worker pool.
+// @Tags         emails
+// @Accept       application/mbox
+// @Accept       plain
+// @Produce      json
+// @Param        format        query   string  false  "mbox variant" Enums(mboxrd, mboxo, mboxcl, mboxcl2) default(mboxrd)
+// @Param        max_workers   query   int     false  "Max parallel workers (1..100)" minimum(1) maximum(100) default(5)
+// @Param        item_timeout  query   string  false  "Per-message timeout (e.g. 500ms, 2s)" default(500ms)
+// @Success      200  {object}  MboxResponse
+// @Failure      400  {object}  map[string]string
+// @Router       /parse/mbox [post]
+func (pc *ParserController) MboxParseAndSave(c *gin.Context) {
+	log := pc.reqLogger(c).WithField("handler", "MboxParseAndSave")
+
+	format, err := mbox.ParseFormat(c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	maxWorkers, itemTimeout := poolParams(c)
+
+	// Mailboxes take longer to upload than the server-wide read/write timeouts
+	// allow; let the route's own deadline govern instead.
+	if dl, ok := c.Request.Context().Deadline(); ok {
+		rc := http.NewResponseController(c.Writer)
+		_ = rc.SetReadDeadline(dl)
+		_ = rc.SetWriteDeadline(dl)
+	}
+
+	log = log.WithFields(logrus.Fields{
+		"format":       format,
+		"max_workers":  maxWorkers,
+		"item_timeout": itemTimeout.String(),
+	})
+	log.Info("mbox import started")
+
+	jobs := make(chan parseJob, maxWorkers)
+	results := pc.runWorkers(c.Request.Context(), log, jobs, maxWorkers, itemTimeout, false)
+
+	var out []BatchItemResult
+	collected := make(chan struct{})
+	go func() {
+		for r := range results {
+			out = append(out, r)
+		}
+		close(collected)
+	}()
+
+	// Oversized messages never reach the workers; they are reported directly.
+	var skipped []BatchItemResult
+	var streamErr error
+	reader := mbox.NewReader(c.Request.Body, format)
+	count := 0
+	for {
+		msg, err := reader.Next()
+		if errors.Is(err, mbox.ErrMessageTooLarge) {
+			skipped = append(skipped, BatchItemResult{Index: msg.Index, Status: "error", Error: err.Error()})
+			count++
+			continue
+		}
+		if err != nil {
+			if err != io.EOF {
+				streamErr = err
+			}
+			break
+		}
+		count++
+		select {
+		case jobs <- parseJob{index: msg.Index, raw: msg.Data}:
+		case <-c.Request.Context().Done():
+			streamErr = c.Request.Context().Err()
+		}
+		if streamErr != nil {
+			break
+		}
+	}
+	close(jobs)
+	<-collected
+
+	if count == 0 {
+		msg := "no messages found"
+		if streamErr != nil {
+			msg = streamErr.Error()
+		}
+		log.WithError(streamErr).Warn("empty mbox")
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	out = append(out, skipped...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Index < out[j].Index })
+	resp := MboxResponse{Format: string(format), BatchResponse: BatchResponse{Processed: len(out), Results: out}}
+	for _, r := range out {
+		if r.Status == "ok" {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+	}
+	if streamErr != nil {
+		resp.Error = streamErr.Error()
+	}
+
+	log.WithFields(logrus.Fields{
+		"messages":  resp.Processed,
+		"succeeded": resp.Succeeded,
+		"failed":    resp.Failed,
+		"dur_ms":    time.Since(c.GetTime("start")).Milliseconds(),
+	}).Info("mbox import finished")
+
+	c.JSON(http.StatusOK, resp)
+}
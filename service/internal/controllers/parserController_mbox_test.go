@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// subjectParser turns the Subject line into the entity and fails on "bad".
+type subjectParser struct{}
+
+func (subjectParser) Parse(_ context.Context, raw []byte) (*repository.EmailEntity, error) {
+	s := string(raw)
+	if strings.Contains(s, "bad") {
+		return nil, errors.New("unparseable")
+	}
+	subj := strings.TrimPrefix(strings.SplitN(s, "\n", 2)[0], "Subject: ")
+	return &repository.EmailEntity{ID: "id-" + subj, MessageID: subj + "@example.com", Subject: subj, Text: s}, nil
+}
+
+type lockedRepo struct {
+	mu sync.Mutex
+	memRepo
+}
+
+func (l *lockedRepo) SaveEmail(ctx context.Context, e *repository.EmailEntity) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.memRepo.SaveEmail(ctx, e)
+}
+
+const testMailbox = "From a@example.com Mon Jan  2 15:04:05 2006\n" +
+	"Subject: one\n\n>From here\n\n" +
+	"From b@example.com Mon Jan  2 15:04:05 2006\n" +
+	"Subject: bad\n\nx\n\n" +
+	"From c@example.com Mon Jan  2 15:04:05 2006\n" +
+	"Subject: three\n\nbody\n"
+
+func TestMboxParseAndSave(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &lockedRepo{memRepo: *newMemRepo()}
+	pc := NewParserController(subjectParser{}, repo, logrus.New().WithField("t", "test"))
+	r := gin.New()
+	r.POST("/parse/mbox", pc.MboxParseAndSave)
+
+	req, _ := http.NewRequest("POST", "/parse/mbox?max_workers=3&item_timeout=1s", strings.NewReader(testMailbox))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp MboxResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Format != "mboxrd" || resp.Processed != 3 || resp.Succeeded != 2 || resp.Failed != 1 {
+		t.Fatalf("unexpected summary: %+v", resp)
+	}
+	for i, res := range resp.Results {
+		if res.Index != i {
+			t.Fatalf("results not ordered: %+v", resp.Results)
+		}
+	}
+	if resp.Results[0].MessageID != "one@example.com" || resp.Results[1].Status != "error" || resp.Results[2].Subject != "three" {
+		t.Fatalf("unexpected results: %+v", resp.Results)
+	}
+	if got := repo.byID["id-one"].Text; got != "Subject: one\n\nFrom here\n" {
+		t.Fatalf("message not unquoted: %q", got)
+	}
+}
+
+func TestMboxParseAndSave_BadInput(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pc := NewParserController(subjectParser{}, newMemRepo(), logrus.New().WithField("t", "test"))
+	r := gin.New()
+	r.POST("/parse/mbox", pc.MboxParseAndSave)
+
+	for _, tc := range []struct{ url, body string }{
+		{"/parse/mbox?format=maildir", testMailbox},
+		{"/parse/mbox", "Subject: not an mbox\n\nhi\n"},
+		{"/parse/mbox", ""},
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", tc.url, bytes.NewBufferString(tc.body))
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s %q: expected 400, got %d", tc.url, tc.body, w.Code)
+		}
+	}
+}
@@ -10,7 +10,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Zifeldev/emailback/service/internal/mailheader"
 	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/Zifeldev/emailback/service/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
@@ -25,7 +27,8 @@ func (m mockParser) Parse(ctx context.Context, rawEmail []byte) (*repository.Ema
 }
 
 type memRepo struct {
-	byID map[string]*repository.EmailEntity
+	byID       map[string]*repository.EmailEntity
+	lastFilter repository.EmailFilter
 }
 
 func newMemRepo() *memRepo { return &memRepo{byID: map[string]*repository.EmailEntity{}} }
@@ -40,7 +43,8 @@ func (m *memRepo) GetByID(ctx context.Context, id string) (*repository.EmailEnti
 	}
 	return nil, repository.ErrEmailNotFound
 }
-func (m *memRepo) GetAll(ctx context.Context, limit, offset int) ([]*repository.EmailEntity, error) {
+func (m *memRepo) GetAll(ctx context.Context, limit, offset int, filter repository.EmailFilter) ([]*repository.EmailEntity, error) {
+	m.lastFilter = filter
 	out := make([]*repository.EmailEntity, 0, len(m.byID))
 	for _, v := range m.byID {
 		out = append(out, v)
@@ -59,7 +63,9 @@ func setupRouter(pc *ParserController) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
 	r.POST("/parse", pc.ParseAndSave)
+	r.POST("/parse/preview", pc.Preview)
 	r.GET("/emails/:id", pc.GetByID)
+	r.GET("/emails/:id/headers", pc.Headers)
 	r.GET("/emails", pc.GetAll)
 	return r
 }
@@ -150,6 +156,19 @@ func TestParserController_ParseAndSave_ParseError(t *testing.T) {
 	}
 }
 
+func TestParserController_ParseAndSave_NotMSG(t *testing.T) {
+	pc := NewParserController(mockParser{ent: &repository.EmailEntity{ID: "x"}}, newMemRepo(), logrus.New().WithField("t", "test"))
+	r := setupRouter(pc)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/parse", bytes.NewBufferString("From: a@a\r\n\r\nhi"))
+	req.Header.Set("Content-Type", "application/vnd.ms-outlook")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
 type saveErrRepo struct{ memRepo }
 
 func (r *saveErrRepo) SaveEmail(ctx context.Context, email *repository.EmailEntity) error {
@@ -185,3 +204,111 @@ func TestParserController_GetByID_DBError(t *testing.T) {
 		t.Fatalf("expected 500, got %d", w.Code)
 	}
 }
+
+func TestParserController_GetAll_InvalidRole(t *testing.T) {
+	pc := NewParserController(mockParser{}, newMemRepo(), logrus.New().WithField("t", "test"))
+	r := setupRouter(pc)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/emails?address=a@b.c&role=owner", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestParserController_GetAll_InvalidAutoResponse(t *testing.T) {
+	pc := NewParserController(mockParser{}, newMemRepo(), logrus.New().WithField("t", "test"))
+	r := setupRouter(pc)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/emails?auto_response=ooo", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestParserController_GetAll_InvalidHasWarnings(t *testing.T) {
+	pc := NewParserController(mockParser{}, newMemRepo(), logrus.New().WithField("t", "test"))
+	r := setupRouter(pc)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/emails?has_warnings=maybe", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestParserController_GetAll_LinkDomain(t *testing.T) {
+	repo := newMemRepo()
+	pc := NewParserController(mockParser{}, repo, logrus.New().WithField("t", "test"))
+	r := setupRouter(pc)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/emails?link_domain=%20evil.example.net%20", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if repo.lastFilter.LinkDomain != "evil.example.net" {
+		t.Fatalf("link_domain not passed to the repo: %+v", repo.lastFilter)
+	}
+}
+
+func TestParserController_Preview_DoesNotSave(t *testing.T) {
+	repo := newMemRepo()
+	pc := NewParserController(service.NewEnmimeParser(service.Options{}, nil), repo, logrus.New().WithField("t", "test"))
+	r := setupRouter(pc)
+
+	raw := "From: a@example.com\r\nSubject: Hi\r\nMIME-Version: 1.0\r\nContent-Type: text/html; charset=utf-8\r\n\r\n<p>Hello there</p>"
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/parse/preview", bytes.NewBufferString(raw))
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var pv service.Preview
+	if err := json.Unmarshal(w.Body.Bytes(), &pv); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if pv.Email == nil || pv.Email.HTML != "<p>Hello there</p>" || pv.MIMETree == nil || pv.Cleaning.BodySource == "" {
+		t.Fatalf("unexpected preview %s", w.Body.String())
+	}
+	if len(repo.byID) != 0 {
+		t.Fatalf("preview must not save, repo has %d", len(repo.byID))
+	}
+}
+
+func TestParserController_Headers(t *testing.T) {
+	repo := newMemRepo()
+	repo.byID["e1"] = &repository.EmailEntity{ID: "e1", Headers: []mailheader.Field{
+		{Name: "Received", Raw: "from b", Value: "from b"},
+		{Name: "Subject", Raw: "=?UTF-8?B?0J/RgNC40LLQtdGC?=", Value: "Привет"},
+		{Name: "Received", Raw: "from a", Value: "from a"},
+	}}
+	pc := NewParserController(mockParser{}, repo, logrus.New().WithField("t", "test"))
+	r := setupRouter(pc)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/emails/e1/headers?name=received", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var resp EmailHeadersResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Count != 2 || resp.Items[0].Value != "from b" || resp.Items[1].Value != "from a" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/emails/missing/headers", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"html"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/Zifeldev/emailback/service/internal/sanitize"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// RenderController serves an email as HTML that is safe to embed in a page.
+type RenderController struct {
+	emails      repository.EmailRepository
+	attachments repository.AttachmentRepository
+	images      string // default sanitize.ImageModes value
+	proxyURL    string
+	log         *logrus.Entry
+}
+
+func NewRenderController(emails repository.EmailRepository, attachments repository.AttachmentRepository, images, proxyURL string, log *logrus.Entry) *RenderController {
+	if !slices.Contains(sanitize.ImageModes, images) {
+		images = sanitize.ImagesBlock
+	}
+	return &RenderController{
+		emails:      emails,
+		attachments: attachments,
+		images:      images,
+		proxyURL:    proxyURL,
+		log:         log,
+	}
+}
+
+// Render
+// @Summary      Render an email as sanitized HTML
+// @Description  The stored HTML body reduced to an allowlist of tags, attributes and inline styles, with cid: images pointing at the attachment download endpoint and remote images allowed, blocked or proxied. Emails stored without HTML are rendered from their text. A Content-Security-Policy header limits what the page may load.
+// @Tags         emails
+// @Produce      html
+// @Param        id      path   string  true   "Email ID"
+// @Param        images  query  string  false  "Remote images"  Enums(allow, block, proxy)
+// @Success      200
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /emails/{id}/render [get]
+func (rc *RenderController) Render(c *gin.Context) {
+	log := rc.log.WithField("handler", "Render")
+	id := c.Param("id")
+
+	images := rc.images
+	if v := c.Query("images"); v != "" {
+		if !slices.Contains(sanitize.ImageModes, v) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid images"})
+			return
+		}
+		images = v
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Second)
+	defer cancel()
+
+	ent, err := rc.emails.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrEmailNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+			return
+		}
+		log.WithError(err).Error("repo.GetByID failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	body := `<pre style="white-space: pre-wrap">` + html.EscapeString(ent.Text) + `</pre>`
+	if ent.HTML != "" {
+		atts, err := rc.attachments.ListAttachments(ctx, ent.ID)
+		if err != nil {
+			log.WithError(err).Error("repo.ListAttachments failed")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			return
+		}
+		cids := make(map[string]string, len(atts))
+		for _, a := range atts {
+			if a.ContentID != "" {
+				cids[a.ContentID] = "/emails/" + url.PathEscape(ent.ID) + "/attachments/" + url.PathEscape(a.ID)
+			}
+		}
+		body = sanitize.HTML(ent.HTML, sanitize.Options{
+			CID:      func(cid string) string { return cids[cid] },
+			Images:   images,
+			ProxyURL: rc.proxyURL,
+		})
+	}
+
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; img-src "+imageSources(images, rc.proxyURL)+"; base-uri 'none'; form-action 'none'")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(body))
+}
+
+// imageSources is the CSP img-src list for an image mode: attachments and
+// data: URIs always, plus the web or the proxy's origin.
+func imageSources(images, proxyURL string) string {
+	src := "'self' data:"
+	switch images {
+	case sanitize.ImagesAllow:
+		src += " http: https:"
+	case sanitize.ImagesProxy:
+		if u, err := url.Parse(proxyURL); err == nil && u.Host != "" {
+			src += " " + u.Scheme + "://" + u.Host
+		}
+	}
+	return src
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func setupRenderRouter(images, proxy string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	emails := newMemRepo()
+	emails.byID["e1"] = &repository.EmailEntity{
+		ID:   "e1",
+		HTML: `<p onclick="x()">Hi</p><script>alert(1)</script><img src="cid:logo@example.com"><img src="https://t.example/p.gif">`,
+	}
+	emails.byID["e2"] = &repository.EmailEntity{ID: "e2", Text: "plain <b>text</b>"}
+	atts := &memAttachmentRepo{items: []*repository.AttachmentEntity{
+		{ID: "a1", EmailID: "e1", ContentID: "logo@example.com"},
+	}}
+	rc := NewRenderController(emails, atts, images, proxy, logrus.New().WithField("t", "test"))
+	r := gin.New()
+	r.GET("/emails/:id/render", rc.Render)
+	return r
+}
+
+func TestRenderController_Render(t *testing.T) {
+	r := setupRenderRouter("block", "https://proxy.example/i?u=")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/emails/e1/render", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("status %d, type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	body := w.Body.String()
+	if strings.Contains(body, "script") || strings.Contains(body, "onclick") || strings.Contains(body, "t.example") {
+		t.Fatalf("unsafe content rendered: %s", body)
+	}
+	if !strings.Contains(body, `src="/emails/e1/attachments/a1"`) {
+		t.Fatalf("cid image not rewritten: %s", body)
+	}
+	if csp := w.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "img-src 'self' data:;") {
+		t.Fatalf("unexpected csp: %q", csp)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/emails/e1/render?images=proxy", nil)
+	r.ServeHTTP(w, req)
+	if !strings.Contains(w.Body.String(), `src="https://proxy.example/i?u=https%3A%2F%2Ft.example%2Fp.gif"`) {
+		t.Fatalf("image not proxied: %s", w.Body.String())
+	}
+	if csp := w.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "https://proxy.example") {
+		t.Fatalf("proxy origin missing from csp: %q", csp)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/emails/e2/render", nil)
+	r.ServeHTTP(w, req)
+	if !strings.Contains(w.Body.String(), "plain &lt;b&gt;text&lt;/b&gt;") {
+		t.Fatalf("text fallback not escaped: %s", w.Body.String())
+	}
+}
+
+func TestRenderController_Render_Errors(t *testing.T) {
+	r := setupRenderRouter("", "")
+	for path, code := range map[string]int{
+		"/emails/e1/render?images=all": http.StatusBadRequest,
+		"/emails/missing/render":       http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		r.ServeHTTP(w, req)
+		if w.Code != code {
+			t.Fatalf("%s: expected %d, got %d", path, code, w.Code)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/Zifeldev/emailback/service/internal/thread"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type ThreadResponse struct {
+	ThreadID string            `json:"thread_id"`
+	Count    int               `json:"count"`
+	Messages []*thread.Message `json:"messages"` // reading order
+	Tree     []*thread.Node    `json:"tree"`
+}
+
+type ThreadController struct {
+	emails  repository.EmailRepository
+	threads repository.ThreadRepository
+	log     *logrus.Entry
+}
+
+func NewThreadController(emails repository.EmailRepository, threads repository.ThreadRepository, log *logrus.Entry) *ThreadController {
+	return &ThreadController{
+		emails:  emails,
+		threads: threads,
+		log:     log,
+	}
+}
+
+// GetThread
+// @Summary      Get a conversation
+// @Tags         threads
+// @Produce      json
+// @Param        id   path      string  true  "Thread ID"
+// @Success      200  {object}  ThreadResponse
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /threads/{id} [get]
+func (tc *ThreadController) GetThread(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Second)
+	defer cancel()
+	tc.respond(ctx, c, c.Param("id"))
+}
+
+// GetEmailThread
+// @Summary      Get the conversation an email belongs to
+// @Tags         threads
+// @Produce      json
+// @Param        id   path      string  true  "Email ID"
+// @Success      200  {object}  ThreadResponse
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /emails/{id}/thread [get]
+func (tc *ThreadController) GetEmailThread(c *gin.Context) {
+	log := tc.log.WithField("handler", "GetEmailThread")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Second)
+	defer cancel()
+
+	ent, err := tc.emails.GetByID(ctx, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrEmailNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+			return
+		}
+		log.WithError(err).Error("repo.GetByID failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if ent.ThreadID == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no_thread"})
+		return
+	}
+	tc.respond(ctx, c, ent.ThreadID)
+}
+
+func (tc *ThreadController) respond(ctx context.Context, c *gin.Context, threadID string) {
+	msgs, err := tc.threads.GetThreadMessages(ctx, threadID)
+	if err != nil {
+		if errors.Is(err, repository.ErrThreadNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+			return
+		}
+		tc.log.WithError(err).WithField("thread_id", threadID).Error("repo.GetThreadMessages failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	tree := thread.Build(msgs)
+	c.JSON(http.StatusOK, ThreadResponse{
+		ThreadID: threadID,
+		Count:    len(msgs),
+		Messages: thread.Flatten(tree),
+		Tree:     tree,
+	})
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/Zifeldev/emailback/service/internal/thread"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type memThreadRepo struct {
+	byThread map[string][]*thread.Message
+}
+
+func (m *memThreadRepo) GetThreadMessages(ctx context.Context, threadID string) ([]*thread.Message, error) {
+	if v, ok := m.byThread[threadID]; ok {
+		return v, nil
+	}
+	return nil, repository.ErrThreadNotFound
+}
+
+func TestThreadController_GetEmailThread(t *testing.T) {
+	emails := newMemRepo()
+	_ = emails.SaveEmail(context.Background(), &repository.EmailEntity{ID: "e2", MessageID: "b@x", ThreadID: "t1"})
+	threads := &memThreadRepo{byThread: map[string][]*thread.Message{
+		"t1": {
+			{ID: "e2", MessageID: "b@x", InReplyTo: "a@x", Subject: "Re: hi"},
+			{ID: "e1", MessageID: "a@x", Subject: "hi"},
+		},
+	}}
+
+	gin.SetMode(gin.TestMode)
+	tc := NewThreadController(emails, threads, logrus.New().WithField("t", "test"))
+	r := gin.New()
+	r.GET("/threads/:id", tc.GetThread)
+	r.GET("/emails/:id/thread", tc.GetEmailThread)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/emails/e2/thread", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var resp ThreadResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("bad json: %v", err)
+	}
+	if resp.Count != 2 || resp.Messages[0].ID != "e1" || resp.Messages[1].ID != "e2" {
+		t.Fatalf("unexpected thread: %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/threads/missing", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,353 @@
+// Package ical reads the parts of iCalendar (RFC 5545) objects that matter
+// for invitations sent by mail (iTIP, RFC 5546): the method and each VEVENT
+// with its organizer, attendees, times and recurrence rule.
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// iTIP methods.
+const (
+	MethodPublish = "PUBLISH"
+	MethodRequest = "REQUEST"
+	MethodReply   = "REPLY"
+	MethodCancel  = "CANCEL"
+)
+
+var ErrNotCalendar = errors.New("ical: no VCALENDAR object")
+
+// Calendar is a decoded VCALENDAR object.
+type Calendar struct {
+	Method string
+	ProdID string
+	Events []Event
+}
+
+// Event is a VEVENT. Start and End are in UTC; TZID keeps the zone they were
+// written in. All-day events have AllDay set and dates at midnight UTC.
+// SeriesEnd is set for a recurring event whose RRULE ends, after the end of
+// its last occurrence.
+type Event struct {
+	UID          string
+	RecurrenceID string
+	Sequence     int
+	Summary      string
+	Description  string
+	Location     string
+	Status       string
+	Organizer    *Attendee
+	Attendees    []Attendee
+	Start        *time.Time
+	End          *time.Time
+	AllDay       bool
+	TZID         string
+	RRule        string
+	SeriesEnd    *time.Time
+}
+
+// Attendee is an ORGANIZER or ATTENDEE property.
+type Attendee struct {
+	Name     string `json:"name,omitempty"`
+	Email    string `json:"email"`
+	Role     string `json:"role,omitempty"`
+	PartStat string `json:"partstat,omitempty"`
+	RSVP     bool   `json:"rsvp,omitempty"`
+}
+
+// property is one content line: NAME;PARAM=value:value.
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+func (p property) param(name string) string { return p.params[name] }
+
+// component is a BEGIN/END block with its properties and sub-components.
+type component struct {
+	name     string
+	props    []property
+	children []*component
+}
+
+func (c *component) get(name string) (property, bool) {
+	for _, p := range c.props {
+		if p.name == name {
+			return p, true
+		}
+	}
+	return property{}, false
+}
+
+func (c *component) text(name string) string {
+	p, _ := c.get(name)
+	return unescape(p.value)
+}
+
+// Parse decodes the first VCALENDAR object in data.
+func Parse(data []byte) (*Calendar, error) {
+	root, err := parseComponents(data)
+	if err != nil {
+		return nil, err
+	}
+	var vcal *component
+	for _, c := range root.children {
+		if c.name == "VCALENDAR" {
+			vcal = c
+			break
+		}
+	}
+	if vcal == nil {
+		return nil, ErrNotCalendar
+	}
+
+	cal := &Calendar{
+		Method: strings.ToUpper(strings.TrimSpace(vcal.text("METHOD"))),
+		ProdID: vcal.text("PRODID"),
+	}
+	zones := make(map[string]*vtimezone)
+	for _, c := range vcal.children {
+		if c.name == "VTIMEZONE" {
+			if z := parseVTimezone(c); z != nil {
+				zones[z.id] = z
+			}
+		}
+	}
+	for _, c := range vcal.children {
+		if c.name == "VEVENT" {
+			cal.Events = append(cal.Events, parseEvent(c, zones))
+		}
+	}
+	return cal, nil
+}
+
+func parseEvent(c *component, zones map[string]*vtimezone) Event {
+	ev := Event{
+		UID:         c.text("UID"),
+		Summary:     c.text("SUMMARY"),
+		Description: c.text("DESCRIPTION"),
+		Location:    c.text("LOCATION"),
+		Status:      strings.ToUpper(c.text("STATUS")),
+		RRule:       c.text("RRULE"),
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(c.text("SEQUENCE"))); err == nil {
+		ev.Sequence = n
+	}
+	if p, ok := c.get("RECURRENCE-ID"); ok {
+		if t, _, _, err := parseTime(p, zones); err == nil {
+			ev.RecurrenceID = t.Format(time.RFC3339)
+		}
+	}
+	if p, ok := c.get("ORGANIZER"); ok {
+		a := parseAttendee(p)
+		ev.Organizer = &a
+	}
+	for _, p := range c.props {
+		if p.name == "ATTENDEE" {
+			ev.Attendees = append(ev.Attendees, parseAttendee(p))
+		}
+	}
+
+	if p, ok := c.get("DTSTART"); ok {
+		if t, allDay, tzid, err := parseTime(p, zones); err == nil {
+			ev.Start, ev.AllDay, ev.TZID = &t, allDay, tzid
+		}
+	}
+	if p, ok := c.get("DTEND"); ok {
+		if t, _, _, err := parseTime(p, zones); err == nil {
+			ev.End = &t
+		}
+	} else if p, ok := c.get("DURATION"); ok && ev.Start != nil {
+		if d, err := parseDuration(p.value); err == nil {
+			t := ev.Start.Add(d)
+			ev.End = &t
+		}
+	} else if ev.AllDay {
+		// A date-only DTSTART without an end covers that one day.
+		t := ev.Start.AddDate(0, 0, 1)
+		ev.End = &t
+	}
+	ev.SeriesEnd = seriesEnd(ev, zones)
+	return ev
+}
+
+func parseAttendee(p property) Attendee {
+	a := Attendee{
+		Name:     p.param("CN"),
+		Email:    mailto(p.value),
+		Role:     strings.ToUpper(p.param("ROLE")),
+		PartStat: strings.ToUpper(p.param("PARTSTAT")),
+		RSVP:     strings.EqualFold(p.param("RSVP"), "TRUE"),
+	}
+	if a.Email == "" {
+		a.Email = mailto(p.param("EMAIL"))
+	}
+	return a
+}
+
+func mailto(v string) string {
+	v = strings.TrimSpace(v)
+	if len(v) >= 7 && strings.EqualFold(v[:7], "mailto:") {
+		v = v[7:]
+	}
+	return v
+}
+
+// parseComponents builds the component tree from unfolded content lines.
+// Lines that are not NAME:value pairs are skipped.
+func parseComponents(data []byte) (*component, error) {
+	root := &component{}
+	stack := []*component{root}
+	sc := bufio.NewScanner(bytes.NewReader(unfold(data)))
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		p, ok := parseLine(line)
+		if !ok {
+			continue
+		}
+		top := stack[len(stack)-1]
+		switch p.name {
+		case "BEGIN":
+			c := &component{name: strings.ToUpper(p.value)}
+			top.children = append(top.children, c)
+			stack = append(stack, c)
+		case "END":
+			if len(stack) > 1 && stack[len(stack)-1].name == strings.ToUpper(p.value) {
+				stack = stack[:len(stack)-1]
+			}
+		default:
+			top.props = append(top.props, p)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// unfold joins continuation lines, which start with a space or tab.
+func unfold(data []byte) []byte {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	data = bytes.ReplaceAll(data, []byte("\n "), nil)
+	return bytes.ReplaceAll(data, []byte("\n\t"), nil)
+}
+
+func parseLine(line string) (property, bool) {
+	// The value starts at the first colon outside a quoted parameter value.
+	inQuote := false
+	colon := -1
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"':
+			inQuote = !inQuote
+		case ':':
+			if !inQuote {
+				colon = i
+			}
+		}
+		if colon >= 0 {
+			break
+		}
+	}
+	if colon <= 0 {
+		return property{}, false
+	}
+	p := property{value: line[colon+1:]}
+	head := splitUnquoted(line[:colon], ';')
+	p.name = strings.ToUpper(strings.TrimSpace(head[0]))
+	for _, kv := range head[1:] {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		if p.params == nil {
+			p.params = make(map[string]string)
+		}
+		p.params[strings.ToUpper(strings.TrimSpace(k))] = strings.Trim(v, `"`)
+	}
+	return p, true
+}
+
+func splitUnquoted(s string, sep byte) []string {
+	var out []string
+	inQuote := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '"':
+			inQuote = !inQuote
+		case s[i] == sep && !inQuote:
+			out = append(out, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, s[start:])
+}
+
+// unescape decodes TEXT values (RFC 5545 section 3.3.11).
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// parseDuration reads a DURATION value such as P1D, PT1H30M or -P1W.
+func parseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(strings.ToUpper(s))
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimLeft(s, "+-")
+	if !strings.HasPrefix(s, "P") {
+		return 0, errors.New("ical: bad duration")
+	}
+	var d time.Duration
+	num := 0
+	digits := false
+	for _, r := range s[1:] {
+		switch {
+		case r >= '0' && r <= '9':
+			num = num*10 + int(r-'0')
+			digits = true
+			continue
+		case r == 'T':
+			continue
+		}
+		if !digits {
+			return 0, errors.New("ical: bad duration")
+		}
+		unit := map[rune]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour, 'H': time.Hour, 'M': time.Minute, 'S': time.Second}[r]
+		if unit == 0 {
+			return 0, errors.New("ical: bad duration")
+		}
+		d += time.Duration(num) * unit
+		num, digits = 0, false
+	}
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
@@ -0,0 +1,152 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const outlookInvite = `BEGIN:VCALENDAR
+METHOD:REQUEST
+PRODID:Microsoft Exchange Server 2010
+VERSION:2.0
+BEGIN:VTIMEZONE
+TZID:Custom Berlin
+BEGIN:STANDARD
+DTSTART:16010101T030000
+TZOFFSETFROM:+0200
+TZOFFSETTO:+0100
+RRULE:FREQ=YEARLY;INTERVAL=1;BYDAY=-1SU;BYMONTH=10
+END:STANDARD
+BEGIN:DAYLIGHT
+DTSTART:16010101T020000
+TZOFFSETFROM:+0100
+TZOFFSETTO:+0200
+RRULE:FREQ=YEARLY;INTERVAL=1;BYDAY=-1SU;BYMONTH=3
+END:DAYLIGHT
+END:VTIMEZONE
+BEGIN:VEVENT
+ORGANIZER;CN="Doe, Jane":mailto:jane@example.com
+ATTENDEE;ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE;CN=Bob:mailto:b
+ ob@example.com
+ATTENDEE;ROLE=OPT-PARTICIPANT;PARTSTAT=ACCEPTED;CN=Carol:MAILTO:carol@example.com
+DESCRIPTION;LANGUAGE=en-US:Agenda:\n- budget\, hiring
+UID:040000008200E00074C5B7101A82E008
+SUMMARY;LANGUAGE=en-US:Quarterly review
+DTSTART;TZID=Custom Berlin:20240715T100000
+DTEND;TZID=Custom Berlin:20240715T113000
+RRULE:FREQ=WEEKLY;COUNT=4;BYDAY=MO
+LOCATION:Room 4
+SEQUENCE:2
+STATUS:CONFIRMED
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestParse_OutlookRequest(t *testing.T) {
+	cal, err := Parse([]byte(strings.ReplaceAll(outlookInvite, "\n", "\r\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cal.Method != MethodRequest || len(cal.Events) != 1 {
+		t.Fatalf("calendar = %+v", cal)
+	}
+	ev := cal.Events[0]
+	if ev.UID != "040000008200E00074C5B7101A82E008" || ev.Summary != "Quarterly review" || ev.Location != "Room 4" || ev.Sequence != 2 {
+		t.Fatalf("event = %+v", ev)
+	}
+	if ev.Description != "Agenda:\n- budget, hiring" {
+		t.Fatalf("description = %q", ev.Description)
+	}
+	if ev.Organizer == nil || ev.Organizer.Name != "Doe, Jane" || ev.Organizer.Email != "jane@example.com" {
+		t.Fatalf("organizer = %+v", ev.Organizer)
+	}
+	if len(ev.Attendees) != 2 {
+		t.Fatalf("attendees = %+v", ev.Attendees)
+	}
+	if a := ev.Attendees[0]; a.Email != "bob@example.com" || a.PartStat != "NEEDS-ACTION" || !a.RSVP || a.Role != "REQ-PARTICIPANT" {
+		t.Fatalf("attendee = %+v", a)
+	}
+	if a := ev.Attendees[1]; a.Email != "carol@example.com" || a.PartStat != "ACCEPTED" {
+		t.Fatalf("attendee = %+v", a)
+	}
+	// Summer time in the custom zone: UTC+2.
+	if want := time.Date(2024, 7, 15, 8, 0, 0, 0, time.UTC); ev.Start == nil || !ev.Start.Equal(want) {
+		t.Fatalf("start = %v, want %v", ev.Start, want)
+	}
+	if want := time.Date(2024, 7, 15, 9, 30, 0, 0, time.UTC); ev.End == nil || !ev.End.Equal(want) {
+		t.Fatalf("end = %v, want %v", ev.End, want)
+	}
+	if ev.TZID != "Custom Berlin" || ev.RRule != "FREQ=WEEKLY;COUNT=4;BYDAY=MO" || ev.AllDay {
+		t.Fatalf("event = %+v", ev)
+	}
+}
+
+func TestParseTime_Zones(t *testing.T) {
+	z := parseVTimezone(mustComponent(t, outlookInvite).children[0].children[0])
+	zones := map[string]*vtimezone{z.id: z}
+	cases := []struct {
+		value, tzid string
+		want        time.Time
+	}{
+		{"20240115T100000", "Custom Berlin", time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)},
+		{"20240331T030000", "Custom Berlin", time.Date(2024, 3, 31, 1, 0, 0, 0, time.UTC)},
+		{"20240115T100000", "America/New_York", time.Date(2024, 1, 15, 15, 0, 0, 0, time.UTC)},
+		{"20240715T100000", "Pacific Standard Time", time.Date(2024, 7, 15, 17, 0, 0, 0, time.UTC)},
+		{"20240715T100000", "", time.Date(2024, 7, 15, 10, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		p := property{name: "DTSTART", value: c.value}
+		if c.tzid != "" {
+			p.params = map[string]string{"TZID": c.tzid}
+		}
+		got, _, _, err := parseTime(p, zones)
+		if err != nil || !got.Equal(c.want) {
+			t.Errorf("%s %s: got %v, %v; want %v", c.value, c.tzid, got, err, c.want)
+		}
+	}
+}
+
+func TestParse_AllDayAndDuration(t *testing.T) {
+	cal, err := Parse([]byte(`BEGIN:VCALENDAR
+METHOD:CANCEL
+BEGIN:VEVENT
+UID:a
+DTSTART;VALUE=DATE:20240501
+END:VEVENT
+BEGIN:VEVENT
+UID:b
+DTSTART:20240501T090000Z
+DURATION:PT1H30M
+END:VEVENT
+END:VCALENDAR`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cal.Method != MethodCancel || len(cal.Events) != 2 {
+		t.Fatalf("calendar = %+v", cal)
+	}
+	a := cal.Events[0]
+	if !a.AllDay || !a.End.Equal(time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("all-day event = %+v", a)
+	}
+	b := cal.Events[1]
+	if b.AllDay || !b.End.Equal(time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)) {
+		t.Fatalf("event with duration = %+v", b)
+	}
+}
+
+func TestParse_NotCalendar(t *testing.T) {
+	if _, err := Parse([]byte("BEGIN:VCARD\nFN:Bob\nEND:VCARD\n")); err != ErrNotCalendar {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func mustComponent(t *testing.T, s string) *component {
+	t.Helper()
+	root, err := parseComponents([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
@@ -0,0 +1,95 @@
+package ical
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// seriesEnd returns a time by which every occurrence of a recurring event
+// has ended, or nil when the series is unbounded or its end is not cheap to
+// work out. UNTIL bounds the last start; COUNT is stepped through only for
+// rules whose occurrences are spaced evenly (no BY* parts except BYDAY on a
+// weekly rule, which still leaves at least one occurrence per week).
+func seriesEnd(ev Event, zones map[string]*vtimezone) *time.Time {
+	if ev.RRule == "" || ev.Start == nil {
+		return nil
+	}
+	parts := map[string]string{}
+	for _, kv := range strings.Split(ev.RRule, ";") {
+		k, v, _ := strings.Cut(kv, "=")
+		parts[strings.ToUpper(strings.TrimSpace(k))] = strings.TrimSpace(v)
+	}
+	var length time.Duration
+	if ev.End != nil {
+		length = ev.End.Sub(*ev.Start)
+	}
+
+	if until, ok := parts["UNTIL"]; ok {
+		p := property{value: until}
+		if ev.TZID != "" && ev.TZID != "UTC" {
+			p.params = map[string]string{"TZID": ev.TZID}
+		}
+		t, allDay, _, err := parseTime(p, zones)
+		if err != nil {
+			return nil
+		}
+		if allDay && !ev.AllDay {
+			// A date-only UNTIL on a timed event still allows that whole day.
+			t = t.AddDate(0, 0, 1)
+		}
+		end := t.Add(length)
+		return &end
+	}
+
+	count, err := strconv.Atoi(parts["COUNT"])
+	if err != nil || count < 1 {
+		return nil
+	}
+	interval := 1
+	if n, err := strconv.Atoi(parts["INTERVAL"]); err == nil && n > 0 {
+		interval = n
+	}
+	freq := strings.ToUpper(parts["FREQ"])
+	steps := count - 1
+	for k := range parts {
+		if !strings.HasPrefix(k, "BY") {
+			continue
+		}
+		if k != "BYDAY" || freq != "WEEKLY" {
+			return nil
+		}
+		steps = count
+	}
+
+	start := *ev.Start
+	if loc := loadLocation(ev.TZID); loc != nil {
+		start = start.In(loc)
+	}
+	if (freq == "MONTHLY" && start.Day() > 28) || (freq == "YEARLY" && start.Month() == time.February && start.Day() == 29) {
+		// Months or years without that day are skipped, not shifted.
+		return nil
+	}
+	n := steps * interval
+	var last time.Time
+	switch freq {
+	case "SECONDLY":
+		last = start.Add(time.Duration(n) * time.Second)
+	case "MINUTELY":
+		last = start.Add(time.Duration(n) * time.Minute)
+	case "HOURLY":
+		last = start.Add(time.Duration(n) * time.Hour)
+	case "DAILY":
+		last = start.AddDate(0, 0, n)
+	case "WEEKLY":
+		last = start.AddDate(0, 0, 7*n)
+	case "MONTHLY":
+		last = start.AddDate(0, n, 0)
+	case "YEARLY":
+		last = start.AddDate(n, 0, 0)
+	default:
+		return nil
+	}
+	end := last.Add(length).UTC()
+	return &end
+}
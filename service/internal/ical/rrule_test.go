@@ -0,0 +1,48 @@
+package ical
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSeriesEnd(t *testing.T) {
+	start := time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	at := func(y int, m time.Month, d, h int) *time.Time {
+		t := time.Date(y, m, d, h, 0, 0, 0, time.UTC)
+		return &t
+	}
+	cases := []struct {
+		rrule string
+		tzid  string
+		want  *time.Time
+	}{
+		{"", "UTC", nil},
+		{"FREQ=WEEKLY", "UTC", nil},
+		{"FREQ=WEEKLY;UNTIL=20240129T090000Z", "UTC", at(2024, 1, 29, 10)},
+		{"FREQ=DAILY;UNTIL=20240110", "UTC", at(2024, 1, 11, 1)},
+		{"FREQ=DAILY;COUNT=3", "UTC", at(2024, 1, 10, 10)},
+		{"FREQ=MONTHLY;INTERVAL=2;COUNT=3", "UTC", at(2024, 5, 8, 10)},
+		{"FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4", "UTC", at(2024, 2, 5, 10)},
+		{"FREQ=MONTHLY;BYMONTHDAY=31;COUNT=2", "UTC", nil},
+		// Floating UNTIL is read in the zone of DTSTART (UTC+1 in winter).
+		{"FREQ=DAILY;UNTIL=20240110T100000", "Europe/Berlin", at(2024, 1, 10, 10)},
+	}
+	for _, c := range cases {
+		got := seriesEnd(Event{Start: &start, End: &end, TZID: c.tzid, RRule: c.rrule}, nil)
+		if (got == nil) != (c.want == nil) || (got != nil && !got.Equal(*c.want)) {
+			t.Errorf("%q: got %v, want %v", c.rrule, got, c.want)
+		}
+	}
+}
+
+func TestParse_SeriesEnd(t *testing.T) {
+	cal, err := Parse([]byte("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:r\r\nDTSTART:20200106T090000Z\r\n" +
+		"DTEND:20200106T093000Z\r\nRRULE:FREQ=WEEKLY;COUNT=2\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cal.Events[0].SeriesEnd; got == nil || !got.Equal(time.Date(2020, 1, 13, 9, 30, 0, 0, time.UTC)) {
+		t.Fatalf("series end = %v", got)
+	}
+}
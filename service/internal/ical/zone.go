@@ -0,0 +1,253 @@
+package ical
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // invites name zones the container may not ship
+)
+
+// parseTime reads a DATE or DATE-TIME property and returns it in UTC.
+// Zones are resolved from the IANA database, then from the Windows names
+// Outlook and Exchange use, then from the VTIMEZONE definitions in the
+// calendar; a floating time with no usable zone is taken as UTC.
+func parseTime(p property, zones map[string]*vtimezone) (time.Time, bool, string, error) {
+	v := strings.TrimSpace(p.value)
+	if strings.EqualFold(p.param("VALUE"), "DATE") || len(v) == 8 {
+		t, err := time.Parse("20060102", v)
+		return t, true, "", err
+	}
+	if strings.HasSuffix(v, "Z") {
+		t, err := time.Parse("20060102T150405Z", v)
+		return t, false, "UTC", err
+	}
+	local, err := time.ParseInLocation("20060102T150405", v, time.UTC)
+	if err != nil {
+		return time.Time{}, false, "", err
+	}
+	tzid := strings.TrimPrefix(p.param("TZID"), "/")
+	if tzid == "" {
+		return local, false, "", nil
+	}
+	if loc := loadLocation(tzid); loc != nil {
+		t := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), 0, loc)
+		return t.UTC(), false, tzid, nil
+	}
+	if z, ok := zones[tzid]; ok {
+		return local.Add(-time.Duration(z.offsetAt(local)) * time.Second), false, tzid, nil
+	}
+	return local, false, tzid, nil
+}
+
+func loadLocation(tzid string) *time.Location {
+	if loc, err := time.LoadLocation(tzid); err == nil {
+		return loc
+	}
+	if name, ok := windowsZones[tzid]; ok {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return nil
+}
+
+// vtimezone is a VTIMEZONE definition reduced to its observances.
+type vtimezone struct {
+	id          string
+	observances []observance
+}
+
+// observance is a STANDARD or DAYLIGHT block. start is local wall time
+// stored as UTC.
+type observance struct {
+	start    time.Time
+	offsetTo int // seconds east of UTC
+	month    time.Month
+	week     int // 1..5 from the start of the month, negative from the end
+	weekday  time.Weekday
+	until    time.Time
+	yearly   bool
+}
+
+func parseVTimezone(c *component) *vtimezone {
+	z := &vtimezone{id: strings.TrimPrefix(c.text("TZID"), "/")}
+	if z.id == "" {
+		return nil
+	}
+	for _, sub := range c.children {
+		if sub.name != "STANDARD" && sub.name != "DAYLIGHT" {
+			continue
+		}
+		off, err := parseOffset(sub.text("TZOFFSETTO"))
+		if err != nil {
+			continue
+		}
+		o := observance{offsetTo: off}
+		if t, err := time.ParseInLocation("20060102T150405", strings.TrimSpace(sub.text("DTSTART")), time.UTC); err == nil {
+			o.start = t
+		}
+		o.parseRule(sub.text("RRULE"))
+		z.observances = append(z.observances, o)
+	}
+	if len(z.observances) == 0 {
+		return nil
+	}
+	return z
+}
+
+// parseRule understands the yearly BYMONTH/BYDAY rules zone definitions use,
+// e.g. FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU.
+func (o *observance) parseRule(rule string) {
+	if rule == "" {
+		return
+	}
+	parts := map[string]string{}
+	for _, kv := range strings.Split(rule, ";") {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			parts[strings.ToUpper(k)] = strings.ToUpper(v)
+		}
+	}
+	if parts["FREQ"] != "YEARLY" {
+		return
+	}
+	m, err := strconv.Atoi(parts["BYMONTH"])
+	if err != nil || m < 1 || m > 12 {
+		return
+	}
+	day := parts["BYDAY"]
+	if len(day) < 3 {
+		return
+	}
+	wd, ok := weekdays[day[len(day)-2:]]
+	if !ok {
+		return
+	}
+	week := 1
+	if n := day[:len(day)-2]; n != "" {
+		if week, err = strconv.Atoi(n); err != nil || week == 0 {
+			return
+		}
+	}
+	if u := parts["UNTIL"]; u != "" {
+		if t, err := time.Parse("20060102T150405Z", u); err == nil {
+			o.until = t
+		}
+	}
+	o.month, o.week, o.weekday, o.yearly = time.Month(m), week, wd, true
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// onset returns the yearly transition of o in year, in local wall time.
+func (o observance) onset(year int) (time.Time, bool) {
+	if !o.yearly || year < o.start.Year() {
+		return time.Time{}, false
+	}
+	clock := time.Duration(o.start.Hour())*time.Hour + time.Duration(o.start.Minute())*time.Minute
+	var d time.Time
+	if o.week > 0 {
+		d = time.Date(year, o.month, 1, 0, 0, 0, 0, time.UTC)
+		d = d.AddDate(0, 0, (int(o.weekday)-int(d.Weekday())+7)%7+7*(o.week-1))
+	} else {
+		d = time.Date(year, o.month+1, 0, 0, 0, 0, 0, time.UTC)
+		d = d.AddDate(0, 0, -((int(d.Weekday())-int(o.weekday)+7)%7)+7*(o.week+1))
+	}
+	t := d.Add(clock)
+	if !o.until.IsZero() && t.After(o.until) {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// offsetAt returns the UTC offset in seconds in effect at the local time: that
+// of the latest observance onset not after it.
+func (z *vtimezone) offsetAt(local time.Time) int {
+	best := z.observances[0]
+	var bestAt time.Time
+	for _, o := range z.observances {
+		for _, y := range []int{local.Year() - 1, local.Year()} {
+			if at, ok := o.onset(y); ok && !at.After(local) && at.After(bestAt) {
+				best, bestAt = o, at
+			}
+		}
+		if !o.yearly && !o.start.After(local) && o.start.After(bestAt) {
+			best, bestAt = o, o.start
+		}
+	}
+	return best.offsetTo
+}
+
+// parseOffset reads a UTC-OFFSET value: +HHMM, -HHMM or with seconds.
+func parseOffset(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if len(s) != 5 && len(s) != 7 || (s[0] != '+' && s[0] != '-') {
+		return 0, errors.New("ical: bad utc offset")
+	}
+	h, err1 := strconv.Atoi(s[1:3])
+	m, err2 := strconv.Atoi(s[3:5])
+	sec := 0
+	var err3 error
+	if len(s) == 7 {
+		sec, err3 = strconv.Atoi(s[5:7])
+	}
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0, errors.New("ical: bad utc offset")
+	}
+	off := h*3600 + m*60 + sec
+	if s[0] == '-' {
+		off = -off
+	}
+	return off, nil
+}
+
+// windowsZones maps the Windows time zone names found in Outlook invites to
+// IANA zones (from CLDR windowsZones.xml, territory 001).
+var windowsZones = map[string]string{
+	"Dateline Standard Time":          "Etc/GMT+12",
+	"Hawaiian Standard Time":          "Pacific/Honolulu",
+	"Alaskan Standard Time":           "America/Anchorage",
+	"Pacific Standard Time":           "America/Los_Angeles",
+	"US Mountain Standard Time":       "America/Phoenix",
+	"Mountain Standard Time":          "America/Denver",
+	"Central Standard Time":           "America/Chicago",
+	"Central America Standard Time":   "America/Guatemala",
+	"Eastern Standard Time":           "America/New_York",
+	"Atlantic Standard Time":          "America/Halifax",
+	"Newfoundland Standard Time":      "America/St_Johns",
+	"E. South America Standard Time":  "America/Sao_Paulo",
+	"SA Pacific Standard Time":        "America/Bogota",
+	"Argentina Standard Time":         "America/Buenos_Aires",
+	"UTC":                             "Etc/UTC",
+	"GMT Standard Time":               "Europe/London",
+	"Greenwich Standard Time":         "Atlantic/Reykjavik",
+	"W. Europe Standard Time":         "Europe/Berlin",
+	"Central Europe Standard Time":    "Europe/Budapest",
+	"Central European Standard Time":  "Europe/Warsaw",
+	"Romance Standard Time":           "Europe/Paris",
+	"E. Europe Standard Time":         "Europe/Chisinau",
+	"FLE Standard Time":               "Europe/Kiev",
+	"GTB Standard Time":               "Europe/Bucharest",
+	"Israel Standard Time":            "Asia/Jerusalem",
+	"South Africa Standard Time":      "Africa/Johannesburg",
+	"Turkey Standard Time":            "Europe/Istanbul",
+	"Russian Standard Time":           "Europe/Moscow",
+	"Arabian Standard Time":           "Asia/Dubai",
+	"Pakistan Standard Time":          "Asia/Karachi",
+	"India Standard Time":             "Asia/Calcutta",
+	"Ekaterinburg Standard Time":      "Asia/Yekaterinburg",
+	"N. Central Asia Standard Time":   "Asia/Novosibirsk",
+	"SE Asia Standard Time":           "Asia/Bangkok",
+	"China Standard Time":             "Asia/Shanghai",
+	"Singapore Standard Time":         "Asia/Singapore",
+	"Tokyo Standard Time":             "Asia/Tokyo",
+	"Korea Standard Time":             "Asia/Seoul",
+	"AUS Eastern Standard Time":       "Australia/Sydney",
+	"E. Australia Standard Time":      "Australia/Brisbane",
+	"New Zealand Standard Time":       "Pacific/Auckland",
+	"Vladivostok Standard Time":       "Asia/Vladivostok",
+	"W. Central Africa Standard Time": "Africa/Lagos",
+}
@@ -0,0 +1,93 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Checkpoint records finished files, one path per line, so an interrupted
+// import can resume where it stopped.
+type Checkpoint struct {
+	mu   sync.Mutex
+	done map[string]struct{}
+	f    *os.File
+	w    *bufio.Writer
+}
+
+// OpenCheckpoint loads path if it exists and opens it for appending.
+func OpenCheckpoint(path string) (*Checkpoint, error) {
+	cp := &Checkpoint{done: map[string]struct{}{}}
+	if f, err := os.Open(path); err == nil {
+		sc := bufio.NewScanner(f)
+		sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+		for sc.Scan() {
+			if line := strings.TrimSpace(sc.Text()); line != "" {
+				cp.done[line] = struct{}{}
+			}
+		}
+		err = sc.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	cp.f, cp.w = f, bufio.NewWriter(f)
+	return cp, nil
+}
+
+// Len returns the number of files recorded so far.
+func (c *Checkpoint) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.done)
+}
+
+func (c *Checkpoint) Done(path string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.done[path]
+	return ok
+}
+
+// Mark records path as finished. Writes are buffered; Flush or Close persists them.
+func (c *Checkpoint) Mark(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.done[path]; ok {
+		return nil
+	}
+	c.done[path] = struct{}{}
+	if _, err := c.w.WriteString(path + "\n"); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Checkpoint) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.w.Flush()
+}
+
+func (c *Checkpoint) Close() error {
+	if err := c.Flush(); err != nil {
+		c.f.Close()
+		return err
+	}
+	if err := c.f.Sync(); err != nil {
+		c.f.Close()
+		return err
+	}
+	return c.f.Close()
+}
@@ -0,0 +1,232 @@
+// Package importer bulk-loads messages from Maildir folders and .eml trees
+// straight into the repository, bypassing the HTTP API.
+package importer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/Zifeldev/emailback/service/internal/service"
+)
+
+// Stats are running totals; read them with Snapshot while an import is active.
+type Stats struct {
+	Found    int64 `json:"found"`
+	Skipped  int64 `json:"skipped"` // already in the checkpoint
+	Imported int64 `json:"imported"`
+	Failed   int64 `json:"failed"`
+	Bytes    int64 `json:"bytes"`
+}
+
+func (s *Stats) Snapshot() Stats {
+	return Stats{
+		Found:    atomic.LoadInt64(&s.Found),
+		Skipped:  atomic.LoadInt64(&s.Skipped),
+		Imported: atomic.LoadInt64(&s.Imported),
+		Failed:   atomic.LoadInt64(&s.Failed),
+		Bytes:    atomic.LoadInt64(&s.Bytes),
+	}
+}
+
+// DefaultMaxSize bounds a message file read into memory when MaxSize is zero.
+const DefaultMaxSize = 25 << 20
+
+var ErrTooLarge = errors.New("importer: file exceeds size limit")
+
+// Failure describes one file, or directory, that could not be imported.
+type Failure struct {
+	Path string
+	Err  error
+}
+
+type Importer struct {
+	Parser      service.Parser
+	Repo        repository.EmailRepository // unused in dry-run mode
+	Concurrency int
+	Timeout     time.Duration // per message; zero means none
+	MaxSize     int64         // per file in bytes; zero means DefaultMaxSize
+	DryRun      bool          // parse only; nothing is saved or checkpointed
+	Checkpoint  *Checkpoint   // optional
+
+	// OnProgress is called every ProgressEvery with the running totals.
+	OnProgress    func(Stats)
+	ProgressEvery time.Duration
+	// OnFailure is called for every failed file; it may be called concurrently.
+	OnFailure func(Failure)
+}
+
+// Run imports every message under roots. Per-file failures and unreadable
+// directories are reported via OnFailure and counted; Run only returns an
+// error when a root cannot be walked or ctx is cancelled.
+func (im *Importer) Run(ctx context.Context, roots ...string) (Stats, error) {
+	var stats Stats
+	workers := im.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	paths := make(chan string, workers*2)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for p := range paths {
+				im.importFile(ctx, p, &stats)
+			}
+		}()
+	}
+
+	stopProgress := im.startProgress(&stats)
+
+	var walkErr error
+	for _, root := range roots {
+		walkErr = Walk(root, func(path string) error {
+			atomic.AddInt64(&stats.Found, 1)
+			if im.Checkpoint != nil && im.Checkpoint.Done(path) {
+				atomic.AddInt64(&stats.Skipped, 1)
+				return nil
+			}
+			select {
+			case paths <- path:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}, func(path string, err error) {
+			im.fail(&stats, path, err)
+		})
+		if walkErr != nil {
+			break
+		}
+	}
+	close(paths)
+	wg.Wait()
+	stopProgress()
+
+	if im.Checkpoint != nil {
+		if err := im.Checkpoint.Flush(); err != nil && walkErr == nil {
+			walkErr = err
+		}
+	}
+	return stats.Snapshot(), walkErr
+}
+
+func (im *Importer) importFile(ctx context.Context, path string, stats *Stats) {
+	if ctx.Err() != nil {
+		return
+	}
+	fail := func(err error) { im.fail(stats, path, err) }
+
+	// Files over the limit are not checkpointed, so a rerun with a higher
+	// MaxSize picks them up.
+	raw, err := im.readFile(path)
+	if err != nil {
+		fail(err)
+		return
+	}
+	atomic.AddInt64(&stats.Bytes, int64(len(raw)))
+
+	mctx, cancel := ctx, context.CancelFunc(func() {})
+	if im.Timeout > 0 {
+		mctx, cancel = context.WithTimeout(ctx, im.Timeout)
+	}
+	defer cancel()
+
+	ent, err := im.Parser.Parse(mctx, raw)
+	if ctx.Err() != nil {
+		return // interrupted; leave the file for the next run
+	}
+	if err != nil {
+		fail(err)
+		// Unparseable files will not get better on a retry.
+		im.mark(path)
+		return
+	}
+	if im.DryRun {
+		atomic.AddInt64(&stats.Imported, 1)
+		return
+	}
+	if err := im.Repo.SaveEmail(mctx, ent); err != nil {
+		if ctx.Err() == nil {
+			fail(err)
+		}
+		return
+	}
+	atomic.AddInt64(&stats.Imported, 1)
+	im.mark(path)
+}
+
+func (im *Importer) fail(stats *Stats, path string, err error) {
+	atomic.AddInt64(&stats.Failed, 1)
+	if im.OnFailure != nil {
+		im.OnFailure(Failure{Path: path, Err: err})
+	}
+}
+
+// readFile reads a message file of at most MaxSize bytes. The size is checked
+// before reading and again while reading, in case the file grows.
+func (im *Importer) readFile(path string) ([]byte, error) {
+	limit := im.MaxSize
+	if limit <= 0 {
+		limit = DefaultMaxSize
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err != nil {
+		return nil, err
+	} else if fi.Size() > limit {
+		return nil, ErrTooLarge
+	}
+	raw, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(raw)) > limit {
+		return nil, ErrTooLarge
+	}
+	return raw, nil
+}
+
+func (im *Importer) mark(path string) {
+	if im.Checkpoint != nil && !im.DryRun {
+		_ = im.Checkpoint.Mark(path)
+	}
+}
+
+func (im *Importer) startProgress(stats *Stats) func() {
+	if im.OnProgress == nil || im.ProgressEvery <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		t := time.NewTicker(im.ProgressEvery)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				if im.Checkpoint != nil {
+					_ = im.Checkpoint.Flush()
+				}
+				im.OnProgress(stats.Snapshot())
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
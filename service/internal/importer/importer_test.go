@@ -0,0 +1,212 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Zifeldev/emailback/service/internal/repository"
+)
+
+type fakeParser struct{}
+
+func (fakeParser) Parse(_ context.Context, raw []byte) (*repository.EmailEntity, error) {
+	if strings.HasPrefix(string(raw), "broken") {
+		return nil, errors.New("unparseable")
+	}
+	return &repository.EmailEntity{ID: string(raw), MessageID: string(raw)}, nil
+}
+
+type fakeRepo struct {
+	mu    sync.Mutex
+	saved []string
+	fail  map[string]bool
+}
+
+func (r *fakeRepo) SaveEmail(_ context.Context, e *repository.EmailEntity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail[e.ID] {
+		return errors.New("db down")
+	}
+	r.saved = append(r.saved, e.ID)
+	return nil
+}
+func (r *fakeRepo) GetByID(context.Context, string) (*repository.EmailEntity, error) {
+	return nil, repository.ErrEmailNotFound
+}
+func (r *fakeRepo) GetAll(context.Context, int, int, repository.EmailFilter) ([]*repository.EmailEntity, error) {
+	return nil, nil
+}
+
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, body := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(p, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func testTree(t *testing.T) string {
+	return writeTree(t, map[string]string{
+		"INBOX/cur/1:2,S":          "m1",
+		"INBOX/new/2":              "m2",
+		"INBOX/tmp/3":              "partial",
+		"INBOX/.Sent/cur/4:2,S":    "m4",
+		"INBOX/.Sent/new/":         "",
+		"INBOX/.Sent/tmp/":         "",
+		"export/a.eml":             "m5",
+		"export/nested/b.EML":      "broken6",
+		"export/notes.txt":         "ignored",
+		"export/cur/not-a-maildir": "ignored",
+	})
+}
+
+func TestWalk(t *testing.T) {
+	root := testTree(t)
+	var got []string
+	if err := Walk(root, func(p string) error {
+		rel, _ := filepath.Rel(root, p)
+		got = append(got, filepath.ToSlash(rel))
+		return nil
+	}, func(p string, err error) { t.Errorf("%s: %v", p, err) }); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"INBOX/.Sent/cur/4:2,S", "INBOX/cur/1:2,S", "INBOX/new/2", "export/a.eml", "export/nested/b.EML"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("walk = %v, want %v", got, want)
+	}
+}
+
+func TestImporter_RunAndResume(t *testing.T) {
+	root := testTree(t)
+	cpPath := filepath.Join(t.TempDir(), "import.checkpoint")
+	repo := &fakeRepo{fail: map[string]bool{"m5": true}}
+
+	cp, err := OpenCheckpoint(cpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failures []string
+	var mu sync.Mutex
+	im := &Importer{Parser: fakeParser{}, Repo: repo, Concurrency: 3, Checkpoint: cp,
+		OnFailure: func(f Failure) { mu.Lock(); failures = append(failures, filepath.Base(f.Path)); mu.Unlock() }}
+	stats, err := im.Run(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Found != 5 || stats.Imported != 3 || stats.Failed != 2 || stats.Skipped != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	sort.Strings(failures)
+	if strings.Join(failures, ",") != "a.eml,b.EML" {
+		t.Fatalf("unexpected failures: %v", failures)
+	}
+
+	// Second run: saved and unparseable files are skipped, the save failure is retried.
+	repo.fail = nil
+	cp, err = OpenCheckpoint(cpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cp.Close()
+	if cp.Len() != 4 {
+		t.Fatalf("checkpoint should hold 4 files, got %d", cp.Len())
+	}
+	im.Checkpoint = cp
+	stats, err = im.Run(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Skipped != 4 || stats.Imported != 1 || stats.Failed != 0 {
+		t.Fatalf("unexpected resume stats: %+v", stats)
+	}
+	if len(repo.saved) != 4 {
+		t.Fatalf("expected 4 saved messages overall, got %v", repo.saved)
+	}
+}
+
+func TestImporter_DryRun(t *testing.T) {
+	root := testTree(t)
+	repo := &fakeRepo{}
+	cp, err := OpenCheckpoint(filepath.Join(t.TempDir(), "cp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cp.Close()
+
+	stats, err := (&Importer{Parser: fakeParser{}, Repo: repo, DryRun: true, Checkpoint: cp}).Run(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Imported != 4 || stats.Failed != 1 || len(repo.saved) != 0 || cp.Len() != 0 {
+		t.Fatalf("dry run should parse only: %+v saved=%v checkpoint=%d", stats, repo.saved, cp.Len())
+	}
+}
+
+func TestImporter_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := (&Importer{Parser: fakeParser{}, Repo: &fakeRepo{}}).Run(ctx, testTree(t))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, got %v", err)
+	}
+}
+
+func TestImporter_TooLarge(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.eml": "m1", "big.eml": strings.Repeat("x", 64)})
+	var failures []Failure
+	repo := &fakeRepo{}
+	stats, err := (&Importer{Parser: fakeParser{}, Repo: repo, MaxSize: 32,
+		OnFailure: func(f Failure) { failures = append(failures, f) }}).Run(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Imported != 1 || stats.Failed != 1 || len(failures) != 1 ||
+		filepath.Base(failures[0].Path) != "big.eml" || !errors.Is(failures[0].Err, ErrTooLarge) {
+		t.Fatalf("oversized file should fail: %+v %+v", stats, failures)
+	}
+}
+
+func TestImporter_UnreadableDirectoryIsSkipped(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+	root := writeTree(t, map[string]string{"a/1.eml": "m1", "b/2.eml": "m2"})
+	locked := filepath.Join(root, "a")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(locked, 0o755)
+
+	var failures []Failure
+	stats, err := (&Importer{Parser: fakeParser{}, Repo: &fakeRepo{},
+		OnFailure: func(f Failure) { failures = append(failures, f) }}).Run(context.Background(), root)
+	if err != nil {
+		t.Fatalf("an unreadable subdirectory must not stop the import: %v", err)
+	}
+	if stats.Imported != 1 || stats.Failed != 1 || len(failures) != 1 || failures[0].Path != locked {
+		t.Fatalf("unexpected result: %+v %+v", stats, failures)
+	}
+}
@@ -0,0 +1,63 @@
+package importer
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Walk calls fn for every message file under root, in lexical order. A file
+// is a message if it sits in the cur/ or new/ directory of a Maildir (a
+// directory that also has tmp/), or if its extension is .eml. Maildir tmp/
+// directories hold partial deliveries and are skipped. root may also be a
+// single file. Entries below root that cannot be read are passed to onErr
+// and skipped; only an unreadable root ends the walk.
+func Walk(root string, fn func(path string) error, onErr func(path string, err error)) error {
+	maildirs := map[string]bool{}
+	isMaildir := func(dir string) bool {
+		if v, ok := maildirs[dir]; ok {
+			return v
+		}
+		v := true
+		for _, sub := range []string{"cur", "new", "tmp"} {
+			if fi, err := os.Stat(filepath.Join(dir, sub)); err != nil || !fi.IsDir() {
+				v = false
+				break
+			}
+		}
+		maildirs[dir] = v
+		return v
+	}
+
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			onErr(path, err)
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == "tmp" && path != root && isMaildir(filepath.Dir(path)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if path == root || strings.EqualFold(filepath.Ext(path), ".eml") {
+			return fn(path)
+		}
+		dir := filepath.Dir(path)
+		if base := filepath.Base(dir); (base == "cur" || base == "new") &&
+			!strings.HasPrefix(d.Name(), ".") && isMaildir(filepath.Dir(dir)) {
+			return fn(path)
+		}
+		return nil
+	})
+}
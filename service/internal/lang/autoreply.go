@@ -0,0 +1,55 @@
+package lang
+
+import "regexp"
+
+var (
+	// Subjects clients put on out-of-office replies, usually as a prefix to
+	// the original subject: Outlook, Exchange, Gmail, Yandex, Mail.ru, Zimbra.
+	reVacationSubject = regexp.MustCompile(`(?i)` +
+		`out of (?:the )?office|automatic reply|auto[- ]?reply|auto[- ]?response|autoresponder|away from (?:the )?office|` +
+		`abwesenheitsnotiz|abwesenheit|automatische antwort|nicht im büro|` +
+		`автоответ|автоматический ответ|вне офиса|` +
+		`absence du bureau|réponse automatique|absent du bureau|` +
+		`respuesta automática|fuera de la oficina|` +
+		`risposta automatica|fuori sede|fuori ufficio|` +
+		`automatisch antwoord|` +
+		`resposta automática|ausente do escritório|` +
+		`odpowiedź automatyczna|nieobecność`)
+
+	// Phrases an out-of-office body is built around. They also occur in
+	// ordinary mail, so they only count for messages already marked automatic.
+	reVacationBody = regexp.MustCompile(`(?i)` +
+		`out of (?:the )?office|on (?:annual )?(?:vacation|holiday|leave)|away from (?:the )?office|limited access to (?:my )?e-?mail|will (?:be )?(?:back|return)|` +
+		`nicht im büro|abwesend|im urlaub|eingeschränkt erreichbar|ab dem .{1,20} wieder|` +
+		`в отпуске|вне офиса|отсутствую|не в офисе|ограниченный доступ к почте|` +
+		`absent du bureau|en congé|en vacances|` +
+		`fuera de la oficina|de vacaciones|` +
+		`fuori ufficio|in ferie|` +
+		`afwezig|met vakantie|` +
+		`de férias|ausente|` +
+		`na urlopie|poza biurem`)
+
+	// Subjects of mail triggered by something the recipient did: orders,
+	// receipts, account security, sign-up confirmations.
+	reTransactionalSubject = regexp.MustCompile(`(?i)` +
+		`\b(?:order|receipt|invoice|payment|shipment|shipped|delivery|password|verification|verify|confirm|confirmation|security (?:alert|code)|sign[- ]?in|login|one[- ]time|otp|2fa|account)\b|` +
+		`bestellung|rechnung|zahlung|versand|passwort|bestätig|anmeldung|konto|` +
+		`заказ|квитанц|чек|счёт|счет|оплат|доставк|пароль|подтвержд|код|вход|аккаунт`)
+)
+
+// IsVacationSubject reports whether subject marks an out-of-office reply
+// ("Out of Office: ...", "Abwesenheitsnotiz: ...", "Автоответ: ...").
+func IsVacationSubject(subject string) bool {
+	return reVacationSubject.MatchString(subject)
+}
+
+// IsVacationBody reports whether body reads like an out-of-office notice.
+func IsVacationBody(body string) bool {
+	return reVacationBody.MatchString(body)
+}
+
+// IsTransactionalSubject reports whether subject reads like a receipt,
+// order update or account notice rather than a newsletter.
+func IsTransactionalSubject(subject string) bool {
+	return reTransactionalSubject.MatchString(subject)
+}
@@ -0,0 +1,48 @@
+package lang
+
+import "testing"
+
+func TestIsVacationSubject(t *testing.T) {
+	for _, s := range []string{
+		"Out of Office: Quarterly report",
+		"Automatic reply: invoice #42",
+		"Abwesenheitsnotiz: Angebot",
+		"Автоответ: Re: договор",
+		"Réponse automatique : devis",
+	} {
+		if !IsVacationSubject(s) {
+			t.Errorf("expected vacation subject: %q", s)
+		}
+	}
+	for _, s := range []string{"Re: Quarterly report", "Office party on Friday", "Договор на подпись"} {
+		if IsVacationSubject(s) {
+			t.Errorf("unexpected vacation subject: %q", s)
+		}
+	}
+}
+
+func TestIsVacationBody(t *testing.T) {
+	for _, s := range []string{
+		"I am currently out of the office with limited access to email.",
+		"Ich bin bis zum 12.08. im Urlaub und ab dem 13.08. wieder erreichbar.",
+		"Я в отпуске до 15 июля.",
+	} {
+		if !IsVacationBody(s) {
+			t.Errorf("expected vacation body: %q", s)
+		}
+	}
+	if IsVacationBody("Your ticket #123 has been received.") {
+		t.Errorf("ticket acknowledgement classified as vacation")
+	}
+}
+
+func TestIsTransactionalSubject(t *testing.T) {
+	for _, s := range []string{"Your order #123 has shipped", "Ihre Rechnung für Juli", "Код подтверждения: 4821", "Reset your password"} {
+		if !IsTransactionalSubject(s) {
+			t.Errorf("expected transactional subject: %q", s)
+		}
+	}
+	if IsTransactionalSubject("This week in Go: generics deep dive") {
+		t.Errorf("newsletter subject classified as transactional")
+	}
+}
@@ -8,7 +8,39 @@ import (
 	"golang.org/x/net/html"
 )
 
+// CleanReport describes what CleanText removed from a body. It is only
+// collected on request, for parse previews.
+type CleanReport struct {
+	InputChars   int  `json:"input_chars"`
+	OutputChars  int  `json:"output_chars"`
+	HTML         bool `json:"html"`          // body was converted from HTML
+	TagsStripped bool `json:"tags_stripped"` // HTML parsing failed; tags were cut out instead
+	ReplyHeaders int  `json:"reply_headers"` // "On ... wrote:" style lines
+	// SignatureChars is how much trailing text was cut at a sign-off or
+	// signature delimiter.
+	SignatureChars int `json:"signature_chars"`
+	ForwardHeaders int `json:"forward_headers"`
+	QuotedLines    int `json:"quoted_lines"`
+	HeaderLines    int `json:"header_lines"`
+	URLs           int `json:"urls"`
+	Emails         int `json:"emails"`
+}
+
 func CleanText(s string) string {
+	return cleanText(s, nil)
+}
+
+// CleanTextReport is CleanText that also reports what was removed.
+func CleanTextReport(s string) (string, CleanReport) {
+	var r CleanReport
+	out := cleanText(s, &r)
+	return out, r
+}
+
+func cleanText(s string, r *CleanReport) string {
+	if r != nil {
+		r.InputChars = len([]rune(s))
+	}
 	if s == "" {
 		return s
 	}
@@ -18,11 +50,21 @@ func CleanText(s string) string {
 			s = t
 		} else {
 			s = stripTagsFallback(s)
+			if r != nil {
+				r.TagsStripped = true
+			}
+		}
+		if r != nil {
+			r.HTML = true
 		}
 	}
 
+	if r != nil {
+		r.ReplyHeaders += countMatches(reReplyHeaderAnywhere, s)
+	}
 	s = reReplyHeaderAnywhere.ReplaceAllString(s, "\n")
 
+	before := len([]rune(s))
 	if loc := reSignatureAnywhere.FindStringIndex(s); loc != nil {
 		s = s[:loc[0]]
 	}
@@ -30,10 +72,17 @@ func CleanText(s string) string {
 	if idx := findSignatureIndex(s); idx >= 0 {
 		s = s[:idx]
 	}
+	if r != nil {
+		r.SignatureChars = before - len([]rune(s))
+	}
 
 	s = strings.ReplaceAll(s, "\r\n", "\n")
 	s = strings.ReplaceAll(s, "\r", "\n")
 
+	if r != nil {
+		r.ReplyHeaders += countMatches(reReplyHeader, s)
+		r.ForwardHeaders += countMatches(reForwardHeader, s) + countMatches(reOriginalMessage, s)
+	}
 	s = reReplyHeader.ReplaceAllString(s, "\n")
 	s = reForwardHeader.ReplaceAllString(s, "\n")
 	s = reOriginalMessage.ReplaceAllString(s, "\n")
@@ -46,18 +95,28 @@ func CleanText(s string) string {
 			continue
 		}
 		if reQuoteLine.MatchString(trim) {
+			if r != nil {
+				r.QuotedLines++
+			}
 			continue
 		}
 		if reSignatureDelimiter.MatchString(trim) {
 			continue
 		}
 		if reHeaderLike.MatchString(trim) {
+			if r != nil {
+				r.HeaderLines++
+			}
 			continue
 		}
 		outLines = append(outLines, trim)
 	}
 	s = strings.Join(outLines, "\n")
 
+	if r != nil {
+		r.URLs = countMatches(reURL, s)
+		r.Emails = countMatches(reEmail, s)
+	}
 	s = reURL.ReplaceAllString(s, " ")
 	s = reEmail.ReplaceAllString(s, " ")
 	s = reSignatureWords.ReplaceAllString(s, " ")
@@ -67,9 +126,16 @@ func CleanText(s string) string {
 	s = reMultiNewlines.ReplaceAllString(s, "\n\n")
 	s = strings.TrimSpace(s)
 
+	if r != nil {
+		r.OutputChars = len([]rune(s))
+	}
 	return s
 }
 
+func countMatches(re *regexp.Regexp, s string) int {
+	return len(re.FindAllStringIndex(s, -1))
+}
+
 func findSignatureIndex(s string) int {
 	candidates := []string{
 		"\n--",
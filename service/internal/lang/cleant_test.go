@@ -62,3 +62,17 @@ func TestCleanText_EmailAndURLRemoved(t *testing.T) {
 		t.Fatalf("expected emails and urls removed, got: %q", got)
 	}
 }
+
+func TestCleanTextReport(t *testing.T) {
+	raw := "Sounds good, see https://example.com/a\n\nFrom: Bob\n> quoted one\n> quoted two\n-- \nBob"
+	got, r := CleanTextReport(raw)
+	if got != CleanText(raw) {
+		t.Fatalf("report output %q differs from CleanText %q", got, CleanText(raw))
+	}
+	if r.HTML || r.SignatureChars == 0 || r.URLs != 1 {
+		t.Fatalf("unexpected report %+v", r)
+	}
+	if r.InputChars != len([]rune(raw)) || r.OutputChars != len([]rune(got)) {
+		t.Fatalf("unexpected sizes %+v", r)
+	}
+}
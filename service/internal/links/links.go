@@ -0,0 +1,203 @@
+// Package links extracts the hyperlinks of a message from its text and HTML
+// bodies and flags anchors whose visible text names another domain than the
+// one they point at, a common phishing trick.
+package links
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	SourceText = "text"
+	SourceHTML = "html"
+)
+
+// Link is one http(s) hyperlink.
+type Link struct {
+	URL    string `json:"url"`
+	Text   string `json:"text,omitempty"` // anchor text; empty for links in plain text
+	Domain string `json:"domain"`         // host of URL, lower-cased
+	Source string `json:"source"`         // SourceText or SourceHTML
+	// TextDomain is the domain shown in Text, if any; Mismatch is set when it
+	// belongs to another organisation than Domain.
+	TextDomain string `json:"text_domain,omitempty"`
+	Mismatch   bool   `json:"mismatch,omitempty"`
+}
+
+var (
+	reTextURL = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"'` + "`" + `]+`)
+	// reDomain finds a host name in anchor text such as "paypal.com/login".
+	// Punycode TLDs count too, and the end is matched by hand since \b only
+	// knows ASCII letters.
+	reDomain = regexp.MustCompile(`(?i)(?:https?://)?((?:[\p{L}\p{N}](?:[\p{L}\p{N}-]*[\p{L}\p{N}])?\.)+(?:\p{L}{2,}|xn--[a-z0-9-]+))(?:[^\p{L}\p{N}_-]|$)`)
+)
+
+// Extract returns the links of the HTML body, in document order, followed by
+// the links of the text body whose URL the HTML did not already have.
+func Extract(text, htmlBody string) []Link {
+	out := FromHTML(htmlBody)
+	seen := make(map[string]bool, len(out))
+	for _, l := range out {
+		seen[l.URL] = true
+	}
+	for _, l := range FromText(text) {
+		if !seen[l.URL] {
+			seen[l.URL] = true
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// FromText finds the URLs written out in plain text; "www." addresses are
+// given an http scheme.
+func FromText(s string) []Link {
+	var out []Link
+	seen := map[string]bool{}
+	for _, m := range reTextURL.FindAllString(s, -1) {
+		m = trimURL(m)
+		if strings.HasPrefix(strings.ToLower(m), "www.") {
+			m = "http://" + m
+		}
+		l, ok := newLink(m, "", SourceText)
+		if !ok || seen[l.URL] {
+			continue
+		}
+		seen[l.URL] = true
+		out = append(out, l)
+	}
+	return out
+}
+
+// FromHTML returns the http(s) anchors of an HTML document with their text.
+// The same URL with the same text is listed once.
+func FromHTML(s string) []Link {
+	if s == "" {
+		return nil
+	}
+	var out []Link
+	seen := map[[2]string]bool{}
+	z := html.NewTokenizer(strings.NewReader(s))
+	var href string
+	var text strings.Builder
+	inAnchor := false
+	flush := func() {
+		if !inAnchor {
+			return
+		}
+		inAnchor = false
+		l, ok := newLink(href, strings.Join(strings.Fields(text.String()), " "), SourceHTML)
+		if !ok || seen[[2]string{l.URL, l.Text}] {
+			return
+		}
+		seen[[2]string{l.URL, l.Text}] = true
+		out = append(out, l)
+	}
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			flush()
+			return out
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			switch tok.DataAtom {
+			case atom.A, atom.Area:
+				flush() // an unclosed <a> ends at the next one
+				href = attr(tok, "href")
+				text.Reset()
+				inAnchor = tok.DataAtom == atom.A
+				if !inAnchor {
+					if l, ok := newLink(href, attr(tok, "alt"), SourceHTML); ok && !seen[[2]string{l.URL, l.Text}] {
+						seen[[2]string{l.URL, l.Text}] = true
+						out = append(out, l)
+					}
+				}
+			case atom.Img:
+				if inAnchor {
+					text.WriteString(" " + attr(tok, "alt") + " ")
+				}
+			}
+		case html.EndTagToken:
+			if tok := z.Token(); tok.DataAtom == atom.A {
+				flush()
+			}
+		case html.TextToken:
+			if inAnchor {
+				text.Write(z.Text())
+			}
+		}
+	}
+}
+
+func attr(tok html.Token, name string) string {
+	for _, a := range tok.Attr {
+		if a.Key == name {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
+
+// newLink accepts absolute http(s) URLs only; relative, mailto: and
+// javascript: targets are not links to anywhere.
+func newLink(raw, text, source string) (Link, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return Link{}, false
+	}
+	l := Link{URL: u.String(), Text: text, Domain: strings.ToLower(u.Hostname()), Source: source}
+	if m := reDomain.FindStringSubmatch(text); m != nil && isDomain(asciiHost(m[1])) {
+		l.TextDomain = strings.ToLower(m[1])
+		l.Mismatch = organisation(asciiHost(l.TextDomain)) != organisation(asciiHost(l.Domain))
+	}
+	return l, true
+}
+
+// asciiHost converts an internationalised host to its punycode form, which
+// the public suffix list uses, so "пример.рф" and "xn--e1afmkfd.xn--p1ai"
+// compare equal. Hosts IDNA rejects are only lower-cased.
+func asciiHost(host string) string {
+	if a, err := idna.Lookup.ToASCII(host); err == nil {
+		return a
+	}
+	return strings.ToLower(host)
+}
+
+// isDomain rejects names whose last label is no public suffix, such as
+// "report.pdf" or "e.g".
+func isDomain(host string) bool {
+	suffix, icann := publicsuffix.PublicSuffix(host)
+	return suffix != host && (icann || strings.Contains(suffix, "."))
+}
+
+// organisation reduces a host to its registrable domain, so that
+// "news.example.com" and "example.com" are the same sender.
+func organisation(host string) string {
+	host = strings.TrimPrefix(host, "www.")
+	if org, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return org
+	}
+	return host
+}
+
+// trimURL drops punctuation that ends the sentence rather than the URL, and
+// a closing parenthesis that has no opening one inside the URL.
+func trimURL(s string) string {
+	for {
+		t := strings.TrimRight(s, ".,;:!?*")
+		if strings.HasSuffix(t, ")") && strings.Count(t, "(") < strings.Count(t, ")") {
+			t = t[:len(t)-1]
+		}
+		if t == s {
+			return s
+		}
+		s = t
+	}
+}
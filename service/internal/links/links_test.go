@@ -0,0 +1,77 @@
+package links
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFromHTML(t *testing.T) {
+	got := FromHTML(`<p>Log in at <a href="https://paypal.com.account-check.ru/login">www.paypal.com</a>
+or <a href=" https://news.example.com/u?id=1 ">Unsubscribe
+   now</a>, <a href="mailto:help@example.com">help</a>, <a href="/relative">x</a>
+<a href="https://example.com/offer"><img src="cid:banner" alt="Example.com deals"></a>
+<a href="https://news.example.com/u?id=1">Unsubscribe now</a>
+<map><area href="https://shop.example.org/" alt="Shop"></map></p>`)
+	want := []Link{
+		{URL: "https://paypal.com.account-check.ru/login", Text: "www.paypal.com", Domain: "paypal.com.account-check.ru", Source: SourceHTML, TextDomain: "www.paypal.com", Mismatch: true},
+		{URL: "https://news.example.com/u?id=1", Text: "Unsubscribe now", Domain: "news.example.com", Source: SourceHTML},
+		{URL: "https://example.com/offer", Text: "Example.com deals", Domain: "example.com", Source: SourceHTML, TextDomain: "example.com"},
+		{URL: "https://shop.example.org/", Text: "Shop", Domain: "shop.example.org", Source: SourceHTML},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestFromText(t *testing.T) {
+	got := FromText("See https://example.com/a?b=1. Docs (https://en.wikipedia.org/wiki/Go_(language)) and www.Example.org!\n" +
+		"Again: https://example.com/a?b=1, and report.pdf is not a link.")
+	want := []Link{
+		{URL: "https://example.com/a?b=1", Domain: "example.com", Source: SourceText},
+		{URL: "https://en.wikipedia.org/wiki/Go_(language)", Domain: "en.wikipedia.org", Source: SourceText},
+		{URL: "http://www.Example.org", Domain: "www.example.org", Source: SourceText},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestExtract_TextLinksAlreadyInHTMLAreSkipped(t *testing.T) {
+	got := Extract("Read https://example.com/post and https://other.example.net/",
+		`<a href="https://example.com/post">Read the post</a>`)
+	if len(got) != 2 || got[0].Source != SourceHTML || got[0].Text != "Read the post" || got[1].Domain != "other.example.net" {
+		t.Fatalf("unexpected links: %+v", got)
+	}
+}
+
+func TestNewLink_Mismatch(t *testing.T) {
+	for text, mismatch := range map[string]bool{
+		"https://www.example.com/login": false,
+		"example.com":                   false,
+		"Click here":                    false,
+		"secure.bank.com":               true,
+		"Visit evil.example.net today":  true,
+	} {
+		l, ok := newLink("https://mail.example.com/x", text, SourceHTML)
+		if !ok || l.Mismatch != mismatch {
+			t.Fatalf("%q: mismatch = %v, want %v (%+v)", text, l.Mismatch, mismatch, l)
+		}
+	}
+}
+
+func TestNewLink_InternationalDomains(t *testing.T) {
+	for _, c := range []struct {
+		url, text string
+		mismatch  bool
+	}{
+		{"https://xn--e1afmkfd.xn--p1ai/login", "пример.рф", false},
+		{"https://пример.рф/login", "www.xn--e1afmkfd.xn--p1ai", false},
+		{"https://Пример.РФ/", "shop.пример.рф", false},
+		{"https://xn--e1afmkfd.xn--p1ai/", "пример.com", true},
+	} {
+		l, ok := newLink(c.url, c.text, SourceHTML)
+		if !ok || l.TextDomain == "" || l.Mismatch != c.mismatch {
+			t.Fatalf("%q -> %q: mismatch = %v, want %v (%+v)", c.text, c.url, l.Mismatch, c.mismatch, l)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package mailauth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Authentication results as defined by RFC 8601.
+const (
+	ResultNone      = "none"
+	ResultPass      = "pass"
+	ResultFail      = "fail"
+	ResultNeutral   = "neutral"
+	ResultPolicy    = "policy"
+	ResultSoftFail  = "softfail"
+	ResultTempError = "temperror"
+	ResultPermError = "permerror"
+)
+
+// maxDKIMSignatures caps the work a single message can cause.
+const maxDKIMSignatures = 10
+
+// DKIMResult is the outcome for one DKIM-Signature header.
+type DKIMResult struct {
+	Domain    string `json:"domain"`
+	Selector  string `json:"selector"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Identity  string `json:"identity,omitempty"`
+	Result    string `json:"result"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+type dkimSignature struct {
+	version     string
+	algorithm   string
+	signature   []byte
+	bodyHash    []byte
+	headerCanon string
+	bodyCanon   string
+	domain      string
+	headers     []string
+	identity    string
+	length      int64 // -1 when absent
+	selector    string
+	expires     time.Time
+	field       headerField
+}
+
+// VerifyDKIM checks every DKIM-Signature header of raw and returns one result
+// per signature, in header order. A message without signatures yields nil.
+func VerifyDKIM(ctx context.Context, raw []byte, keys KeyResolver) []DKIMResult {
+	fields, body := splitMessage(raw)
+
+	var out []DKIMResult
+	for _, f := range fields {
+		if f.key() != "dkim-signature" {
+			continue
+		}
+		if len(out) == maxDKIMSignatures {
+			break
+		}
+		out = append(out, verifyOne(ctx, f, fields, body, keys))
+	}
+	return out
+}
+
+func verifyOne(ctx context.Context, f headerField, fields []headerField, body []byte, keys KeyResolver) DKIMResult {
+	sig, err := parseSignature(f)
+	res := DKIMResult{}
+	if sig != nil {
+		res = DKIMResult{Domain: sig.domain, Selector: sig.selector, Algorithm: sig.algorithm, Identity: sig.identity}
+	}
+	if err != nil {
+		res.Result, res.Reason = ResultPermError, err.Error()
+		return res
+	}
+	if !sig.expires.IsZero() && time.Now().After(sig.expires) {
+		res.Result, res.Reason = ResultPermError, "signature expired"
+		return res
+	}
+
+	record, err := keys.LookupKey(ctx, sig.selector, sig.domain)
+	if err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			res.Result, res.Reason = ResultPermError, "no key for signature"
+		} else {
+			res.Result, res.Reason = ResultTempError, "key unavailable: "+err.Error()
+		}
+		return res
+	}
+	pub, err := parseKeyRecord(record, sig.algorithm)
+	if err != nil {
+		res.Result, res.Reason = ResultPermError, err.Error()
+		return res
+	}
+
+	newHash, cryptoHash := hashFor(sig.algorithm)
+
+	bh := newHash()
+	canonBody := canonicalBody(body, sig.bodyCanon)
+	if sig.length >= 0 {
+		if sig.length > int64(len(canonBody)) {
+			res.Result, res.Reason = ResultPermError, "l= exceeds body length"
+			return res
+		}
+		canonBody = canonBody[:sig.length]
+	}
+	bh.Write(canonBody)
+	if !bytes.Equal(bh.Sum(nil), sig.bodyHash) {
+		res.Result, res.Reason = ResultFail, "body hash did not verify"
+		return res
+	}
+
+	hh := newHash()
+	hh.Write(signedHeaders(fields, sig))
+	digest := hh.Sum(nil)
+
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 1024 {
+			res.Result, res.Reason = ResultPermError, "rsa key too short"
+			return res
+		}
+		if err := rsa.VerifyPKCS1v15(k, cryptoHash, digest, sig.signature); err != nil {
+			res.Result, res.Reason = ResultFail, "signature did not verify"
+			return res
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, digest, sig.signature) {
+			res.Result, res.Reason = ResultFail, "signature did not verify"
+			return res
+		}
+	default:
+		res.Result, res.Reason = ResultPermError, "unsupported key type"
+		return res
+	}
+
+	res.Result = ResultPass
+	return res
+}
+
+func parseSignature(f headerField) (*dkimSignature, error) {
+	tags := parseTags(unfold(f.value()))
+	sig := &dkimSignature{
+		version:   tags["v"],
+		algorithm: strings.ToLower(tags["a"]),
+		domain:    strings.ToLower(tags["d"]),
+		selector:  tags["s"],
+		identity:  tags["i"],
+		length:    -1,
+		field:     f,
+	}
+
+	for _, req := range []string{"v", "a", "b", "bh", "d", "h", "s"} {
+		if _, ok := tags[req]; !ok {
+			return sig, fmt.Errorf("missing required tag %q", req)
+		}
+	}
+	if sig.version != "1" {
+		return sig, fmt.Errorf("unsupported version %q", sig.version)
+	}
+	switch sig.algorithm {
+	case "rsa-sha256", "rsa-sha1", "ed25519-sha256":
+	default:
+		return sig, fmt.Errorf("unsupported algorithm %q", sig.algorithm)
+	}
+
+	var err error
+	if sig.signature, err = base64.StdEncoding.DecodeString(stripWSP(tags["b"])); err != nil {
+		return sig, errors.New("malformed b= tag")
+	}
+	if sig.bodyHash, err = base64.StdEncoding.DecodeString(stripWSP(tags["bh"])); err != nil {
+		return sig, errors.New("malformed bh= tag")
+	}
+
+	sig.headerCanon, sig.bodyCanon = "simple", "simple"
+	if c := strings.ToLower(tags["c"]); c != "" {
+		hc, bc, hasBody := strings.Cut(c, "/")
+		sig.headerCanon = hc
+		if hasBody {
+			sig.bodyCanon = bc
+		}
+	}
+	for _, c := range []string{sig.headerCanon, sig.bodyCanon} {
+		if c != "simple" && c != "relaxed" {
+			return sig, fmt.Errorf("unsupported canonicalization %q", c)
+		}
+	}
+
+	hasFrom := false
+	for _, h := range strings.Split(tags["h"], ":") {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		sig.headers = append(sig.headers, h)
+		if strings.EqualFold(h, "from") {
+			hasFrom = true
+		}
+	}
+	if !hasFrom {
+		return sig, errors.New("from header not signed")
+	}
+
+	if sig.identity != "" {
+		_, idDomain, _ := strings.Cut(sig.identity, "@")
+		idDomain = strings.ToLower(idDomain)
+		if idDomain != sig.domain && !strings.HasSuffix(idDomain, "."+sig.domain) {
+			return sig, errors.New("i= domain does not match d=")
+		}
+	}
+	if l, ok := tags["l"]; ok {
+		n, err := strconv.ParseInt(l, 10, 64)
+		if err != nil || n < 0 {
+			return sig, errors.New("malformed l= tag")
+		}
+		sig.length = n
+	}
+	if x, ok := tags["x"]; ok {
+		n, err := strconv.ParseInt(x, 10, 64)
+		if err != nil {
+			return sig, errors.New("malformed x= tag")
+		}
+		sig.expires = time.Unix(n, 0)
+	}
+	return sig, nil
+}
+
+func parseKeyRecord(record, algorithm string) (crypto.PublicKey, error) {
+	tags := parseTags(record)
+	if v, ok := tags["v"]; ok && v != "DKIM1" {
+		return nil, fmt.Errorf("unsupported key record version %q", v)
+	}
+	p := stripWSP(tags["p"])
+	if p == "" {
+		return nil, errors.New("key revoked")
+	}
+	if hs, ok := tags["h"]; ok {
+		want := algorithm[strings.IndexByte(algorithm, '-')+1:]
+		allowed := false
+		for _, h := range strings.Split(hs, ":") {
+			if strings.EqualFold(strings.TrimSpace(h), want) {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return nil, errors.New("hash algorithm not allowed by key")
+		}
+	}
+	der, err := base64.StdEncoding.DecodeString(p)
+	if err != nil {
+		return nil, errors.New("malformed key")
+	}
+
+	keyType := strings.ToLower(tags["k"])
+	if keyType == "" {
+		keyType = "rsa"
+	}
+	switch keyType {
+	case "rsa":
+		if !strings.HasPrefix(algorithm, "rsa-") {
+			return nil, errors.New("key type does not match algorithm")
+		}
+		if pub, err := x509.ParsePKIXPublicKey(der); err == nil {
+			if k, ok := pub.(*rsa.PublicKey); ok {
+				return k, nil
+			}
+			return nil, errors.New("key is not rsa")
+		}
+		if k, err := x509.ParsePKCS1PublicKey(der); err == nil {
+			return k, nil
+		}
+		return nil, errors.New("malformed rsa key")
+	case "ed25519":
+		if !strings.HasPrefix(algorithm, "ed25519-") {
+			return nil, errors.New("key type does not match algorithm")
+		}
+		if len(der) != ed25519.PublicKeySize {
+			return nil, errors.New("malformed ed25519 key")
+		}
+		return ed25519.PublicKey(der), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", keyType)
+	}
+}
+
+func hashFor(algorithm string) (func() hash.Hash, crypto.Hash) {
+	if strings.HasSuffix(algorithm, "-sha1") {
+		return sha1.New, crypto.SHA1
+	}
+	return sha256.New, crypto.SHA256
+}
+
+var (
+	reWSPRun     = regexp.MustCompile(`[ \t]+`)
+	reSigBValue  = regexp.MustCompile(`(^|;)([ \t\r\n]*b[ \t\r\n]*=)[^;]*`)
+	reTrailingWS = regexp.MustCompile(`[ \t]+\r\n`)
+)
+
+// canonicalBody implements the body canonicalizations of RFC 6376 section 3.4.
+// The input must already use CRLF line endings.
+func canonicalBody(body []byte, canon string) []byte {
+	b := body
+	if canon == "relaxed" {
+		b = reTrailingWS.ReplaceAll(b, []byte("\r\n"))
+		b = reWSPRun.ReplaceAll(b, []byte(" "))
+		if bytes.HasSuffix(b, []byte(" ")) || bytes.HasSuffix(b, []byte("\t")) {
+			b = bytes.TrimRight(b, " \t")
+		}
+	}
+	if len(b) > 0 && !bytes.HasSuffix(b, []byte("\r\n")) {
+		b = append(append([]byte{}, b...), '\r', '\n')
+	}
+	for bytes.HasSuffix(b, []byte("\r\n\r\n")) {
+		b = b[:len(b)-2]
+	}
+	if len(b) == 0 && canon == "simple" {
+		return []byte("\r\n")
+	}
+	if bytes.Equal(b, []byte("\r\n")) && canon == "relaxed" {
+		return nil
+	}
+	return b
+}
+
+// canonicalHeader implements the header canonicalizations of RFC 6376 section 3.4.
+func canonicalHeader(f headerField, canon string) string {
+	if canon == "simple" {
+		return f.raw
+	}
+	v := unfold(f.value())
+	v = reWSPRun.ReplaceAllString(v, " ")
+	return f.key() + ":" + strings.Trim(v, " \t") + "\r\n"
+}
+
+// signedHeaders builds the header hash input: the fields listed in h=, each
+// picked from the bottom up, followed by the signature field with an empty b=.
+func signedHeaders(fields []headerField, sig *dkimSignature) []byte {
+	used := make(map[int]bool, len(sig.headers))
+	var buf bytes.Buffer
+	for _, name := range sig.headers {
+		name = strings.ToLower(name)
+		for i := len(fields) - 1; i >= 0; i-- {
+			if used[i] || fields[i].key() != name {
+				continue
+			}
+			used[i] = true
+			buf.WriteString(canonicalHeader(fields[i], sig.headerCanon))
+			break
+		}
+	}
+
+	self := sig.field
+	_, val, _ := strings.Cut(self.raw, ":")
+	self.raw = self.name + ":" + reSigBValue.ReplaceAllString(val, "$1$2")
+	buf.WriteString(strings.TrimSuffix(canonicalHeader(self, sig.headerCanon), "\r\n"))
+	return buf.Bytes()
+}
@@ -0,0 +1,168 @@
+package mailauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+const dkimTestMessage = "From: Alice <alice@example.com>\r\n" +
+	"To: bob@example.org\r\n" +
+	"Subject:  Hello   there \r\n" +
+	"Date: Mon, 02 Jan 2006 15:04:05 +0000\r\n" +
+	"Message-ID: <m1@example.com>\r\n" +
+	"\r\n" +
+	"Hi Bob,  \r\n" +
+	"\r\n" +
+	"just checking in.\r\n" +
+	"\r\n\r\n"
+
+// sign produces a DKIM-Signature header for msg using the package's own
+// canonicalization helpers, so tests exercise the verification path end to end.
+func sign(t *testing.T, msg, algorithm, canon, domain, selector string, key crypto.Signer) string {
+	t.Helper()
+	fields, body := splitMessage([]byte(msg))
+	hc, bc, _ := strings.Cut(canon, "/")
+
+	bh := sha256.Sum256(canonicalBody(body, bc))
+	tmpl := "DKIM-Signature: v=1; a=" + algorithm + "; c=" + canon + "; d=" + domain + "; s=" + selector + ";\r\n" +
+		"\th=from:to:subject:date; bh=" + base64.StdEncoding.EncodeToString(bh[:]) + ";\r\n\tb=\r\n"
+	f := headerField{name: "DKIM-Signature", raw: tmpl}
+	sig := &dkimSignature{headerCanon: hc, headers: []string{"from", "to", "subject", "date"}, field: f}
+	digest := sha256.Sum256(signedHeaders(fields, sig))
+
+	var b []byte
+	var err error
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		b, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	case ed25519.PrivateKey:
+		b = ed25519.Sign(k, digest[:])
+	}
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return strings.TrimSuffix(tmpl, "\r\n") + base64.StdEncoding.EncodeToString(b) + "\r\n"
+}
+
+func rsaRecord(t *testing.T, k *rsa.PrivateKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(&k.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(der)
+}
+
+func TestVerifyDKIM_RSA(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := NewStaticKeyResolver(map[string]string{"sel._domainkey.example.com": rsaRecord(t, key)})
+
+	for _, canon := range []string{"simple/simple", "relaxed/relaxed", "relaxed/simple"} {
+		t.Run(canon, func(t *testing.T) {
+			msg := sign(t, dkimTestMessage, "rsa-sha256", canon, "example.com", "sel", key) + dkimTestMessage
+			res := VerifyDKIM(context.Background(), []byte(msg), keys)
+			if len(res) != 1 {
+				t.Fatalf("want 1 result, got %d", len(res))
+			}
+			if res[0].Result != ResultPass {
+				t.Fatalf("want pass, got %+v", res[0])
+			}
+			if res[0].Domain != "example.com" || res[0].Selector != "sel" || res[0].Algorithm != "rsa-sha256" {
+				t.Fatalf("unexpected metadata: %+v", res[0])
+			}
+		})
+	}
+}
+
+func TestVerifyDKIM_RelaxedSurvivesWhitespaceAndLF(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keys := NewStaticKeyResolver(map[string]string{"sel._domainkey.example.com": rsaRecord(t, key)})
+	msg := sign(t, dkimTestMessage, "rsa-sha256", "relaxed/relaxed", "example.com", "sel", key) + dkimTestMessage
+
+	mangled := strings.ReplaceAll(msg, "Subject:  Hello   there", "subject: Hello there")
+	mangled = strings.ReplaceAll(mangled, "\r\n", "\n")
+	res := VerifyDKIM(context.Background(), []byte(mangled), keys)
+	if len(res) != 1 || res[0].Result != ResultPass {
+		t.Fatalf("want pass, got %+v", res)
+	}
+}
+
+func TestVerifyDKIM_Ed25519(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := "v=DKIM1; k=ed25519; p=" + base64.StdEncoding.EncodeToString(pub)
+	keys := NewStaticKeyResolver(map[string]string{"ed._domainkey.example.com": record})
+
+	msg := sign(t, dkimTestMessage, "ed25519-sha256", "relaxed/relaxed", "example.com", "ed", priv) + dkimTestMessage
+	res := VerifyDKIM(context.Background(), []byte(msg), keys)
+	if len(res) != 1 || res[0].Result != ResultPass {
+		t.Fatalf("want pass, got %+v", res)
+	}
+}
+
+func TestVerifyDKIM_Failures(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keys := NewStaticKeyResolver(map[string]string{"sel._domainkey.example.com": rsaRecord(t, key)})
+	sig := sign(t, dkimTestMessage, "rsa-sha256", "simple/simple", "example.com", "sel", key)
+
+	cases := []struct {
+		name   string
+		msg    string
+		keys   KeyResolver
+		result string
+		reason string
+	}{
+		{"tampered body", sig + strings.Replace(dkimTestMessage, "checking", "cheking", 1), keys, ResultFail, "body hash"},
+		{"tampered header", sig + strings.Replace(dkimTestMessage, "bob@example.org", "eve@example.org", 1), keys, ResultFail, "signature did not verify"},
+		{"missing key", sig + dkimTestMessage, NewStaticKeyResolver(nil), ResultPermError, "no key"},
+		{"revoked key", sig + dkimTestMessage, NewStaticKeyResolver(map[string]string{"sel._domainkey.example.com": "v=DKIM1; p="}), ResultPermError, "revoked"},
+		{"missing tags", "DKIM-Signature: v=1; a=rsa-sha256; d=example.com\r\n" + dkimTestMessage, keys, ResultPermError, "missing required tag"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := VerifyDKIM(context.Background(), []byte(tc.msg), tc.keys)
+			if len(res) != 1 {
+				t.Fatalf("want 1 result, got %d", len(res))
+			}
+			if res[0].Result != tc.result || !strings.Contains(res[0].Reason, tc.reason) {
+				t.Fatalf("want %s (%s), got %+v", tc.result, tc.reason, res[0])
+			}
+		})
+	}
+}
+
+func TestVerifyDKIM_NoSignature(t *testing.T) {
+	if res := VerifyDKIM(context.Background(), []byte(dkimTestMessage), NewStaticKeyResolver(nil)); res != nil {
+		t.Fatalf("want nil, got %+v", res)
+	}
+}
+
+func TestCanonicalBody(t *testing.T) {
+	cases := []struct {
+		canon, in, want string
+	}{
+		{"simple", "", "\r\n"},
+		{"simple", "a \r\n\r\n\r\n", "a \r\n"},
+		{"relaxed", "", ""},
+		{"relaxed", "a  \t b \r\n\r\n", "a b\r\n"},
+		{"relaxed", "\r\n\r\n", ""},
+	}
+	for _, tc := range cases {
+		if got := string(canonicalBody([]byte(tc.in), tc.canon)); got != tc.want {
+			t.Errorf("%s(%q) = %q, want %q", tc.canon, tc.in, got, tc.want)
+		}
+	}
+}
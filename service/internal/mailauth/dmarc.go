@@ -0,0 +1,196 @@
+package mailauth
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// DMARC dispositions (RFC 7489 section 6.3, p= and sp= tags).
+const (
+	PolicyNone       = "none"
+	PolicyQuarantine = "quarantine"
+	PolicyReject     = "reject"
+)
+
+// DMARCResult is the DMARC verdict for the RFC5322.From domain.
+type DMARCResult struct {
+	Domain      string `json:"domain"`           // RFC5322.From domain
+	Record      string `json:"record,omitempty"` // domain the policy was found at
+	Result      string `json:"result"`           // pass, fail, none, temperror, permerror
+	Policy      string `json:"policy,omitempty"` // published p= (or sp= for subdomains)
+	Applied     string `json:"applied_policy"`   // disposition after pct sampling
+	Pct         int    `json:"pct,omitempty"`
+	SPFAligned  bool   `json:"spf_aligned"`
+	DKIMAligned bool   `json:"dkim_aligned"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// sampled decides whether a failing message falls within the pct= share. The
+// draw comes from a hash of the Message-ID, so a message stored twice gets the
+// same disposition.
+func sampled(pct int, messageID string) bool {
+	if pct >= 100 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(messageID))
+	return int(h.Sum32()%100) < pct
+}
+
+// CheckDMARC looks up the DMARC policy for fromDomain and evaluates identifier
+// alignment against the SPF and DKIM results already computed for the message.
+// messageID selects whether a failing message falls within pct=.
+func CheckDMARC(ctx context.Context, r TXTResolver, fromDomain, messageID string, spf SPFResult, dkim []DKIMResult) DMARCResult {
+	fromDomain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(fromDomain), "."))
+	res := DMARCResult{Domain: fromDomain, Applied: PolicyNone}
+	if !validDomain(fromDomain) {
+		res.Result, res.Reason = ResultNone, "no valid from domain"
+		return res
+	}
+
+	org := OrganizationalDomain(fromDomain)
+	tags, at, err := lookupDMARC(ctx, r, fromDomain)
+	if err == nil && tags == nil && org != fromDomain {
+		tags, at, err = lookupDMARC(ctx, r, org)
+	}
+	switch {
+	case errors.Is(err, ErrTemporary):
+		res.Result, res.Reason = ResultTempError, err.Error()
+		return res
+	case err != nil:
+		res.Result, res.Reason = ResultPermError, err.Error()
+		return res
+	case tags == nil:
+		res.Result, res.Reason = ResultNone, "no dmarc record"
+		return res
+	}
+	res.Record = at
+
+	res.Policy = strings.ToLower(tags["p"])
+	if sp, ok := tags["sp"]; ok && at != fromDomain {
+		res.Policy = strings.ToLower(sp)
+	}
+	res.Pct = 100
+	if v, ok := tags["pct"]; ok {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 && n <= 100 {
+			res.Pct = n
+		}
+	}
+	strictSPF := strings.EqualFold(tags["aspf"], "s")
+	strictDKIM := strings.EqualFold(tags["adkim"], "s")
+
+	if spf.Result == ResultPass {
+		_, mfDomain, _ := strings.Cut(spf.MailFrom, "@")
+		res.SPFAligned = aligned(fromDomain, mfDomain, strictSPF)
+	}
+	for _, d := range dkim {
+		if d.Result == ResultPass && aligned(fromDomain, d.Domain, strictDKIM) {
+			res.DKIMAligned = true
+			break
+		}
+	}
+
+	if res.SPFAligned || res.DKIMAligned {
+		res.Result = ResultPass
+		return res
+	}
+	res.Result, res.Reason = ResultFail, "no aligned spf or dkim pass"
+	res.Applied = res.Policy
+	if !sampled(res.Pct, messageID) {
+		// Messages outside pct get the next less strict policy (RFC 7489 6.6.4).
+		switch res.Policy {
+		case PolicyReject:
+			res.Applied = PolicyQuarantine
+		case PolicyQuarantine:
+			res.Applied = PolicyNone
+		}
+	}
+	return res
+}
+
+// lookupDMARC fetches and parses the record at _dmarc.<domain>. It returns nil
+// tags when no record is published. A record with a missing or invalid p=,
+// or an invalid sp=, reads as p=none when its rua= has a valid URI and is a
+// permerror otherwise (RFC 7489 section 6.6.3).
+func lookupDMARC(ctx context.Context, r TXTResolver, domain string) (map[string]string, string, error) {
+	txts, err := r.LookupTXT(ctx, "_dmarc."+domain)
+	if err != nil {
+		err = classifyDNSError(err, errNoRecords)
+		if errors.Is(err, errNoRecords) {
+			return nil, "", nil
+		}
+		return nil, "", err
+	}
+	var records []string
+	for _, t := range txts {
+		if v, _, _ := strings.Cut(t, ";"); strings.EqualFold(strings.ReplaceAll(v, " ", ""), "v=DMARC1") {
+			records = append(records, t)
+		}
+	}
+	if len(records) != 1 {
+		// Zero or several records: treat as if none was published.
+		return nil, "", nil
+	}
+	tags := parseTags(records[0])
+	p, hasP := tags["p"]
+	sp, hasSP := tags["sp"]
+	if hasP && validPolicy(p) && (!hasSP || validPolicy(sp)) {
+		return tags, domain, nil
+	}
+	if !validRUA(tags["rua"]) {
+		if !hasP {
+			return nil, "", errors.New("dmarc record without p= at " + domain)
+		}
+		return nil, "", errors.New("invalid policy " + strconv.Quote(p) + " at " + domain)
+	}
+	tags["p"] = PolicyNone
+	delete(tags, "sp")
+	return tags, domain, nil
+}
+
+func validPolicy(p string) bool {
+	switch strings.ToLower(p) {
+	case PolicyNone, PolicyQuarantine, PolicyReject:
+		return true
+	}
+	return false
+}
+
+// validRUA reports whether a rua= list holds at least one URI that parses,
+// ignoring the optional "!size" limit after each.
+func validRUA(rua string) bool {
+	for _, u := range strings.Split(rua, ",") {
+		u, _, _ = strings.Cut(strings.TrimSpace(u), "!")
+		if parsed, err := url.Parse(u); err == nil && parsed.Scheme != "" && parsed.Opaque+parsed.Host+parsed.Path != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// aligned reports identifier alignment: strict requires an exact match,
+// relaxed a shared organizational domain.
+func aligned(fromDomain, other string, strict bool) bool {
+	other = strings.ToLower(strings.TrimSuffix(other, "."))
+	if other == "" {
+		return false
+	}
+	if strict {
+		return other == fromDomain
+	}
+	return OrganizationalDomain(other) == OrganizationalDomain(fromDomain)
+}
+
+// OrganizationalDomain returns the registrable domain (public suffix + 1 label).
+func OrganizationalDomain(domain string) string {
+	if org, err := publicsuffix.EffectiveTLDPlusOne(domain); err == nil {
+		return org
+	}
+	return domain
+}
@@ -0,0 +1,94 @@
+package mailauth
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func dmarcZone() *fakeZone {
+	return &fakeZone{
+		txt: map[string][]string{
+			"_dmarc.example.com":     {"v=DMARC1; p=reject; sp=quarantine; adkim=s; rua=mailto:d@example.com"},
+			"_dmarc.relaxed.org":     {"v=DMARC1; p=quarantine"},
+			"_dmarc.sampled.org":     {"v=DMARC1; p=reject; pct=0"},
+			"_dmarc.half.org":        {"v=DMARC1; p=quarantine; pct=50"},
+			"_dmarc.nopolicy.org":    {"v=DMARC1; rua=mailto:x@nopolicy.org"},
+			"_dmarc.badpolicy.org":   {"v=DMARC1; p=block; sp=reject; rua=mailto:x@badpolicy.org!10m"},
+			"_dmarc.noreport.org":    {"v=DMARC1; rua=x@noreport.org"},
+			"_dmarc.sub.example.net": {"v=DMARC1; p=none"},
+		},
+		fail: map[string]bool{"_dmarc.down.org": true},
+	}
+}
+
+func TestCheckDMARC(t *testing.T) {
+	pass := func(d string) []DKIMResult { return []DKIMResult{{Domain: d, Result: ResultPass}} }
+	spfPass := func(from string) SPFResult { return SPFResult{MailFrom: from, Result: ResultPass} }
+
+	cases := []struct {
+		name        string
+		from        string
+		spf         SPFResult
+		dkim        []DKIMResult
+		result      string
+		policy      string
+		applied     string
+		spfAligned  bool
+		dkimAligned bool
+	}{
+		{"dkim strict aligned", "example.com", SPFResult{Result: ResultFail}, pass("example.com"), ResultPass, PolicyReject, PolicyNone, false, true},
+		{"dkim strict misaligned", "example.com", SPFResult{}, pass("mail.example.com"), ResultFail, PolicyReject, PolicyReject, false, false},
+		{"spf relaxed aligned", "example.com", spfPass("bounce@mail.example.com"), nil, ResultPass, PolicyReject, PolicyNone, true, false},
+		{"subdomain uses sp", "news.example.com", SPFResult{Result: ResultSoftFail}, nil, ResultFail, PolicyQuarantine, PolicyQuarantine, false, false},
+		{"relaxed dkim subdomain", "relaxed.org", SPFResult{}, pass("mail.relaxed.org"), ResultPass, PolicyQuarantine, PolicyNone, false, true},
+		{"spoofed from", "relaxed.org", spfPass("x@evil.test"), pass("evil.test"), ResultFail, PolicyQuarantine, PolicyQuarantine, false, false},
+		{"pct sampling", "sampled.org", SPFResult{}, nil, ResultFail, PolicyReject, PolicyQuarantine, false, false},
+		{"exact subdomain record", "sub.example.net", SPFResult{}, nil, ResultFail, PolicyNone, PolicyNone, false, false},
+		{"no record", "plain.org", SPFResult{}, nil, ResultNone, "", PolicyNone, false, false},
+		{"no policy tag with rua", "nopolicy.org", SPFResult{}, nil, ResultFail, PolicyNone, PolicyNone, false, false},
+		{"invalid policy with rua", "mail.badpolicy.org", SPFResult{}, nil, ResultFail, PolicyNone, PolicyNone, false, false},
+		{"no policy tag without valid rua", "noreport.org", SPFResult{}, nil, ResultPermError, "", PolicyNone, false, false},
+		{"dns failure", "down.org", SPFResult{}, nil, ResultTempError, "", PolicyNone, false, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := CheckDMARC(context.Background(), dmarcZone(), tc.from, "<m1@example.com>", tc.spf, tc.dkim)
+			if res.Result != tc.result || res.Policy != tc.policy || res.Applied != tc.applied ||
+				res.SPFAligned != tc.spfAligned || res.DKIMAligned != tc.dkimAligned {
+				t.Fatalf("unexpected result: %+v", res)
+			}
+		})
+	}
+}
+
+func TestCheckDMARC_SamplingIsStable(t *testing.T) {
+	applied := map[string]int{}
+	for i := range 200 {
+		id := fmt.Sprintf("<%d@half.org>", i)
+		res := CheckDMARC(context.Background(), dmarcZone(), "half.org", id, SPFResult{}, nil)
+		for range 3 {
+			if again := CheckDMARC(context.Background(), dmarcZone(), "half.org", id, SPFResult{}, nil); again.Applied != res.Applied {
+				t.Fatalf("%s: applied %q, then %q", id, res.Applied, again.Applied)
+			}
+		}
+		applied[res.Applied]++
+	}
+	if applied[PolicyQuarantine] < 60 || applied[PolicyNone] < 60 {
+		t.Fatalf("unexpected split for pct=50: %v", applied)
+	}
+}
+
+func TestOrganizationalDomain(t *testing.T) {
+	cases := map[string]string{
+		"mail.example.com":  "example.com",
+		"example.com":       "example.com",
+		"a.b.example.co.uk": "example.co.uk",
+		"localhost":         "localhost",
+	}
+	for in, want := range cases {
+		if got := OrganizationalDomain(in); got != want {
+			t.Errorf("OrganizationalDomain(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
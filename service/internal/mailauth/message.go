@@ -0,0 +1,118 @@
+package mailauth
+
+import (
+	"bytes"
+	"strings"
+)
+
+// headerField is one header exactly as it appeared on the wire, including
+// folding and the terminating CRLF.
+type headerField struct {
+	name string // as written
+	raw  string
+}
+
+func (h headerField) key() string { return strings.ToLower(strings.TrimSpace(h.name)) }
+
+// value returns the unparsed value after the colon, still folded.
+func (h headerField) value() string {
+	_, v, _ := strings.Cut(h.raw, ":")
+	return strings.TrimSuffix(v, "\r\n")
+}
+
+// splitMessage converts line endings to CRLF and splits the message into
+// ordered header fields and the body.
+func splitMessage(raw []byte) ([]headerField, []byte) {
+	msg := toCRLF(raw)
+
+	var head, body []byte
+	if i := bytes.Index(msg, []byte("\r\n\r\n")); i >= 0 {
+		head, body = msg[:i+2], msg[i+4:]
+	} else {
+		head = msg
+	}
+
+	var fields []headerField
+	for _, line := range strings.SplitAfter(string(head), "\r\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1].raw += line
+			continue
+		}
+		name, _, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields = append(fields, headerField{name: name, raw: line})
+	}
+	return fields, body
+}
+
+func toCRLF(b []byte) []byte {
+	if !bytes.Contains(b, []byte("\n")) {
+		return b
+	}
+	out := make([]byte, 0, len(b)+len(b)/40)
+	for i := 0; i < len(b); i++ {
+		switch {
+		case b[i] == '\r' && i+1 < len(b) && b[i+1] == '\n':
+			out = append(out, '\r', '\n')
+			i++
+		case b[i] == '\n':
+			out = append(out, '\r', '\n')
+		default:
+			out = append(out, b[i])
+		}
+	}
+	return out
+}
+
+// headerValues returns the unfolded, trimmed values of all headers named key, top to bottom.
+func headerValues(fields []headerField, key string) []string {
+	key = strings.ToLower(key)
+	var out []string
+	for _, f := range fields {
+		if f.key() == key {
+			out = append(out, strings.TrimSpace(unfold(f.value())))
+		}
+	}
+	return out
+}
+
+func unfold(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "")
+	return strings.ReplaceAll(s, "\n", "")
+}
+
+// parseTags parses a "tag=value; tag=value" list as used by DKIM, DMARC and
+// key records. Whitespace around tags and values is removed.
+func parseTags(s string) map[string]string {
+	out := make(map[string]string)
+	for _, part := range strings.Split(s, ";") {
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		if _, dup := out[k]; dup {
+			continue
+		}
+		out[k] = strings.TrimSpace(v)
+	}
+	return out
+}
+
+func stripWSP(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\r', '\n':
+			return -1
+		}
+		return r
+	}, s)
+}
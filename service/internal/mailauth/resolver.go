@@ -0,0 +1,179 @@
+package mailauth
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// TXTResolver is the DNS lookup used by DKIM key retrieval.
+// *net.Resolver satisfies it.
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// Resolver is the full set of DNS lookups needed by SPF and DMARC.
+// *net.Resolver satisfies it.
+type Resolver interface {
+	TXTResolver
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// DNSResolver wraps a *net.Resolver and bounds every lookup with Timeout.
+type DNSResolver struct {
+	r       *net.Resolver
+	Timeout time.Duration
+}
+
+func NewDNSResolver(r *net.Resolver, timeout time.Duration) *DNSResolver {
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	return &DNSResolver{r: r, Timeout: timeout}
+}
+
+func (d *DNSResolver) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d.Timeout)
+}
+
+func (d *DNSResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	return d.r.LookupTXT(ctx, name)
+}
+
+func (d *DNSResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	return d.r.LookupIPAddr(ctx, host)
+}
+
+func (d *DNSResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	return d.r.LookupMX(ctx, name)
+}
+
+func (d *DNSResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	return d.r.LookupAddr(ctx, addr)
+}
+
+// KeyResolver returns the DKIM key record published at <selector>._domainkey.<domain>.
+type KeyResolver interface {
+	LookupKey(ctx context.Context, selector, domain string) (string, error)
+}
+
+var (
+	// ErrKeyNotFound means the selector has no key record (a permanent failure).
+	ErrKeyNotFound = errors.New("dkim key not found")
+	// ErrTemporary marks lookups that may succeed when retried.
+	ErrTemporary = errors.New("temporary dns failure")
+)
+
+func keyName(selector, domain string) string {
+	return strings.ToLower(selector + "._domainkey." + strings.TrimSuffix(domain, "."))
+}
+
+// DNSKeyResolver fetches DKIM keys from DNS TXT records.
+type DNSKeyResolver struct {
+	Resolver TXTResolver
+}
+
+func NewDNSKeyResolver(r TXTResolver) *DNSKeyResolver {
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	return &DNSKeyResolver{Resolver: r}
+}
+
+func (d *DNSKeyResolver) LookupKey(ctx context.Context, selector, domain string) (string, error) {
+	txts, err := d.Resolver.LookupTXT(ctx, keyName(selector, domain))
+	if err != nil {
+		return "", classifyDNSError(err, ErrKeyNotFound)
+	}
+	for _, t := range txts {
+		if strings.Contains(t, "p=") {
+			return t, nil
+		}
+	}
+	return "", ErrKeyNotFound
+}
+
+// classifyDNSError maps resolver errors onto notFound or ErrTemporary.
+func classifyDNSError(err error, notFound error) error {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsNotFound {
+			return notFound
+		}
+		return fmt.Errorf("%w: %v", ErrTemporary, err)
+	}
+	if errors.Is(err, notFound) || errors.Is(err, ErrTemporary) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", ErrTemporary, err)
+}
+
+// StaticKeyResolver serves DKIM keys from memory, typically loaded from a file
+// for offline verification and tests.
+type StaticKeyResolver struct {
+	keys map[string]string
+}
+
+func NewStaticKeyResolver(keys map[string]string) *StaticKeyResolver {
+	norm := make(map[string]string, len(keys))
+	for k, v := range keys {
+		norm[strings.ToLower(strings.TrimSuffix(k, "."))] = v
+	}
+	return &StaticKeyResolver{keys: norm}
+}
+
+// LoadStaticKeyResolver reads a file with one "<selector>._domainkey.<domain> <record>"
+// entry per line. Blank lines and lines starting with '#' are ignored.
+func LoadStaticKeyResolver(path string) (*StaticKeyResolver, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	keys := map[string]string{}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	line := 0
+	for sc.Scan() {
+		line++
+		txt := strings.TrimSpace(sc.Text())
+		if txt == "" || strings.HasPrefix(txt, "#") {
+			continue
+		}
+		name, record, ok := strings.Cut(txt, " ")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected \"<name> <record>\"", path, line)
+		}
+		keys[name] = strings.Trim(strings.TrimSpace(record), `"`)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return NewStaticKeyResolver(keys), nil
+}
+
+func (s *StaticKeyResolver) LookupKey(_ context.Context, selector, domain string) (string, error) {
+	if v, ok := s.keys[keyName(selector, domain)]; ok {
+		return v, nil
+	}
+	return "", ErrKeyNotFound
+}
@@ -0,0 +1,51 @@
+package mailauth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type fakeTXT map[string][]string
+
+func (f fakeTXT) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if v, ok := f[name]; ok {
+		return v, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func TestDNSKeyResolver(t *testing.T) {
+	r := NewDNSKeyResolver(fakeTXT{
+		"s1._domainkey.example.com": {"unrelated", "v=DKIM1; p=abc"},
+	})
+	rec, err := r.LookupKey(context.Background(), "s1", "example.com")
+	if err != nil || rec != "v=DKIM1; p=abc" {
+		t.Fatalf("got %q, %v", rec, err)
+	}
+	if _, err := r.LookupKey(context.Background(), "s2", "example.com"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("want ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestLoadStaticKeyResolver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.txt")
+	data := "# test keys\n\nsel._domainkey.Example.com v=DKIM1; k=rsa; p=xyz\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r, err := LoadStaticKeyResolver(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := r.LookupKey(context.Background(), "sel", "example.com")
+	if err != nil || rec != "v=DKIM1; k=rsa; p=xyz" {
+		t.Fatalf("got %q, %v", rec, err)
+	}
+	if _, err := r.LookupKey(context.Background(), "other", "example.com"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("want ErrKeyNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,576 @@
+package mailauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// RFC 7208 section 4.6.4 processing limits.
+const (
+	spfMaxLookups     = 10
+	spfMaxVoidLookups = 2
+	spfMaxMX          = 10
+	spfMaxPTR         = 10
+)
+
+// SPFResult is the outcome of check_host() for the connecting IP.
+type SPFResult struct {
+	Domain   string `json:"domain"`              // domain whose policy was evaluated
+	MailFrom string `json:"mail_from,omitempty"` // envelope sender, or postmaster@HELO
+	IP       string `json:"ip,omitempty"`
+	Result   string `json:"result"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// SPFRequest identifies the SMTP transaction being checked.
+type SPFRequest struct {
+	IP       net.IP
+	MailFrom string // envelope sender (Return-Path); may be empty
+	HELO     string
+}
+
+// CheckSPF evaluates the SPF policy of the envelope sender's domain (or of the
+// HELO name for null senders) against req.IP.
+func CheckSPF(ctx context.Context, r Resolver, req SPFRequest) SPFResult {
+	sender := strings.TrimSpace(req.MailFrom)
+	if sender == "" || !strings.Contains(sender, "@") {
+		if req.HELO == "" {
+			return SPFResult{Result: ResultNone, Reason: "no sender identity"}
+		}
+		sender = "postmaster@" + req.HELO
+	}
+	_, domain, _ := strings.Cut(sender, "@")
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+
+	res := SPFResult{Domain: domain, MailFrom: sender}
+	if req.IP == nil {
+		res.Result, res.Reason = ResultNone, "no connecting ip"
+		return res
+	}
+	res.IP = req.IP.String()
+
+	c := &spfChecker{r: r, ip: req.IP, sender: sender, helo: req.HELO}
+	res.Result, res.Reason = c.checkHost(ctx, domain, 0)
+	return res
+}
+
+type spfChecker struct {
+	r      Resolver
+	ip     net.IP
+	sender string
+	helo   string
+
+	lookups     int
+	voidLookups int
+}
+
+// spfAbort stops evaluation with a final result, e.g. when a limit is exceeded.
+type spfAbort struct {
+	result string
+	reason string
+}
+
+func (e *spfAbort) Error() string { return e.result + ": " + e.reason }
+
+func (c *spfChecker) checkHost(ctx context.Context, domain string, depth int) (string, string) {
+	result, reason, err := c.evaluate(ctx, domain, depth)
+	var abort *spfAbort
+	if errors.As(err, &abort) {
+		return abort.result, abort.reason
+	}
+	return result, reason
+}
+
+func (c *spfChecker) evaluate(ctx context.Context, domain string, depth int) (string, string, error) {
+	if !validDomain(domain) {
+		return ResultNone, "invalid domain " + strconv.Quote(domain), nil
+	}
+	if depth > spfMaxLookups {
+		return "", "", &spfAbort{ResultPermError, "too many nested evaluations"}
+	}
+
+	record, err := c.fetchRecord(ctx, domain)
+	if err != nil {
+		return "", "", err
+	}
+	if record == "" {
+		return ResultNone, "no spf record for " + domain, nil
+	}
+
+	terms := strings.Fields(record)[1:]
+	var redirect string
+	for _, term := range terms {
+		if name, value, ok := cutModifier(term); ok {
+			if name == "redirect" {
+				if redirect != "" {
+					return "", "", &spfAbort{ResultPermError, "duplicate redirect modifier"}
+				}
+				redirect = value
+			}
+			continue
+		}
+
+		qualifier, mech := ResultPass, term
+		switch term[0] {
+		case '+':
+			mech = term[1:]
+		case '-':
+			qualifier, mech = ResultFail, term[1:]
+		case '~':
+			qualifier, mech = ResultSoftFail, term[1:]
+		case '?':
+			qualifier, mech = ResultNeutral, term[1:]
+		}
+
+		matched, err := c.match(ctx, domain, mech, depth)
+		if err != nil {
+			return "", "", err
+		}
+		if matched {
+			return qualifier, "matched " + term + " in " + domain, nil
+		}
+	}
+
+	if redirect != "" {
+		target, err := c.expand(redirect, domain)
+		if err != nil {
+			return "", "", err
+		}
+		if err := c.countLookup(); err != nil {
+			return "", "", err
+		}
+		result, reason, err := c.evaluate(ctx, target, depth+1)
+		if err != nil {
+			return "", "", err
+		}
+		if result == ResultNone {
+			return "", "", &spfAbort{ResultPermError, "redirect target " + target + " has no spf record"}
+		}
+		return result, reason, nil
+	}
+	return ResultNeutral, "no mechanism matched in " + domain, nil
+}
+
+// fetchRecord returns the single "v=spf1" record of domain, "" if there is none.
+func (c *spfChecker) fetchRecord(ctx context.Context, domain string) (string, error) {
+	txts, err := c.r.LookupTXT(ctx, domain)
+	if err != nil {
+		if errors.Is(classifyDNSError(err, errNoRecords), errNoRecords) {
+			return "", nil
+		}
+		return "", &spfAbort{ResultTempError, "dns lookup for " + domain + " failed"}
+	}
+	var found []string
+	for _, t := range txts {
+		lt := strings.ToLower(t)
+		if lt == "v=spf1" || strings.HasPrefix(lt, "v=spf1 ") {
+			found = append(found, t)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", nil
+	case 1:
+		return found[0], nil
+	default:
+		return "", &spfAbort{ResultPermError, "multiple spf records for " + domain}
+	}
+}
+
+var errNoRecords = errors.New("no records")
+
+func cutModifier(term string) (string, string, bool) {
+	name, value, ok := strings.Cut(term, "=")
+	if !ok || name == "" || strings.ContainsAny(name, ":/") {
+		return "", "", false
+	}
+	return strings.ToLower(name), value, true
+}
+
+func (c *spfChecker) match(ctx context.Context, domain, mech string, depth int) (bool, error) {
+	name, arg := mech, ""
+	if i := strings.IndexAny(mech, ":/"); i >= 0 {
+		name, arg = mech[:i], mech[i:]
+	}
+	name = strings.ToLower(name)
+
+	switch name {
+	case "all":
+		if arg != "" {
+			return false, &spfAbort{ResultPermError, "malformed mechanism " + mech}
+		}
+		return true, nil
+
+	case "ip4", "ip6":
+		if !strings.HasPrefix(arg, ":") {
+			return false, &spfAbort{ResultPermError, "malformed mechanism " + mech}
+		}
+		network := arg[1:]
+		if !strings.Contains(network, "/") {
+			if name == "ip4" {
+				network += "/32"
+			} else {
+				network += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(network)
+		if err != nil || (name == "ip4") != (n.IP.To4() != nil) {
+			return false, &spfAbort{ResultPermError, "malformed mechanism " + mech}
+		}
+		return n.Contains(c.ip), nil
+
+	case "include":
+		target, _, _, err := c.target(arg, domain, false)
+		if err != nil || arg == "" {
+			return false, &spfAbort{ResultPermError, "malformed mechanism " + mech}
+		}
+		if err := c.countLookup(); err != nil {
+			return false, err
+		}
+		result, _, err := c.evaluate(ctx, target, depth+1)
+		if err != nil {
+			return false, err
+		}
+		switch result {
+		case ResultPass:
+			return true, nil
+		case ResultNone:
+			return false, &spfAbort{ResultPermError, "included domain " + target + " has no spf record"}
+		}
+		return false, nil
+
+	case "a", "mx":
+		target, v4, v6, err := c.target(arg, domain, true)
+		if err != nil {
+			return false, err
+		}
+		if err := c.countLookup(); err != nil {
+			return false, err
+		}
+		hosts := []string{target}
+		if name == "mx" {
+			mxs, err := c.r.LookupMX(ctx, target)
+			if err != nil {
+				return false, c.voidOrTemp(err, target)
+			}
+			if len(mxs) > spfMaxMX {
+				return false, &spfAbort{ResultPermError, "too many mx records for " + target}
+			}
+			if len(mxs) == 0 {
+				return false, c.voidOrTemp(nil, target)
+			}
+			hosts = hosts[:0]
+			for _, mx := range mxs {
+				hosts = append(hosts, mx.Host)
+			}
+		}
+		for _, h := range hosts {
+			addrs, err := c.r.LookupIPAddr(ctx, h)
+			if err != nil {
+				if name == "a" {
+					return false, c.voidOrTemp(err, h)
+				}
+				continue
+			}
+			for _, a := range addrs {
+				if cidrMatch(c.ip, a.IP, v4, v6) {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+
+	case "ptr":
+		target, _, _, err := c.target(arg, domain, false)
+		if err != nil {
+			return false, err
+		}
+		if err := c.countLookup(); err != nil {
+			return false, err
+		}
+		names, err := c.r.LookupAddr(ctx, c.ip.String())
+		if err != nil {
+			return false, nil
+		}
+		if len(names) > spfMaxPTR {
+			names = names[:spfMaxPTR]
+		}
+		for _, n := range names {
+			n = strings.ToLower(strings.TrimSuffix(n, "."))
+			if n != target && !strings.HasSuffix(n, "."+target) {
+				continue
+			}
+			addrs, err := c.r.LookupIPAddr(ctx, n)
+			if err != nil {
+				continue
+			}
+			for _, a := range addrs {
+				if a.IP.Equal(c.ip) {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+
+	case "exists":
+		target, _, _, err := c.target(arg, domain, false)
+		if err != nil || arg == "" {
+			return false, &spfAbort{ResultPermError, "malformed mechanism " + mech}
+		}
+		if err := c.countLookup(); err != nil {
+			return false, err
+		}
+		addrs, err := c.r.LookupIPAddr(ctx, target)
+		if err != nil {
+			return false, c.voidOrTemp(err, target)
+		}
+		for _, a := range addrs {
+			if a.IP.To4() != nil {
+				return true, nil
+			}
+		}
+		return false, c.voidOrTemp(nil, target)
+	}
+	return false, &spfAbort{ResultPermError, "unknown mechanism " + mech}
+}
+
+// target splits ":domain-spec/cidr4//cidr6" and expands the domain spec,
+// defaulting to the current domain.
+func (c *spfChecker) target(arg, domain string, allowCIDR bool) (string, int, int, error) {
+	v4, v6 := 32, 128
+	spec := ""
+	if strings.HasPrefix(arg, ":") {
+		spec = arg[1:]
+	}
+	if i := strings.Index(arg, "/"); i >= 0 {
+		if !allowCIDR {
+			return "", 0, 0, &spfAbort{ResultPermError, "unexpected cidr length in " + arg}
+		}
+		cidr := arg[i:]
+		if strings.HasPrefix(arg, ":") {
+			spec = arg[1:i]
+		}
+		var err error
+		if v4, v6, err = parseDualCIDR(cidr); err != nil {
+			return "", 0, 0, &spfAbort{ResultPermError, "malformed cidr length " + cidr}
+		}
+	}
+	if spec == "" {
+		return domain, v4, v6, nil
+	}
+	t, err := c.expand(spec, domain)
+	return t, v4, v6, err
+}
+
+func parseDualCIDR(s string) (int, int, error) {
+	v4, v6 := 32, 128
+	four, six, hasSix := strings.Cut(s, "//")
+	if hasSix {
+		n, err := strconv.Atoi(six)
+		if err != nil || n < 0 || n > 128 {
+			return 0, 0, errors.New("bad ip6 cidr")
+		}
+		v6 = n
+	}
+	if four != "" {
+		n, err := strconv.Atoi(strings.TrimPrefix(four, "/"))
+		if err != nil || n < 0 || n > 32 || !strings.HasPrefix(four, "/") {
+			return 0, 0, errors.New("bad ip4 cidr")
+		}
+		v4 = n
+	}
+	return v4, v6, nil
+}
+
+func cidrMatch(ip, candidate net.IP, v4, v6 int) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		c4 := candidate.To4()
+		if c4 == nil {
+			return false
+		}
+		m := net.CIDRMask(v4, 32)
+		return ip4.Mask(m).Equal(c4.Mask(m))
+	}
+	if candidate.To4() != nil {
+		return false
+	}
+	m := net.CIDRMask(v6, 128)
+	return ip.Mask(m).Equal(candidate.Mask(m))
+}
+
+func (c *spfChecker) countLookup() error {
+	c.lookups++
+	if c.lookups > spfMaxLookups {
+		return &spfAbort{ResultPermError, "more than 10 dns lookups"}
+	}
+	return nil
+}
+
+// voidOrTemp counts a lookup that returned no answers, or turns a DNS failure
+// into temperror.
+func (c *spfChecker) voidOrTemp(err error, name string) error {
+	if err != nil && !errors.Is(classifyDNSError(err, errNoRecords), errNoRecords) {
+		return &spfAbort{ResultTempError, "dns lookup for " + name + " failed"}
+	}
+	c.voidLookups++
+	if c.voidLookups > spfMaxVoidLookups {
+		return &spfAbort{ResultPermError, "more than 2 void lookups"}
+	}
+	return nil
+}
+
+// expand performs macro expansion (RFC 7208 section 7) of a domain-spec and
+// truncates the result to 253 characters from the left.
+func (c *spfChecker) expand(spec, domain string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(spec); i++ {
+		if spec[i] != '%' {
+			b.WriteByte(spec[i])
+			continue
+		}
+		if i+1 >= len(spec) {
+			return "", &spfAbort{ResultPermError, "malformed macro in " + spec}
+		}
+		i++
+		switch spec[i] {
+		case '%':
+			b.WriteByte('%')
+		case '_':
+			b.WriteByte(' ')
+		case '-':
+			b.WriteString("%20")
+		case '{':
+			end := strings.IndexByte(spec[i:], '}')
+			if end < 0 {
+				return "", &spfAbort{ResultPermError, "malformed macro in " + spec}
+			}
+			v, err := c.macro(spec[i+1:i+end], domain)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(v)
+			i += end
+		default:
+			return "", &spfAbort{ResultPermError, "malformed macro in " + spec}
+		}
+	}
+
+	out := strings.ToLower(strings.TrimSuffix(b.String(), "."))
+	for len(out) > 253 {
+		_, rest, ok := strings.Cut(out, ".")
+		if !ok {
+			break
+		}
+		out = rest
+	}
+	return out, nil
+}
+
+func (c *spfChecker) macro(m, domain string) (string, error) {
+	if m == "" {
+		return "", &spfAbort{ResultPermError, "empty macro"}
+	}
+	letter := m[0]
+	escape := letter >= 'A' && letter <= 'Z'
+	if escape {
+		letter += 'a' - 'A'
+	}
+
+	local, senderDomain, _ := strings.Cut(c.sender, "@")
+	var value string
+	switch letter {
+	case 's':
+		value = c.sender
+	case 'l':
+		value = local
+	case 'o':
+		value = senderDomain
+	case 'd':
+		value = domain
+	case 'i':
+		value = dottedIP(c.ip)
+	case 'p':
+		value = "unknown"
+	case 'v':
+		value = "in-addr"
+		if c.ip.To4() == nil {
+			value = "ip6"
+		}
+	case 'h':
+		value = c.helo
+	default:
+		return "", &spfAbort{ResultPermError, fmt.Sprintf("unknown macro letter %q", m[0])}
+	}
+
+	rest := m[1:]
+	digits := 0
+	for digits < len(rest) && rest[digits] >= '0' && rest[digits] <= '9' {
+		digits++
+	}
+	keep := 0
+	if digits > 0 {
+		n, err := strconv.Atoi(rest[:digits])
+		if err != nil || n == 0 {
+			return "", &spfAbort{ResultPermError, "malformed macro transformer"}
+		}
+		keep = n
+	}
+	rest = rest[digits:]
+	reverse := false
+	if strings.HasPrefix(rest, "r") || strings.HasPrefix(rest, "R") {
+		reverse, rest = true, rest[1:]
+	}
+	delims := "."
+	if rest != "" {
+		if strings.Trim(rest, ".-+,/_=") != "" {
+			return "", &spfAbort{ResultPermError, "malformed macro delimiter"}
+		}
+		delims = rest
+	}
+
+	parts := strings.FieldsFunc(value, func(r rune) bool { return strings.ContainsRune(delims, r) })
+	if reverse {
+		for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+			parts[i], parts[j] = parts[j], parts[i]
+		}
+	}
+	if keep > 0 && keep < len(parts) {
+		parts = parts[len(parts)-keep:]
+	}
+	value = strings.Join(parts, ".")
+	if escape {
+		value = strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+	}
+	return value, nil
+}
+
+// dottedIP renders an address for the %{i} macro: dotted quad for IPv4,
+// dot-separated nibbles for IPv6.
+func dottedIP(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.String()
+	}
+	ip16 := ip.To16()
+	nibbles := make([]string, 0, 32)
+	for _, b := range ip16 {
+		nibbles = append(nibbles, strconv.FormatUint(uint64(b>>4), 16), strconv.FormatUint(uint64(b&0xf), 16))
+	}
+	return strings.Join(nibbles, ".")
+}
+
+func validDomain(d string) bool {
+	if d == "" || len(d) > 253 || !strings.Contains(d, ".") {
+		return false
+	}
+	for _, label := range strings.Split(d, ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+package mailauth
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func spfZone() *fakeZone {
+	return &fakeZone{
+		txt: map[string][]string{
+			"example.com":          {"google-site-verification=abc", "v=spf1 ip4:192.0.2.0/24 include:_spf.example.net a:relay.example.com mx -all"},
+			"_spf.example.net":     {"v=spf1 ip6:2001:db8::/32 ~all"},
+			"soft.example.org":     {"v=spf1 ~all"},
+			"redirect.example.org": {"v=spf1 redirect=example.com"},
+			"macro.example.org":    {"v=spf1 exists:%{ir}.%{l1r+-}._spf.%{d} -all"},
+			"ptr.example.org":      {"v=spf1 ptr -all"},
+			"twice.example.org":    {"v=spf1 -all", "v=spf1 +all"},
+			"broken.example.org":   {"v=spf1 ip4:999.1.1.1 -all"},
+			"temp.example.org":     {"v=spf1 include:down.example.org -all"},
+			"missing.example.org":  {"v=spf1 include:nospf.example.org -all"},
+		},
+		ip: map[string][]string{
+			"relay.example.com":                        {"198.51.100.7"},
+			"mx1.example.com":                          {"203.0.113.25"},
+			"4.3.2.1.bob.macro._spf.macro.example.org": {"127.0.0.2"},
+			"mail.ptr.example.org":                     {"203.0.113.99"},
+		},
+		mx:   map[string][]string{"example.com": {"mx1.example.com"}},
+		ptr:  map[string][]string{"203.0.113.99": {"mail.ptr.example.org."}},
+		fail: map[string]bool{"down.example.org": true},
+	}
+}
+
+func TestCheckSPF(t *testing.T) {
+	cases := []struct {
+		name, ip, from, want string
+	}{
+		{"ip4 range", "192.0.2.10", "alice@example.com", ResultPass},
+		{"include ip6", "2001:db8::1", "alice@example.com", ResultPass},
+		{"a mechanism", "198.51.100.7", "alice@example.com", ResultPass},
+		{"mx mechanism", "203.0.113.25", "alice@example.com", ResultPass},
+		{"hard fail", "203.0.113.1", "alice@example.com", ResultFail},
+		{"soft fail", "203.0.113.1", "x@soft.example.org", ResultSoftFail},
+		{"redirect", "192.0.2.1", "x@redirect.example.org", ResultPass},
+		{"macros", "1.2.3.4", "bob.macro@macro.example.org", ResultPass},
+		{"macros no match", "1.2.3.5", "bob.macro@macro.example.org", ResultFail},
+		{"ptr", "203.0.113.99", "x@ptr.example.org", ResultPass},
+		{"no record", "192.0.2.1", "x@nowhere.example.org", ResultNone},
+		{"multiple records", "192.0.2.1", "x@twice.example.org", ResultPermError},
+		{"malformed", "192.0.2.1", "x@broken.example.org", ResultPermError},
+		{"dns failure", "192.0.2.1", "x@temp.example.org", ResultTempError},
+		{"include without record", "192.0.2.1", "x@missing.example.org", ResultPermError},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := CheckSPF(context.Background(), spfZone(), SPFRequest{IP: net.ParseIP(tc.ip), MailFrom: tc.from})
+			if res.Result != tc.want {
+				t.Fatalf("want %s, got %+v", tc.want, res)
+			}
+		})
+	}
+}
+
+func TestCheckSPF_HELOFallback(t *testing.T) {
+	res := CheckSPF(context.Background(), spfZone(), SPFRequest{IP: net.ParseIP("192.0.2.5"), HELO: "example.com"})
+	if res.Result != ResultPass || res.MailFrom != "postmaster@example.com" || res.Domain != "example.com" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if res := CheckSPF(context.Background(), spfZone(), SPFRequest{IP: net.ParseIP("192.0.2.5")}); res.Result != ResultNone {
+		t.Fatalf("want none without identity, got %+v", res)
+	}
+}
+
+func TestCheckSPF_LookupLimit(t *testing.T) {
+	z := &fakeZone{txt: map[string][]string{}}
+	for i := 0; i < 12; i++ {
+		z.txt[fmt.Sprintf("l%d.example.com", i)] = []string{fmt.Sprintf("v=spf1 include:l%d.example.com -all", i+1)}
+	}
+	res := CheckSPF(context.Background(), z, SPFRequest{IP: net.ParseIP("192.0.2.1"), MailFrom: "a@l0.example.com"})
+	if res.Result != ResultPermError {
+		t.Fatalf("want permerror, got %+v", res)
+	}
+	if z.queries > 12 {
+		t.Fatalf("evaluation should stop at the limit, made %d queries", z.queries)
+	}
+}
+
+func TestCheckSPF_VoidLookupLimit(t *testing.T) {
+	z := &fakeZone{txt: map[string][]string{
+		"example.com": {"v=spf1 a:n1.example.com a:n2.example.com a:n3.example.com +all"},
+	}}
+	res := CheckSPF(context.Background(), z, SPFRequest{IP: net.ParseIP("192.0.2.1"), MailFrom: "a@example.com"})
+	if res.Result != ResultPermError {
+		t.Fatalf("want permerror, got %+v", res)
+	}
+}
+
+func TestExpandMacros(t *testing.T) {
+	c := &spfChecker{ip: net.ParseIP("192.0.2.3"), sender: "strong-bad@email.example.com", helo: "mx.example.org"}
+	cases := map[string]string{
+		"%{s}":                  "strong-bad@email.example.com",
+		"%{o}":                  "email.example.com",
+		"%{d4}":                 "email.example.com",
+		"%{d2}":                 "example.com",
+		"%{dr}":                 "com.example.email",
+		"%{d2r}":                "example.email",
+		"%{l-}":                 "strong.bad",
+		"%{lr-}":                "bad.strong",
+		"%{ir}.%{v}._spf.%{d2}": "3.2.0.192.in-addr._spf.example.com",
+		"%{h}":                  "mx.example.org",
+	}
+	for in, want := range cases {
+		got, err := c.expand(in, "email.example.com")
+		if err != nil || got != want {
+			t.Errorf("expand(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+
+	c.ip = net.ParseIP("2001:db8::cb01")
+	got, _ := c.expand("%{ir}.%{v}", "example.com")
+	if want := "1.0.b.c.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6"; got != want {
+		t.Errorf("ipv6 expansion = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,79 @@
+package mailauth
+
+import (
+	"context"
+	"net"
+	"strings"
+)
+
+// fakeZone is an in-memory DNS zone implementing Resolver.
+type fakeZone struct {
+	txt     map[string][]string
+	ip      map[string][]string
+	mx      map[string][]string
+	ptr     map[string][]string
+	fail    map[string]bool // names whose lookups return a server failure
+	queries int
+}
+
+func (z *fakeZone) lookup(name string) error {
+	z.queries++
+	if z.fail[strings.ToLower(name)] {
+		return &net.DNSError{Err: "server misbehaving", Name: name, IsTemporary: true}
+	}
+	return nil
+}
+
+func notFound(name string) error {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (z *fakeZone) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if err := z.lookup(name); err != nil {
+		return nil, err
+	}
+	if v, ok := z.txt[strings.ToLower(name)]; ok {
+		return v, nil
+	}
+	return nil, notFound(name)
+}
+
+func (z *fakeZone) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	if err := z.lookup(host); err != nil {
+		return nil, err
+	}
+	v, ok := z.ip[strings.ToLower(strings.TrimSuffix(host, "."))]
+	if !ok {
+		return nil, notFound(host)
+	}
+	out := make([]net.IPAddr, 0, len(v))
+	for _, s := range v {
+		out = append(out, net.IPAddr{IP: net.ParseIP(s)})
+	}
+	return out, nil
+}
+
+func (z *fakeZone) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	if err := z.lookup(name); err != nil {
+		return nil, err
+	}
+	v, ok := z.mx[strings.ToLower(name)]
+	if !ok {
+		return nil, notFound(name)
+	}
+	out := make([]*net.MX, 0, len(v))
+	for i, h := range v {
+		out = append(out, &net.MX{Host: h + ".", Pref: uint16(10 * (i + 1))})
+	}
+	return out, nil
+}
+
+func (z *fakeZone) LookupAddr(_ context.Context, addr string) ([]string, error) {
+	if err := z.lookup(addr); err != nil {
+		return nil, err
+	}
+	if v, ok := z.ptr[addr]; ok {
+		return v, nil
+	}
+	return nil, notFound(addr)
+}
@@ -0,0 +1,290 @@
+// Package maildate parses the Date values found in real mail, which often
+// stray from RFC 5322: missing seconds, named zones such as "MSK", two-digit
+// years, month names in other languages and ISO 8601 timestamps.
+package maildate
+
+import (
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// isoLayouts are tried before the RFC 5322 style tokenizer.
+var isoLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 -07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"02.01.2006 15:04:05 -0700",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+}
+
+// Parse returns the time in s with the sender's UTC offset as its location.
+// A value without a zone is read as UTC.
+func Parse(s string) (time.Time, bool) {
+	s = strings.Join(strings.Fields(s), " ")
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range isoLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	if t, ok := parseTokens(s); ok {
+		return t, true
+	}
+	if t, err := mail.ParseDate(s); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// Plausible reports whether t can be a real send time: not before 1980 and
+// at most a day after ref, the time the message was received.
+func Plausible(t, ref time.Time) bool {
+	return t.Year() >= 1980 && !t.After(ref.Add(24*time.Hour))
+}
+
+// parseTokens reads day, month, year, time and zone in any order. Unknown
+// words such as weekday names are skipped; of several month names the last
+// one wins, since a weekday ("mar" for martes) comes first.
+func parseTokens(s string) (time.Time, bool) {
+	var (
+		day, month, year   int
+		hour, minute, sec  int
+		offset             int
+		haveTime, haveZone bool
+		pm, am             bool
+		commentZone        *int
+	)
+	for _, tok := range tokenize(s) {
+		switch {
+		case tok.comment:
+			if off, ok := zoneOffset(tok.text); ok && commentZone == nil {
+				commentZone = &off
+			}
+		case strings.Contains(tok.text, ":") && isDigit(tok.text[0]):
+			h, m, sc, ok := parseClock(tok.text)
+			if !ok || haveTime {
+				continue
+			}
+			hour, minute, sec, haveTime = h, m, sc, true
+		case isNumber(tok.text):
+			n, _ := strconv.Atoi(tok.text)
+			switch {
+			case len(tok.text) == 4 && year == 0:
+				year = n
+			case day == 0 && n >= 1 && n <= 31:
+				day = n
+			case year == 0 && len(tok.text) == 2:
+				year = twoDigitYear(n)
+			}
+		case tok.text == "pm" || tok.text == "am":
+			pm, am = tok.text == "pm", tok.text == "am"
+		default:
+			if m, ok := monthOf(tok.text); ok {
+				month = m
+				continue
+			}
+			if off, ok := zoneOffset(tok.text); ok && !haveZone {
+				offset, haveZone = off, true
+			}
+		}
+	}
+	if day == 0 || month == 0 || year == 0 {
+		return time.Time{}, false
+	}
+	if !haveZone && commentZone != nil {
+		offset = *commentZone
+	}
+	switch {
+	case pm && hour < 12:
+		hour += 12
+	case am && hour == 12:
+		hour = 0
+	}
+	t := time.Date(year, time.Month(month), day, hour, minute, sec, 0, time.FixedZone("", offset))
+	if t.Day() != day || hour > 23 || minute > 59 || sec > 60 {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+type token struct {
+	text    string
+	comment bool // inside parentheses
+}
+
+// tokenize lowercases s and splits it on spaces, commas and the dashes of
+// "02-Jan-2006", keeping zone signs and parenthesized comments intact.
+func tokenize(s string) []token {
+	var out []token
+	depth := 0
+	var b strings.Builder
+	flush := func() {
+		if w := strings.Trim(b.String(), "."); w != "" {
+			out = append(out, token{text: w, comment: depth > 0})
+		}
+		b.Reset()
+	}
+	rs := []rune(strings.ToLower(s))
+	for i, r := range rs {
+		switch {
+		case r == '(':
+			flush()
+			depth++
+		case r == ')':
+			flush()
+			if depth > 0 {
+				depth--
+			}
+		case r == ' ' || r == ',':
+			flush()
+		case r == '-' && b.Len() > 0 && i+1 < len(rs) && !unicode.IsSpace(rs[i+1]) && !isZoneName(b.String()):
+			// "02-jan-2006": a dash inside a word separates; a leading one,
+			// or one after "gmt", is a sign.
+			flush()
+		default:
+			b.WriteRune(r)
+		}
+	}
+	flush()
+	return out
+}
+
+// parseClock reads hh:mm, hh:mm:ss or hh:mm:ss.fff.
+func parseClock(s string) (h, m, sec int, ok bool) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, 0, 0, false
+	}
+	if i := strings.IndexByte(parts[len(parts)-1], '.'); i >= 0 {
+		parts[len(parts)-1] = parts[len(parts)-1][:i]
+	}
+	var vals [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || len(p) > 2 {
+			return 0, 0, 0, false
+		}
+		vals[i] = n
+	}
+	return vals[0], vals[1], vals[2], true
+}
+
+// twoDigitYear follows RFC 5322 obs-year: 00-49 are 2000-2049.
+func twoDigitYear(n int) int {
+	if n < 50 {
+		return 2000 + n
+	}
+	return 1900 + n
+}
+
+// months maps name prefixes in English, German, French, Spanish, Italian,
+// Portuguese and Russian to month numbers. Longer prefixes are checked first.
+var months = map[string]int{
+	"jan": 1, "ene": 1, "gen": 1, "янв": 1,
+	"feb": 2, "fév": 2, "fev": 2, "фев": 2,
+	"mar": 3, "mär": 3, "mrz": 3, "мар": 3,
+	"apr": 4, "avr": 4, "abr": 4, "апр": 4,
+	"may": 5, "mai": 5, "mag": 5, "мая": 5, "май": 5,
+	"jun": 6, "juin": 6, "giu": 6, "июн": 6,
+	"jul": 7, "juil": 7, "lug": 7, "июл": 7,
+	"aug": 8, "aoû": 8, "aou": 8, "ago": 8, "авг": 8,
+	"sep": 9, "set": 9, "сен": 9,
+	"oct": 10, "okt": 10, "ott": 10, "out": 10, "окт": 10,
+	"nov": 11, "ноя": 11,
+	"dec": 12, "dez": 12, "déc": 12, "dic": 12, "дек": 12,
+}
+
+func monthOf(w string) (int, bool) {
+	rs := []rune(w)
+	for _, n := range []int{4, 3} {
+		if len(rs) < n {
+			continue
+		}
+		if m, ok := months[string(rs[:n])]; ok {
+			return m, true
+		}
+	}
+	return 0, false
+}
+
+// zones are the named zones seen in Date headers, in seconds east of UTC.
+var zones = map[string]int{
+	"ut": 0, "utc": 0, "gmt": 0, "z": 0, "wet": 0,
+	"est": -5 * 3600, "edt": -4 * 3600, "cst": -6 * 3600, "cdt": -5 * 3600,
+	"mst": -7 * 3600, "mdt": -6 * 3600, "pst": -8 * 3600, "pdt": -7 * 3600,
+	"akst": -9 * 3600, "akdt": -8 * 3600, "hst": -10 * 3600,
+	"bst": 3600, "west": 3600, "cet": 3600, "met": 3600, "cest": 2 * 3600, "mest": 2 * 3600,
+	"eet": 2 * 3600, "eest": 3 * 3600, "msk": 3 * 3600, "msd": 4 * 3600,
+	"ist": 5*3600 + 1800, "yekt": 5 * 3600, "omst": 6 * 3600, "krat": 7 * 3600,
+	"hkt": 8 * 3600, "sgt": 8 * 3600, "awst": 8 * 3600, "irkt": 8 * 3600,
+	"jst": 9 * 3600, "kst": 9 * 3600, "yakt": 9 * 3600, "acst": 9*3600 + 1800,
+	"aest": 10 * 3600, "vlat": 10 * 3600, "aedt": 11 * 3600,
+	"nzst": 12 * 3600, "nzdt": 13 * 3600,
+}
+
+// zoneOffset reads +hhmm, +hh:mm, +hh, a named zone, or a named zone with an
+// offset such as "gmt+3" or "utc+03:00".
+func zoneOffset(w string) (int, bool) {
+	if off, ok := zones[w]; ok {
+		return off, true
+	}
+	if i := strings.IndexAny(w, "+-"); i > 0 {
+		if _, ok := zones[w[:i]]; !ok {
+			return 0, false
+		}
+		w = w[i:]
+	}
+	if len(w) < 2 || (w[0] != '+' && w[0] != '-') {
+		return 0, false
+	}
+	digits := strings.ReplaceAll(w[1:], ":", "")
+	if !isNumber(digits) {
+		return 0, false
+	}
+	var h, m int
+	switch len(digits) {
+	case 1, 2:
+		h, _ = strconv.Atoi(digits)
+	case 4:
+		h, _ = strconv.Atoi(digits[:2])
+		m, _ = strconv.Atoi(digits[2:])
+	default:
+		return 0, false
+	}
+	if h > 14 || m > 59 {
+		return 0, false
+	}
+	off := h*3600 + m*60
+	if w[0] == '-' {
+		off = -off
+	}
+	return off, true
+}
+
+func isZoneName(w string) bool {
+	_, ok := zones[w]
+	return ok
+}
+
+func isNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
@@ -0,0 +1,61 @@
+package maildate
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		in     string
+		want   string // RFC 3339
+		offset int    // seconds east of UTC
+	}{
+		{"Mon, 02 Jan 2006 15:04:05 -0700", "2006-01-02T15:04:05-07:00", -7 * 3600},
+		{"Mon, 02 Jan 2006 15:04:05 +0300 (MSK)", "2006-01-02T15:04:05+03:00", 3 * 3600},
+		{"Mon, 2 Jan 2006 15:04 +0000", "2006-01-02T15:04:00Z", 0},
+		{"Mon, 2 Jan 2006 15:04:05 MSK", "2006-01-02T15:04:05+03:00", 3 * 3600},
+		{"Mon, 2 Jan 2006 15:04:05 EST", "2006-01-02T15:04:05-05:00", -5 * 3600},
+		{"Mon, 2 Jan 06 15:04:05 GMT", "2006-01-02T15:04:05Z", 0},
+		{"Thu, 1 Jan 98 00:00:00 +0100", "1998-01-01T00:00:00+01:00", 3600},
+		{"пн, 2 янв 2006 15:04:05 +0300", "2006-01-02T15:04:05+03:00", 3 * 3600},
+		{"2 мая 2006 г., 15:04", "2006-05-02T15:04:00Z", 0},
+		{"Di, 14 Mär 2006 09:00:00 +0100", "2006-03-14T09:00:00+01:00", 3600},
+		{"mar., 14 févr. 2006 09:00:00 +0100", "2006-02-14T09:00:00+01:00", 3600},
+		{"mar, 3 ene 2006 10:00:00 -0300", "2006-01-03T10:00:00-03:00", -3 * 3600},
+		{"Jan 2, 2006 3:04 PM GMT+3", "2006-01-02T15:04:00+03:00", 3 * 3600},
+		{"02-Jan-2006 15:04:05 +0530", "2006-01-02T15:04:05+05:30", 5*3600 + 1800},
+		{"Mon, 2 Jan 2006 15:04:05 GMT-03:00", "2006-01-02T15:04:05-03:00", -3 * 3600},
+		{"2006-01-02T15:04:05+02:00", "2006-01-02T15:04:05+02:00", 2 * 3600},
+		{"2006-01-02 15:04:05", "2006-01-02T15:04:05Z", 0},
+	} {
+		got, ok := Parse(tc.in)
+		if !ok {
+			t.Fatalf("%q: not parsed", tc.in)
+		}
+		if got.Format(time.RFC3339) != tc.want {
+			t.Fatalf("%q: got %s, want %s", tc.in, got.Format(time.RFC3339), tc.want)
+		}
+		if _, off := got.Zone(); off != tc.offset {
+			t.Fatalf("%q: offset %d, want %d", tc.in, off, tc.offset)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, in := range []string{"", "yesterday", "Mon, 31 Feb 2006 10:00:00 +0000", "12:00"} {
+		if got, ok := Parse(in); ok {
+			t.Fatalf("%q: unexpected %v", in, got)
+		}
+	}
+}
+
+func TestPlausible(t *testing.T) {
+	ref := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if !Plausible(ref.Add(-time.Hour), ref) || !Plausible(ref.Add(2*time.Hour), ref) {
+		t.Fatal("recent dates must be plausible")
+	}
+	if Plausible(time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), ref) || Plausible(ref.AddDate(1, 0, 0), ref) {
+		t.Fatal("epoch and future dates must not be plausible")
+	}
+}
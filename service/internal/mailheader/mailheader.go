@@ -0,0 +1,64 @@
+// Package mailheader reads a message header block as an ordered list of
+// fields and decodes RFC 2047 encoded words in any charset.
+package mailheader
+
+import (
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/Zifeldev/emailback/service/internal/rawmime"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// Field is one header field. Repeated fields such as Received stay separate
+// entries, in the order they appear in the message.
+type Field struct {
+	Name  string `json:"name"`
+	Raw   string `json:"raw"`   // value as sent, folding included
+	Value string `json:"value"` // unfolded, encoded words decoded
+}
+
+// WordDecoder decodes encoded words in every charset golang.org/x/text
+// knows, not only the UTF-8 and ISO-8859-1 that mime.WordDecoder handles.
+var WordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, err
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+var unfolder = strings.NewReplacer("\r\n", "", "\n", "")
+
+// Decode unfolds a raw header value and decodes its encoded words. A value
+// with a word that cannot be decoded is returned unfolded but otherwise as is.
+func Decode(raw string) string {
+	v := strings.TrimSpace(unfolder.Replace(raw))
+	if dec, err := WordDecoder.DecodeHeader(v); err == nil {
+		return dec
+	}
+	return v
+}
+
+// Parse returns the fields of the header block at the start of msg.
+// Lines that are neither a field nor a continuation are skipped.
+func Parse(msg []byte) []Field {
+	hdr, _, ok := rawmime.SplitEntity(rawmime.ToCRLF(msg))
+	if !ok {
+		return nil
+	}
+	var out []Field
+	for _, f := range rawmime.HeaderFields(hdr) {
+		i := strings.IndexByte(f, ':')
+		name := strings.TrimSpace(f[:i])
+		if name == "" {
+			continue
+		}
+		raw := strings.TrimRight(strings.TrimLeft(f[i+1:], " \t"), "\r\n")
+		out = append(out, Field{Name: name, Raw: raw, Value: Decode(raw)})
+	}
+	return out
+}
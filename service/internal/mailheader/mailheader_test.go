@@ -0,0 +1,45 @@
+package mailheader
+
+import (
+	"testing"
+)
+
+func TestParse_OrderedAndDecoded(t *testing.T) {
+	msg := "Received: from b.example by c.example;\n Mon, 2 Jan 2006 15:04:07 +0000\n" +
+		"Received: from a.example by b.example; Mon, 2 Jan 2006 15:04:05 +0000\n" +
+		"From: =?UTF-8?B?0JjQstCw0L0=?= <ivan@example.com>\n" +
+		"Keywords: =?koi8-r?B?8NLJ18XU?=, news\n" +
+		"Comments: =?windows-1251?Q?=EF=F0=E8=E2=E5=F2?=\n" +
+		"Subject: plain\n" +
+		"\n" +
+		"Body: not a header\n"
+
+	got := Parse([]byte(msg))
+	if len(got) != 6 {
+		t.Fatalf("expected 6 fields, got %d: %+v", len(got), got)
+	}
+	if got[0].Name != "Received" || got[1].Name != "Received" {
+		t.Fatalf("repeated fields not kept in order: %+v", got[:2])
+	}
+	if got[0].Raw != "from b.example by c.example;\r\n Mon, 2 Jan 2006 15:04:07 +0000" {
+		t.Fatalf("raw value should keep folding, got %q", got[0].Raw)
+	}
+	if got[0].Value != "from b.example by c.example; Mon, 2 Jan 2006 15:04:07 +0000" {
+		t.Fatalf("unexpected unfolded value %q", got[0].Value)
+	}
+	want := map[int]string{2: "Иван <ivan@example.com>", 3: "Привет, news", 4: "привет", 5: "plain"}
+	for i, v := range want {
+		if got[i].Value != v {
+			t.Fatalf("field %d (%s): got %q, want %q", i, got[i].Name, got[i].Value, v)
+		}
+	}
+	if got[3].Raw != "=?koi8-r?B?8NLJ18XU?=, news" {
+		t.Fatalf("raw form should stay encoded, got %q", got[3].Raw)
+	}
+}
+
+func TestDecode_UnknownCharsetKeepsRaw(t *testing.T) {
+	if got := Decode("=?x-unknown?Q?abc?="); got != "=?x-unknown?Q?abc?=" {
+		t.Fatalf("unexpected %q", got)
+	}
+}
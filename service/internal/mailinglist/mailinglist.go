@@ -0,0 +1,91 @@
+// Package mailinglist parses the RFC 2369/2919 List-* header fields and the
+// RFC 8058 one-click unsubscribe marker.
+package mailinglist
+
+import (
+	"net/textproto"
+	"regexp"
+	"strings"
+
+	"github.com/Zifeldev/emailback/service/internal/mailheader"
+)
+
+// Info is the list metadata of a message.
+type Info struct {
+	// ID is the List-Id identifier without angle brackets, e.g.
+	// "dev.lists.example.com"; Name is its optional description.
+	ID          string   `json:"id,omitempty"`
+	Name        string   `json:"name,omitempty"`
+	Unsubscribe []string `json:"unsubscribe,omitempty"` // mailto: and http(s): URIs in header order
+	// OneClick is set when List-Unsubscribe-Post allows unsubscribing with a
+	// single POST to the https URI in Unsubscribe (RFC 8058).
+	OneClick bool     `json:"one_click_unsubscribe"`
+	Post     []string `json:"post,omitempty"` // empty when List-Post is "NO"
+	Archive  []string `json:"archive,omitempty"`
+}
+
+var (
+	reAngleURI = regexp.MustCompile(`<([^<>]*)>`)
+	reSpace    = regexp.MustCompile(`\s+`)
+)
+
+// Parse returns the list metadata in h, or nil when it has no List-* fields.
+func Parse(h textproto.MIMEHeader) *Info {
+	info := &Info{
+		Unsubscribe: uris(h.Get("List-Unsubscribe")),
+		Post:        uris(h.Get("List-Post")),
+		Archive:     uris(h.Get("List-Archive")),
+	}
+	info.ID, info.Name = listID(h.Get("List-Id"))
+	if info.ID == "" && len(info.Unsubscribe) == 0 && len(info.Post) == 0 && len(info.Archive) == 0 {
+		return nil
+	}
+	if strings.EqualFold(strings.Join(strings.Fields(h.Get("List-Unsubscribe-Post")), ""), "List-Unsubscribe=One-Click") {
+		info.OneClick = OneClickURI(info.Unsubscribe) != ""
+	}
+	return info
+}
+
+// OneClickURI returns the first https URI of a List-Unsubscribe field, the
+// one an RFC 8058 POST goes to.
+func OneClickURI(unsubscribe []string) string {
+	for _, u := range unsubscribe {
+		if strings.HasPrefix(strings.ToLower(u), "https://") {
+			return u
+		}
+	}
+	return ""
+}
+
+// listID splits `Description <id>` into its parts. A bare identifier, which
+// some senders use, is accepted as well.
+func listID(v string) (id, name string) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return "", ""
+	}
+	if m := reAngleURI.FindStringSubmatchIndex(v); m != nil {
+		id = strings.TrimSpace(v[m[2]:m[3]])
+		name = strings.TrimSpace(v[:m[0]])
+	} else {
+		id = v
+	}
+	name = strings.Trim(name, `"`)
+	if dec, err := mailheader.WordDecoder.DecodeHeader(name); err == nil {
+		name = dec
+	}
+	return strings.ToLower(id), name
+}
+
+// uris extracts the angle-bracketed URIs of a List-* field. Folding
+// whitespace inside the brackets is removed; comments outside them are
+// skipped. A field of "NO" (List-Post for announcement-only lists) yields none.
+func uris(v string) []string {
+	var out []string
+	for _, m := range reAngleURI.FindAllStringSubmatch(v, -1) {
+		if u := reSpace.ReplaceAllString(m[1], ""); u != "" {
+			out = append(out, u)
+		}
+	}
+	return out
+}
@@ -0,0 +1,52 @@
+package mailinglist
+
+import (
+	"net/textproto"
+	"reflect"
+	"testing"
+)
+
+func TestParse_Newsletter(t *testing.T) {
+	h := textproto.MIMEHeader{}
+	h.Set("List-Id", `"=?utf-8?q?Acme_News?=" <News.Acme.example>`)
+	h.Set("List-Unsubscribe", "<mailto:unsub@acme.example?subject=unsubscribe>, (web)\r\n <https://acme.example/u?\r\n id=42>")
+	h.Set("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	h.Set("List-Post", "NO (posting not allowed)")
+	h.Set("List-Archive", "<https://acme.example/archive>")
+
+	info := Parse(h)
+	if info == nil {
+		t.Fatal("expected list info")
+	}
+	if info.ID != "news.acme.example" || info.Name != "Acme News" {
+		t.Fatalf("unexpected id/name %q %q", info.ID, info.Name)
+	}
+	want := []string{"mailto:unsub@acme.example?subject=unsubscribe", "https://acme.example/u?id=42"}
+	if !reflect.DeepEqual(info.Unsubscribe, want) {
+		t.Fatalf("unexpected unsubscribe %v", info.Unsubscribe)
+	}
+	if !info.OneClick || OneClickURI(info.Unsubscribe) != "https://acme.example/u?id=42" {
+		t.Fatalf("one-click not detected: %+v", info)
+	}
+	if info.Post != nil || len(info.Archive) != 1 {
+		t.Fatalf("unexpected post/archive %v %v", info.Post, info.Archive)
+	}
+}
+
+func TestParse_OneClickNeedsHTTPS(t *testing.T) {
+	h := textproto.MIMEHeader{}
+	h.Set("List-Unsubscribe", "<mailto:unsub@acme.example>")
+	h.Set("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	info := Parse(h)
+	if info == nil || info.ID != "" || info.OneClick {
+		t.Fatalf("unexpected info %+v", info)
+	}
+}
+
+func TestParse_NoListHeaders(t *testing.T) {
+	h := textproto.MIMEHeader{}
+	h.Set("Subject", "Lunch")
+	if info := Parse(h); info != nil {
+		t.Fatalf("expected nil, got %+v", info)
+	}
+}
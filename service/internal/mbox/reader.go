@@ -0,0 +1,298 @@
+// Package mbox splits mbox mailboxes into individual RFC 5322 messages.
+package mbox
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Format selects how message boundaries and quoted "From " lines are handled.
+type Format string
+
+const (
+	// MboxRD quotes every ">*From " body line with one more '>'; unquoting is lossless.
+	MboxRD Format = "mboxrd"
+	// MboxO quotes only "From " body lines; ">From " is unquoted as well, which is lossy.
+	MboxO Format = "mboxo"
+	// MboxCL is mboxo plus a Content-Length header delimiting the body.
+	MboxCL Format = "mboxcl"
+	// MboxCL2 relies on Content-Length alone and never quotes body lines.
+	MboxCL2 Format = "mboxcl2"
+)
+
+// DefaultMaxMessageSize bounds a single message held in memory.
+const DefaultMaxMessageSize = 25 << 20
+
+var (
+	ErrMessageTooLarge = errors.New("mbox: message exceeds size limit")
+	ErrNotMbox         = errors.New("mbox: input does not start with a \"From \" line")
+)
+
+// ParseFormat maps a user-supplied name to a Format; empty means MboxRD.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case "":
+		return MboxRD, nil
+	case MboxRD, MboxO, MboxCL, MboxCL2:
+		return f, nil
+	}
+	return "", fmt.Errorf("mbox: unknown format %q", s)
+}
+
+// Message is one entry of the mailbox.
+type Message struct {
+	Index     int    // zero-based position in the mailbox
+	Separator string // the "From " line without the "From " prefix and line ending
+	Offset    int64  // byte offset of the separator line
+	Data      []byte
+}
+
+// Reader streams messages out of an mbox without loading the whole file.
+type Reader struct {
+	MaxMessageSize int
+
+	br     *bufio.Reader
+	format Format
+	offset int64
+	n      int
+
+	sep       []byte // separator line read ahead of the next message
+	sepOffset int64
+	started   bool
+	eof       bool
+}
+
+func NewReader(r io.Reader, format Format) *Reader {
+	return &Reader{
+		MaxMessageSize: DefaultMaxMessageSize,
+		br:             bufio.NewReaderSize(r, 64<<10),
+		format:         format,
+	}
+}
+
+// Next returns the next message, or io.EOF when the mailbox is exhausted.
+// A message over MaxMessageSize is skipped: Next returns it without Data
+// together with ErrMessageTooLarge, and the following call continues with
+// the next message.
+func (r *Reader) Next() (*Message, error) {
+	if !r.started {
+		r.started = true
+		if err := r.findFirst(); err != nil {
+			return nil, err
+		}
+	}
+	if r.sep == nil {
+		return nil, io.EOF
+	}
+
+	msg := &Message{
+		Index:     r.n,
+		Separator: strings.TrimRight(string(r.sep[len("From "):]), "\r\n"),
+		Offset:    r.sepOffset,
+	}
+	r.n++
+	r.sep = nil
+
+	b := &bounded{max: r.MaxMessageSize}
+	if r.format == MboxCL || r.format == MboxCL2 {
+		if err := r.readWithLength(b); err != nil {
+			return nil, err
+		}
+	} else if err := r.readLines(b); err != nil {
+		return nil, err
+	}
+	if b.over {
+		return msg, ErrMessageTooLarge
+	}
+	msg.Data = trimSeparatorBlank(b.buf.Bytes())
+	return msg, nil
+}
+
+func (r *Reader) findFirst() error {
+	for {
+		start := r.offset
+		line, _, err := r.readLine()
+		if len(line) > 0 {
+			if isSeparator(line) {
+				r.sep, r.sepOffset = line, start
+				return nil
+			}
+			if len(bytes.TrimSpace(line)) > 0 {
+				return ErrNotMbox
+			}
+		}
+		if err == io.EOF {
+			return io.EOF
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readLines collects lines up to the next separator, unquoting as the format requires.
+func (r *Reader) readLines(b *bounded) error {
+	for !r.eof {
+		start := r.offset
+		line, long, err := r.readLine()
+		switch {
+		case isSeparator(line):
+			r.sep, r.sepOffset = line, start
+			return nil
+		case long:
+			b.overflow()
+		case len(line) > 0:
+			b.write(r.unquote(line))
+		}
+		if err == io.EOF {
+			r.eof = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readWithLength reads the header block, then exactly Content-Length body
+// bytes. Without a usable Content-Length it falls back to separator scanning.
+func (r *Reader) readWithLength(b *bounded) error {
+	length := int64(-1)
+	for !r.eof {
+		line, long, err := r.readLine()
+		if long {
+			b.overflow()
+		} else if len(line) > 0 {
+			b.write(r.unquote(line))
+			if name, value, ok := bytes.Cut(line, []byte(":")); ok && strings.EqualFold(string(name), "Content-Length") {
+				if n, perr := strconv.ParseInt(strings.TrimSpace(string(value)), 10, 64); perr == nil && n >= 0 {
+					length = n
+				}
+			}
+		}
+		if err == io.EOF {
+			r.eof = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimRight(line, "\r\n")) == 0 {
+			break
+		}
+	}
+	if length < 0 {
+		return r.readLines(b)
+	}
+
+	n, err := io.Copy(b, io.LimitReader(r.br, length))
+	r.offset += n
+	if err != nil {
+		return err
+	}
+	if n < length {
+		r.eof = true
+		return nil
+	}
+	// Skip the blank line(s) between the body and the next separator.
+	for !r.eof {
+		start := r.offset
+		line, _, err := r.readLine()
+		if isSeparator(line) {
+			r.sep, r.sepOffset = line, start
+			return nil
+		}
+		if err == io.EOF {
+			r.eof = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readLine returns the next line including its terminator. Lines longer than
+// the buffer are assembled from fragments. A line over MaxMessageSize cannot
+// belong to a message that fits, so only its first MaxMessageSize bytes are
+// kept, the rest is read and dropped, and long is set.
+func (r *Reader) readLine() (line []byte, long bool, err error) {
+	for {
+		frag, err := r.br.ReadSlice('\n')
+		r.offset += int64(len(frag))
+		if r.MaxMessageSize > 0 && len(line)+len(frag) > r.MaxMessageSize {
+			long = true
+			frag = frag[:max(0, r.MaxMessageSize-len(line))]
+		}
+		line = append(line, frag...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		return line, long, err
+	}
+}
+
+func (r *Reader) unquote(line []byte) []byte {
+	switch r.format {
+	case MboxRD:
+		i := 0
+		for i < len(line) && line[i] == '>' {
+			i++
+		}
+		if i > 0 && bytes.HasPrefix(line[i:], []byte("From ")) {
+			return line[1:]
+		}
+	case MboxO, MboxCL:
+		if bytes.HasPrefix(line, []byte(">From ")) {
+			return line[1:]
+		}
+	}
+	return line
+}
+
+func isSeparator(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("From "))
+}
+
+// trimSeparatorBlank drops the empty line writers put before each separator.
+func trimSeparatorBlank(b []byte) []byte {
+	switch {
+	case bytes.HasSuffix(b, []byte("\r\n\r\n")):
+		return b[:len(b)-2]
+	case bytes.HasSuffix(b, []byte("\n\n")):
+		return b[:len(b)-1]
+	}
+	return b
+}
+
+// bounded buffers up to max bytes and then discards, remembering the overflow.
+type bounded struct {
+	buf  bytes.Buffer
+	max  int
+	over bool
+}
+
+func (b *bounded) write(p []byte) { _, _ = b.Write(p) }
+
+func (b *bounded) overflow() {
+	b.over = true
+	b.buf = bytes.Buffer{}
+}
+
+func (b *bounded) Write(p []byte) (int, error) {
+	if b.over {
+		return len(p), nil
+	}
+	if b.max > 0 && b.buf.Len()+len(p) > b.max {
+		b.overflow()
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
@@ -0,0 +1,145 @@
+package mbox
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func readAll(t *testing.T, r *Reader) ([]*Message, []error) {
+	t.Helper()
+	var msgs []*Message
+	var errs []error
+	for {
+		m, err := r.Next()
+		if err == io.EOF {
+			return msgs, errs
+		}
+		if m == nil {
+			t.Fatalf("fatal error: %v", err)
+		}
+		msgs = append(msgs, m)
+		errs = append(errs, err)
+	}
+}
+
+const rdMailbox = "From alice@example.com Mon Jan  2 15:04:05 2006\n" +
+	"Subject: one\n" +
+	"\n" +
+	">From the start\n" +
+	">>From quoted twice\n" +
+	"\n" +
+	"From bob@example.com Mon Jan  2 16:04:05 2006\n" +
+	"Subject: two\n" +
+	"\n" +
+	"body two\n"
+
+func TestReader_MboxRD(t *testing.T) {
+	msgs, errs := readAll(t, NewReader(strings.NewReader(rdMailbox), MboxRD))
+	if len(msgs) != 2 || errs[0] != nil || errs[1] != nil {
+		t.Fatalf("want 2 messages, got %d (%v)", len(msgs), errs)
+	}
+	if want := "Subject: one\n\nFrom the start\n>From quoted twice\n"; string(msgs[0].Data) != want {
+		t.Fatalf("unexpected first message %q", msgs[0].Data)
+	}
+	if msgs[0].Separator != "alice@example.com Mon Jan  2 15:04:05 2006" || msgs[0].Offset != 0 {
+		t.Fatalf("unexpected separator %q at %d", msgs[0].Separator, msgs[0].Offset)
+	}
+	if string(msgs[1].Data) != "Subject: two\n\nbody two\n" || msgs[1].Index != 1 {
+		t.Fatalf("unexpected second message %q", msgs[1].Data)
+	}
+	if want := int64(strings.Index(rdMailbox, "From bob")); msgs[1].Offset != want {
+		t.Fatalf("offset = %d, want %d", msgs[1].Offset, want)
+	}
+}
+
+func TestReader_MboxO(t *testing.T) {
+	msgs, _ := readAll(t, NewReader(strings.NewReader(rdMailbox), MboxO))
+	if want := "Subject: one\n\nFrom the start\n>>From quoted twice\n"; string(msgs[0].Data) != want {
+		t.Fatalf("unexpected message %q", msgs[0].Data)
+	}
+}
+
+func TestReader_MboxCL2(t *testing.T) {
+	body := "line one\r\nFrom inside the body\r\n"
+	mailbox := "From a@example.com Mon Jan  2 15:04:05 2006\r\n" +
+		"Subject: cl2\r\nContent-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body + "\r\n" +
+		"From b@example.com Mon Jan  2 15:04:05 2006\r\n" +
+		"Subject: no length\r\n\r\nshort\r\n"
+
+	msgs, errs := readAll(t, NewReader(strings.NewReader(mailbox), MboxCL2))
+	if len(msgs) != 2 || errs[0] != nil {
+		t.Fatalf("want 2 messages, got %d", len(msgs))
+	}
+	if !strings.HasSuffix(string(msgs[0].Data), "\r\n\r\n"+body) {
+		t.Fatalf("content-length body not preserved: %q", msgs[0].Data)
+	}
+	if string(msgs[1].Data) != "Subject: no length\r\n\r\nshort\r\n" {
+		t.Fatalf("unexpected fallback message %q", msgs[1].Data)
+	}
+}
+
+func TestReader_TooLargeIsSkipped(t *testing.T) {
+	r := NewReader(strings.NewReader(rdMailbox), MboxRD)
+	r.MaxMessageSize = 30
+	msgs, errs := readAll(t, r)
+	if len(msgs) != 2 || !errors.Is(errs[0], ErrMessageTooLarge) || msgs[0].Data != nil {
+		t.Fatalf("first message should be reported too large: %v", errs)
+	}
+	if errs[1] != nil || string(msgs[1].Data) != "Subject: two\n\nbody two\n" {
+		t.Fatalf("reader should continue after oversized message: %v %q", errs[1], msgs[1].Data)
+	}
+}
+
+func TestReader_EndlessLineIsBounded(t *testing.T) {
+	endless := io.LimitReader(repeatReader('x'), 8<<20)
+	in := io.MultiReader(strings.NewReader("From a@example.com Mon Jan  1 00:00:00 2024\nSubject: one\n\n"),
+		endless, strings.NewReader("\nFrom b@example.com Mon Jan  1 00:00:00 2024\nSubject: two\n\nbody two\n"))
+	r := NewReader(in, MboxRD)
+	r.MaxMessageSize = 1 << 10
+	msgs, errs := readAll(t, r)
+	if len(msgs) != 2 || !errors.Is(errs[0], ErrMessageTooLarge) || msgs[0].Data != nil {
+		t.Fatalf("endless line should make its message too large: %v", errs)
+	}
+	if errs[1] != nil || string(msgs[1].Data) != "Subject: two\n\nbody two\n" {
+		t.Fatalf("reader should continue after the long line: %v %q", errs[1], msgs[1].Data)
+	}
+
+	line := NewReader(io.LimitReader(repeatReader('x'), 1<<20), MboxRD)
+	line.MaxMessageSize = 1 << 10
+	if got, long, _ := line.readLine(); !long || len(got) != 1<<10 {
+		t.Fatalf("long line should be cut at the limit: %d bytes, long=%v", len(got), long)
+	}
+}
+
+type repeatReader byte
+
+func (c repeatReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(c)
+	}
+	return len(p), nil
+}
+
+func TestReader_NotMbox(t *testing.T) {
+	if _, err := NewReader(strings.NewReader("Subject: hi\n\nbody"), MboxRD).Next(); !errors.Is(err, ErrNotMbox) {
+		t.Fatalf("want ErrNotMbox, got %v", err)
+	}
+	if _, err := NewReader(strings.NewReader(""), MboxRD).Next(); err != io.EOF {
+		t.Fatalf("want EOF for empty input, got %v", err)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat(""); err != nil || f != MboxRD {
+		t.Fatalf("default format: %v %v", f, err)
+	}
+	if f, err := ParseFormat("MBOXCL2"); err != nil || f != MboxCL2 {
+		t.Fatalf("case-insensitive: %v %v", f, err)
+	}
+	if _, err := ParseFormat("maildir"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
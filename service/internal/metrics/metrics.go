@@ -20,6 +20,17 @@ var (
 		Help:    "Histogram of email processing durations in seconds",
 		Buckets: prometheus.DefBuckets,
 	})
+
+	DeliveryLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "emailback_delivery_latency_seconds",
+		Help:    "Histogram of message transit time from Date (or first hop) to the last Received hop",
+		Buckets: []float64{1, 5, 15, 30, 60, 120, 300, 900, 1800, 3600, 4 * 3600, 24 * 3600},
+	})
+
+	ParseWarnings = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "emailback_parse_warnings_total",
+		Help: "MIME defects the parser recovered from, by defect type and severity",
+	}, []string{"type", "severity"})
 )
 
 
@@ -27,4 +38,6 @@ func RegisterMetrics() {
 	prometheus.MustRegister(EmailsProcessed)
 	prometheus.MustRegister(EmailsFailed)
 	prometheus.MustRegister(EmailProcessingDuration)
+	prometheus.MustRegister(DeliveryLatency)
+	prometheus.MustRegister(ParseWarnings)
 }
@@ -14,6 +14,8 @@ func TestRegisterMetrics(t *testing.T) {
 	prometheus.Unregister(EmailsProcessed)
 	prometheus.Unregister(EmailsFailed)
 	prometheus.Unregister(EmailProcessingDuration)
+	prometheus.Unregister(DeliveryLatency)
+	prometheus.Unregister(ParseWarnings)
 }
 
 func TestRegisterAndIncrementMetrics(t *testing.T) {
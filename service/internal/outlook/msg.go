@@ -0,0 +1,435 @@
+// Package outlook decodes Outlook .msg files (MS-OXMSG): a compound file whose
+// streams hold the MAPI properties of a message, its recipients and attachments.
+package outlook
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/Zifeldev/emailback/service/internal/cfb"
+	"github.com/Zifeldev/emailback/service/internal/tnef"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// Recipient types (PR_RECIPIENT_TYPE).
+const (
+	RecipientTo  = 1
+	RecipientCc  = 2
+	RecipientBcc = 3
+)
+
+// Attachment methods (PR_ATTACH_METHOD).
+const (
+	attachByValue  = 1
+	attachEmbedded = 5
+)
+
+// Property types.
+const (
+	ptInt32   = 0x0003
+	ptBoolean = 0x000B
+	ptObject  = 0x000D
+	ptString8 = 0x001E
+	ptUnicode = 0x001F
+	ptSysTime = 0x0040
+	ptBinary  = 0x0102
+)
+
+// Property IDs.
+const (
+	prMessageClass         = 0x001A
+	prSubject              = 0x0037
+	prClientSubmitTime     = 0x0039
+	prSentRepresentingName = 0x0042
+	prSentRepresentingAddr = 0x0065
+	prSentRepresentingType = 0x0064
+	prTransportHeaders     = 0x007D
+	prRecipientType        = 0x0C15
+	prSenderName           = 0x0C1A
+	prSenderAddrType       = 0x0C1E
+	prSenderEmail          = 0x0C1F
+	prMessageDeliveryTime  = 0x0E06
+	prBody                 = 0x1000
+	prRTFCompressed        = 0x1009
+	prHTML                 = 0x1013
+	prInternetMessageID    = 0x1035
+	prInternetReferences   = 0x1039
+	prInReplyTo            = 0x1042
+	prDisplayName          = 0x3001
+	prAddrType             = 0x3002
+	prEmailAddress         = 0x3003
+	prAttachData           = 0x3701
+	prAttachFilename       = 0x3704
+	prAttachMethod         = 0x3705
+	prAttachLongFilename   = 0x3707
+	prAttachMimeTag        = 0x370E
+	prAttachContentID      = 0x3712
+	prSMTPAddress          = 0x39FE
+	prInternetCodepage     = 0x3FDE
+	prMessageCodepage      = 0x3FFD
+	prSenderSMTPAddress    = 0x5D01
+	prSentRepresentingSMTP = 0x5D02
+	prAttachmentHidden     = 0x7FFE
+)
+
+// Stream and storage names, and the size of the properties stream header
+// for each kind of storage.
+const (
+	propertiesStream   = "__properties_version1.0"
+	recipientPrefix    = "__recip_version1.0_"
+	attachmentPrefix   = "__attach_version1.0_"
+	substgPrefix       = "__substg1.0_"
+	topLevelHeaderSize = 32
+	embeddedHeaderSize = 24
+	subobjectHeader    = 8
+)
+
+var ErrNotMSG = errors.New("outlook: not a .msg file")
+
+// Message is the decoded content of a .msg file.
+type Message struct {
+	MessageClass string
+	Subject      string
+	SenderName   string
+	SenderEmail  string
+	Recipients   []Recipient
+	// Headers holds the original internet headers for received mail; drafts
+	// and sent items have none.
+	Headers     string
+	MessageID   string
+	InReplyTo   string
+	References  string
+	Date        *time.Time
+	Body        string
+	HTML        string
+	Attachments []Attachment
+}
+
+type Recipient struct {
+	Type  int
+	Name  string
+	Email string
+}
+
+type Attachment struct {
+	Name      string
+	MIMEType  string
+	ContentID string
+	Hidden    bool
+	Data      []byte
+	// Message is set for attached Outlook items (forwarded mail) instead of Data.
+	Message *Message
+}
+
+// IsMSG reports whether data looks like an Outlook .msg file. Only the
+// compound file signature is checked; Decode rejects other OLE documents.
+func IsMSG(data []byte) bool {
+	return cfb.IsCFB(data)
+}
+
+// Decode parses a complete .msg file.
+func Decode(data []byte) (*Message, error) {
+	f, err := cfb.Open(data)
+	if err != nil {
+		if errors.Is(err, cfb.ErrNotCFB) {
+			return nil, ErrNotMSG
+		}
+		return nil, err
+	}
+	if f.Root().Child(propertiesStream) == nil {
+		return nil, ErrNotMSG
+	}
+	return decodeMessage(f, f.Root(), topLevelHeaderSize, 0)
+}
+
+func decodeMessage(f *cfb.File, e *cfb.Entry, headerSize int, parentCP int) (*Message, error) {
+	s, err := openStorage(f, e, headerSize, parentCP)
+	if err != nil {
+		return nil, err
+	}
+	if cp, ok := s.int32(prInternetCodepage); ok {
+		s.codepage = int(cp)
+	} else if cp, ok := s.int32(prMessageCodepage); ok {
+		s.codepage = int(cp)
+	}
+
+	m := &Message{
+		MessageClass: s.string(prMessageClass),
+		Subject:      s.string(prSubject),
+		Headers:      s.string(prTransportHeaders),
+		MessageID:    s.string(prInternetMessageID),
+		InReplyTo:    s.string(prInReplyTo),
+		References:   s.string(prInternetReferences),
+		Body:         s.string(prBody),
+	}
+	m.SenderName = firstNonEmpty(s.string(prSenderName), s.string(prSentRepresentingName))
+	m.SenderEmail = firstNonEmpty(
+		s.string(prSenderSMTPAddress),
+		s.smtp(prSenderEmail, prSenderAddrType),
+		s.string(prSentRepresentingSMTP),
+		s.smtp(prSentRepresentingAddr, prSentRepresentingType),
+	)
+	if t, ok := s.time(prClientSubmitTime); ok {
+		m.Date = &t
+	} else if t, ok := s.time(prMessageDeliveryTime); ok {
+		m.Date = &t
+	}
+
+	if html := s.binary(prHTML); len(html) > 0 {
+		m.HTML = s.decodeText(html)
+	} else {
+		m.HTML = s.string(prHTML)
+	}
+	if rtf := s.binary(prRTFCompressed); len(rtf) > 0 && (m.HTML == "" || m.Body == "") {
+		if raw, err := tnef.DecompressRTF(rtf); err == nil {
+			if m.HTML == "" {
+				if html, ok := tnef.RTFToHTML(raw); ok {
+					m.HTML = html
+				}
+			}
+			if m.Body == "" {
+				m.Body = tnef.RTFToText(raw)
+			}
+		}
+	}
+
+	for _, c := range e.Children() {
+		switch {
+		case !c.IsStorage():
+		case strings.HasPrefix(c.Name, recipientPrefix):
+			r, err := openStorage(f, c, subobjectHeader, s.codepage)
+			if err != nil {
+				return nil, err
+			}
+			typ, _ := r.int32(prRecipientType)
+			m.Recipients = append(m.Recipients, Recipient{
+				Type:  int(typ),
+				Name:  r.string(prDisplayName),
+				Email: firstNonEmpty(r.string(prSMTPAddress), r.smtp(prEmailAddress, prAddrType)),
+			})
+		case strings.HasPrefix(c.Name, attachmentPrefix):
+			a, err := decodeAttachment(f, c, s.codepage)
+			if err != nil {
+				return nil, err
+			}
+			if a != nil {
+				m.Attachments = append(m.Attachments, *a)
+			}
+		}
+	}
+	return m, nil
+}
+
+func decodeAttachment(f *cfb.File, e *cfb.Entry, cp int) (*Attachment, error) {
+	s, err := openStorage(f, e, subobjectHeader, cp)
+	if err != nil {
+		return nil, err
+	}
+	a := &Attachment{
+		Name:      firstNonEmpty(s.string(prAttachLongFilename), s.string(prAttachFilename), s.string(prDisplayName)),
+		MIMEType:  s.string(prAttachMimeTag),
+		ContentID: s.string(prAttachContentID),
+	}
+	a.Hidden, _ = s.bool(prAttachmentHidden)
+
+	method, _ := s.int32(prAttachMethod)
+	switch method {
+	case attachEmbedded:
+		obj := e.Child(substgName(prAttachData, ptObject))
+		if obj == nil || !obj.IsStorage() {
+			return nil, nil
+		}
+		a.Message, err = decodeMessage(f, obj, embeddedHeaderSize, cp)
+		if err != nil {
+			return nil, fmt.Errorf("outlook: embedded message: %w", err)
+		}
+		if a.Name == "" {
+			a.Name = a.Message.Subject
+		}
+	default:
+		// By value and anything else that carries bytes; OLE objects and
+		// references to external files have nothing usable.
+		a.Data = s.binary(prAttachData)
+		if a.Data == nil && method != attachByValue {
+			return nil, nil
+		}
+	}
+	return a, nil
+}
+
+// storage reads the properties of one message, recipient or attachment.
+// Fixed-size values live in the properties stream; strings and binaries in
+// their own __substg1.0_ streams.
+type storage struct {
+	f        *cfb.File
+	e        *cfb.Entry
+	fixed    map[uint32][]byte
+	codepage int
+}
+
+func openStorage(f *cfb.File, e *cfb.Entry, headerSize int, cp int) (*storage, error) {
+	s := &storage{f: f, e: e, fixed: map[uint32][]byte{}, codepage: cp}
+	ps := e.Child(propertiesStream)
+	if ps == nil {
+		return s, nil
+	}
+	b, err := f.ReadStream(ps)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < headerSize {
+		return nil, fmt.Errorf("outlook: short property stream in %q", e.Name)
+	}
+	for b = b[headerSize:]; len(b) >= 16; b = b[16:] {
+		tag := binary.LittleEndian.Uint32(b)
+		s.fixed[tag] = b[8:16]
+	}
+	return s, nil
+}
+
+func substgName(id, typ uint16) string {
+	return fmt.Sprintf("%s%04X%04X", substgPrefix, id, typ)
+}
+
+func (s *storage) stream(id, typ uint16) []byte {
+	c := s.e.Child(substgName(id, typ))
+	if c == nil || c.IsStorage() {
+		return nil
+	}
+	b, err := s.f.ReadStream(c)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+func (s *storage) string(id uint16) string {
+	if b := s.stream(id, ptUnicode); b != nil {
+		u := make([]uint16, 0, len(b)/2)
+		for i := 0; i+1 < len(b); i += 2 {
+			u = append(u, binary.LittleEndian.Uint16(b[i:]))
+		}
+		return strings.TrimRight(string(utf16.Decode(u)), "\x00")
+	}
+	if b := s.stream(id, ptString8); b != nil {
+		return strings.TrimRight(s.decodeText(b), "\x00")
+	}
+	return ""
+}
+
+func (s *storage) binary(id uint16) []byte {
+	return s.stream(id, ptBinary)
+}
+
+// smtp returns the address property only when its address type is SMTP;
+// Exchange DNs are not usable as email addresses.
+func (s *storage) smtp(addrID, typeID uint16) string {
+	if !strings.EqualFold(s.string(typeID), "SMTP") {
+		return ""
+	}
+	return s.string(addrID)
+}
+
+func (s *storage) value(id, typ uint16) ([]byte, bool) {
+	v, ok := s.fixed[uint32(id)<<16|uint32(typ)]
+	return v, ok
+}
+
+func (s *storage) int32(id uint16) (int32, bool) {
+	v, ok := s.value(id, ptInt32)
+	if !ok {
+		return 0, false
+	}
+	return int32(binary.LittleEndian.Uint32(v)), true
+}
+
+func (s *storage) bool(id uint16) (bool, bool) {
+	v, ok := s.value(id, ptBoolean)
+	if !ok {
+		return false, false
+	}
+	return v[0] != 0, true
+}
+
+func (s *storage) time(id uint16) (time.Time, bool) {
+	v, ok := s.value(id, ptSysTime)
+	if !ok {
+		return time.Time{}, false
+	}
+	ft := binary.LittleEndian.Uint64(v)
+	if ft == 0 {
+		return time.Time{}, false
+	}
+	return filetime(ft), true
+}
+
+// decodeText converts 8-bit text in the message code page to UTF-8.
+func (s *storage) decodeText(b []byte) string {
+	if enc := codepageEncoding(s.codepage); enc != nil {
+		if out, err := enc.NewDecoder().Bytes(b); err == nil {
+			return string(out)
+		}
+	}
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	out, _ := codepageEncoding(1252).NewDecoder().Bytes(b)
+	return string(out)
+}
+
+// codepageEncoding maps Windows code page numbers to encodings; nil means
+// UTF-8 or unknown.
+func codepageEncoding(cp int) encoding.Encoding {
+	var name string
+	switch {
+	case cp == 0 || cp == 65001 || cp == 20127:
+		return nil
+	case cp == 874 || cp >= 1250 && cp <= 1258:
+		name = fmt.Sprintf("windows-%d", cp)
+	case cp >= 28591 && cp <= 28605:
+		name = fmt.Sprintf("iso-8859-%d", cp-28590)
+	case cp == 20866:
+		name = "koi8-r"
+	case cp == 21866:
+		name = "koi8-u"
+	case cp == 932:
+		name = "shift_jis"
+	case cp == 936:
+		name = "gbk"
+	case cp == 949:
+		name = "euc-kr"
+	case cp == 950:
+		name = "big5"
+	case cp == 50220:
+		name = "iso-2022-jp"
+	default:
+		return nil
+	}
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil
+	}
+	return enc
+}
+
+// filetime converts a Windows FILETIME (100ns ticks since 1601) to UTC.
+func filetime(ft uint64) time.Time {
+	const epochDiff = 116444736000000000 // 1601-01-01 to 1970-01-01 in ticks
+	ticks := int64(ft) - epochDiff
+	return time.Unix(ticks/1e7, ticks%1e7*100).UTC()
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,140 @@
+package outlook
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/Zifeldev/emailback/service/internal/cfb/cfbtest"
+)
+
+func unicodeProp(id uint16, s string) *cfbtest.Node {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(s + "\x00")) {
+		b = binary.LittleEndian.AppendUint16(b, c)
+	}
+	return cfbtest.Stream(substgName(id, ptUnicode), b)
+}
+
+func ansiProp(id uint16, b []byte) *cfbtest.Node {
+	return cfbtest.Stream(substgName(id, ptString8), b)
+}
+
+func binaryProp(id uint16, b []byte) *cfbtest.Node {
+	return cfbtest.Stream(substgName(id, ptBinary), b)
+}
+
+type fixedProp struct {
+	id, typ uint16
+	value   uint64
+}
+
+func propStream(headerSize int, props ...fixedProp) *cfbtest.Node {
+	b := make([]byte, headerSize)
+	for _, p := range props {
+		b = binary.LittleEndian.AppendUint32(b, uint32(p.id)<<16|uint32(p.typ))
+		b = binary.LittleEndian.AppendUint32(b, 0x6) // readable|writable
+		b = binary.LittleEndian.AppendUint64(b, p.value)
+	}
+	return cfbtest.Stream(propertiesStream, b)
+}
+
+func toFiletime(t time.Time) uint64 {
+	return uint64(t.UnixNano()/100 + 116444736000000000)
+}
+
+func sampleMSG() []byte {
+	sent := time.Date(2025, 10, 30, 18, 0, 0, 0, time.UTC)
+	embedded := cfbtest.Storage(substgName(prAttachData, ptObject),
+		propStream(embeddedHeaderSize),
+		unicodeProp(prSubject, "Original"),
+		unicodeProp(prBody, "forwarded body"),
+	)
+	return cfbtest.Build(
+		propStream(topLevelHeaderSize,
+			fixedProp{prClientSubmitTime, ptSysTime, toFiletime(sent)},
+			fixedProp{prMessageCodepage, ptInt32, 1251},
+		),
+		unicodeProp(prMessageClass, "IPM.Note"),
+		unicodeProp(prSubject, "Квартальный отчёт"),
+		unicodeProp(prSenderName, "Alice"),
+		unicodeProp(prSenderAddrType, "EX"),
+		unicodeProp(prSenderEmail, "/O=CORP/OU=EXCHANGE/CN=ALICE"),
+		unicodeProp(prSenderSMTPAddress, "alice@example.com"),
+		unicodeProp(prInternetMessageID, "<msg-1@example.com>"),
+		ansiProp(prBody, []byte("\xcf\xf0\xe8\xe2\xe5\xf2")), // "Привет" in CP1251
+		binaryProp(prHTML, []byte("<p>\xcf\xf0\xe8\xe2\xe5\xf2</p>")),
+		cfbtest.Storage(recipientPrefix+"#00000000",
+			propStream(subobjectHeader, fixedProp{prRecipientType, ptInt32, RecipientTo}),
+			unicodeProp(prDisplayName, "Bob"),
+			unicodeProp(prAddrType, "SMTP"),
+			unicodeProp(prEmailAddress, "bob@example.com"),
+		),
+		cfbtest.Storage(recipientPrefix+"#00000001",
+			propStream(subobjectHeader, fixedProp{prRecipientType, ptInt32, RecipientCc}),
+			unicodeProp(prDisplayName, "Carol"),
+			unicodeProp(prSMTPAddress, "carol@example.com"),
+		),
+		cfbtest.Storage(attachmentPrefix+"#00000000",
+			propStream(subobjectHeader, fixedProp{prAttachMethod, ptInt32, attachByValue}),
+			unicodeProp(prAttachFilename, "REPORT~1.PDF"),
+			unicodeProp(prAttachLongFilename, "report.pdf"),
+			unicodeProp(prAttachMimeTag, "application/pdf"),
+			binaryProp(prAttachData, []byte("%PDF-1.4\n")),
+		),
+		cfbtest.Storage(attachmentPrefix+"#00000001",
+			propStream(subobjectHeader, fixedProp{prAttachMethod, ptInt32, attachEmbedded}),
+			embedded,
+		),
+	)
+}
+
+func TestDecode(t *testing.T) {
+	data := sampleMSG()
+	if !IsMSG(data) {
+		t.Fatalf("magic not detected")
+	}
+	m, err := Decode(data)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if m.MessageClass != "IPM.Note" || m.Subject != "Квартальный отчёт" || m.MessageID != "<msg-1@example.com>" {
+		t.Fatalf("unexpected message fields: %+v", m)
+	}
+	if m.SenderName != "Alice" || m.SenderEmail != "alice@example.com" {
+		t.Fatalf("unexpected sender %q <%s>", m.SenderName, m.SenderEmail)
+	}
+	if m.Date == nil || !m.Date.Equal(time.Date(2025, 10, 30, 18, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected date %v", m.Date)
+	}
+	if m.Body != "Привет" || m.HTML != "<p>Привет</p>" {
+		t.Fatalf("code page not applied: body=%q html=%q", m.Body, m.HTML)
+	}
+	want := []Recipient{{RecipientTo, "Bob", "bob@example.com"}, {RecipientCc, "Carol", "carol@example.com"}}
+	if len(m.Recipients) != 2 || m.Recipients[0] != want[0] || m.Recipients[1] != want[1] {
+		t.Fatalf("unexpected recipients %+v", m.Recipients)
+	}
+	if len(m.Attachments) != 2 {
+		t.Fatalf("want 2 attachments, got %d", len(m.Attachments))
+	}
+	a := m.Attachments[0]
+	if a.Name != "report.pdf" || a.MIMEType != "application/pdf" || string(a.Data) != "%PDF-1.4\n" {
+		t.Fatalf("unexpected attachment %+v", a)
+	}
+	e := m.Attachments[1]
+	if e.Message == nil || e.Message.Subject != "Original" || e.Message.Body != "forwarded body" || e.Name != "Original" {
+		t.Fatalf("unexpected embedded message %+v", e)
+	}
+}
+
+func TestDecode_NotMSG(t *testing.T) {
+	if _, err := Decode([]byte("From: a@b\r\n\r\nhi")); err != ErrNotMSG {
+		t.Fatalf("want ErrNotMSG, got %v", err)
+	}
+	// A compound file that is not a message (e.g. a .doc).
+	doc := cfbtest.Build(cfbtest.Stream("WordDocument", []byte("x")))
+	if _, err := Decode(doc); err != ErrNotMSG {
+		t.Fatalf("want ErrNotMSG for other OLE documents, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package pgp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// LoadKeyrings reads OpenPGP keyrings, armored or binary, from the given
+// files and from every file in the given directories. Missing paths are
+// skipped. Encrypted private keys are unlocked with passphrase.
+func LoadKeyrings(paths []string, passphrase []byte) (openpgp.EntityList, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if !e.IsDir() && e.Name()[0] != '.' {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+	}
+
+	var keyring openpgp.EntityList
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		el, err := readKeyring(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		for _, e := range el {
+			if err := unlock(e, passphrase); err != nil {
+				return nil, fmt.Errorf("%s: %w", f, err)
+			}
+		}
+		keyring = append(keyring, el...)
+	}
+	return keyring, nil
+}
+
+// readKeyring accepts binary keyrings and files with one or more armored
+// key blocks, such as a public and a private key exported together.
+func readKeyring(data []byte) (openpgp.EntityList, error) {
+	marker := []byte("-----BEGIN PGP ")
+	if !bytes.Contains(data, marker) {
+		return openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	var out openpgp.EntityList
+	for {
+		i := bytes.Index(data, marker)
+		if i < 0 {
+			return out, nil
+		}
+		data = data[i:]
+		next := bytes.Index(data[len(marker):], marker)
+		block := data
+		if next >= 0 {
+			block = data[:len(marker)+next]
+		}
+		el, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(block))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, el...)
+		data = data[len(block):]
+	}
+}
+
+func unlock(e *openpgp.Entity, passphrase []byte) error {
+	encrypted := e.PrivateKey != nil && e.PrivateKey.Encrypted
+	for _, sk := range e.Subkeys {
+		encrypted = encrypted || sk.PrivateKey != nil && sk.PrivateKey.Encrypted
+	}
+	if !encrypted {
+		return nil
+	}
+	if len(passphrase) == 0 {
+		return errors.New("encrypted private key and no passphrase configured")
+	}
+	return e.DecryptPrivateKeys(passphrase)
+}
@@ -0,0 +1,346 @@
+// Package pgp verifies and decrypts OpenPGP mail: PGP/MIME (RFC 3156)
+// multipart/encrypted and multipart/signed, and inline armored blocks in
+// text bodies.
+package pgp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/Zifeldev/emailback/service/internal/rawmime"
+)
+
+// Signature validity.
+const (
+	ValidityValid      = "valid"       // signature checks out against a known key
+	ValidityInvalid    = "invalid"     // content was altered or the signature is malformed
+	ValidityUnknownKey = "unknown_key" // signer's public key is not in the keyring
+	ValidityExpired    = "expired"     // signature or signing key has expired
+	ValidityRevoked    = "revoked"     // signing key has been revoked
+)
+
+// maxLayers bounds nesting such as signed-then-encrypted-then-signed.
+const maxLayers = 4
+
+const (
+	beginMessage = "-----BEGIN PGP MESSAGE-----"
+	endMessage   = "-----END PGP MESSAGE-----"
+	beginSigned  = "-----BEGIN PGP SIGNED MESSAGE-----"
+)
+
+// Signature describes one OpenPGP signature and the key that made it.
+type Signature struct {
+	KeyID       string     `json:"key_id"`
+	Fingerprint string     `json:"fingerprint,omitempty"`
+	UserID      string     `json:"user_id,omitempty"`
+	Created     *time.Time `json:"created,omitempty"`
+	Validity    string     `json:"validity"`
+	Reason      string     `json:"reason,omitempty"`
+}
+
+// Result summarises the OpenPGP layers and blocks found on a message.
+type Result struct {
+	Signed     bool        `json:"signed"`
+	Encrypted  bool        `json:"encrypted"`
+	Decrypted  bool        `json:"decrypted"`
+	Inline     bool        `json:"inline,omitempty"`
+	Recipients []string    `json:"recipients,omitempty"`
+	Signatures []Signature `json:"signatures,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// Processor unwraps OpenPGP layers using the public keys in Keyring for
+// verification and its private keys for decryption.
+type Processor struct {
+	Keyring openpgp.EntityList
+}
+
+func NewProcessor(keyring openpgp.EntityList) *Processor {
+	return &Processor{Keyring: keyring}
+}
+
+// Unwrap returns the innermost PGP/MIME entity with the original outer headers
+// and a summary of the layers removed. Messages without PGP/MIME come back
+// unchanged with a nil result. A layer that cannot be opened stops
+// unwrapping; the message up to that point is returned and the reason
+// recorded in the result.
+func (p *Processor) Unwrap(raw []byte) ([]byte, *Result) {
+	var res *Result
+	msg := rawmime.ToCRLF(raw)
+	for i := 0; i < maxLayers; i++ {
+		hdr, body, ok := rawmime.SplitEntity(msg)
+		if !ok {
+			break
+		}
+		ct, params := rawmime.MediaType(hdr)
+		protocol := strings.ToLower(params["protocol"])
+		var inner []byte
+		var err error
+		switch {
+		case ct == "multipart/signed" && protocol == "application/pgp-signature":
+			if res == nil {
+				res = &Result{}
+			}
+			res.Signed = true
+			inner, err = p.detached(body, params["boundary"], res)
+		case ct == "multipart/encrypted" && protocol == "application/pgp-encrypted":
+			if res == nil {
+				res = &Result{}
+			}
+			res.Encrypted = true
+			inner, err = p.encrypted(body, params["boundary"], res)
+		default:
+			return msg, res
+		}
+		if err != nil {
+			res.Error = err.Error()
+			return msg, res
+		}
+		msg = rawmime.Rewrap(hdr, rawmime.ToCRLF(inner))
+	}
+	return msg, res
+}
+
+// detached verifies a multipart/signed body and returns the signed entity.
+func (p *Processor) detached(body []byte, boundary string, res *Result) ([]byte, error) {
+	parts := rawmime.SplitMultipart(body, boundary)
+	if len(parts) < 2 {
+		return nil, errors.New("pgp: multipart/signed needs two parts")
+	}
+	_, sigBody, ok := rawmime.SplitEntity(parts[1])
+	if !ok {
+		return nil, errors.New("pgp: malformed signature part")
+	}
+	sig, err := dearmor(sigBody)
+	if err != nil {
+		return nil, fmt.Errorf("pgp: signature: %w", err)
+	}
+	res.Signatures = append(res.Signatures, p.verify(parts[0], sig))
+	return parts[0], nil
+}
+
+// encrypted decrypts the second part of a multipart/encrypted body; the
+// first only carries the version.
+func (p *Processor) encrypted(body []byte, boundary string, res *Result) ([]byte, error) {
+	parts := rawmime.SplitMultipart(body, boundary)
+	if len(parts) < 2 {
+		return nil, errors.New("pgp: multipart/encrypted needs two parts")
+	}
+	_, data, ok := rawmime.SplitEntity(parts[1])
+	if !ok {
+		return nil, errors.New("pgp: malformed encrypted part")
+	}
+	return p.decrypt(data, res)
+}
+
+// decrypt opens an armored PGP message. A signature inside the encryption
+// layer is recorded once the plaintext has been read in full.
+func (p *Processor) decrypt(armored []byte, res *Result) ([]byte, error) {
+	ciphertext, err := dearmor(armored)
+	if err != nil {
+		return nil, fmt.Errorf("pgp: %w", err)
+	}
+	if len(p.Keyring.DecryptionKeys()) == 0 {
+		return nil, errors.New("pgp: encrypted message and no private keys configured")
+	}
+	md, err := openpgp.ReadMessage(bytes.NewReader(ciphertext), p.Keyring, nil, nil)
+	if err != nil {
+		if errors.Is(err, pgperrors.ErrKeyIncorrect) {
+			return nil, errors.New("pgp: no private key matches the message recipients")
+		}
+		return nil, fmt.Errorf("pgp: %w", err)
+	}
+	for _, id := range md.EncryptedToKeyIds {
+		res.Recipients = append(res.Recipients, keyID(id))
+	}
+	plain, err := io.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return nil, fmt.Errorf("pgp: %w", err)
+	}
+	res.Decrypted = md.IsEncrypted
+	if md.IsSigned {
+		res.Signed = true
+		res.Signatures = append(res.Signatures, p.embedded(md))
+	}
+	return plain, nil
+}
+
+// Inline decrypts armored PGP messages and verifies clearsigned blocks in a
+// decoded text body, replacing each block with its plaintext. Blocks that
+// cannot be opened stay as they are. The returned result is res, created if
+// needed, or nil when the text has no PGP blocks.
+func (p *Processor) Inline(text string, res *Result) (string, *Result) {
+	if blockStart(text, beginMessage) < 0 && blockStart(text, beginSigned) < 0 {
+		return text, res
+	}
+	if res == nil {
+		res = &Result{}
+	}
+	res.Inline = true
+
+	var out strings.Builder
+	for {
+		i := blockStart(text, beginMessage)
+		j := blockStart(text, beginSigned)
+		if i < 0 && j < 0 {
+			break
+		}
+		if i < 0 || (j >= 0 && j < i) {
+			out.WriteString(text[:j])
+			block, rest := clearsign.Decode([]byte(text[j:]))
+			if block == nil {
+				out.WriteString(beginSigned)
+				text = text[j+len(beginSigned):]
+				continue
+			}
+			res.Signed = true
+			res.Signatures = append(res.Signatures, p.clearsigned(block))
+			out.Write(block.Plaintext)
+			text = text[len(text)-len(rest):]
+			continue
+		}
+
+		out.WriteString(text[:i])
+		end := strings.Index(text[i:], endMessage)
+		if end < 0 {
+			out.WriteString(text[i:])
+			text = ""
+			break
+		}
+		end += i + len(endMessage)
+		res.Encrypted = true
+		plain, err := p.decrypt([]byte(text[i:end]), res)
+		if err != nil {
+			res.Error = err.Error()
+			out.WriteString(text[i:end])
+		} else {
+			out.Write(plain)
+		}
+		text = text[end:]
+	}
+	out.WriteString(text)
+	return out.String(), res
+}
+
+// blockStart finds marker at the start of a line.
+func blockStart(text, marker string) int {
+	for off := 0; ; {
+		i := strings.Index(text[off:], marker)
+		if i < 0 {
+			return -1
+		}
+		i += off
+		if i == 0 || text[i-1] == '\n' {
+			return i
+		}
+		off = i + len(marker)
+	}
+}
+
+func (p *Processor) clearsigned(block *clearsign.Block) Signature {
+	sig, err := io.ReadAll(block.ArmoredSignature.Body)
+	if err != nil {
+		return Signature{Validity: ValidityInvalid, Reason: err.Error()}
+	}
+	return p.verify(block.Bytes, sig)
+}
+
+// verify checks a detached signature over signed. Fields that come from the
+// signature packet are filled in even when the key is unknown.
+func (p *Processor) verify(signed, sig []byte) Signature {
+	s := Signature{}
+	var issuer uint64
+	if pkt, err := packet.Read(bytes.NewReader(sig)); err == nil {
+		if ps, ok := pkt.(*packet.Signature); ok {
+			if ps.IssuerKeyId != nil {
+				issuer = *ps.IssuerKeyId
+				s.KeyID = keyID(issuer)
+			}
+			if len(ps.IssuerFingerprint) > 0 {
+				s.Fingerprint = hex.EncodeToString(ps.IssuerFingerprint)
+			}
+			created := ps.CreationTime.UTC()
+			s.Created = &created
+		}
+	}
+
+	_, signer, err := openpgp.VerifyDetachedSignature(p.Keyring, bytes.NewReader(signed), bytes.NewReader(sig), nil)
+	s.Validity, s.Reason = validity(err)
+	if signer != nil {
+		p.describeKey(&s, issuer)
+	}
+	return s
+}
+
+// embedded describes the signature inside an encrypted message.
+func (p *Processor) embedded(md *openpgp.MessageDetails) Signature {
+	s := Signature{KeyID: keyID(md.SignedByKeyId)}
+	if len(md.SignedByFingerprint) > 0 {
+		s.Fingerprint = hex.EncodeToString(md.SignedByFingerprint)
+	}
+	if md.Signature != nil {
+		created := md.Signature.CreationTime.UTC()
+		s.Created = &created
+	}
+	if md.SignedBy == nil {
+		s.Validity, s.Reason = ValidityUnknownKey, pgperrors.ErrUnknownIssuer.Error()
+		return s
+	}
+	s.Validity, s.Reason = validity(md.SignatureError)
+	p.describeKey(&s, md.SignedByKeyId)
+	return s
+}
+
+// describeKey fills the fingerprint and user ID from the keyring.
+func (p *Processor) describeKey(s *Signature, id uint64) {
+	keys := p.Keyring.KeysById(id)
+	if len(keys) == 0 {
+		return
+	}
+	s.Fingerprint = hex.EncodeToString(keys[0].PublicKey.Fingerprint)
+	if ident := keys[0].Entity.PrimaryIdentity(); ident != nil {
+		s.UserID = ident.Name
+	}
+}
+
+func validity(err error) (string, string) {
+	switch {
+	case err == nil:
+		return ValidityValid, ""
+	case errors.Is(err, pgperrors.ErrUnknownIssuer):
+		return ValidityUnknownKey, err.Error()
+	case errors.Is(err, pgperrors.ErrSignatureExpired), errors.Is(err, pgperrors.ErrKeyExpired):
+		return ValidityExpired, err.Error()
+	case errors.Is(err, pgperrors.ErrKeyRevoked):
+		return ValidityRevoked, err.Error()
+	default:
+		return ValidityInvalid, err.Error()
+	}
+}
+
+// dearmor decodes ASCII armor; binary input is returned as it is.
+func dearmor(data []byte) ([]byte, error) {
+	i := bytes.Index(data, []byte("-----BEGIN PGP "))
+	if i < 0 {
+		return data, nil
+	}
+	block, err := armor.Decode(bytes.NewReader(data[i:]))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(block.Body)
+}
+
+func keyID(id uint64) string {
+	return fmt.Sprintf("%016X", id)
+}
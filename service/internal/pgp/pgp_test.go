@@ -0,0 +1,193 @@
+package pgp
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+func newEntity(t *testing.T, name, email string) *openpgp.Entity {
+	t.Helper()
+	e, err := openpgp.NewEntity(name, "", email, &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func armored(t *testing.T, typ string, data []byte) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, typ, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(data)
+	w.Close()
+	return buf.String()
+}
+
+func encrypt(t *testing.T, to, signer *openpgp.Entity, plain string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := openpgp.Encrypt(&buf, []*openpgp.Entity{to}, signer, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte(plain))
+	w.Close()
+	return armored(t, "PGP MESSAGE", buf.Bytes())
+}
+
+func detachSign(t *testing.T, signer *openpgp.Entity, data []byte) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := openpgp.DetachSign(&buf, signer, bytes.NewReader(data), nil); err != nil {
+		t.Fatal(err)
+	}
+	return armored(t, "PGP SIGNATURE", buf.Bytes())
+}
+
+func TestUnwrap_Encrypted(t *testing.T) {
+	bob := newEntity(t, "Bob", "bob@example.com")
+	alice := newEntity(t, "Alice", "alice@example.com")
+	inner := "Content-Type: text/plain; charset=utf-8\r\n\r\nthe report\r\n"
+	msg := "From: alice@example.com\r\nSubject: report\r\nMIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/encrypted; protocol=\"application/pgp-encrypted\"; boundary=\"b\"\r\n\r\n" +
+		"--b\r\nContent-Type: application/pgp-encrypted\r\n\r\nVersion: 1\r\n" +
+		"--b\r\nContent-Type: application/octet-stream\r\n\r\n" + encrypt(t, bob, alice, inner) + "\r\n--b--\r\n"
+
+	p := NewProcessor(openpgp.EntityList{bob, alice})
+	out, res := p.Unwrap([]byte(msg))
+	if res == nil || !res.Encrypted || !res.Decrypted || res.Error != "" {
+		t.Fatalf("result = %+v", res)
+	}
+	if !strings.Contains(string(out), "Subject: report\r\n") || !strings.HasSuffix(string(out), inner) {
+		t.Fatalf("unwrapped = %q", out)
+	}
+	if len(res.Signatures) != 1 || res.Signatures[0].Validity != ValidityValid || res.Signatures[0].UserID != "Alice <alice@example.com>" {
+		t.Fatalf("signatures = %+v", res.Signatures)
+	}
+	if s := res.Signatures[0]; len(s.KeyID) != 16 || s.Fingerprint == "" {
+		t.Fatalf("signature key = %+v", s)
+	}
+
+	// Without Bob's key the message is left encrypted.
+	p = NewProcessor(openpgp.EntityList{alice})
+	out, res = p.Unwrap([]byte(msg))
+	if res == nil || !res.Encrypted || res.Decrypted || res.Error == "" {
+		t.Fatalf("result without key = %+v", res)
+	}
+	if !strings.Contains(string(out), "multipart/encrypted") {
+		t.Fatalf("unwrapped without key = %q", out)
+	}
+}
+
+func TestUnwrap_Signed(t *testing.T) {
+	alice := newEntity(t, "Alice", "alice@example.com")
+	signed := "Content-Type: text/plain\r\n\r\nsigned text\r\n"
+	build := func(content string) string {
+		return "From: alice@example.com\r\nMIME-Version: 1.0\r\n" +
+			"Content-Type: multipart/signed; micalg=pgp-sha256; protocol=\"application/pgp-signature\"; boundary=\"s\"\r\n\r\n" +
+			"--s\r\n" + content + "\r\n--s\r\nContent-Type: application/pgp-signature\r\n\r\n" +
+			detachSign(t, alice, []byte(signed)) + "\r\n--s--\r\n"
+	}
+
+	p := NewProcessor(openpgp.EntityList{alice})
+	out, res := p.Unwrap([]byte(build(signed)))
+	if res == nil || !res.Signed || len(res.Signatures) != 1 {
+		t.Fatalf("result = %+v", res)
+	}
+	s := res.Signatures[0]
+	if s.Validity != ValidityValid || s.KeyID == "" || s.Fingerprint == "" || s.Created == nil {
+		t.Fatalf("signature = %+v", s)
+	}
+	if !strings.HasSuffix(string(out), signed) {
+		t.Fatalf("unwrapped = %q", out)
+	}
+
+	// Unknown key: the key ID still comes from the signature packet.
+	_, res = NewProcessor(nil).Unwrap([]byte(build(signed)))
+	if s := res.Signatures[0]; s.Validity != ValidityUnknownKey || s.KeyID == "" {
+		t.Fatalf("unknown key signature = %+v", s)
+	}
+
+	// Tampered content.
+	msg := build(signed)
+	msg = strings.Replace(msg, "signed text", "forged text", 1)
+	_, res = p.Unwrap([]byte(msg))
+	if s := res.Signatures[0]; s.Validity != ValidityInvalid {
+		t.Fatalf("tampered signature = %+v", s)
+	}
+}
+
+func TestInline(t *testing.T) {
+	bob := newEntity(t, "Bob", "bob@example.com")
+	alice := newEntity(t, "Alice", "alice@example.com")
+	p := NewProcessor(openpgp.EntityList{bob, alice})
+
+	text := "Hi,\n\n" + encrypt(t, bob, nil, "secret finding") + "\nthanks\n"
+	out, res := p.Inline(text, nil)
+	if res == nil || !res.Inline || !res.Decrypted {
+		t.Fatalf("result = %+v", res)
+	}
+	if out != "Hi,\n\nsecret finding\nthanks\n" {
+		t.Fatalf("text = %q", out)
+	}
+
+	var buf bytes.Buffer
+	w, err := clearsign.Encode(&buf, alice.PrivateKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("signed body"))
+	w.Close()
+	out, res = p.Inline("intro\n"+buf.String(), nil)
+	if res == nil || !res.Signed || len(res.Signatures) != 1 || res.Signatures[0].Validity != ValidityValid {
+		t.Fatalf("clearsigned result = %+v", res)
+	}
+	if !strings.HasPrefix(out, "intro\nsigned body") || strings.Contains(out, "BEGIN PGP") {
+		t.Fatalf("clearsigned text = %q", out)
+	}
+
+	if out, res := p.Inline("no pgp here", nil); res != nil || out != "no pgp here" {
+		t.Fatalf("plain text = %q, %+v", out, res)
+	}
+}
+
+func TestLoadKeyrings(t *testing.T) {
+	bob := newEntity(t, "Bob", "bob@example.com")
+	if err := bob.EncryptPrivateKeys([]byte("hunter2"), nil); err != nil {
+		t.Fatal(err)
+	}
+	alice := newEntity(t, "Alice", "alice@example.com")
+
+	var priv, pub bytes.Buffer
+	if err := bob.SerializePrivateWithoutSigning(&priv, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.Serialize(&pub); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "bob.asc"), []byte(armored(t, "PGP PRIVATE KEY BLOCK", priv.Bytes())), 0o600)
+	os.WriteFile(filepath.Join(dir, "alice.gpg"), pub.Bytes(), 0o600)
+
+	if _, err := LoadKeyrings([]string{dir}, nil); err == nil {
+		t.Fatal("expected an error for a locked key without passphrase")
+	}
+	el, err := LoadKeyrings([]string{dir, filepath.Join(dir, "missing")}, []byte("hunter2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(el) != 2 || len(el.DecryptionKeys()) == 0 {
+		t.Fatalf("keyring = %d entities, %d decryption keys", len(el), len(el.DecryptionKeys()))
+	}
+}
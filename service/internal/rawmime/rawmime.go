@@ -0,0 +1,157 @@
+// Package rawmime slices raw MIME entities without decoding them, for code
+// that must see the exact bytes a signature was computed over.
+package rawmime
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"mime"
+	"net/textproto"
+	"strings"
+)
+
+// ToCRLF normalises bare LF line endings; signatures are computed over the
+// canonical CRLF form.
+func ToCRLF(b []byte) []byte {
+	if !bytes.Contains(b, []byte("\n")) || bytes.Count(b, []byte("\r\n")) == bytes.Count(b, []byte("\n")) {
+		return b
+	}
+	out := make([]byte, 0, len(b)+bytes.Count(b, []byte("\n")))
+	for i, c := range b {
+		if c == '\n' && (i == 0 || b[i-1] != '\r') {
+			out = append(out, '\r')
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// SplitEntity separates the header block (including its final CRLF) from the
+// body. An entity without a blank line is all header.
+func SplitEntity(b []byte) (hdr, body []byte, ok bool) {
+	if bytes.HasPrefix(b, []byte("\r\n")) {
+		return nil, b[2:], true
+	}
+	i := bytes.Index(b, []byte("\r\n\r\n"))
+	if i < 0 {
+		if len(b) == 0 {
+			return nil, nil, false
+		}
+		return b, nil, true
+	}
+	return b[:i+2], b[i+4:], true
+}
+
+// ReadHeader parses a header block; malformed trailing fields are dropped.
+func ReadHeader(hdr []byte) textproto.MIMEHeader {
+	r := textproto.NewReader(bufio.NewReader(bytes.NewReader(append(append([]byte(nil), hdr...), "\r\n"...))))
+	h, _ := r.ReadMIMEHeader()
+	return h
+}
+
+// MediaType returns the lowercased Content-Type and its parameters,
+// defaulting to text/plain.
+func MediaType(hdr []byte) (string, map[string]string) {
+	ct := ReadHeader(hdr).Get("Content-Type")
+	if ct == "" {
+		return "text/plain", nil
+	}
+	mt, params, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(strings.SplitN(ct, ";", 2)[0])), params
+	}
+	return mt, params
+}
+
+// SplitMultipart returns the raw bytes of each body part, exactly as they
+// appear between the boundary delimiters.
+func SplitMultipart(body []byte, boundary string) [][]byte {
+	if boundary == "" {
+		return nil
+	}
+	delim := []byte("--" + boundary)
+	var start int
+	switch {
+	case bytes.HasPrefix(body, delim):
+		start = len(delim)
+	default:
+		i := bytes.Index(body, append([]byte("\r\n"), delim...))
+		if i < 0 {
+			return nil
+		}
+		start = i + 2 + len(delim)
+	}
+
+	var parts [][]byte
+	for {
+		rest := body[start:]
+		if bytes.HasPrefix(rest, []byte("--")) {
+			return parts // close delimiter
+		}
+		eol := bytes.Index(rest, []byte("\r\n"))
+		if eol < 0 {
+			return parts
+		}
+		rest = rest[eol+2:]
+		end := bytes.Index(rest, append([]byte("\r\n"), delim...))
+		if end < 0 {
+			return parts
+		}
+		parts = append(parts, rest[:end])
+		start = len(body) - len(rest) + end + 2 + len(delim)
+	}
+}
+
+// DecodeBody undoes a base64 transfer encoding; other encodings are
+// returned as they are.
+func DecodeBody(hdr, body []byte) ([]byte, error) {
+	if !strings.EqualFold(strings.TrimSpace(ReadHeader(hdr).Get("Content-Transfer-Encoding")), "base64") {
+		return body, nil
+	}
+	clean := bytes.Map(func(r rune) rune {
+		switch r {
+		case '\r', '\n', ' ', '\t':
+			return -1
+		}
+		return r
+	}, body)
+	// Some clients drop the padding.
+	return base64.RawStdEncoding.DecodeString(string(bytes.TrimRight(clean, "=")))
+}
+
+// Rewrap puts the decoded entity under the outer message headers, replacing
+// the outer Content-* fields with the entity's own.
+func Rewrap(outer, entity []byte) []byte {
+	var buf bytes.Buffer
+	for _, f := range HeaderFields(outer) {
+		name := strings.ToLower(strings.TrimSpace(f[:strings.IndexByte(f, ':')]))
+		if strings.HasPrefix(name, "content-") || name == "mime-version" {
+			continue
+		}
+		buf.WriteString(f)
+	}
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.Write(entity)
+	return buf.Bytes()
+}
+
+// HeaderFields splits a header block into raw fields, continuation lines
+// included, each ending in CRLF.
+func HeaderFields(hdr []byte) []string {
+	var fields []string
+	for _, line := range strings.SplitAfter(string(hdr), "\r\n") {
+		if line == "" || line == "\r\n" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1] += line
+			continue
+		}
+		if !strings.Contains(line, ":") {
+			continue
+		}
+		fields = append(fields, line)
+	}
+	return fields
+}
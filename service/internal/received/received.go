@@ -0,0 +1,252 @@
+// Package received parses the Received trace headers of a message into an
+// ordered list of relay hops.
+package received
+
+import (
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Zifeldev/emailback/service/internal/maildate"
+)
+
+// Hop is one relay step. Hops are ordered from the originating server to the
+// final recipient, i.e. the reverse of header order.
+type Hop struct {
+	From      string     `json:"from,omitempty"`      // name announced in HELO/EHLO
+	FromRDNS  string     `json:"from_rdns,omitempty"` // reverse DNS name recorded by the receiver
+	IP        string     `json:"ip,omitempty"`
+	By        string     `json:"by,omitempty"`
+	Via       string     `json:"via,omitempty"`
+	Protocol  string     `json:"protocol,omitempty"` // SMTP, ESMTPS, LMTP, ...
+	ID        string     `json:"id,omitempty"`
+	For       string     `json:"for,omitempty"`
+	TLS       string     `json:"tls,omitempty"` // protocol version and cipher, when recorded
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+	// Delay is the time in seconds since the previous hop (or the Date header
+	// for the first hop). It can be negative when server clocks disagree.
+	Delay *float64 `json:"delay_seconds,omitempty"`
+}
+
+// Parse turns Received header values, in header order (newest first), into
+// hops ordered oldest first. sent is the message Date and may be nil.
+func Parse(values []string, sent *time.Time) []Hop {
+	if len(values) == 0 {
+		return nil
+	}
+	hops := make([]Hop, 0, len(values))
+	for i := len(values) - 1; i >= 0; i-- {
+		hops = append(hops, parseOne(values[i]))
+	}
+
+	prev := sent
+	for i := range hops {
+		ts := hops[i].Timestamp
+		if ts != nil && prev != nil {
+			d := ts.Sub(*prev).Seconds()
+			hops[i].Delay = &d
+		}
+		if ts != nil {
+			prev = ts
+		}
+	}
+	return hops
+}
+
+// Transit returns the time from sent (or the first timestamped hop when sent
+// is nil) to the last timestamped hop.
+func Transit(hops []Hop, sent *time.Time) (time.Duration, bool) {
+	start := sent
+	var end *time.Time
+	for _, h := range hops {
+		if h.Timestamp == nil {
+			continue
+		}
+		if start == nil {
+			start = h.Timestamp
+		}
+		end = h.Timestamp
+	}
+	if start == nil || end == nil || start == end {
+		return 0, false
+	}
+	return end.Sub(*start), true
+}
+
+// Latest returns the newest hop timestamp, the time the message reached its
+// final recipient, or nil when no hop has one.
+func Latest(hops []Hop) *time.Time {
+	var out *time.Time
+	for _, h := range hops {
+		if h.Timestamp != nil && (out == nil || h.Timestamp.After(*out)) {
+			out = h.Timestamp
+		}
+	}
+	return out
+}
+
+// OriginIP returns the address of the earliest hop that has a public IP.
+func OriginIP(hops []Hop) string {
+	for _, h := range hops {
+		if IsPublic(h.IP) {
+			return h.IP
+		}
+	}
+	return ""
+}
+
+// IsPublic reports whether s is a routable unicast address.
+func IsPublic(s string) bool {
+	ip := net.ParseIP(s)
+	return ip != nil && !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsUnspecified()
+}
+
+var (
+	reBracketIP = regexp.MustCompile(`\[(?:IPv6:)?([0-9A-Fa-f:.]+)\]`)
+	reBareIP    = regexp.MustCompile(`^(?:IPv6:)?[0-9A-Fa-f:.]+$`)
+	reTLSVer    = regexp.MustCompile(`(?i)\b(TLS_?v?1(?:[._]\d)?|SSLv3)\b`)
+	reCipher    = regexp.MustCompile(`(?i)cipher[= ]([A-Z0-9_-]+)`)
+)
+
+func parseOne(v string) Hop {
+	v = strings.Join(strings.Fields(v), " ")
+	var h Hop
+
+	clauses := v
+	if i := strings.LastIndex(v, ";"); i >= 0 {
+		clauses = v[:i]
+		if t, ok := maildate.Parse(v[i+1:]); ok {
+			t = t.UTC()
+			h.Timestamp = &t
+		}
+	}
+
+	for _, c := range splitClauses(clauses) {
+		switch c.keyword {
+		case "from":
+			h.From = strings.Trim(firstWord(c.words), "[]")
+			parseFromComments(&h, c)
+		case "by":
+			h.By = firstWord(c.words)
+		case "via":
+			h.Via = firstWord(c.words)
+		case "with":
+			h.Protocol = strings.ToUpper(firstWord(c.words))
+		case "id":
+			h.ID = strings.Trim(firstWord(c.words), "<>")
+		case "for":
+			h.For = strings.Trim(firstWord(c.words), "<>")
+		}
+	}
+
+	var tls []string
+	if m := reTLSVer.FindStringSubmatch(v); m != nil {
+		tls = append(tls, strings.ReplaceAll(m[1], "_", "."))
+	}
+	if m := reCipher.FindStringSubmatch(v); m != nil {
+		tls = append(tls, m[1])
+	}
+	h.TLS = strings.Join(tls, " ")
+	return h
+}
+
+func parseFromComments(h *Hop, c clause) {
+	all := append([]string{strings.Join(c.words, " ")}, c.comments...)
+	for _, s := range all {
+		if m := reBracketIP.FindStringSubmatch(s); m != nil && net.ParseIP(m[1]) != nil {
+			h.IP = m[1]
+			break
+		}
+	}
+	for _, cm := range c.comments {
+		for _, w := range strings.Fields(cm) {
+			w = strings.TrimRight(w, ",")
+			switch {
+			case h.IP == "" && reBareIP.MatchString(w) && net.ParseIP(strings.TrimPrefix(w, "IPv6:")) != nil:
+				h.IP = strings.TrimPrefix(w, "IPv6:")
+			case h.FromRDNS == "" && looksLikeHost(w):
+				h.FromRDNS = strings.ToLower(strings.TrimSuffix(w, "."))
+			}
+		}
+	}
+	if h.IP == "" && net.ParseIP(h.From) != nil {
+		h.IP = h.From
+	}
+}
+
+func looksLikeHost(w string) bool {
+	if !strings.Contains(w, ".") || strings.ContainsAny(w, "[]=()@") || net.ParseIP(w) != nil {
+		return false
+	}
+	return !strings.EqualFold(w, "unknown")
+}
+
+func firstWord(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+	return words[0]
+}
+
+type clause struct {
+	keyword  string
+	words    []string
+	comments []string
+}
+
+// splitClauses tokenizes the part before ';' into keyword clauses, keeping
+// parenthesized comments (which may nest) attached to the clause they follow.
+func splitClauses(s string) []clause {
+	var out []clause
+	cur := clause{}
+	var word strings.Builder
+
+	flush := func() {
+		if word.Len() == 0 {
+			return
+		}
+		w := word.String()
+		word.Reset()
+		switch lw := strings.ToLower(w); lw {
+		case "from", "by", "via", "with", "id", "for":
+			if cur.keyword != "" || len(cur.words) > 0 {
+				out = append(out, cur)
+			}
+			cur = clause{keyword: lw}
+		default:
+			cur.words = append(cur.words, w)
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; ch {
+		case '(':
+			flush()
+			depth, j := 1, i+1
+			for ; j < len(s) && depth > 0; j++ {
+				switch s[j] {
+				case '(':
+					depth++
+				case ')':
+					depth--
+				}
+			}
+			end := j - 1
+			if depth > 0 {
+				end = len(s)
+			}
+			cur.comments = append(cur.comments, strings.TrimSpace(s[i+1:end]))
+			i = j - 1
+		case ' ', '\t':
+			flush()
+		default:
+			word.WriteByte(ch)
+		}
+	}
+	flush()
+	if cur.keyword != "" || len(cur.words) > 0 {
+		out = append(out, cur)
+	}
+	return out
+}
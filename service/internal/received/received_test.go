@@ -0,0 +1,89 @@
+package received
+
+import (
+	"testing"
+	"time"
+)
+
+var chain = []string{
+	"by 2002:a05:6402:1234 with SMTP id x1csp123; Mon, 2 Jan 2006 15:04:20 -0000",
+	"from mail-sor-f41.google.com (mail-sor-f41.google.com. [209.85.220.41])\r\n" +
+		"        by mx.example.org with SMTPS id a1sor;\r\n        Mon, 02 Jan 2006 15:04:15 +0000 (UTC)",
+	"from mx.example.com (unknown [IPv6:2001:db8::25]) (using TLSv1.3 with cipher TLS_AES_256_GCM_SHA384 (256/256 bits))" +
+		" by relay.example.com (Postfix) with ESMTPS id 4Q1; Mon, 02 Jan 2006 10:04:10 -0500",
+	"from [192.168.1.20] (helo=laptop) by mx.example.com with esmtpsa (Exim 4.96) id 1abc-000 for <bob@example.org>; Mon, 02 Jan 2006 15:04:08 +0000",
+}
+
+func TestParse(t *testing.T) {
+	sent := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	hops := Parse(chain, &sent)
+	if len(hops) != 4 {
+		t.Fatalf("want 4 hops, got %d", len(hops))
+	}
+
+	first := hops[0]
+	if first.IP != "192.168.1.20" || first.By != "mx.example.com" || first.Protocol != "ESMTPSA" || first.For != "bob@example.org" || first.ID != "1abc-000" {
+		t.Fatalf("unexpected first hop: %+v", first)
+	}
+	if first.Delay == nil || *first.Delay != 3 {
+		t.Fatalf("first hop delay should be measured from Date: %+v", first.Delay)
+	}
+
+	postfix := hops[1]
+	if postfix.From != "mx.example.com" || postfix.IP != "2001:db8::25" || postfix.By != "relay.example.com" ||
+		postfix.Protocol != "ESMTPS" || postfix.TLS != "TLSv1.3 TLS_AES_256_GCM_SHA384" {
+		t.Fatalf("unexpected postfix hop: %+v", postfix)
+	}
+	if postfix.Timestamp == nil || !postfix.Timestamp.Equal(time.Date(2006, 1, 2, 15, 4, 10, 0, time.UTC)) {
+		t.Fatalf("timezone not normalized: %v", postfix.Timestamp)
+	}
+	if *postfix.Delay != 2 {
+		t.Fatalf("unexpected delay %v", *postfix.Delay)
+	}
+
+	google := hops[2]
+	if google.From != "mail-sor-f41.google.com" || google.FromRDNS != "mail-sor-f41.google.com" || google.IP != "209.85.220.41" || google.Protocol != "SMTPS" {
+		t.Fatalf("unexpected google hop: %+v", google)
+	}
+
+	last := hops[3]
+	if last.From != "" || last.By != "2002:a05:6402:1234" || last.ID != "x1csp123" {
+		t.Fatalf("unexpected last hop: %+v", last)
+	}
+
+	total, ok := Transit(hops, &sent)
+	if !ok || total != 15*time.Second {
+		t.Fatalf("transit = %v, %v", total, ok)
+	}
+	if got := OriginIP(hops); got != "2001:db8::25" {
+		t.Fatalf("origin ip = %q", got)
+	}
+}
+
+func TestParse_MissingDates(t *testing.T) {
+	hops := Parse([]string{"from a.example (a.example [198.51.100.1]) by b.example", "garbage without structure"}, nil)
+	if len(hops) != 2 || hops[1].IP != "198.51.100.1" || hops[1].Timestamp != nil || hops[1].Delay != nil {
+		t.Fatalf("unexpected hops: %+v", hops)
+	}
+	if _, ok := Transit(hops, nil); ok {
+		t.Fatalf("transit should be unknown without timestamps")
+	}
+	if Parse(nil, nil) != nil {
+		t.Fatalf("no headers should yield nil")
+	}
+}
+
+func TestLatest(t *testing.T) {
+	hops := Parse([]string{
+		"from b.example by c.example; Mon, 02 Jan 2006 15:04:05 +0000",
+		"from a.example by b.example; Mon, 02 Jan 2006 18:05:00 +0300 (MSK)",
+		"from x.example by a.example",
+	}, nil)
+	got := Latest(hops)
+	if got == nil || !got.Equal(time.Date(2006, 1, 2, 15, 5, 0, 0, time.UTC)) {
+		t.Fatalf("latest = %v", got)
+	}
+	if Latest(hops[:1]) != nil {
+		t.Fatalf("latest without timestamps should be nil")
+	}
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+const (
+	RoleFrom    = "from"
+	RoleSender  = "sender"
+	RoleReplyTo = "reply_to"
+	RoleTo      = "to"
+	RoleCc      = "cc"
+	RoleBcc     = "bcc"
+)
+
+// AddressRoles lists the header roles stored in email_addresses, in display order.
+var AddressRoles = []string{RoleFrom, RoleSender, RoleReplyTo, RoleTo, RoleCc, RoleBcc}
+
+type Address struct {
+	Name      string `json:"name,omitempty"`
+	Address   string `json:"address"`
+	LocalPart string `json:"local_part"`
+	Domain    string `json:"domain"`
+}
+
+// NewAddress splits addr at the last '@'. The domain is lower-cased; the local
+// part is kept as sent since it is case-sensitive per RFC 5321.
+func NewAddress(name, addr string) Address {
+	addr = strings.TrimSpace(addr)
+	a := Address{Name: strings.TrimSpace(name), Address: addr}
+	if i := strings.LastIndexByte(addr, '@'); i >= 0 {
+		a.LocalPart = addr[:i]
+		a.Domain = strings.ToLower(addr[i+1:])
+	} else {
+		a.LocalPart = addr
+	}
+	return a
+}
+
+const deleteAddresses = `DELETE FROM email_addresses WHERE email_id = $1`
+
+const insertAddresses = `
+INSERT INTO email_addresses (email_id, role, position, name, address, local_part, domain)
+SELECT $1, r, p, n, a, l, d
+FROM unnest($2::text[], $3::int[], $4::text[], $5::text[], $6::text[], $7::text[]) AS t(r, p, n, a, l, d)
+`
+
+// selectAddressesJSON is embedded as a column of the email selects so that a
+// single round trip returns the entity together with its participants.
+const selectAddressesJSON = `(SELECT COALESCE(jsonb_agg(jsonb_build_object(
+         'role', a.role, 'name', a.name, 'address', a.address,
+         'local_part', a.local_part, 'domain', a.domain) ORDER BY a.role, a.position), '[]'::jsonb)
+       FROM email_addresses a WHERE a.email_id = emails.id)`
+
+type roleAddress struct {
+	Role string `json:"role"`
+	Address
+}
+
+func (e *EmailEntity) addressesByRole() []roleAddress {
+	var out []roleAddress
+	add := func(role string, as ...Address) {
+		for _, a := range as {
+			out = append(out, roleAddress{Role: role, Address: a})
+		}
+	}
+	if e.FromAddress != nil {
+		add(RoleFrom, *e.FromAddress)
+	}
+	if e.Sender != nil {
+		add(RoleSender, *e.Sender)
+	}
+	add(RoleReplyTo, e.ReplyTo...)
+	add(RoleTo, e.ToAddresses...)
+	add(RoleCc, e.Cc...)
+	add(RoleBcc, e.Bcc...)
+	return out
+}
+
+func (e *EmailEntity) applyAddressesJSON(bs []byte) {
+	if len(bs) == 0 {
+		return
+	}
+	var list []roleAddress
+	if json.Unmarshal(bs, &list) != nil {
+		return
+	}
+	for _, ra := range list {
+		a := ra.Address
+		switch ra.Role {
+		case RoleFrom:
+			if e.FromAddress == nil {
+				e.FromAddress = &a
+			}
+		case RoleSender:
+			if e.Sender == nil {
+				e.Sender = &a
+			}
+		case RoleReplyTo:
+			e.ReplyTo = append(e.ReplyTo, a)
+		case RoleTo:
+			e.ToAddresses = append(e.ToAddresses, a)
+		case RoleCc:
+			e.Cc = append(e.Cc, a)
+		case RoleBcc:
+			e.Bcc = append(e.Bcc, a)
+		}
+	}
+}
+
+func (r *PostgresEmailRepo) saveAddresses(ctx context.Context, email *EmailEntity) error {
+	if _, err := r.pool.Exec(ctx, deleteAddresses, email.ID); err != nil {
+		return err
+	}
+	list := email.addressesByRole()
+	if len(list) == 0 {
+		return nil
+	}
+	roles := make([]string, len(list))
+	positions := make([]int32, len(list))
+	names := make([]string, len(list))
+	addrs := make([]string, len(list))
+	locals := make([]string, len(list))
+	domains := make([]string, len(list))
+	pos := map[string]int32{}
+	for i, ra := range list {
+		roles[i] = ra.Role
+		positions[i] = pos[ra.Role]
+		pos[ra.Role]++
+		names[i] = ra.Name
+		addrs[i] = ra.Address.Address
+		locals[i] = ra.LocalPart
+		domains[i] = ra.Domain
+	}
+	_, err := r.pool.Exec(ctx, insertAddresses, email.ID, roles, positions, names, addrs, locals, domains)
+	return err
+}
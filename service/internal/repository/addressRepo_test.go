@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNewAddress(t *testing.T) {
+	a := NewAddress(" Alice ", "Alice.Smith@Example.COM")
+	if a.Name != "Alice" || a.LocalPart != "Alice.Smith" || a.Domain != "example.com" {
+		t.Fatalf("unexpected address: %+v", a)
+	}
+	if b := NewAddress("", "undisclosed"); b.LocalPart != "undisclosed" || b.Domain != "" {
+		t.Fatalf("unexpected address without domain: %+v", b)
+	}
+}
+
+func TestAddresses_RoundTripThroughJSON(t *testing.T) {
+	from := NewAddress("Alice", "alice@example.com")
+	e := &EmailEntity{
+		FromAddress: &from,
+		ToAddresses: []Address{NewAddress("", "b@example.com"), NewAddress("", "c@example.com")},
+		Cc:          []Address{NewAddress("Dan", "d@example.com")},
+	}
+	list := e.addressesByRole()
+	if len(list) != 4 || list[0].Role != RoleFrom || list[3].Role != RoleCc {
+		t.Fatalf("unexpected role list: %+v", list)
+	}
+
+	var got EmailEntity
+	got.applyAddressesJSON([]byte(`[
+		{"role":"cc","name":"Dan","address":"d@example.com","local_part":"d","domain":"example.com"},
+		{"role":"from","name":"Alice","address":"alice@example.com","local_part":"alice","domain":"example.com"},
+		{"role":"to","name":"","address":"b@example.com","local_part":"b","domain":"example.com"}
+	]`))
+	if got.FromAddress == nil || got.FromAddress.Name != "Alice" || len(got.ToAddresses) != 1 || len(got.Cc) != 1 {
+		t.Fatalf("unexpected decoded entity: %+v", got)
+	}
+}
+
+func TestPostgresEmailRepo_SaveAddresses(t *testing.T) {
+	mp := &mockPool{}
+	repo := &PostgresEmailRepo{pool: mp}
+	from := NewAddress("Alice", "alice@example.com")
+	e := &EmailEntity{ID: "id1", FromAddress: &from, ToAddresses: []Address{NewAddress("", "b@example.com")}}
+	if err := repo.saveAddresses(context.Background(), e); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if mp.execSQL != insertAddresses {
+		t.Fatalf("expected batch insert, got %s", mp.execSQL)
+	}
+	roles := mp.execArgs[1].([]string)
+	if len(roles) != 2 || roles[0] != RoleFrom || roles[1] != RoleTo {
+		t.Fatalf("unexpected roles: %v", roles)
+	}
+}
+
+func TestBuildListQuery_AddressFilter(t *testing.T) {
+	q, args := buildListQuery(10, 5, EmailFilter{Address: "a@x.com", Role: RoleCc})
+	if !strings.Contains(q, "email_addresses") || !strings.Contains(q, "a.role = $2") {
+		t.Fatalf("unexpected query: %s", q)
+	}
+	if len(args) != 4 || args[0] != "a@x.com" || args[1] != RoleCc || args[2] != 10 || args[3] != 5 {
+		t.Fatalf("unexpected args: %v", args)
+	}
+
+	q, args = buildListQuery(10, 0, EmailFilter{})
+	if strings.Contains(q, "FROM emails\nWHERE") || len(args) != 2 {
+		t.Fatalf("unexpected unfiltered query: %s %v", q, args)
+	}
+}
+
+func TestBuildListQuery_AutoResponseFilter(t *testing.T) {
+	q, args := buildListQuery(10, 0, EmailFilter{Domain: "example.com", AutoResponse: AutoResponseVacation})
+	if !strings.Contains(q, "\n  AND auto_response = $2") {
+		t.Fatalf("unexpected query: %s", q)
+	}
+	if len(args) != 4 || args[1] != AutoResponseVacation {
+		t.Fatalf("unexpected args: %v", args)
+	}
+}
+
+func TestBuildListQuery_HasWarningsFilter(t *testing.T) {
+	yes, no := true, false
+	q, args := buildListQuery(10, 0, EmailFilter{HasWarnings: &yes})
+	if !strings.Contains(q, "WHERE parse_warnings <> '[]'::jsonb") || len(args) != 2 {
+		t.Fatalf("unexpected query: %s %v", q, args)
+	}
+	q, _ = buildListQuery(10, 0, EmailFilter{HasWarnings: &no})
+	if !strings.Contains(q, "WHERE parse_warnings = '[]'::jsonb") {
+		t.Fatalf("unexpected query: %s", q)
+	}
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type AttachmentEntity struct {
+	ID          string    `db:"id" json:"id"`
+	EmailID     string    `db:"email_id" json:"email_id"`
+	Filename    string    `db:"filename" json:"filename"`
+	ContentType string    `db:"content_type" json:"content_type"`
+	SniffedType string    `db:"sniffed_type" json:"sniffed_type"`
+	Disposition string    `db:"disposition" json:"disposition,omitempty"`
+	ContentID   string    `db:"content_id" json:"content_id,omitempty"`
+	Size        int64     `db:"size" json:"size"`
+	SHA256      string    `db:"sha256" json:"sha256"`
+	StorageKey  string    `db:"storage_key" json:"-"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+
+	// Data holds the decoded payload between parsing and blob upload; never persisted in Postgres.
+	Data []byte `db:"-" json:"-"`
+}
+
+type AttachmentRepository interface {
+	ListAttachments(ctx context.Context, emailID string) ([]*AttachmentEntity, error)
+	GetAttachment(ctx context.Context, emailID, attachmentID string) (*AttachmentEntity, error)
+}
+
+var ErrAttachmentNotFound = errors.New("attachment not found")
+
+const deleteAttachments = `DELETE FROM attachments WHERE email_id = $1`
+
+const insertAttachment = `
+INSERT INTO attachments (
+  id, email_id, filename, content_type, sniffed_type, disposition,
+  content_id, size, sha256, storage_key, created_at
+) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+`
+
+const selectAttachments = `
+SELECT id, email_id, filename, content_type, sniffed_type, disposition,
+       content_id, size, sha256, storage_key, created_at
+FROM attachments WHERE email_id = $1
+ORDER BY created_at, filename
+`
+
+const selectAttachment = `
+SELECT id, email_id, filename, content_type, sniffed_type, disposition,
+       content_id, size, sha256, storage_key, created_at
+FROM attachments WHERE email_id = $1 AND id = $2
+`
+
+// saveAttachments replaces the attachment rows of an email. Payloads are expected
+// to be in the blob store already (see BlobEmailRepo); only metadata lands here.
+func (r *PostgresEmailRepo) saveAttachments(ctx context.Context, email *EmailEntity) error {
+	if _, err := r.pool.Exec(ctx, deleteAttachments, email.ID); err != nil {
+		return err
+	}
+	for i := range email.Attachments {
+		a := &email.Attachments[i]
+		a.EmailID = email.ID
+		if a.CreatedAt.IsZero() {
+			a.CreatedAt = time.Now().UTC()
+		}
+		if _, err := r.pool.Exec(ctx, insertAttachment,
+			a.ID, a.EmailID, a.Filename, a.ContentType, a.SniffedType, a.Disposition,
+			a.ContentID, a.Size, a.SHA256, a.StorageKey, a.CreatedAt,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *PostgresEmailRepo) ListAttachments(ctx context.Context, emailID string) ([]*AttachmentEntity, error) {
+	rows, err := r.pool.Query(ctx, selectAttachments, emailID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]*AttachmentEntity, 0, 4)
+	for rows.Next() {
+		var a AttachmentEntity
+		if err := rows.Scan(
+			&a.ID, &a.EmailID, &a.Filename, &a.ContentType, &a.SniffedType, &a.Disposition,
+			&a.ContentID, &a.Size, &a.SHA256, &a.StorageKey, &a.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, &a)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+func (r *PostgresEmailRepo) GetAttachment(ctx context.Context, emailID, attachmentID string) (*AttachmentEntity, error) {
+	var a AttachmentEntity
+	err := r.pool.QueryRow(ctx, selectAttachment, emailID, attachmentID).Scan(
+		&a.ID, &a.EmailID, &a.Filename, &a.ContentType, &a.SniffedType, &a.Disposition,
+		&a.ContentID, &a.Size, &a.SHA256, &a.StorageKey, &a.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+	return &a, nil
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func attachmentScan(id string, now time.Time) func(dest ...any) error {
+	return func(dest ...any) error {
+		*(dest[0].(*string)) = id
+		*(dest[1].(*string)) = "email-1"
+		*(dest[2].(*string)) = "invoice.pdf"
+		*(dest[3].(*string)) = "application/pdf"
+		*(dest[4].(*string)) = "application/pdf"
+		*(dest[5].(*string)) = "attachment"
+		*(dest[6].(*string)) = ""
+		*(dest[7].(*int64)) = 1234
+		*(dest[8].(*string)) = "deadbeef"
+		*(dest[9].(*string)) = "attachments/de/deadbeef"
+		*(dest[10].(*time.Time)) = now
+		return nil
+	}
+}
+
+func TestPostgresEmailRepo_ListAttachments(t *testing.T) {
+	now := time.Now().UTC()
+	rows := &fakeRows{scans: []func(dest ...any) error{attachmentScan("a1", now), attachmentScan("a2", now)}}
+	repo := &PostgresEmailRepo{pool: &mockPoolQuery{rows: rows}}
+
+	got, err := repo.ListAttachments(context.Background(), "email-1")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "a1" || got[1].StorageKey != "attachments/de/deadbeef" {
+		t.Fatalf("unexpected attachments: %+v", got)
+	}
+}
+
+func TestPostgresEmailRepo_GetAttachment(t *testing.T) {
+	now := time.Now().UTC()
+	repo := &PostgresEmailRepo{pool: &mockPool{row: mockRow{scan: attachmentScan("a1", now)}}}
+	got, err := repo.GetAttachment(context.Background(), "email-1", "a1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Size != 1234 || got.Filename != "invoice.pdf" {
+		t.Fatalf("unexpected attachment: %+v", got)
+	}
+
+	repo = &PostgresEmailRepo{pool: &mockPool{row: mockRow{scan: func(dest ...any) error { return pgx.ErrNoRows }}}}
+	if _, err := repo.GetAttachment(context.Background(), "email-1", "nope"); err != ErrAttachmentNotFound {
+		t.Fatalf("expected ErrAttachmentNotFound, got %v", err)
+	}
+}
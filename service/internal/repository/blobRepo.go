@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/Zifeldev/emailback/service/internal/storage"
+)
+
+// BlobEmailRepo is a decorator that uploads attachment payloads and the gzipped
+// original message to a blob store before delegating metadata persistence to
+// the underlying repository.
+type BlobEmailRepo struct {
+	underlying EmailRepository
+	blobs      storage.BlobStore
+}
+
+func NewBlobEmailRepo(under EmailRepository, blobs storage.BlobStore) *BlobEmailRepo {
+	return &BlobEmailRepo{underlying: under, blobs: blobs}
+}
+
+// AttachmentKey returns the content-addressed blob key for an attachment digest,
+// so identical files received many times are stored once.
+func AttachmentKey(sha256Hex string) string {
+	if len(sha256Hex) < 2 {
+		return "attachments/" + sha256Hex
+	}
+	return fmt.Sprintf("attachments/%s/%s", sha256Hex[:2], sha256Hex)
+}
+
+// RawMessageKey returns the content-addressed blob key for a raw message digest.
+func RawMessageKey(sha256Hex string) string {
+	if len(sha256Hex) < 2 {
+		return "raw/" + sha256Hex + ".eml.gz"
+	}
+	return fmt.Sprintf("raw/%s/%s.eml.gz", sha256Hex[:2], sha256Hex)
+}
+
+func (b *BlobEmailRepo) SaveEmail(ctx context.Context, email *EmailEntity) error {
+	if err := b.putBlobs(ctx, email); err != nil {
+		return err
+	}
+	return b.underlying.SaveEmail(ctx, email)
+}
+
+// putBlobs uploads the payloads of email and of the attached messages the
+// underlying repository will store as its children.
+func (b *BlobEmailRepo) putBlobs(ctx context.Context, email *EmailEntity) error {
+	if email.Raw != nil {
+		if err := b.putRaw(ctx, email); err != nil {
+			return fmt.Errorf("store raw message: %w", err)
+		}
+	}
+	for i := range email.Attachments {
+		a := &email.Attachments[i]
+		if a.Data == nil {
+			continue
+		}
+		key := AttachmentKey(a.SHA256)
+		if err := b.blobs.Put(ctx, key, bytes.NewReader(a.Data)); err != nil {
+			return fmt.Errorf("store attachment %q: %w", a.Filename, err)
+		}
+		a.StorageKey = key
+	}
+	for _, child := range email.Embedded {
+		if err := b.putBlobs(ctx, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *BlobEmailRepo) putRaw(ctx context.Context, email *EmailEntity) error {
+	sum := sha256.Sum256(email.Raw)
+	digest := hex.EncodeToString(sum[:])
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(email.Raw); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	key := RawMessageKey(digest)
+	if err := b.blobs.Put(ctx, key, &buf); err != nil {
+		return err
+	}
+	email.RawMessage = &RawMessageEntity{
+		StorageKey: key,
+		SHA256:     digest,
+		Size:       int64(len(email.Raw)),
+		Encoding:   "gzip",
+	}
+	return nil
+}
+
+func (b *BlobEmailRepo) GetByID(ctx context.Context, id string) (*EmailEntity, error) {
+	return b.underlying.GetByID(ctx, id)
+}
+
+func (b *BlobEmailRepo) GetAll(ctx context.Context, limit, offset int, filter EmailFilter) ([]*EmailEntity, error) {
+	return b.underlying.GetAll(ctx, limit, offset, filter)
+}
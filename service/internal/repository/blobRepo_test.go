@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/Zifeldev/emailback/service/internal/storage"
+)
+
+func TestBlobEmailRepo_SaveEmail_UploadsAttachments(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	base := &stubRepo{}
+	repo := NewBlobEmailRepo(base, store)
+
+	e := &EmailEntity{ID: "1", MessageID: "m1", Attachments: []AttachmentEntity{
+		{ID: "a1", Filename: "note.txt", SHA256: "0123abcd", Data: []byte("hello")},
+	}}
+	if err := repo.SaveEmail(context.Background(), e); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	saved := base.saved["1"].Attachments[0]
+	if saved.StorageKey != "attachments/01/0123abcd" {
+		t.Fatalf("unexpected storage key %q", saved.StorageKey)
+	}
+	rc, err := store.Get(context.Background(), saved.StorageKey)
+	if err != nil {
+		t.Fatalf("blob missing: %v", err)
+	}
+	defer rc.Close()
+	bs, _ := io.ReadAll(rc)
+	if string(bs) != "hello" {
+		t.Fatalf("unexpected blob %q", bs)
+	}
+}
+
+func TestBlobEmailRepo_SaveEmail_StoresGzippedRaw(t *testing.T) {
+	store, _ := storage.NewLocalStore(t.TempDir())
+	base := &stubRepo{}
+	repo := NewBlobEmailRepo(base, store)
+
+	raw := []byte("Subject: x\r\n\r\nhello")
+	e := &EmailEntity{ID: "1", MessageID: "m1", Raw: raw}
+	if err := repo.SaveEmail(context.Background(), e); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	meta := base.saved["1"].RawMessage
+	if meta == nil || meta.Encoding != "gzip" || meta.Size != int64(len(raw)) {
+		t.Fatalf("unexpected raw meta: %+v", meta)
+	}
+	rc, err := store.Get(context.Background(), meta.StorageKey)
+	if err != nil {
+		t.Fatalf("blob missing: %v", err)
+	}
+	defer rc.Close()
+	zr, err := gzip.NewReader(rc)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	bs, _ := io.ReadAll(zr)
+	if string(bs) != string(raw) {
+		t.Fatalf("unexpected raw %q", bs)
+	}
+}
+
+func TestBlobEmailRepo_SaveEmail_UploadsAttachedMessages(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	repo := NewBlobEmailRepo(&stubRepo{}, store)
+
+	child := &EmailEntity{ID: "2", MessageID: "m2", Raw: []byte("Subject: inner\r\n\r\nhi\r\n"), Attachments: []AttachmentEntity{
+		{ID: "a1", Filename: "note.txt", SHA256: "4567abcd", Data: []byte("hello")},
+	}}
+	e := &EmailEntity{ID: "1", MessageID: "m1", Embedded: []*EmailEntity{child}}
+	if err := repo.SaveEmail(context.Background(), e); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if child.RawMessage == nil || child.Attachments[0].StorageKey != "attachments/45/4567abcd" {
+		t.Fatalf("child blobs not stored: %+v %+v", child.RawMessage, child.Attachments[0])
+	}
+}
@@ -30,11 +30,23 @@ func (c *CacheEmailRepo) SaveEmail(ctx context.Context, email *EmailEntity) erro
     if err := c.underlying.SaveEmail(ctx, email); err != nil {
         return err
     }
-    // Best-effort invalidation; ignore errors
-    _ = c.rdb.Del(ctx, c.cacheKeyByID(email.ID)).Err()
+    c.invalidate(ctx, email)
     return nil
 }
 
+// invalidate drops the cached copies of email, of the attached messages
+// that were stored with it and of the emails whose thread it merged.
+// Best-effort; errors are ignored.
+func (c *CacheEmailRepo) invalidate(ctx context.Context, email *EmailEntity) {
+    _ = c.rdb.Del(ctx, c.cacheKeyByID(email.ID)).Err()
+    for _, id := range email.Rethreaded {
+        _ = c.rdb.Del(ctx, c.cacheKeyByID(id)).Err()
+    }
+    for _, child := range email.Embedded {
+        c.invalidate(ctx, child)
+    }
+}
+
 // GetByID returns entity from cache first; falls back to DB and populates cache.
 func (c *CacheEmailRepo) GetByID(ctx context.Context, id string) (*EmailEntity, error) {
     key := c.cacheKeyByID(id)
@@ -59,6 +71,6 @@ func (c *CacheEmailRepo) GetByID(ctx context.Context, id string) (*EmailEntity,
 }
 
 // GetAll is not cached by default (pagination + freshness). Delegates to underlying.
-func (c *CacheEmailRepo) GetAll(ctx context.Context, limit, offset int) ([]*EmailEntity, error) {
-    return c.underlying.GetAll(ctx, limit, offset)
+func (c *CacheEmailRepo) GetAll(ctx context.Context, limit, offset int, filter EmailFilter) ([]*EmailEntity, error) {
+    return c.underlying.GetAll(ctx, limit, offset, filter)
 }
@@ -26,7 +26,7 @@ func (s *stubRepo) GetByID(ctx context.Context, id string) (*EmailEntity, error)
 	}
 	return nil, ErrEmailNotFound
 }
-func (s *stubRepo) GetAll(ctx context.Context, limit, offset int) ([]*EmailEntity, error) {
+func (s *stubRepo) GetAll(ctx context.Context, limit, offset int, filter EmailFilter) ([]*EmailEntity, error) {
 	out := make([]*EmailEntity, 0, len(s.saved))
 	for _, v := range s.saved {
 		out = append(out, v)
@@ -93,3 +93,22 @@ func TestCacheEmailRepo_BadJSON_FallbackToDB(t *testing.T) {
 		t.Fatalf("expected fallback to DB, got %v err=%v", got, err)
 	}
 }
+
+func TestCacheEmailRepo_SaveEmail_InvalidatesRethreaded(t *testing.T) {
+	mr, _ := miniredis.Run()
+	defer mr.Close()
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	base := &stubRepo{saved: map[string]*EmailEntity{"old": {ID: "old", ThreadID: "t-child"}}}
+	repo := NewCacheEmailRepo(base, rdb, "test:", time.Minute)
+
+	ctx := context.Background()
+	_, _ = repo.GetByID(ctx, "old")
+	if !mr.Exists("test:email:id:old") {
+		t.Fatal("expected cached email")
+	}
+	base.saved["old"].ThreadID = "t-parent"
+	_ = repo.SaveEmail(ctx, &EmailEntity{ID: "new", ThreadID: "t-parent", Rethreaded: []string{"old"}})
+	if mr.Exists("test:email:id:old") {
+		t.Fatal("merged email still cached with its old thread")
+	}
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ChildEmail summarises a message that arrived attached to another one.
+type ChildEmail struct {
+	ID        string     `json:"id"`
+	MessageID string     `json:"message_id"`
+	From      string     `json:"from"`
+	Subject   string     `json:"subject"`
+	Date      *time.Time `json:"date"`
+	PartPath  string     `json:"part_path"`
+}
+
+// selectChildrenJSON is embedded as a column of the email selects, like
+// selectAddressesJSON, so an email is read together with its children.
+const selectChildrenJSON = `(SELECT COALESCE(jsonb_agg(jsonb_build_object(
+         'id', c.id, 'message_id', c.message_id, 'from', c.from_addr,
+         'subject', c.subject, 'date', c.date, 'part_path', c.part_path) ORDER BY c.part_path), '[]'::jsonb)
+       FROM emails c WHERE c.parent_id = emails.id)`
+
+// saveChildren stores the attached messages of email as emails of their own,
+// linked to the stored parent, and records them in Children. An attached copy
+// of an email stored in its own right is left out rather than overwriting it.
+func (r *PostgresEmailRepo) saveChildren(ctx context.Context, email *EmailEntity) error {
+	if len(email.Embedded) == 0 {
+		return nil
+	}
+	email.Children = email.Children[:0]
+	for _, child := range email.Embedded {
+		child.ParentID = email.ID
+		err := r.saveEmail(ctx, child)
+		if errors.Is(err, errMessageIDTaken) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		email.Children = append(email.Children, ChildEmail{
+			ID:        child.ID,
+			MessageID: child.MessageID,
+			From:      child.From,
+			Subject:   child.Subject,
+			Date:      child.Date,
+			PartPath:  child.PartPath,
+		})
+	}
+	return nil
+}
+
+func (e *EmailEntity) applyChildrenJSON(b []byte) {
+	if len(b) > 0 {
+		_ = json.Unmarshal(b, &e.Children)
+	}
+	if len(e.Children) == 0 {
+		e.Children = nil
+	}
+}
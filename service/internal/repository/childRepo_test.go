@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestPostgresEmailRepo_SaveEmail_StoresAttachedMessagesAsChildren(t *testing.T) {
+	mp := &mockPool{}
+	var parentArgs []interface{}
+	mp.row = mockRow{scan: func(dest ...any) error {
+		if parentArgs == nil {
+			parentArgs = mp.rowArgs
+		}
+		*(dest[0].(*string)) = "stored-" + mp.rowArgs[1].(string)
+		return nil
+	}}
+	repo := &PostgresEmailRepo{pool: mp}
+	child := &EmailEntity{ID: "c1", MessageID: "spam-1", Subject: "You won", PartPath: "2", ThreadID: "t2"}
+	e := &EmailEntity{ID: "p1", MessageID: "fwd-1", ThreadID: "t1", Embedded: []*EmailEntity{child}}
+	if err := repo.SaveEmail(context.Background(), e); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if parentArgs[26] != (*string)(nil) {
+		t.Fatalf("top-level email should have a NULL parent, got %v", parentArgs[26])
+	}
+	if got := mp.rowArgs[26].(*string); got == nil || *got != "stored-fwd-1" || mp.rowArgs[27] != "2" {
+		t.Fatalf("child not linked to stored parent: %v %v", mp.rowArgs[26], mp.rowArgs[27])
+	}
+	if len(e.Children) != 1 || e.Children[0].ID != "stored-spam-1" || e.Children[0].Subject != "You won" {
+		t.Fatalf("unexpected children: %+v", e.Children)
+	}
+}
+
+func TestPostgresEmailRepo_SaveEmail_AttachedCopyLeavesStoredEmailAlone(t *testing.T) {
+	mp := &mockPool{}
+	mp.row = mockRow{scan: func(dest ...any) error {
+		// "orig-1" is stored on its own, so the child upsert's WHERE rejects it.
+		if mp.rowSQL == upsertChildEmail && mp.rowArgs[1] == "orig-1" {
+			return pgx.ErrNoRows
+		}
+		*(dest[0].(*string)) = "stored-" + mp.rowArgs[1].(string)
+		return nil
+	}}
+	repo := &PostgresEmailRepo{pool: mp}
+
+	orig := &EmailEntity{ID: "o1", MessageID: "orig-1", Subject: "Invoice", ThreadID: "t1"}
+	if err := repo.SaveEmail(context.Background(), orig); err != nil {
+		t.Fatalf("save standalone: %v", err)
+	}
+	if mp.rowSQL != upsertEmail {
+		t.Fatalf("standalone email must use the plain upsert")
+	}
+
+	forged := &EmailEntity{ID: "c1", MessageID: "orig-1", Subject: "Invoice (updated bank details)", PartPath: "2", ThreadID: "t2"}
+	fwd := &EmailEntity{ID: "p1", MessageID: "fwd-1", ThreadID: "t3", Embedded: []*EmailEntity{forged}}
+	if err := repo.SaveEmail(context.Background(), fwd); err != nil {
+		t.Fatalf("save forward: %v", err)
+	}
+	if mp.rowSQL != upsertChildEmail || !strings.Contains(upsertChildEmail, "WHERE emails.parent_id = EXCLUDED.parent_id") {
+		t.Fatalf("attached message must use the guarded upsert")
+	}
+	if len(fwd.Children) != 0 {
+		t.Fatalf("attached copy must not be listed as a child: %+v", fwd.Children)
+	}
+	if mp.execArgs[0] != "stored-fwd-1" {
+		t.Fatalf("child tables written for the attached copy: %v", mp.execArgs)
+	}
+}
+
+func TestEmailEntity_ApplyChildrenJSON(t *testing.T) {
+	var e EmailEntity
+	e.applyChildrenJSON([]byte(`[{"id":"c1","message_id":"m","subject":"s","date":"2024-07-15T08:00:00Z","part_path":"2"}]`))
+	if len(e.Children) != 1 || e.Children[0].PartPath != "2" || e.Children[0].Date == nil {
+		t.Fatalf("unexpected children: %+v", e.Children)
+	}
+	e.applyChildrenJSON([]byte(`[]`))
+	if e.Children != nil {
+		t.Fatalf("empty list should be omitted, got %+v", e.Children)
+	}
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DeliveryStatusEntity is the delivery result for one recipient reported by
+// a bounce. OriginalEmailID is set when the bounced message is stored too.
+type DeliveryStatusEntity struct {
+	ID                string     `db:"id" json:"id"`
+	EmailID           string     `db:"email_id" json:"email_id"`
+	OriginalMessageID string     `db:"original_message_id" json:"original_message_id,omitempty"`
+	OriginalEmailID   string     `db:"-" json:"original_email_id,omitempty"`
+	Recipient         string     `db:"recipient" json:"recipient"`
+	OriginalRecipient string     `db:"original_recipient" json:"original_recipient,omitempty"`
+	Action            string     `db:"action" json:"action"`
+	Status            string     `db:"status" json:"status,omitempty"`
+	DiagnosticCode    string     `db:"diagnostic_code" json:"diagnostic_code,omitempty"`
+	RemoteMTA         string     `db:"remote_mta" json:"remote_mta,omitempty"`
+	ReportingMTA      string     `db:"reporting_mta" json:"reporting_mta,omitempty"`
+	BounceType        string     `db:"bounce_type" json:"bounce_type,omitempty"`
+	Standard          bool       `db:"standard" json:"standard"`
+	LastAttempt       *time.Time `db:"last_attempt" json:"last_attempt,omitempty"`
+	CreatedAt         time.Time  `db:"created_at" json:"created_at"`
+}
+
+// BounceFilter narrows ListBounces. Zero values mean "no constraint".
+type BounceFilter struct {
+	Recipient string // case-insensitive
+	Type      string // bounce.TypeHard or bounce.TypeSoft
+}
+
+type DeliveryStatusRepository interface {
+	ListEmailDeliveryStatus(ctx context.Context, emailID string) ([]*DeliveryStatusEntity, error)
+	ListBounces(ctx context.Context, limit, offset int, filter BounceFilter) ([]*DeliveryStatusEntity, error)
+}
+
+const deleteDeliveryStatus = `DELETE FROM delivery_status WHERE email_id = $1`
+
+const insertDeliveryStatus = `
+INSERT INTO delivery_status (
+  id, email_id, original_message_id, recipient, original_recipient, action, status,
+  diagnostic_code, remote_mta, reporting_mta, bounce_type, standard, last_attempt, created_at
+) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)
+`
+
+// The original email is joined on read so that a bounce processed before
+// its outgoing message was imported still gets linked.
+const deliveryStatusColumns = `
+       d.id, d.email_id, d.original_message_id, COALESCE(o.id::text, ''),
+       d.recipient, d.original_recipient, d.action, d.status,
+       d.diagnostic_code, d.remote_mta, d.reporting_mta, d.bounce_type, d.standard,
+       d.last_attempt, d.created_at
+`
+
+const deliveryStatusFrom = `
+FROM delivery_status d
+LEFT JOIN emails o ON o.message_id = d.original_message_id AND d.original_message_id <> ''`
+
+const selectEmailDeliveryStatus = `SELECT` + deliveryStatusColumns + deliveryStatusFrom + `
+WHERE d.email_id = $1 ORDER BY d.recipient`
+
+// saveDeliveryStatus replaces the per-recipient results of a bounce.
+func (r *PostgresEmailRepo) saveDeliveryStatus(ctx context.Context, email *EmailEntity) error {
+	if _, err := r.pool.Exec(ctx, deleteDeliveryStatus, email.ID); err != nil {
+		return err
+	}
+	for i := range email.DeliveryStatus {
+		d := &email.DeliveryStatus[i]
+		d.EmailID = email.ID
+		if d.CreatedAt.IsZero() {
+			d.CreatedAt = time.Now().UTC()
+		}
+		if _, err := r.pool.Exec(ctx, insertDeliveryStatus,
+			d.ID, d.EmailID, d.OriginalMessageID, d.Recipient, d.OriginalRecipient, d.Action, d.Status,
+			d.DiagnosticCode, d.RemoteMTA, d.ReportingMTA, d.BounceType, d.Standard, d.LastAttempt, d.CreatedAt,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *PostgresEmailRepo) ListEmailDeliveryStatus(ctx context.Context, emailID string) ([]*DeliveryStatusEntity, error) {
+	return r.queryDeliveryStatus(ctx, selectEmailDeliveryStatus, emailID)
+}
+
+func (r *PostgresEmailRepo) ListBounces(ctx context.Context, limit, offset int, filter BounceFilter) ([]*DeliveryStatusEntity, error) {
+	query, args := buildBounceListQuery(limit, offset, filter)
+	return r.queryDeliveryStatus(ctx, query, args...)
+}
+
+// buildBounceListQuery renders the ListBounces query; every filter value is
+// bound as a parameter. Successful deliveries are never listed.
+func buildBounceListQuery(limit, offset int, f BounceFilter) (string, []interface{}) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	where := []string{"d.bounce_type <> ''"}
+	if f.Recipient != "" {
+		where = append(where, "lower(d.recipient) = lower("+arg(f.Recipient)+")")
+	}
+	if f.Type != "" {
+		where = append(where, "d.bounce_type = "+arg(f.Type))
+	}
+
+	q := `SELECT` + deliveryStatusColumns + deliveryStatusFrom
+	q += "\nWHERE " + strings.Join(where, "\n  AND ")
+	q += "\nORDER BY d.created_at DESC, d.recipient\nLIMIT " + arg(limit) + " OFFSET " + arg(offset)
+	return q, args
+}
+
+func (r *PostgresEmailRepo) queryDeliveryStatus(ctx context.Context, query string, args ...interface{}) ([]*DeliveryStatusEntity, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]*DeliveryStatusEntity, 0, 4)
+	for rows.Next() {
+		var d DeliveryStatusEntity
+		if err := rows.Scan(
+			&d.ID, &d.EmailID, &d.OriginalMessageID, &d.OriginalEmailID,
+			&d.Recipient, &d.OriginalRecipient, &d.Action, &d.Status,
+			&d.DiagnosticCode, &d.RemoteMTA, &d.ReportingMTA, &d.BounceType, &d.Standard,
+			&d.LastAttempt, &d.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, &d)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPostgresEmailRepo_SaveDeliveryStatus(t *testing.T) {
+	mp := &mockPool{}
+	repo := &PostgresEmailRepo{pool: mp}
+	e := &EmailEntity{ID: "id1", DeliveryStatus: []DeliveryStatusEntity{{
+		ID: "ds1", OriginalMessageID: "nl-42@example.com", Recipient: "bob@example.org",
+		Action: "failed", Status: "5.1.1", BounceType: "hard", Standard: true,
+	}}}
+	if err := repo.saveDeliveryStatus(context.Background(), e); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if mp.execSQL != insertDeliveryStatus || len(mp.execArgs) != 14 {
+		t.Fatalf("unexpected insert: %s %d args", mp.execSQL, len(mp.execArgs))
+	}
+	if mp.execArgs[1] != "id1" || mp.execArgs[2] != "nl-42@example.com" || mp.execArgs[10] != "hard" {
+		t.Fatalf("unexpected args: %v", mp.execArgs)
+	}
+}
+
+func TestPostgresEmailRepo_ListBounces(t *testing.T) {
+	scan := func(dest ...any) error {
+		*(dest[0].(*string)) = "ds1"
+		*(dest[1].(*string)) = "bounce-1"
+		*(dest[2].(*string)) = "nl-42@example.com"
+		*(dest[3].(*string)) = "orig-1"
+		*(dest[4].(*string)) = "bob@example.org"
+		*(dest[11].(*string)) = "hard"
+		return nil
+	}
+	repo := &PostgresEmailRepo{pool: &mockPoolQuery{rows: &fakeRows{scans: []func(dest ...any) error{scan}}}}
+	got, err := repo.ListBounces(context.Background(), 10, 0, BounceFilter{Recipient: "Bob@Example.org"})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(got) != 1 || got[0].OriginalEmailID != "orig-1" || got[0].BounceType != "hard" {
+		t.Fatalf("unexpected bounces: %+v", got)
+	}
+}
+
+func TestBuildBounceListQuery(t *testing.T) {
+	q, args := buildBounceListQuery(20, 5, BounceFilter{Recipient: "bob@example.org", Type: "soft"})
+	for _, want := range []string{"d.bounce_type <> ''", "lower(d.recipient) = lower($1)", "d.bounce_type = $2", "LIMIT $3 OFFSET $4", "LEFT JOIN emails o"} {
+		if !strings.Contains(q, want) {
+			t.Fatalf("query missing %q: %s", want, q)
+		}
+	}
+	if len(args) != 4 || args[0] != "bob@example.org" || args[1] != "soft" {
+		t.Fatalf("unexpected args: %v", args)
+	}
+}
@@ -5,9 +5,20 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/Zifeldev/emailback/service/internal/charset"
 	"github.com/Zifeldev/emailback/service/internal/db"
+	"github.com/Zifeldev/emailback/service/internal/links"
+	"github.com/Zifeldev/emailback/service/internal/mailauth"
+	"github.com/Zifeldev/emailback/service/internal/mailheader"
+	"github.com/Zifeldev/emailback/service/internal/mailinglist"
+	"github.com/Zifeldev/emailback/service/internal/pgp"
+	"github.com/Zifeldev/emailback/service/internal/received"
+	"github.com/Zifeldev/emailback/service/internal/smime"
+	"github.com/Zifeldev/emailback/service/internal/thread"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
@@ -24,15 +35,139 @@ type EmailEntity struct {
 	Language   string                 `db:"language,omitempty" json:"language,omitempty"`
 	Confidence float64                `db:"language_confidence,omitempty" json:"language_confidence,omitempty"`
 	Metrics    map[string]interface{} `db:"metrics" json:"metrics"`
-	Headers    map[string]string      `db:"headers" json:"headers"`
+	Headers    []mailheader.Field     `db:"headers" json:"headers"`
 	CreatedAt  time.Time              `db:"created_at" json:"created_at"`
 	RawSize    int                    `db:"raw_size" json:"raw_size"`
+	InReplyTo  string                 `db:"in_reply_to" json:"in_reply_to,omitempty"`
+	References []string               `db:"references_ids" json:"references,omitempty"`
+	ThreadID   string                 `db:"thread_id" json:"thread_id,omitempty"`
+
+	// DateTZOffset is the sender's UTC offset in minutes, from the Date
+	// header; Date is returned in that zone. DateSource is one of DateSources.
+	DateTZOffset *int   `db:"date_tz_offset" json:"date_tz_offset,omitempty"`
+	DateSource   string `db:"date_source" json:"date_source"`
+
+	// ParentID is set on messages that arrived attached (message/rfc822) to
+	// another email; PartPath is the MIME part they were found in.
+	ParentID string       `db:"parent_id" json:"parent_id,omitempty"`
+	PartPath string       `db:"part_path" json:"part_path,omitempty"`
+	Children []ChildEmail `db:"-" json:"children,omitempty"`
+
+	// AutoResponse is one of AutoResponses: vacation notices and other
+	// machine-generated mail are told apart from mail written by a person.
+	AutoResponse string `db:"auto_response" json:"auto_response"`
+	// Category is one of Categories; List holds the parsed List-* fields.
+	Category string            `db:"category" json:"category"`
+	List     *mailinglist.Info `db:"list" json:"list,omitempty"`
+	// ParseWarnings are the defects the MIME parser recovered from; mail with
+	// any is likely to be mis-decoded.
+	ParseWarnings []ParseWarning `db:"parse_warnings" json:"parse_warnings,omitempty"`
+	// Charset records the body's declared charset and the one its text was
+	// finally decoded with.
+	Charset *charset.Result `db:"charset" json:"charset,omitempty"`
+
+	// Structured participants; From/To above keep the bare addresses for compatibility.
+	FromAddress *Address  `db:"-" json:"from_address,omitempty"`
+	Sender      *Address  `db:"-" json:"sender,omitempty"`
+	ReplyTo     []Address `db:"-" json:"reply_to,omitempty"`
+	ToAddresses []Address `db:"-" json:"to_addresses,omitempty"`
+	Cc          []Address `db:"-" json:"cc,omitempty"`
+	Bcc         []Address `db:"-" json:"bcc,omitempty"`
+
+	Attachments []AttachmentEntity `db:"-" json:"attachments,omitempty"`
+	// Links are the hyperlinks of the text and HTML bodies, which cleaning
+	// removes from Text.
+	Links []links.Link `db:"-" json:"links,omitempty"`
+	// Events are the calendar invitations carried by the message.
+	Events []EventEntity `db:"-" json:"events,omitempty"`
+	// DeliveryStatus holds the per-recipient results when the message is a bounce.
+	DeliveryStatus []DeliveryStatusEntity `db:"-" json:"delivery_status,omitempty"`
+
+	// DKIM holds one verification result per DKIM-Signature header.
+	DKIM  []mailauth.DKIMResult `db:"dkim_results" json:"dkim,omitempty"`
+	SPF   *mailauth.SPFResult   `db:"spf" json:"spf,omitempty"`
+	DMARC *mailauth.DMARCResult `db:"dmarc" json:"dmarc,omitempty"`
+
+	// Hops is the Received chain, oldest first.
+	Hops           []received.Hop `db:"hops" json:"hops,omitempty"`
+	TransitSeconds *float64       `db:"transit_seconds" json:"transit_seconds,omitempty"`
+	OriginIP       string         `db:"origin_ip" json:"origin_ip,omitempty"`
+
+	// SMIME describes the signature and encryption layers that were removed
+	// before parsing; nil for ordinary mail.
+	SMIME *smime.Result `db:"smime" json:"smime,omitempty"`
+	// PGP does the same for OpenPGP/MIME layers and inline armored blocks.
+	PGP *pgp.Result `db:"pgp" json:"pgp,omitempty"`
+
+	// Raw carries the original message from the parser to BlobEmailRepo;
+	// RawMessage describes where it ended up. Neither is part of the API.
+	Raw        []byte            `db:"-" json:"-"`
+	RawMessage *RawMessageEntity `db:"-" json:"-"`
+	// Embedded are the parsed attached messages; SaveEmail stores them as
+	// children and lists them in Children.
+	Embedded []*EmailEntity `db:"-" json:"-"`
+	// Rethreaded lists the stored emails moved into this one's thread when
+	// saving it merged two threads; caches of them are stale.
+	Rethreaded []string `db:"-" json:"-"`
 }
 
 type EmailRepository interface {
 	SaveEmail(ctx context.Context, email *EmailEntity) error
 	GetByID(ctx context.Context, id string) (*EmailEntity, error)
-	GetAll(ctx context.Context, limit, offset int) ([]*EmailEntity, error)
+	GetAll(ctx context.Context, limit, offset int, filter EmailFilter) ([]*EmailEntity, error)
+}
+
+const (
+	AutoResponseNone          = "none"
+	AutoResponseVacation      = "vacation"
+	AutoResponseAutoGenerated = "auto-generated"
+	AutoResponseList          = "list"
+)
+
+// AutoResponses lists the values of EmailEntity.AutoResponse.
+var AutoResponses = []string{AutoResponseNone, AutoResponseVacation, AutoResponseAutoGenerated, AutoResponseList}
+
+const (
+	CategoryNewsletter    = "newsletter"
+	CategoryTransactional = "transactional"
+	CategoryPersonal      = "personal"
+)
+
+// Categories lists the values of EmailEntity.Category.
+var Categories = []string{CategoryNewsletter, CategoryTransactional, CategoryPersonal}
+
+const (
+	DateSourceHeader   = "header"   // the Date header
+	DateSourceReceived = "received" // the newest Received timestamp
+	DateSourceNone     = "none"
+)
+
+// DateSources lists the values of EmailEntity.DateSource.
+var DateSources = []string{DateSourceHeader, DateSourceReceived, DateSourceNone}
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// ParseWarning is one defect found while reading the MIME structure, such as
+// a malformed header, an unknown charset or a missing boundary. Type is the
+// parser's name for it; PartPath is the MIME part, empty for the root.
+type ParseWarning struct {
+	Type     string `json:"type"`
+	Severity string `json:"severity"` // SeverityError or SeverityWarning
+	PartPath string `json:"part_path,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// EmailFilter narrows GetAll. Zero values mean "no constraint".
+type EmailFilter struct {
+	Address      string // participant address, case-insensitive
+	Domain       string // participant domain
+	Role         string // restricts Address/Domain to one of AddressRoles
+	AutoResponse string // one of AutoResponses
+	HasWarnings  *bool  // with or without parse warnings
+	LinkDomain   string // links to this host or its subdomains
 }
 
 // dbExecutor captures the subset of pool API we use, to enable testing/mocking.
@@ -42,6 +177,12 @@ type dbExecutor interface {
 	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
 }
 
+// txBeginner is implemented by the pool and by pgx.Tx. SaveEmail writes the
+// email and its child tables in one transaction when the executor has it.
+type txBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
 type PostgresEmailRepo struct {
 	pool dbExecutor
 }
@@ -52,13 +193,29 @@ func NewPostgresEmailRepo(pool *db.TimeoutPool) *PostgresEmailRepo {
 
 var ErrEmailNotFound = errors.New("email not found")
 
-const upsertEmail = `
+const insertEmail = `
 INSERT INTO emails (
   id, message_id, from_addr, to_addrs, subject, date, body_text, body_html,
-  language, language_confidence, metrics, headers, created_at, raw_size
+  language, language_confidence, metrics, headers, created_at, raw_size,
+  in_reply_to, references_ids, thread_id, thread_subject,
+  dkim_results, spf, dmarc,
+  hops, transit_seconds, origin_ip,
+  smime, pgp,
+  parent_id, part_path,
+  auto_response, category, list, list_id,
+  parse_warnings, charset,
+  date_tz_offset, date_source
 ) VALUES (
   $1,$2,$3,$4,$5,$6,$7,$8,
-  $9,$10,$11,$12,$13,$14
+  $9,$10,$11,$12,$13,$14,
+  $15,$16,$17,$18,
+  $19,$20,$21,
+  $22,$23,$24,
+  $25,$26,
+  $27,$28,
+  $29,$30,$31,$32,
+  $33,$34,
+  $35,$36
 )
 ON CONFLICT (message_id) DO UPDATE SET
   from_addr = EXCLUDED.from_addr,
@@ -71,52 +228,255 @@ ON CONFLICT (message_id) DO UPDATE SET
   language_confidence = EXCLUDED.language_confidence,
   metrics = EXCLUDED.metrics,
   headers = EXCLUDED.headers,
-  raw_size = EXCLUDED.raw_size
+  raw_size = EXCLUDED.raw_size,
+  in_reply_to = EXCLUDED.in_reply_to,
+  references_ids = EXCLUDED.references_ids,
+  thread_id = COALESCE(emails.thread_id, EXCLUDED.thread_id),
+  thread_subject = EXCLUDED.thread_subject,
+  dkim_results = EXCLUDED.dkim_results,
+  spf = EXCLUDED.spf,
+  dmarc = EXCLUDED.dmarc,
+  hops = EXCLUDED.hops,
+  transit_seconds = EXCLUDED.transit_seconds,
+  origin_ip = EXCLUDED.origin_ip,
+  smime = EXCLUDED.smime,
+  pgp = EXCLUDED.pgp,
+  parent_id = COALESCE(emails.parent_id, EXCLUDED.parent_id),
+  part_path = CASE WHEN emails.parent_id IS NULL THEN EXCLUDED.part_path ELSE emails.part_path END,
+  auto_response = EXCLUDED.auto_response,
+  category = EXCLUDED.category,
+  list = EXCLUDED.list,
+  list_id = EXCLUDED.list_id,
+  parse_warnings = EXCLUDED.parse_warnings,
+  charset = EXCLUDED.charset,
+  date_tz_offset = EXCLUDED.date_tz_offset,
+  date_source = EXCLUDED.date_source
 `
 
-const selectByID = `
-SELECT id, message_id, from_addr, to_addrs, subject, date,
-       body_text, body_html, language, language_confidence,
-       metrics, headers, created_at, raw_size
-FROM emails WHERE id = $1
+const upsertEmail = insertEmail + `RETURNING id, COALESCE(thread_id::text, '')
 `
 
-const selectAll = `
-SELECT id, message_id, from_addr, to_addrs, subject, date,
+// upsertChildEmail stores an attached message. Anyone can attach a message
+// with the Message-ID of a stored email, so only a row that is already this
+// parent's attachment is updated; for any other row nothing is returned.
+const upsertChildEmail = insertEmail + `WHERE emails.parent_id = EXCLUDED.parent_id
+RETURNING id, COALESCE(thread_id::text, '')
+`
+
+// errMessageIDTaken reports an attached message whose Message-ID belongs to
+// an email stored on its own or attached elsewhere.
+var errMessageIDTaken = errors.New("message id belongs to another stored email")
+
+const emailColumns = `
+       id, message_id, from_addr, to_addrs, subject, date,
        body_text, body_html, language, language_confidence,
-       metrics, headers, created_at, raw_size
-FROM emails
-ORDER BY created_at DESC
-LIMIT $1 OFFSET $2
+       metrics, headers, created_at, raw_size,
+       in_reply_to, references_ids, COALESCE(thread_id::text, ''),
+       ` + selectAddressesJSON + `,
+       dkim_results, spf, dmarc,
+       hops, transit_seconds, origin_ip,
+       smime, pgp,
+       COALESCE(parent_id::text, ''), part_path,
+       ` + selectChildrenJSON + `,
+       auto_response, category, list,
+       parse_warnings, charset,
+       date_tz_offset, date_source,
+       ` + selectLinksJSON + `
 `
 
+const selectByID = `SELECT` + emailColumns + `FROM emails WHERE id = $1`
+
+// buildListQuery renders the GetAll query; every filter value is bound as a parameter.
+func buildListQuery(limit, offset int, f EmailFilter) (string, []interface{}) {
+	var where []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if f.Address != "" || f.Domain != "" {
+		conds := []string{"a.email_id = emails.id"}
+		if f.Address != "" {
+			conds = append(conds, "lower(a.address) = lower("+arg(f.Address)+")")
+		}
+		if f.Domain != "" {
+			conds = append(conds, "a.domain = lower("+arg(f.Domain)+")")
+		}
+		if f.Role != "" {
+			conds = append(conds, "a.role = "+arg(f.Role))
+		}
+		where = append(where, "EXISTS (SELECT 1 FROM email_addresses a WHERE "+strings.Join(conds, " AND ")+")")
+	}
+	if f.LinkDomain != "" {
+		d := arg(f.LinkDomain)
+		where = append(where, "EXISTS (SELECT 1 FROM email_links l WHERE l.email_id = emails.id AND (l.domain = lower("+d+") OR right(l.domain, length("+d+") + 1) = '.' || lower("+d+")))")
+	}
+	if f.AutoResponse != "" {
+		where = append(where, "auto_response = "+arg(f.AutoResponse))
+	}
+	if f.HasWarnings != nil {
+		if *f.HasWarnings {
+			where = append(where, "parse_warnings <> '[]'::jsonb")
+		} else {
+			where = append(where, "parse_warnings = '[]'::jsonb")
+		}
+	}
+
+	q := `SELECT` + emailColumns + `FROM emails`
+	if len(where) > 0 {
+		q += "\nWHERE " + strings.Join(where, "\n  AND ")
+	}
+	q += "\nORDER BY created_at DESC\nLIMIT " + arg(limit) + " OFFSET " + arg(offset)
+	return q, args
+}
+
+// SaveEmail upserts the email and replaces its addresses, links, events,
+// delivery status, attachments, raw message and attached emails. All of it is
+// committed together, so a failure part-way leaves the stored email as it was.
 func (r *PostgresEmailRepo) SaveEmail(ctx context.Context, email *EmailEntity) error {
+	b, ok := r.pool.(txBeginner)
+	if !ok {
+		return r.saveEmail(ctx, email)
+	}
+	tx, err := b.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if err := (&PostgresEmailRepo{pool: tx}).saveEmail(ctx, email); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *PostgresEmailRepo) saveEmail(ctx context.Context, email *EmailEntity) error {
 	metricsJSON, err := json.Marshal(email.Metrics)
 	if err != nil {
 		return err
 	}
-	headersJSON, err := json.Marshal(email.Headers)
+	headersJSON, err := json.Marshal(nonNil(email.Headers))
 	if err != nil {
 		return err
 	}
+	dkimJSON, err := json.Marshal(nonNil(email.DKIM))
+	if err != nil {
+		return err
+	}
+	spfJSON, err := jsonOrNull(email.SPF)
+	if err != nil {
+		return err
+	}
+	dmarcJSON, err := jsonOrNull(email.DMARC)
+	if err != nil {
+		return err
+	}
+	hopsJSON, err := json.Marshal(nonNil(email.Hops))
+	if err != nil {
+		return err
+	}
+	smimeJSON, err := jsonOrNull(email.SMIME)
+	if err != nil {
+		return err
+	}
+	pgpJSON, err := jsonOrNull(email.PGP)
+	if err != nil {
+		return err
+	}
+	listJSON, err := jsonOrNull(email.List)
+	if err != nil {
+		return err
+	}
+	warningsJSON, err := json.Marshal(nonNil(email.ParseWarnings))
+	if err != nil {
+		return err
+	}
+	charsetJSON, err := jsonOrNull(email.Charset)
+	if err != nil {
+		return err
+	}
+	var listID string
+	if email.List != nil {
+		listID = email.List.ID
+	}
 	createdAt := email.CreatedAt
 	if createdAt.IsZero() {
 		createdAt = time.Now()
 	}
+	if email.AutoResponse == "" {
+		email.AutoResponse = AutoResponseNone
+	}
+	if email.Category == "" {
+		email.Category = CategoryPersonal
+	}
+	if email.DateSource == "" {
+		email.DateSource = DateSourceNone
+		if email.Date != nil {
+			email.DateSource = DateSourceHeader
+		}
+	}
+
+	if err := r.assignThread(ctx, email); err != nil {
+		return err
+	}
 
-	_, err = r.pool.Exec(ctx, upsertEmail,
+	// On a message_id conflict the existing row keeps its id and thread; adopt
+	// them so that child rows and the caller's follow-up reads point at the stored email.
+	query := upsertEmail
+	if email.ParentID != "" {
+		query = upsertChildEmail
+	}
+	var id, threadID string
+	err = r.pool.QueryRow(ctx, query,
 		email.ID, email.MessageID, email.From, email.To, email.Subject, email.Date,
 		email.Text, email.HTML, email.Language, email.Confidence,
 		metricsJSON, headersJSON, createdAt, email.RawSize,
-	)
-	return err
+		email.InReplyTo, nonNil(email.References), email.ThreadID, thread.NormalizeSubject(email.Subject),
+		dkimJSON, spfJSON, dmarcJSON,
+		hopsJSON, email.TransitSeconds, email.OriginIP,
+		smimeJSON, pgpJSON,
+		nullString(email.ParentID), email.PartPath,
+		email.AutoResponse, email.Category, listJSON, listID,
+		warningsJSON, charsetJSON,
+		email.DateTZOffset, email.DateSource,
+	).Scan(&id, &threadID)
+	if errors.Is(err, pgx.ErrNoRows) && email.ParentID != "" {
+		return errMessageIDTaken
+	}
+	if err != nil {
+		return err
+	}
+	email.ID = id
+	if threadID != "" {
+		email.ThreadID = threadID
+	}
+
+	if err := r.saveAddresses(ctx, email); err != nil {
+		return err
+	}
+	if err := r.saveLinks(ctx, email); err != nil {
+		return err
+	}
+	if err := r.saveEvents(ctx, email); err != nil {
+		return err
+	}
+	if err := r.saveDeliveryStatus(ctx, email); err != nil {
+		return err
+	}
+	if err := r.saveAttachments(ctx, email); err != nil {
+		return err
+	}
+	if err := r.saveRawMessage(ctx, email); err != nil {
+		return err
+	}
+	return r.saveChildren(ctx, email)
 }
 
 func (r *PostgresEmailRepo) GetByID(ctx context.Context, id string) (*EmailEntity, error) {
 	row := r.pool.QueryRow(ctx, selectByID, id)
 
 	var email EmailEntity
-	var metricsJSON, headersJSON []byte
+	var metricsJSON, headersJSON, addressesJSON, dkimJSON, spfJSON, dmarcJSON, hopsJSON, smimeJSON, pgpJSON, childrenJSON, listJSON, warningsJSON, charsetJSON, linksJSON []byte
 	var dateNT sql.NullTime
 	var confNF sql.NullFloat64
 
@@ -124,6 +484,15 @@ func (r *PostgresEmailRepo) GetByID(ctx context.Context, id string) (*EmailEntit
 		&email.ID, &email.MessageID, &email.From, &email.To, &email.Subject, &dateNT,
 		&email.Text, &email.HTML, &email.Language, &confNF,
 		&metricsJSON, &headersJSON, &email.CreatedAt, &email.RawSize,
+		&email.InReplyTo, &email.References, &email.ThreadID,
+		&addressesJSON, &dkimJSON, &spfJSON, &dmarcJSON,
+		&hopsJSON, &email.TransitSeconds, &email.OriginIP,
+		&smimeJSON, &pgpJSON,
+		&email.ParentID, &email.PartPath, &childrenJSON,
+		&email.AutoResponse, &email.Category, &listJSON,
+		&warningsJSON, &charsetJSON,
+		&email.DateTZOffset, &email.DateSource,
+		&linksJSON,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -132,9 +501,7 @@ func (r *PostgresEmailRepo) GetByID(ctx context.Context, id string) (*EmailEntit
 		return nil, err
 	}
 
-	if dateNT.Valid {
-		email.Date = &dateNT.Time
-	}
+	email.applyDate(dateNT)
 	if confNF.Valid {
 		email.Confidence = confNF.Float64
 	}
@@ -144,14 +511,37 @@ func (r *PostgresEmailRepo) GetByID(ctx context.Context, id string) (*EmailEntit
 	if len(headersJSON) > 0 {
 		_ = json.Unmarshal(headersJSON, &email.Headers)
 	}
+	email.applyAddressesJSON(addressesJSON)
+	email.applyAuthJSON(dkimJSON, spfJSON, dmarcJSON)
+	if len(hopsJSON) > 0 {
+		_ = json.Unmarshal(hopsJSON, &email.Hops)
+	}
+	if len(smimeJSON) > 0 {
+		_ = json.Unmarshal(smimeJSON, &email.SMIME)
+	}
+	if len(pgpJSON) > 0 {
+		_ = json.Unmarshal(pgpJSON, &email.PGP)
+	}
+	email.applyChildrenJSON(childrenJSON)
+	if len(listJSON) > 0 {
+		_ = json.Unmarshal(listJSON, &email.List)
+	}
+	if len(warningsJSON) > 0 {
+		_ = json.Unmarshal(warningsJSON, &email.ParseWarnings)
+	}
+	if len(charsetJSON) > 0 {
+		_ = json.Unmarshal(charsetJSON, &email.Charset)
+	}
+	email.applyLinksJSON(linksJSON)
 	return &email, nil
 }
 
-func (r *PostgresEmailRepo) GetAll(ctx context.Context, limit, offset int) ([]*EmailEntity, error) {
+func (r *PostgresEmailRepo) GetAll(ctx context.Context, limit, offset int, filter EmailFilter) ([]*EmailEntity, error) {
 	if limit <= 0 {
 		limit = 100
 	}
-	rows, err := r.pool.Query(ctx, selectAll, limit, offset)
+	query, args := buildListQuery(limit, offset, filter)
+	rows, err := r.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -160,7 +550,7 @@ func (r *PostgresEmailRepo) GetAll(ctx context.Context, limit, offset int) ([]*E
 	out := make([]*EmailEntity, 0, limit)
 	for rows.Next() {
 		var e EmailEntity
-		var metricsJSON, headersJSON []byte
+		var metricsJSON, headersJSON, addressesJSON, dkimJSON, spfJSON, dmarcJSON, hopsJSON, smimeJSON, pgpJSON, childrenJSON, listJSON, warningsJSON, charsetJSON, linksJSON []byte
 		var dateNT sql.NullTime
 		var confNF sql.NullFloat64
 
@@ -168,12 +558,19 @@ func (r *PostgresEmailRepo) GetAll(ctx context.Context, limit, offset int) ([]*E
 			&e.ID, &e.MessageID, &e.From, &e.To, &e.Subject, &dateNT,
 			&e.Text, &e.HTML, &e.Language, &confNF,
 			&metricsJSON, &headersJSON, &e.CreatedAt, &e.RawSize,
+			&e.InReplyTo, &e.References, &e.ThreadID,
+			&addressesJSON, &dkimJSON, &spfJSON, &dmarcJSON,
+			&hopsJSON, &e.TransitSeconds, &e.OriginIP,
+			&smimeJSON, &pgpJSON,
+			&e.ParentID, &e.PartPath, &childrenJSON,
+			&e.AutoResponse, &e.Category, &listJSON,
+			&warningsJSON, &charsetJSON,
+			&e.DateTZOffset, &e.DateSource,
+			&linksJSON,
 		); err != nil {
 			return nil, err
 		}
-		if dateNT.Valid {
-			e.Date = &dateNT.Time
-		}
+		e.applyDate(dateNT)
 		if confNF.Valid {
 			e.Confidence = confNF.Float64
 		}
@@ -183,6 +580,28 @@ func (r *PostgresEmailRepo) GetAll(ctx context.Context, limit, offset int) ([]*E
 		if len(headersJSON) > 0 {
 			_ = json.Unmarshal(headersJSON, &e.Headers)
 		}
+		e.applyAddressesJSON(addressesJSON)
+		e.applyAuthJSON(dkimJSON, spfJSON, dmarcJSON)
+		if len(hopsJSON) > 0 {
+			_ = json.Unmarshal(hopsJSON, &e.Hops)
+		}
+		if len(smimeJSON) > 0 {
+			_ = json.Unmarshal(smimeJSON, &e.SMIME)
+		}
+		if len(pgpJSON) > 0 {
+			_ = json.Unmarshal(pgpJSON, &e.PGP)
+		}
+		e.applyChildrenJSON(childrenJSON)
+		if len(listJSON) > 0 {
+			_ = json.Unmarshal(listJSON, &e.List)
+		}
+		if len(warningsJSON) > 0 {
+			_ = json.Unmarshal(warningsJSON, &e.ParseWarnings)
+		}
+		if len(charsetJSON) > 0 {
+			_ = json.Unmarshal(charsetJSON, &e.Charset)
+		}
+		e.applyLinksJSON(linksJSON)
 		out = append(out, &e)
 	}
 	if rows.Err() != nil {
@@ -190,3 +609,43 @@ func (r *PostgresEmailRepo) GetAll(ctx context.Context, limit, offset int) ([]*E
 	}
 	return out, nil
 }
+
+// applyDate sets Date in the sender's zone, which timestamptz does not keep.
+func (e *EmailEntity) applyDate(dateNT sql.NullTime) {
+	if !dateNT.Valid {
+		return
+	}
+	t := dateNT.Time
+	if e.DateTZOffset != nil {
+		t = t.In(time.FixedZone("", *e.DateTZOffset*60))
+	}
+	e.Date = &t
+}
+
+// nullString maps an empty string to SQL NULL, for optional uuid columns.
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// jsonOrNull marshals v, mapping a nil pointer to SQL NULL rather than JSON null.
+func jsonOrNull[T any](v *T) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+func (e *EmailEntity) applyAuthJSON(dkim, spf, dmarc []byte) {
+	if len(dkim) > 0 {
+		_ = json.Unmarshal(dkim, &e.DKIM)
+	}
+	if len(spf) > 0 {
+		_ = json.Unmarshal(spf, &e.SPF)
+	}
+	if len(dmarc) > 0 {
+		_ = json.Unmarshal(dmarc, &e.DMARC)
+	}
+}
@@ -104,7 +104,7 @@ func TestPostgresEmailRepo_GetAll_Success(t *testing.T) {
 	mp := &mockPoolQuery{rows: rows}
 	repo := &PostgresEmailRepo{pool: mp}
 
-	got, err := repo.GetAll(context.Background(), 10, 0)
+	got, err := repo.GetAll(context.Background(), 10, 0, EmailFilter{})
 	if err != nil {
 		t.Fatalf("getall: %v", err)
 	}
@@ -126,7 +126,7 @@ func TestPostgresEmailRepo_GetAll_ScanError(t *testing.T) {
 	rows := &fakeRows{scans: []func(dest ...any) error{func(dest ...any) error { return stdsql.ErrNoRows }}}
 	mp := &mockPoolQuery{rows: rows}
 	repo := &PostgresEmailRepo{pool: mp}
-	_, err := repo.GetAll(context.Background(), 10, 0)
+	_, err := repo.GetAll(context.Background(), 10, 0, EmailFilter{})
 	if err == nil {
 		t.Fatalf("expected error from scan")
 	}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/Zifeldev/emailback/service/internal/mailheader"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
@@ -17,6 +19,8 @@ type mockPool struct {
 	execArgs []interface{}
 	execErr  error
 	row      pgx.Row
+	rowSQL   string
+	rowArgs  []interface{}
 }
 
 func (m *mockPool) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
@@ -31,6 +35,8 @@ func (m *mockPool) Query(ctx context.Context, sql string, args ...interface{}) (
 	panic("not used in tests")
 }
 func (m *mockPool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	m.rowSQL = sql
+	m.rowArgs = args
 	return m.row
 }
 
@@ -39,30 +45,66 @@ type mockRow struct{ scan func(dest ...any) error }
 func (r mockRow) Scan(dest ...any) error { return r.scan(dest...) }
 
 func TestPostgresEmailRepo_SaveEmail_Args(t *testing.T) {
-	mp := &mockPool{}
+	mp := &mockPool{row: mockRow{scan: func(dest ...any) error {
+		*(dest[0].(*string)) = "id1"
+		return nil
+	}}}
 	repo := &PostgresEmailRepo{pool: mp}
 	now := time.Now().UTC()
 	e := &EmailEntity{
 		ID: "id1", MessageID: "m1", From: "a", To: []string{"b", "c"}, Subject: "sub",
 		Date: &now, Text: "text", HTML: "<p>h</p>", Language: "en", Confidence: 0.9,
-		Metrics: map[string]interface{}{"w": 1}, Headers: map[string]string{"X": "y"},
+		Metrics: map[string]interface{}{"w": 1}, Headers: []mailheader.Field{{Name: "X", Raw: "y", Value: "y"}},
 		CreatedAt: now, RawSize: 42,
 	}
 	if err := repo.SaveEmail(context.Background(), e); err != nil {
 		t.Fatalf("save: %v", err)
 	}
-	if len(mp.execArgs) != 14 {
-		t.Fatalf("expected 14 args, got %d", len(mp.execArgs))
+	if len(mp.rowArgs) != 36 {
+		t.Fatalf("expected 36 args, got %d", len(mp.rowArgs))
+	}
+	if mp.rowArgs[0] != "id1" || mp.rowArgs[1] != "m1" || mp.rowArgs[2] != "a" {
+		t.Fatalf("unexpected args prefix: %v", mp.rowArgs[:3])
+	}
+	if got := string(mp.rowArgs[18].([]byte)); got != "[]" {
+		t.Fatalf("dkim results should default to an empty array, got %s", got)
+	}
+	if got := string(mp.rowArgs[32].([]byte)); got != "[]" {
+		t.Fatalf("parse warnings should default to an empty array, got %s", got)
+	}
+	if mp.rowArgs[35] != DateSourceHeader {
+		t.Fatalf("date source should default to header when Date is set, got %v", mp.rowArgs[35])
+	}
+}
+
+func TestPostgresEmailRepo_SaveEmail_AdoptsExistingIDAndStoresAttachments(t *testing.T) {
+	mp := &mockPool{row: mockRow{scan: func(dest ...any) error {
+		*(dest[0].(*string)) = "existing-id"
+		return nil
+	}}}
+	repo := &PostgresEmailRepo{pool: mp}
+	e := &EmailEntity{
+		ID: "new-id", MessageID: "m1",
+		Attachments: []AttachmentEntity{{ID: "att-1", Filename: "a.pdf", SHA256: "abc", StorageKey: "attachments/ab/abc"}},
+	}
+	if err := repo.SaveEmail(context.Background(), e); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if e.ID != "existing-id" {
+		t.Fatalf("expected id adopted from db, got %q", e.ID)
 	}
-	if mp.execArgs[0] != "id1" || mp.execArgs[1] != "m1" || mp.execArgs[2] != "a" {
-		t.Fatalf("unexpected args prefix: %v", mp.execArgs[:3])
+	if e.Attachments[0].EmailID != "existing-id" {
+		t.Fatalf("attachment not linked to stored email: %+v", e.Attachments[0])
+	}
+	if mp.execSQL != insertAttachment || mp.execArgs[0] != "att-1" || mp.execArgs[1] != "existing-id" {
+		t.Fatalf("unexpected attachment insert: %s %v", mp.execSQL, mp.execArgs)
 	}
 }
 
 func TestPostgresEmailRepo_GetByID_Success(t *testing.T) {
 	now := time.Now().UTC()
 	metrics := map[string]interface{}{"raw_size": 10}
-	headers := map[string]string{"h": "v"}
+	headers := []mailheader.Field{{Name: "Received", Raw: "from a", Value: "from a"}, {Name: "Received", Raw: "from b", Value: "from b"}}
 	metricsJSON, _ := json.Marshal(metrics)
 	headersJSON, _ := json.Marshal(headers)
 
@@ -84,6 +126,8 @@ func TestPostgresEmailRepo_GetByID_Success(t *testing.T) {
 		*(dest[11].(*[]byte)) = headersJSON
 		*(dest[12].(*time.Time)) = now
 		*(dest[13].(*int)) = 55
+		off := 180
+		*(dest[34].(**int)) = &off
 		return nil
 	}}
 
@@ -99,13 +143,16 @@ func TestPostgresEmailRepo_GetByID_Success(t *testing.T) {
 	if got.Date == nil || !got.Date.Equal(now) {
 		t.Fatalf("bad date: %v", got.Date)
 	}
+	if _, off := got.Date.Zone(); off != 3*3600 {
+		t.Fatalf("date not in the sender's zone: %v", got.Date)
+	}
 	if got.RawSize != 55 {
 		t.Fatalf("rawsize: %d", got.RawSize)
 	}
 	if got.Metrics["raw_size"].(float64) != 10 {
 		t.Fatalf("metrics: %v", got.Metrics)
 	}
-	if !reflect.DeepEqual(got.Headers["h"], "v") {
+	if !reflect.DeepEqual(got.Headers, headers) {
 		t.Fatalf("headers: %v", got.Headers)
 	}
 }
@@ -119,3 +166,64 @@ func TestPostgresEmailRepo_GetByID_NotFound(t *testing.T) {
 		t.Fatalf("expected not found, got %v %v", got, err)
 	}
 }
+
+// txPool hands out fakeTx transactions that run statements on the mockPool.
+type txPool struct {
+	*mockPool
+	tx *fakeTx
+}
+
+func (p *txPool) Begin(ctx context.Context) (pgx.Tx, error) {
+	p.tx = &fakeTx{mockPool: p.mockPool}
+	return p.tx, nil
+}
+
+type fakeTx struct {
+	pgx.Tx
+	*mockPool
+	committed, rolledBack bool
+}
+
+func (t *fakeTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return t.mockPool.Exec(ctx, sql, args...)
+}
+func (t *fakeTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return t.mockPool.Query(ctx, sql, args...)
+}
+func (t *fakeTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return t.mockPool.QueryRow(ctx, sql, args...)
+}
+func (t *fakeTx) Commit(ctx context.Context) error {
+	t.committed = true
+	return nil
+}
+func (t *fakeTx) Rollback(ctx context.Context) error {
+	if !t.committed {
+		t.rolledBack = true
+	}
+	return nil
+}
+
+func TestPostgresEmailRepo_SaveEmail_Transaction(t *testing.T) {
+	scan := mockRow{scan: func(dest ...any) error {
+		*(dest[0].(*string)) = "id1"
+		return nil
+	}}
+	p := &txPool{mockPool: &mockPool{row: scan}}
+	repo := &PostgresEmailRepo{pool: p}
+	if err := repo.SaveEmail(context.Background(), &EmailEntity{ID: "id1", MessageID: "m1"}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if !p.tx.committed || p.tx.rolledBack {
+		t.Fatalf("expected commit, got %+v", p.tx)
+	}
+
+	p = &txPool{mockPool: &mockPool{row: scan, execErr: errors.New("attachments: timeout")}}
+	repo = &PostgresEmailRepo{pool: p}
+	if err := repo.SaveEmail(context.Background(), &EmailEntity{ID: "id1", MessageID: "m1"}); err == nil {
+		t.Fatal("expected the failed statement's error")
+	}
+	if p.tx.committed || !p.tx.rolledBack {
+		t.Fatalf("expected rollback, got %+v", p.tx)
+	}
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Zifeldev/emailback/service/internal/ical"
+)
+
+// EventEntity is a VEVENT from a text/calendar part or .ics attachment.
+type EventEntity struct {
+	ID           string          `db:"id" json:"id"`
+	EmailID      string          `db:"email_id" json:"email_id"`
+	Method       string          `db:"method" json:"method,omitempty"`
+	UID          string          `db:"uid" json:"uid"`
+	RecurrenceID string          `db:"recurrence_id" json:"recurrence_id,omitempty"`
+	Sequence     int             `db:"sequence" json:"sequence"`
+	Summary      string          `db:"summary" json:"summary"`
+	Description  string          `db:"description" json:"description,omitempty"`
+	Location     string          `db:"location" json:"location,omitempty"`
+	Status       string          `db:"status" json:"status,omitempty"`
+	Organizer    *ical.Attendee  `db:"organizer" json:"organizer,omitempty"`
+	Attendees    []ical.Attendee `db:"attendees" json:"attendees"`
+	Start        *time.Time      `db:"dtstart" json:"start,omitempty"`
+	End          *time.Time      `db:"dtend" json:"end,omitempty"`
+	AllDay       bool            `db:"all_day" json:"all_day"`
+	TZID         string          `db:"tzid" json:"tzid,omitempty"`
+	RRule        string          `db:"rrule" json:"rrule,omitempty"`
+	SeriesEnd    *time.Time      `db:"series_end" json:"-"`
+	CreatedAt    time.Time       `db:"created_at" json:"created_at"`
+}
+
+// EventFilter narrows ListEvents to events overlapping [From, To). Recurring
+// events are kept from their first occurrence until their series ends, since
+// the series is not expanded.
+type EventFilter struct {
+	From *time.Time
+	To   *time.Time
+}
+
+type EventRepository interface {
+	ListEmailEvents(ctx context.Context, emailID string) ([]*EventEntity, error)
+	ListEvents(ctx context.Context, limit, offset int, filter EventFilter) ([]*EventEntity, error)
+}
+
+const deleteEvents = `DELETE FROM email_events WHERE email_id = $1`
+
+const insertEvent = `
+INSERT INTO email_events (
+  id, email_id, method, uid, recurrence_id, sequence, summary, description,
+  location, status, organizer, attendees, dtstart, dtend, all_day, tzid, rrule, series_end, created_at
+) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19)
+`
+
+const eventColumns = `
+       id, email_id, method, uid, recurrence_id, sequence, summary, description,
+       location, status, organizer, attendees, dtstart, dtend, all_day, tzid, rrule, series_end, created_at
+`
+
+const selectEmailEvents = `SELECT` + eventColumns + `FROM email_events WHERE email_id = $1 ORDER BY dtstart NULLS LAST, uid`
+
+// saveEvents replaces the calendar events of an email.
+func (r *PostgresEmailRepo) saveEvents(ctx context.Context, email *EmailEntity) error {
+	if _, err := r.pool.Exec(ctx, deleteEvents, email.ID); err != nil {
+		return err
+	}
+	for i := range email.Events {
+		e := &email.Events[i]
+		e.EmailID = email.ID
+		if e.CreatedAt.IsZero() {
+			e.CreatedAt = time.Now().UTC()
+		}
+		organizerJSON, err := jsonOrNull(e.Organizer)
+		if err != nil {
+			return err
+		}
+		attendeesJSON, err := json.Marshal(nonNil(e.Attendees))
+		if err != nil {
+			return err
+		}
+		if _, err := r.pool.Exec(ctx, insertEvent,
+			e.ID, e.EmailID, e.Method, e.UID, e.RecurrenceID, e.Sequence, e.Summary, e.Description,
+			e.Location, e.Status, organizerJSON, attendeesJSON, e.Start, e.End, e.AllDay, e.TZID, e.RRule, e.SeriesEnd, e.CreatedAt,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *PostgresEmailRepo) ListEmailEvents(ctx context.Context, emailID string) ([]*EventEntity, error) {
+	return r.queryEvents(ctx, selectEmailEvents, emailID)
+}
+
+func (r *PostgresEmailRepo) ListEvents(ctx context.Context, limit, offset int, filter EventFilter) ([]*EventEntity, error) {
+	query, args := buildEventListQuery(limit, offset, filter)
+	return r.queryEvents(ctx, query, args...)
+}
+
+// buildEventListQuery renders the ListEvents query; every filter value is bound as a parameter.
+func buildEventListQuery(limit, offset int, f EventFilter) (string, []interface{}) {
+	var where []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if f.From != nil {
+		from := arg(*f.From)
+		where = append(where, "(COALESCE(dtend, dtstart) > "+from+
+			" OR (rrule <> '' AND (series_end IS NULL OR series_end > "+from+")))")
+	}
+	if f.To != nil {
+		where = append(where, "dtstart < "+arg(*f.To))
+	}
+
+	q := `SELECT` + eventColumns + `FROM email_events`
+	if len(where) > 0 {
+		q += "\nWHERE " + strings.Join(where, "\n  AND ")
+	}
+	q += "\nORDER BY dtstart NULLS LAST, uid\nLIMIT " + arg(limit) + " OFFSET " + arg(offset)
+	return q, args
+}
+
+func (r *PostgresEmailRepo) queryEvents(ctx context.Context, query string, args ...interface{}) ([]*EventEntity, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]*EventEntity, 0, 4)
+	for rows.Next() {
+		var e EventEntity
+		var organizerJSON, attendeesJSON []byte
+		if err := rows.Scan(
+			&e.ID, &e.EmailID, &e.Method, &e.UID, &e.RecurrenceID, &e.Sequence, &e.Summary, &e.Description,
+			&e.Location, &e.Status, &organizerJSON, &attendeesJSON, &e.Start, &e.End, &e.AllDay, &e.TZID, &e.RRule, &e.SeriesEnd, &e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if len(organizerJSON) > 0 {
+			_ = json.Unmarshal(organizerJSON, &e.Organizer)
+		}
+		if len(attendeesJSON) > 0 {
+			_ = json.Unmarshal(attendeesJSON, &e.Attendees)
+		}
+		e.Attendees = nonNil(e.Attendees)
+		out = append(out, &e)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
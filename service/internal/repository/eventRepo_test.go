@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Zifeldev/emailback/service/internal/ical"
+)
+
+func TestPostgresEmailRepo_SaveEvents(t *testing.T) {
+	mp := &mockPool{}
+	repo := &PostgresEmailRepo{pool: mp}
+	start := time.Date(2024, 7, 15, 8, 0, 0, 0, time.UTC)
+	e := &EmailEntity{ID: "id1", Events: []EventEntity{{
+		ID: "ev1", UID: "uid-1", Method: ical.MethodRequest, Start: &start,
+		Organizer: &ical.Attendee{Email: "jane@example.com"},
+	}}}
+	if err := repo.saveEvents(context.Background(), e); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if mp.execSQL != insertEvent || len(mp.execArgs) != 19 {
+		t.Fatalf("unexpected insert: %s %d args", mp.execSQL, len(mp.execArgs))
+	}
+	if mp.execArgs[1] != "id1" || string(mp.execArgs[11].([]byte)) != "[]" {
+		t.Fatalf("unexpected args: %v", mp.execArgs)
+	}
+}
+
+func TestPostgresEmailRepo_ListEmailEvents(t *testing.T) {
+	now := time.Now().UTC()
+	scan := func(dest ...any) error {
+		*(dest[0].(*string)) = "ev1"
+		*(dest[1].(*string)) = "email-1"
+		*(dest[3].(*string)) = "uid-1"
+		*(dest[10].(*[]byte)) = []byte(`{"email":"jane@example.com"}`)
+		*(dest[11].(*[]byte)) = []byte(`[{"email":"bob@example.com","partstat":"ACCEPTED"}]`)
+		*(dest[12].(**time.Time)) = &now
+		return nil
+	}
+	repo := &PostgresEmailRepo{pool: &mockPoolQuery{rows: &fakeRows{scans: []func(dest ...any) error{scan}}}}
+	got, err := repo.ListEmailEvents(context.Background(), "email-1")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(got) != 1 || got[0].Organizer == nil || got[0].Organizer.Email != "jane@example.com" {
+		t.Fatalf("unexpected events: %+v", got)
+	}
+	if a := got[0].Attendees; len(a) != 1 || a[0].PartStat != "ACCEPTED" {
+		t.Fatalf("unexpected attendees: %+v", a)
+	}
+}
+
+func TestBuildEventListQuery(t *testing.T) {
+	from := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	q, args := buildEventListQuery(20, 0, EventFilter{From: &from, To: &to})
+	if !strings.Contains(q, "COALESCE(dtend, dtstart) > $1") || !strings.Contains(q, "series_end > $1") || !strings.Contains(q, "dtstart < $2") {
+		t.Fatalf("unexpected query: %s", q)
+	}
+	if len(args) != 4 || args[0] != from || args[1] != to || args[2] != 20 {
+		t.Fatalf("unexpected args: %v", args)
+	}
+
+	q, args = buildEventListQuery(20, 0, EventFilter{})
+	if strings.Contains(q, "WHERE") || len(args) != 2 {
+		t.Fatalf("unexpected unfiltered query: %s %v", q, args)
+	}
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Zifeldev/emailback/service/internal/links"
+)
+
+const deleteLinks = `DELETE FROM email_links WHERE email_id = $1`
+
+const insertLinks = `
+INSERT INTO email_links (email_id, position, url, text, domain, source, text_domain, mismatch)
+SELECT $1, p, u, t, d, s, td, m
+FROM unnest($2::int[], $3::text[], $4::text[], $5::text[], $6::text[], $7::text[], $8::bool[]) AS l(p, u, t, d, s, td, m)
+`
+
+// selectLinksJSON is embedded as a column of the email selects, like
+// selectAddressesJSON.
+const selectLinksJSON = `(SELECT COALESCE(jsonb_agg(jsonb_build_object(
+         'url', l.url, 'text', l.text, 'domain', l.domain, 'source', l.source,
+         'text_domain', l.text_domain, 'mismatch', l.mismatch) ORDER BY l.position), '[]'::jsonb)
+       FROM email_links l WHERE l.email_id = emails.id)`
+
+func (e *EmailEntity) applyLinksJSON(bs []byte) {
+	if len(bs) == 0 {
+		return
+	}
+	var list []links.Link
+	if json.Unmarshal(bs, &list) == nil && len(list) > 0 {
+		e.Links = list
+	}
+}
+
+func (r *PostgresEmailRepo) saveLinks(ctx context.Context, email *EmailEntity) error {
+	if _, err := r.pool.Exec(ctx, deleteLinks, email.ID); err != nil {
+		return err
+	}
+	if len(email.Links) == 0 {
+		return nil
+	}
+	n := len(email.Links)
+	positions := make([]int32, n)
+	urls := make([]string, n)
+	texts := make([]string, n)
+	domains := make([]string, n)
+	sources := make([]string, n)
+	textDomains := make([]string, n)
+	mismatches := make([]bool, n)
+	for i, l := range email.Links {
+		positions[i] = int32(i)
+		urls[i] = l.URL
+		texts[i] = l.Text
+		domains[i] = l.Domain
+		sources[i] = l.Source
+		textDomains[i] = l.TextDomain
+		mismatches[i] = l.Mismatch
+	}
+	_, err := r.pool.Exec(ctx, insertLinks, email.ID, positions, urls, texts, domains, sources, textDomains, mismatches)
+	return err
+}
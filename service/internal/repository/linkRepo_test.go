@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Zifeldev/emailback/service/internal/links"
+)
+
+func TestPostgresEmailRepo_SaveLinks(t *testing.T) {
+	mp := &mockPool{}
+	repo := &PostgresEmailRepo{pool: mp}
+	e := &EmailEntity{ID: "id1", Links: []links.Link{
+		{URL: "https://evil.example.net/login", Text: "paypal.com", Domain: "evil.example.net", Source: links.SourceHTML, TextDomain: "paypal.com", Mismatch: true},
+		{URL: "https://example.com/", Domain: "example.com", Source: links.SourceText},
+	}}
+	if err := repo.saveLinks(context.Background(), e); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if mp.execSQL != insertLinks {
+		t.Fatalf("expected batch insert, got %s", mp.execSQL)
+	}
+	domains := mp.execArgs[4].([]string)
+	mismatches := mp.execArgs[7].([]bool)
+	if len(domains) != 2 || domains[0] != "evil.example.net" || !mismatches[0] || mismatches[1] {
+		t.Fatalf("unexpected args: %v %v", domains, mismatches)
+	}
+
+	var got EmailEntity
+	got.applyLinksJSON([]byte(`[{"url":"https://example.com/","text":"","domain":"example.com","source":"text","text_domain":"","mismatch":false}]`))
+	if len(got.Links) != 1 || got.Links[0].Domain != "example.com" {
+		t.Fatalf("unexpected decoded links: %+v", got.Links)
+	}
+	got = EmailEntity{}
+	got.applyLinksJSON([]byte(`[]`))
+	if got.Links != nil {
+		t.Fatalf("no links should stay nil, got %+v", got.Links)
+	}
+}
+
+func TestBuildListQuery_LinkDomainFilter(t *testing.T) {
+	q, args := buildListQuery(10, 0, EmailFilter{LinkDomain: "Example.net"})
+	if !strings.Contains(q, "FROM email_links l") || !strings.Contains(q, "right(l.domain, length($1) + 1) = '.' || lower($1)") {
+		t.Fatalf("unexpected query: %s", q)
+	}
+	if len(args) != 3 || args[0] != "Example.net" {
+		t.Fatalf("unexpected args: %v", args)
+	}
+}
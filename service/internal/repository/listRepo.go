@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Zifeldev/emailback/service/internal/mailinglist"
+)
+
+// MailingListEntity aggregates the stored messages of one mailing list or
+// newsletter. Messages with a List-Id are grouped by it; senders that only
+// set List-Unsubscribe are grouped by From address, with ListID empty.
+type MailingListEntity struct {
+	ListID      string    `json:"list_id,omitempty"`
+	Name        string    `json:"name,omitempty"`
+	Senders     []string  `json:"senders"`
+	Messages    int       `json:"messages"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	Unsubscribe []string  `json:"unsubscribe,omitempty"`
+	// OneClickURI is set when the latest message allows RFC 8058 one-click
+	// unsubscribe: a POST with body "List-Unsubscribe=One-Click".
+	OneClickURI string   `json:"one_click_uri,omitempty"`
+	Archive     []string `json:"archive,omitempty"`
+}
+
+type MailingListRepository interface {
+	ListMailingLists(ctx context.Context, limit, offset int) ([]*MailingListEntity, error)
+}
+
+// Unsubscribe targets and names come from the most recent message, since
+// senders rotate their unsubscribe links.
+const selectMailingLists = `
+WITH l AS (
+  SELECT COALESCE(NULLIF(list_id, ''), lower(from_addr)) AS key,
+         list_id, lower(from_addr) AS sender, list,
+         COALESCE(date, created_at) AS seen
+  FROM emails
+  WHERE list IS NOT NULL
+)
+SELECT max(list_id), array_agg(DISTINCT sender ORDER BY sender),
+       count(*), min(seen), max(seen),
+       (array_agg(list ORDER BY seen DESC))[1]
+FROM l
+GROUP BY key
+ORDER BY max(seen) DESC, key
+LIMIT $1 OFFSET $2
+`
+
+func (r *PostgresEmailRepo) ListMailingLists(ctx context.Context, limit, offset int) ([]*MailingListEntity, error) {
+	rows, err := r.pool.Query(ctx, selectMailingLists, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]*MailingListEntity, 0, limit)
+	for rows.Next() {
+		var m MailingListEntity
+		var latestJSON []byte
+		if err := rows.Scan(&m.ListID, &m.Senders, &m.Messages, &m.FirstSeen, &m.LastSeen, &latestJSON); err != nil {
+			return nil, err
+		}
+		var latest mailinglist.Info
+		if len(latestJSON) > 0 {
+			_ = json.Unmarshal(latestJSON, &latest)
+		}
+		m.Name = latest.Name
+		m.Unsubscribe = latest.Unsubscribe
+		m.Archive = latest.Archive
+		if latest.OneClick {
+			m.OneClickURI = mailinglist.OneClickURI(latest.Unsubscribe)
+		}
+		m.Senders = nonNil(m.Senders)
+		out = append(out, &m)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPostgresEmailRepo_ListMailingLists(t *testing.T) {
+	now := time.Now().UTC()
+	scan := func(dest ...any) error {
+		*(dest[0].(*string)) = "news.acme.example"
+		*(dest[1].(*[]string)) = []string{"news@acme.example"}
+		*(dest[2].(*int)) = 12
+		*(dest[3].(*time.Time)) = now.AddDate(0, -3, 0)
+		*(dest[4].(*time.Time)) = now
+		*(dest[5].(*[]byte)) = []byte(`{"id":"news.acme.example","name":"Acme News","unsubscribe":["mailto:u@acme.example","https://acme.example/u"],"one_click_unsubscribe":true}`)
+		return nil
+	}
+	repo := &PostgresEmailRepo{pool: &mockPoolQuery{rows: &fakeRows{scans: []func(dest ...any) error{scan}}}}
+	got, err := repo.ListMailingLists(context.Background(), 10, 0)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected one list, got %d", len(got))
+	}
+	m := got[0]
+	if m.Name != "Acme News" || m.Messages != 12 || len(m.Unsubscribe) != 2 || m.OneClickURI != "https://acme.example/u" {
+		t.Fatalf("unexpected list %+v", m)
+	}
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RawMessageEntity points at the original RFC822 bytes of an email in the blob store.
+type RawMessageEntity struct {
+	EmailID    string    `db:"email_id" json:"email_id"`
+	StorageKey string    `db:"storage_key" json:"-"`
+	SHA256     string    `db:"sha256" json:"sha256"`
+	Size       int64     `db:"size" json:"size"`
+	Encoding   string    `db:"encoding" json:"encoding"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
+
+type RawMessageRepository interface {
+	GetRawMessage(ctx context.Context, emailID string) (*RawMessageEntity, error)
+}
+
+var ErrRawMessageNotFound = errors.New("raw message not found")
+
+const upsertRawMessage = `
+INSERT INTO raw_messages (email_id, storage_key, sha256, size, encoding, created_at)
+VALUES ($1,$2,$3,$4,$5,$6)
+ON CONFLICT (email_id) DO UPDATE SET
+  storage_key = EXCLUDED.storage_key,
+  sha256 = EXCLUDED.sha256,
+  size = EXCLUDED.size,
+  encoding = EXCLUDED.encoding
+`
+
+const selectRawMessage = `
+SELECT email_id, storage_key, sha256, size, encoding, created_at
+FROM raw_messages WHERE email_id = $1
+`
+
+func (r *PostgresEmailRepo) saveRawMessage(ctx context.Context, email *EmailEntity) error {
+	if email.RawMessage == nil {
+		return nil
+	}
+	m := email.RawMessage
+	m.EmailID = email.ID
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now().UTC()
+	}
+	_, err := r.pool.Exec(ctx, upsertRawMessage,
+		m.EmailID, m.StorageKey, m.SHA256, m.Size, m.Encoding, m.CreatedAt,
+	)
+	return err
+}
+
+func (r *PostgresEmailRepo) GetRawMessage(ctx context.Context, emailID string) (*RawMessageEntity, error) {
+	var m RawMessageEntity
+	err := r.pool.QueryRow(ctx, selectRawMessage, emailID).Scan(
+		&m.EmailID, &m.StorageKey, &m.SHA256, &m.Size, &m.Encoding, &m.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRawMessageNotFound
+		}
+		return nil, err
+	}
+	return &m, nil
+}
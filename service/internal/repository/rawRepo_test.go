@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestPostgresEmailRepo_SaveEmail_StoresRawMessage(t *testing.T) {
+	mp := &mockPool{row: mockRow{scan: func(dest ...any) error {
+		*(dest[0].(*string)) = "id1"
+		return nil
+	}}}
+	repo := &PostgresEmailRepo{pool: mp}
+	e := &EmailEntity{ID: "id1", MessageID: "m1", RawMessage: &RawMessageEntity{StorageKey: "raw/ab/abc.eml.gz", SHA256: "abc", Size: 10, Encoding: "gzip"}}
+	if err := repo.SaveEmail(context.Background(), e); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if mp.execSQL != upsertRawMessage || mp.execArgs[0] != "id1" || mp.execArgs[1] != "raw/ab/abc.eml.gz" {
+		t.Fatalf("unexpected raw upsert: %s %v", mp.execSQL, mp.execArgs)
+	}
+}
+
+func TestPostgresEmailRepo_GetRawMessage(t *testing.T) {
+	now := time.Now().UTC()
+	repo := &PostgresEmailRepo{pool: &mockPool{row: mockRow{scan: func(dest ...any) error {
+		*(dest[0].(*string)) = "id1"
+		*(dest[1].(*string)) = "raw/ab/abc.eml.gz"
+		*(dest[2].(*string)) = "abc"
+		*(dest[3].(*int64)) = 10
+		*(dest[4].(*string)) = "gzip"
+		*(dest[5].(*time.Time)) = now
+		return nil
+	}}}}
+	got, err := repo.GetRawMessage(context.Background(), "id1")
+	if err != nil || got.StorageKey != "raw/ab/abc.eml.gz" || got.Size != 10 {
+		t.Fatalf("unexpected raw message %+v err=%v", got, err)
+	}
+
+	repo = &PostgresEmailRepo{pool: &mockPool{row: mockRow{scan: func(dest ...any) error { return pgx.ErrNoRows }}}}
+	if _, err := repo.GetRawMessage(context.Background(), "nope"); err != ErrRawMessageNotFound {
+		t.Fatalf("expected ErrRawMessageNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Zifeldev/emailback/service/internal/thread"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type ThreadRepository interface {
+	// GetThreadMessages returns every stored message of a thread in no particular order.
+	GetThreadMessages(ctx context.Context, threadID string) ([]*thread.Message, error)
+}
+
+var ErrThreadNotFound = errors.New("thread not found")
+
+// subjectThreadWindow bounds how far back a reply without References is
+// matched to an earlier message by subject alone.
+const subjectThreadWindow = 30 * 24 * time.Hour
+
+const selectThreadByParent = `
+SELECT thread_id::text FROM emails
+WHERE message_id = ANY($1) AND thread_id IS NOT NULL
+ORDER BY created_at
+LIMIT 1
+`
+
+const selectThreadByChild = `
+SELECT thread_id::text FROM emails
+WHERE (in_reply_to = $1 OR references_ids @> ARRAY[$1]::text[]) AND thread_id IS NOT NULL
+ORDER BY created_at
+LIMIT 1
+`
+
+const selectThreadBySubject = `
+SELECT thread_id::text FROM emails
+WHERE thread_subject = $1 AND thread_id IS NOT NULL AND created_at > $2
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+const mergeThreads = `
+WITH moved AS (UPDATE emails SET thread_id = $1 WHERE thread_id = $2 RETURNING id)
+SELECT COALESCE(array_agg(id::text), '{}') FROM moved
+`
+
+// lockThreadKeys serialises thread assignment for messages sharing a
+// Message-ID until the surrounding transaction ends. Keys are taken in a
+// fixed order so that two savers cannot deadlock.
+const lockThreadKeys = `
+SELECT pg_advisory_xact_lock(k)
+FROM (SELECT DISTINCT hashtextextended(id, 0) AS k FROM unnest($1::text[]) AS id ORDER BY k) keys
+`
+
+const selectThreadMessages = `
+SELECT id, message_id, in_reply_to, references_ids, from_addr, subject, date
+FROM emails WHERE thread_id = $1
+`
+
+// assignThread picks the thread of a message before it is stored: the thread
+// of any message it references, else the thread of an already stored reply to
+// it (replies may arrive first), else, for "Re:" subjects from clients that
+// drop threading headers, a recent thread with the same normalized subject.
+// When the message bridges two existing threads they are merged. SaveEmail
+// runs it in its transaction, where the Message-IDs involved stay locked
+// until the email is stored, so concurrent savers see each other's threads.
+func (r *PostgresEmailRepo) assignThread(ctx context.Context, email *EmailEntity) error {
+	if email.ThreadID != "" {
+		return nil
+	}
+
+	parents := make([]string, 0, len(email.References)+1)
+	parents = append(parents, email.References...)
+	if email.InReplyTo != "" {
+		parents = append(parents, email.InReplyTo)
+	}
+
+	keys := parents
+	if email.MessageID != "" {
+		keys = append(keys, email.MessageID)
+	}
+	if len(keys) > 0 {
+		if _, err := r.pool.Exec(ctx, lockThreadKeys, keys); err != nil {
+			return err
+		}
+	}
+
+	var byParent, byChild string
+	if len(parents) > 0 {
+		if err := r.lookupThread(ctx, &byParent, selectThreadByParent, parents); err != nil {
+			return err
+		}
+	}
+	if email.MessageID != "" {
+		if err := r.lookupThread(ctx, &byChild, selectThreadByChild, email.MessageID); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case byParent != "" && byChild != "" && byParent != byChild:
+		if err := r.pool.QueryRow(ctx, mergeThreads, byParent, byChild).Scan(&email.Rethreaded); err != nil {
+			return err
+		}
+		email.ThreadID = byParent
+	case byParent != "":
+		email.ThreadID = byParent
+	case byChild != "":
+		email.ThreadID = byChild
+	case len(parents) == 0 && thread.IsReplySubject(email.Subject):
+		var bySubject string
+		if subj := thread.NormalizeSubject(email.Subject); subj != "" {
+			since := time.Now().Add(-subjectThreadWindow)
+			if err := r.lookupThread(ctx, &bySubject, selectThreadBySubject, subj, since); err != nil {
+				return err
+			}
+		}
+		email.ThreadID = bySubject
+	}
+
+	if email.ThreadID == "" {
+		email.ThreadID = uuid.NewString()
+	}
+	return nil
+}
+
+func (r *PostgresEmailRepo) lookupThread(ctx context.Context, dst *string, query string, args ...interface{}) error {
+	err := r.pool.QueryRow(ctx, query, args...).Scan(dst)
+	if errors.Is(err, pgx.ErrNoRows) {
+		*dst = ""
+		return nil
+	}
+	return err
+}
+
+func (r *PostgresEmailRepo) GetThreadMessages(ctx context.Context, threadID string) ([]*thread.Message, error) {
+	rows, err := r.pool.Query(ctx, selectThreadMessages, threadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]*thread.Message, 0, 8)
+	for rows.Next() {
+		var m thread.Message
+		if err := rows.Scan(&m.ID, &m.MessageID, &m.InReplyTo, &m.References, &m.From, &m.Subject, &m.Date); err != nil {
+			return nil, err
+		}
+		out = append(out, &m)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	if len(out) == 0 {
+		return nil, ErrThreadNotFound
+	}
+	return out, nil
+}
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// scriptedPool answers QueryRow by SQL text so multi-step flows can be tested.
+type scriptedPool struct {
+	rows     map[string]string
+	execs    []string
+	execArgs [][]interface{}
+}
+
+func (s *scriptedPool) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	s.execs = append(s.execs, sql)
+	s.execArgs = append(s.execArgs, args)
+	return pgconn.NewCommandTag("UPDATE 1"), nil
+}
+func (s *scriptedPool) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	panic("not used in tests")
+}
+func (s *scriptedPool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	v, ok := s.rows[sql]
+	return mockRow{scan: func(dest ...any) error {
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		switch d := dest[0].(type) {
+		case *[]string:
+			*d = strings.Split(v, ",")
+		case *string:
+			*d = v
+		}
+		return nil
+	}}
+}
+
+func TestAssignThread_FromReferencedParent(t *testing.T) {
+	p := &scriptedPool{rows: map[string]string{selectThreadByParent: "t-parent"}}
+	repo := &PostgresEmailRepo{pool: p}
+	e := &EmailEntity{MessageID: "c@x", InReplyTo: "a@x", Subject: "Re: plan"}
+	if err := repo.assignThread(context.Background(), e); err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+	if e.ThreadID != "t-parent" {
+		t.Fatalf("expected parent thread, got %q", e.ThreadID)
+	}
+}
+
+func TestAssignThread_MergesWhenBridgingThreads(t *testing.T) {
+	p := &scriptedPool{rows: map[string]string{
+		selectThreadByParent: "t-parent",
+		selectThreadByChild:  "t-child",
+		mergeThreads:         "c1,c2",
+	}}
+	repo := &PostgresEmailRepo{pool: p}
+	e := &EmailEntity{MessageID: "b@x", References: []string{"a@x"}}
+	if err := repo.assignThread(context.Background(), e); err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+	if e.ThreadID != "t-parent" || !slices.Equal(e.Rethreaded, []string{"c1", "c2"}) {
+		t.Fatalf("expected merge into parent thread, got %q rethreaded=%v", e.ThreadID, e.Rethreaded)
+	}
+	if len(p.execs) != 1 || p.execs[0] != lockThreadKeys || !slices.Equal(p.execArgs[0][0].([]string), []string{"a@x", "b@x"}) {
+		t.Fatalf("expected the Message-IDs to be locked first, got %v %v", p.execs, p.execArgs)
+	}
+}
+
+func TestAssignThread_SubjectFallbackOnlyForReplies(t *testing.T) {
+	p := &scriptedPool{rows: map[string]string{selectThreadBySubject: "t-subj"}}
+	repo := &PostgresEmailRepo{pool: p}
+
+	reply := &EmailEntity{MessageID: "r@x", Subject: "RE: Invoice 42"}
+	_ = repo.assignThread(context.Background(), reply)
+	if reply.ThreadID != "t-subj" {
+		t.Fatalf("expected subject thread, got %q", reply.ThreadID)
+	}
+
+	fresh := &EmailEntity{MessageID: "n@x", Subject: "Invoice 42"}
+	_ = repo.assignThread(context.Background(), fresh)
+	if fresh.ThreadID == "" || fresh.ThreadID == "t-subj" {
+		t.Fatalf("expected new thread for non-reply, got %q", fresh.ThreadID)
+	}
+}
@@ -0,0 +1,122 @@
+// Package sanitize makes message HTML safe to embed: an allowlist removes
+// scripts, event handlers, forms and style sheets, cid: references are
+// pointed at stored attachments, and remote images, the usual tracking
+// pixels, can be blocked or sent through a proxy.
+package sanitize
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+const (
+	ImagesAllow = "allow" // remote images load directly
+	ImagesBlock = "block" // remote image sources are removed
+	ImagesProxy = "proxy" // remote images load through Options.ProxyURL
+)
+
+// ImageModes lists the values of Options.Images.
+var ImageModes = []string{ImagesAllow, ImagesBlock, ImagesProxy}
+
+// Options control the URLs left in the output. The zero value only applies
+// the allowlist.
+type Options struct {
+	// CID maps the Content-ID of a cid: image to the URL it is served from;
+	// an empty result, or a nil CID, leaves the reference as is.
+	CID func(contentID string) string
+	// Images is one of ImageModes; empty means ImagesAllow.
+	Images string
+	// ProxyURL is prefixed to the query-escaped address of a remote image in
+	// ImagesProxy mode. Without it remote images are blocked.
+	ProxyURL string
+}
+
+// styles are the inline CSS properties kept; each value is checked by
+// bluemonday. Backgrounds other than a plain colour are left out since they
+// can load remote images.
+var styles = []string{
+	"color", "background-color",
+	"font", "font-family", "font-size", "font-style", "font-weight", "line-height",
+	"letter-spacing", "text-align", "text-decoration", "text-indent", "text-transform",
+	"vertical-align", "white-space", "word-break", "word-wrap", "direction",
+	"margin", "margin-top", "margin-right", "margin-bottom", "margin-left",
+	"padding", "padding-top", "padding-right", "padding-bottom", "padding-left",
+	"border", "border-top", "border-right", "border-bottom", "border-left",
+	"border-color", "border-style", "border-width", "border-collapse", "border-spacing", "border-radius",
+	"width", "min-width", "max-width", "height", "min-height", "max-height",
+	"display", "float", "clear", "overflow", "list-style-type", "table-layout",
+}
+
+var (
+	reLocalSrc = regexp.MustCompile(`(?i)^\s*(cid|data):`)
+	// Presentational attributes that email layouts still rely on.
+	tableAttrs = []string{"align", "valign", "bgcolor", "width", "height", "border", "cellpadding", "cellspacing", "colspan", "rowspan", "nowrap"}
+)
+
+// HTML returns s with everything outside the allowlist removed and image
+// sources rewritten per opts.
+func HTML(s string, opts Options) string {
+	return newPolicy(opts).Sanitize(s)
+}
+
+func newPolicy(opts Options) *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowStandardAttributes()
+	p.AllowStandardURLs()
+	p.AllowURLSchemes("cid")
+	p.AllowDataURIImages()
+	p.RequireNoReferrerOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+
+	p.AllowElements("h1", "h2", "h3", "h4", "h5", "h6", "br", "div", "hr", "p", "span", "wbr",
+		"abbr", "cite", "code", "em", "mark", "s", "strong", "sub", "sup", "b", "i", "pre", "small",
+		"big", "strike", "tt", "u", "center", "font", "address", "blockquote", "q", "del", "ins", "caption")
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowAttrs("color", "face", "size").OnElements("font")
+	p.AllowLists()
+	p.AllowTables()
+	p.AllowAttrs(tableAttrs...).OnElements("table", "thead", "tbody", "tfoot", "tr", "td", "th", "col", "colgroup")
+	p.AllowAttrs("align").OnElements("p", "div", "h1", "h2", "h3", "h4", "h5", "h6")
+	p.AllowStyles(styles...).Globally()
+
+	mode := opts.Images
+	if mode == ImagesProxy && opts.ProxyURL == "" {
+		mode = ImagesBlock
+	}
+	p.AllowAttrs("alt", "title", "width", "height", "align", "border").OnElements("img")
+	if mode == ImagesBlock {
+		p.AllowAttrs("src").Matching(reLocalSrc).OnElements("img")
+	} else {
+		p.AllowAttrs("src").OnElements("img")
+	}
+
+	p.RewriteSrc(func(u *url.URL) {
+		switch strings.ToLower(u.Scheme) {
+		case "cid":
+			if opts.CID == nil {
+				return
+			}
+			id, err := url.PathUnescape(u.Opaque)
+			if err != nil {
+				id = u.Opaque
+			}
+			if target := opts.CID(strings.Trim(id, "<> ")); target != "" {
+				replaceURL(u, target)
+			}
+		case "http", "https":
+			if mode == ImagesProxy {
+				replaceURL(u, opts.ProxyURL+url.QueryEscape(u.String()))
+			}
+		}
+	})
+	return p
+}
+
+func replaceURL(u *url.URL, s string) {
+	if nu, err := url.Parse(s); err == nil {
+		*u = *nu
+	}
+}
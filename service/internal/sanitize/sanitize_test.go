@@ -0,0 +1,69 @@
+package sanitize
+
+import (
+	"strings"
+	"testing"
+)
+
+const message = `<html><head><style>body{background:url(https://t.example/bg.png)}</style><script>alert(1)</script></head>
+<body onload="track()">
+<table width="600" cellpadding="0" bgcolor="#ffffff"><tr><td align="center" style="color: #333333; font-size: 14px; background-image: url(https://t.example/p.gif)">
+<p onclick="steal()">Hello <a href="https://shop.example.com/offer">offer</a> <a href="javascript:alert(1)">js</a></p>
+<img src="cid:logo@example.com" alt="logo">
+<img src="https://t.example/open.gif?u=42" width="1" height="1" alt="">
+<iframe src="https://evil.example/"></iframe>
+<form action="https://evil.example/"><input name="pw"></form>
+</td></tr></table>
+</body></html>`
+
+func TestHTML_Allowlist(t *testing.T) {
+	out := HTML(message, Options{})
+	for _, bad := range []string{"<script", "alert(1)", "onload", "onclick", "javascript:", "<iframe", "<form", "<input", "<style", "background-image", "t.example/bg.png"} {
+		if strings.Contains(out, bad) {
+			t.Fatalf("%q survived sanitizing:\n%s", bad, out)
+		}
+	}
+	for _, good := range []string{
+		`<table width="600" cellpadding="0" bgcolor="#ffffff">`,
+		`style="color: #333333; font-size: 14px"`,
+		`href="https://shop.example.com/offer"`,
+		`rel="nofollow noreferrer noopener"`,
+		`target="_blank"`,
+		`src="cid:logo@example.com"`,
+		`src="https://t.example/open.gif?u=42"`,
+	} {
+		if !strings.Contains(out, good) {
+			t.Fatalf("%q missing from output:\n%s", good, out)
+		}
+	}
+}
+
+func TestHTML_ImagesAndCID(t *testing.T) {
+	cid := func(id string) string {
+		if id == "logo@example.com" {
+			return "/emails/e1/attachments/a1"
+		}
+		return ""
+	}
+
+	out := HTML(message, Options{CID: cid, Images: ImagesBlock})
+	if !strings.Contains(out, `src="/emails/e1/attachments/a1"`) {
+		t.Fatalf("cid image not rewritten:\n%s", out)
+	}
+	if strings.Contains(out, "open.gif") || !strings.Contains(out, `width="1"`) {
+		t.Fatalf("remote image should lose only its source:\n%s", out)
+	}
+
+	out = HTML(message, Options{CID: cid, Images: ImagesProxy, ProxyURL: "https://proxy.example/img?url="})
+	if !strings.Contains(out, `src="https://proxy.example/img?url=https%3A%2F%2Ft.example%2Fopen.gif%3Fu%3D42"`) {
+		t.Fatalf("remote image not proxied:\n%s", out)
+	}
+
+	out = HTML(message, Options{Images: ImagesProxy})
+	if strings.Contains(out, "open.gif") {
+		t.Fatalf("proxy mode without a proxy must block:\n%s", out)
+	}
+	if !strings.Contains(out, `src="cid:logo@example.com"`) {
+		t.Fatalf("unresolved cid should stay:\n%s", out)
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"net/mail"
+	"strings"
+
+	"github.com/Zifeldev/emailback/service/internal/mailheader"
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/jhillyerd/enmime"
+)
+
+// addressList parses every occurrence of an address header, decoding RFC 2047
+// display names itself rather than relying on pre-decoded values, which may
+// contain unquoted commas. Lists that net/mail rejects as a whole are retried entry by
+// entry so one malformed recipient does not drop the others.
+func addressList(env *enmime.Envelope, header string) []repository.Address {
+	if env.Root == nil {
+		return nil
+	}
+	var out []repository.Address
+	for _, v := range env.Root.Header.Values(header) {
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+		if addrs, err := addrParser.ParseList(v); err == nil {
+			for _, a := range addrs {
+				out = append(out, repository.NewAddress(a.Name, a.Address))
+			}
+			continue
+		}
+		for _, piece := range strings.Split(v, ",") {
+			piece = strings.TrimSpace(piece)
+			if piece == "" {
+				continue
+			}
+			if a, err := addrParser.Parse(piece); err == nil {
+				out = append(out, repository.NewAddress(a.Name, a.Address))
+			} else if strings.Contains(piece, "@") {
+				out = append(out, repository.NewAddress("", strings.Trim(piece, "<>\" ")))
+			}
+		}
+	}
+	return out
+}
+
+var addrParser = mail.AddressParser{WordDecoder: mailheader.WordDecoder}
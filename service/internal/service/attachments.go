@@ -0,0 +1,62 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/jhillyerd/enmime"
+)
+
+// extractAttachments collects every non-body part of the envelope (attachments,
+// inline images and related parts) together with its decoded payload.
+func extractAttachments(env *enmime.Envelope) []repository.AttachmentEntity {
+	parts := make([]*enmime.Part, 0, len(env.Attachments)+len(env.Inlines)+len(env.OtherParts))
+	parts = append(parts, env.Attachments...)
+	parts = append(parts, env.Inlines...)
+	parts = append(parts, env.OtherParts...)
+
+	now := time.Now().UTC()
+	seen := make(map[*enmime.Part]struct{}, len(parts))
+	out := make([]repository.AttachmentEntity, 0, len(parts))
+	for _, p := range parts {
+		if p == nil || strings.HasPrefix(p.ContentType, "multipart/") {
+			continue
+		}
+		if _, dup := seen[p]; dup {
+			continue
+		}
+		seen[p] = struct{}{}
+		out = append(out, newAttachment(p.FileName, p.ContentType, p.Disposition, p.ContentID, p.Content, now))
+	}
+	return out
+}
+
+func newAttachment(filename, contentType, disposition, contentID string, data []byte, now time.Time) repository.AttachmentEntity {
+	sum := sha256.Sum256(data)
+	return repository.AttachmentEntity{
+		ID:          uuid.NewString(),
+		Filename:    filename,
+		ContentType: contentType,
+		SniffedType: sniffContentType(data),
+		Disposition: disposition,
+		ContentID:   strings.Trim(contentID, "<> "),
+		Size:        int64(len(data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+		CreatedAt:   now,
+		Data:        data,
+	}
+}
+
+// sniffContentType ignores the sender's declared type and looks at the bytes.
+func sniffContentType(data []byte) string {
+	ct := http.DetectContentType(data)
+	if i := strings.IndexByte(ct, ';'); i >= 0 {
+		ct = ct[:i]
+	}
+	return ct
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/Zifeldev/emailback/service/internal/mailauth"
+	"github.com/Zifeldev/emailback/service/internal/received"
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/jhillyerd/enmime"
+)
+
+// authenticate runs the configured DKIM, SPF and DMARC checks and records the
+// results on the entity. DMARC needs the SPF resolver and uses whatever DKIM
+// results are available.
+func (p *EnmimeParser) authenticate(ctx context.Context, raw []byte, env *enmime.Envelope, e *repository.EmailEntity) {
+	if p.opts.DKIM != nil {
+		e.DKIM = mailauth.VerifyDKIM(ctx, raw, p.opts.DKIM)
+	}
+	if p.opts.DNS == nil {
+		return
+	}
+
+	ip, helo := connectingHost(e.Hops)
+	spf := mailauth.CheckSPF(ctx, p.opts.DNS, mailauth.SPFRequest{
+		IP:       ip,
+		MailFrom: envelopeSender(env),
+		HELO:     helo,
+	})
+	e.SPF = &spf
+
+	fromDomain := ""
+	if e.FromAddress != nil {
+		fromDomain = e.FromAddress.Domain
+	}
+	dmarc := mailauth.CheckDMARC(ctx, p.opts.DNS, fromDomain, e.MessageID, spf, e.DKIM)
+	e.DMARC = &dmarc
+}
+
+// connectingHost returns the client IP and HELO name of the most recent hop
+// that names a public address; internal relay hops are skipped.
+func connectingHost(hops []received.Hop) (net.IP, string) {
+	for i := len(hops) - 1; i >= 0; i-- {
+		if received.IsPublic(hops[i].IP) {
+			return net.ParseIP(hops[i].IP), hops[i].From
+		}
+	}
+	return nil, ""
+}
+
+// envelopeSender returns the RFC5321.MailFrom address recorded in Return-Path.
+func envelopeSender(env *enmime.Envelope) string {
+	rp := strings.TrimSpace(env.GetHeader("Return-Path"))
+	return strings.Trim(rp, "<> ")
+}
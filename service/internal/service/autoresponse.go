@@ -0,0 +1,39 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/Zifeldev/emailback/service/internal/lang"
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/jhillyerd/enmime"
+)
+
+// classifyAutoResponse tells vacation notices, other machine-generated mail
+// and mailing-list traffic apart from mail written by a person. Out-of-office
+// subjects are trusted on their own since many responders set no header;
+// body phrases only decide between vacation and auto-generated.
+func classifyAutoResponse(env *enmime.Envelope, subject, body string) string {
+	submitted := strings.ToLower(strings.TrimSpace(env.GetHeader("Auto-Submitted")))
+	if i := strings.IndexByte(submitted, ';'); i >= 0 {
+		submitted = strings.TrimSpace(submitted[:i])
+	}
+	precedence := strings.ToLower(strings.TrimSpace(env.GetHeader("Precedence")))
+
+	automatic := (submitted != "" && submitted != "no") ||
+		env.GetHeader("X-Autoreply") != "" ||
+		env.GetHeader("X-Autorespond") != "" ||
+		env.GetHeader("X-Autoresponse") != "" ||
+		precedence == "auto_reply"
+
+	switch {
+	case lang.IsVacationSubject(subject):
+		return repository.AutoResponseVacation
+	case automatic && lang.IsVacationBody(body):
+		return repository.AutoResponseVacation
+	case automatic:
+		return repository.AutoResponseAutoGenerated
+	case precedence == "list" || precedence == "bulk" || precedence == "junk" || env.GetHeader("List-Id") != "":
+		return repository.AutoResponseList
+	}
+	return repository.AutoResponseNone
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"time"
+
+	"github.com/Zifeldev/emailback/service/internal/bounce"
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/jhillyerd/enmime"
+)
+
+// extractDeliveryStatus turns a bounce into one row per reported recipient;
+// ordinary mail yields none.
+func extractDeliveryStatus(env *enmime.Envelope) []repository.DeliveryStatusEntity {
+	rep := bounce.Parse(env)
+	if rep == nil {
+		return nil
+	}
+	now := time.Now().UTC()
+	out := make([]repository.DeliveryStatusEntity, 0, len(rep.Recipients))
+	for _, r := range rep.Recipients {
+		out = append(out, repository.DeliveryStatusEntity{
+			ID:                uuid.NewString(),
+			OriginalMessageID: rep.OriginalMessageID,
+			Recipient:         r.Recipient,
+			OriginalRecipient: r.OriginalRecipient,
+			Action:            r.Action,
+			Status:            r.Status,
+			DiagnosticCode:    r.DiagnosticCode,
+			RemoteMTA:         r.RemoteMTA,
+			ReportingMTA:      rep.ReportingMTA,
+			BounceType:        r.Type,
+			Standard:          rep.Standard,
+			LastAttempt:       r.LastAttempt,
+			CreatedAt:         now,
+		})
+	}
+	return out
+}
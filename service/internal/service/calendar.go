@@ -0,0 +1,67 @@
+package service
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Zifeldev/emailback/service/internal/ical"
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/google/uuid"
+)
+
+// isCalendarPart reports whether a part carries iCalendar data: a
+// text/calendar part (usually an alternative body) or an .ics file.
+func isCalendarPart(a repository.AttachmentEntity) bool {
+	switch strings.ToLower(a.ContentType) {
+	case "text/calendar", "application/ics", "text/x-vcalendar":
+		return true
+	}
+	return strings.EqualFold(path.Ext(a.Filename), ".ics")
+}
+
+// extractEvents decodes the events of every calendar part. Invitations often
+// carry the same VCALENDAR twice, as an alternative body and as invite.ics;
+// each event instance is kept once.
+func extractEvents(atts []repository.AttachmentEntity) []repository.EventEntity {
+	var out []repository.EventEntity
+	seen := make(map[string]struct{})
+	now := time.Now().UTC()
+	for _, a := range atts {
+		if !isCalendarPart(a) || len(a.Data) == 0 {
+			continue
+		}
+		cal, err := ical.Parse(a.Data)
+		if err != nil {
+			continue
+		}
+		for _, ev := range cal.Events {
+			key := ev.UID + "\x00" + ev.RecurrenceID + "\x00" + cal.Method
+			if _, dup := seen[key]; dup && ev.UID != "" {
+				continue
+			}
+			seen[key] = struct{}{}
+			out = append(out, repository.EventEntity{
+				ID:           uuid.NewString(),
+				Method:       cal.Method,
+				UID:          ev.UID,
+				RecurrenceID: ev.RecurrenceID,
+				Sequence:     ev.Sequence,
+				Summary:      ev.Summary,
+				Description:  ev.Description,
+				Location:     ev.Location,
+				Status:       ev.Status,
+				Organizer:    ev.Organizer,
+				Attendees:    ev.Attendees,
+				Start:        ev.Start,
+				End:          ev.End,
+				AllDay:       ev.AllDay,
+				TZID:         ev.TZID,
+				RRule:        ev.RRule,
+				SeriesEnd:    ev.SeriesEnd,
+				CreatedAt:    now,
+			})
+		}
+	}
+	return out
+}
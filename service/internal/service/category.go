@@ -0,0 +1,45 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/Zifeldev/emailback/service/internal/lang"
+	"github.com/Zifeldev/emailback/service/internal/mailinglist"
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/jhillyerd/enmime"
+)
+
+// reNoReplySender matches the mailboxes services send notifications from.
+var reNoReplySender = regexp.MustCompile(`(?i)^(?:no-?reply|do-?not-?reply|notifications?|notify|alerts?|billing|receipts?|orders?|accounts?|security|mailer|automated?)(?:[-+._].*)?@`)
+
+// espHeaders are set by bulk-sending platforms on everything they deliver.
+var espHeaders = []string{"X-Mailgun-Sid", "X-SES-Outgoing", "X-SG-EID", "X-Mandrill-User", "X-Postmark-Server", "X-MC-User", "X-Campaign", "X-CampaignID", "Feedback-ID"}
+
+// classifyCategory sorts a message into newsletter, transactional or
+// personal mail. Receipts and account notices often carry List-Unsubscribe
+// too, so a transactional subject wins over list headers unless the message
+// names a list with List-Id.
+func classifyCategory(env *enmime.Envelope, list *mailinglist.Info, from, subject, autoResponse string) string {
+	precedence := strings.ToLower(strings.TrimSpace(env.GetHeader("Precedence")))
+	bulk := precedence == "bulk" || precedence == "list" || precedence == "junk"
+	hasListID := list != nil && list.ID != ""
+
+	automated := reNoReplySender.MatchString(from) || autoResponse == repository.AutoResponseAutoGenerated
+	for _, h := range espHeaders {
+		if env.GetHeader(h) != "" {
+			automated = true
+			break
+		}
+	}
+
+	switch {
+	case !hasListID && (automated || list != nil) && lang.IsTransactionalSubject(subject):
+		return repository.CategoryTransactional
+	case list != nil || bulk:
+		return repository.CategoryNewsletter
+	case automated:
+		return repository.CategoryTransactional
+	}
+	return repository.CategoryPersonal
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"github.com/Zifeldev/emailback/service/internal/charset"
+	"github.com/jhillyerd/enmime"
+)
+
+// fixCharset checks a body enmime decoded against the charset its part
+// declared and re-decodes it when they do not fit. Text down-converted from
+// HTML is judged by the HTML part's charset.
+func fixCharset(env *enmime.Envelope, body string, html, repair bool) (string, *charset.Result) {
+	if body == "" || env.Root == nil {
+		return body, nil
+	}
+	var part *enmime.Part
+	if !html {
+		part = bodyPart(env, "text/plain")
+	}
+	if part == nil {
+		part = bodyPart(env, "text/html")
+	}
+	if part == nil {
+		return body, nil
+	}
+	out, res := charset.Fix(body, part.Charset, repair)
+	// enmime replaces a charset its own detector disagrees with; that is a
+	// guess as well.
+	if part.OrigCharset != "" {
+		res.Declared = part.OrigCharset
+		if res.Repair == "" {
+			res.Repair = charset.RepairGuessed
+		}
+	}
+	return out, &res
+}
+
+func bodyPart(env *enmime.Envelope, ct string) *enmime.Part {
+	return env.Root.BreadthMatchFirst(func(p *enmime.Part) bool {
+		return p.ContentType == ct && p.Disposition != "attachment"
+	})
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/jhillyerd/enmime"
+)
+
+// isEmbeddedMessage matches parts that carry a complete message: forwards
+// sent as attachment, abuse reports and the returned copy in a DSN.
+func isEmbeddedMessage(p *enmime.Part) bool {
+	switch strings.ToLower(p.ContentType) {
+	case "message/rfc822", "message/global":
+		return len(p.Content) > 0
+	}
+	return false
+}
+
+// embeddedParts lists the attached messages of env in document order.
+// enmime leaves them undecoded, so Content is the message as sent.
+func embeddedParts(env *enmime.Envelope) []*enmime.Part {
+	if env.Root == nil {
+		return nil
+	}
+	var out []*enmime.Part
+	var walk func(p *enmime.Part)
+	walk = func(p *enmime.Part) {
+		for ; p != nil; p = p.NextSibling {
+			if isEmbeddedMessage(p) {
+				out = append(out, p)
+			}
+			walk(p.FirstChild)
+		}
+	}
+	walk(env.Root)
+	return out
+}
+
+// parseEmbedded parses the attached messages of env into children of parent,
+// which is nested in ancestors. Parts that fail to parse stay plain
+// attachments, and a message attached to itself is not descended into again.
+// Children of a preview are previewed too, so that metrics skip them.
+func (p *EnmimeParser) parseEmbedded(ctx context.Context, env *enmime.Envelope, parent *repository.EmailEntity, ancestors []string, preview bool) {
+	if len(ancestors) >= p.opts.MaxDepth {
+		return
+	}
+	chain := append(ancestors[:len(ancestors):len(ancestors)], parent.MessageID)
+	for _, part := range embeddedParts(env) {
+		var pv *Preview
+		if preview {
+			pv = &Preview{}
+		}
+		child, err := p.parse(ctx, part.Content, chain, part.PartID, pv)
+		if err != nil || slices.Contains(chain, child.MessageID) {
+			continue
+		}
+		parent.Embedded = append(parent.Embedded, child)
+	}
+}
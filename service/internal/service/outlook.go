@@ -0,0 +1,155 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"mime"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/Zifeldev/emailback/service/internal/outlook"
+	"github.com/jhillyerd/enmime"
+)
+
+// mimeManaged are the headers describing the original MIME structure; they
+// are rebuilt for the converted body.
+var mimeManaged = []string{"Mime-Version", "Content-Type", "Content-Transfer-Encoding", "Content-Disposition", "Content-Id"}
+
+// convertMSG turns an Outlook .msg file into an RFC822 message so it can go
+// through the regular MIME pipeline.
+func convertMSG(data []byte) ([]byte, error) {
+	m, err := outlook.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("outlook msg: %w", err)
+	}
+	return encodeMSG(m)
+}
+
+func encodeMSG(m *outlook.Message) ([]byte, error) {
+	var body *enmime.Part
+	if m.Body != "" || m.HTML == "" {
+		body = textPart("text/plain", m.Body)
+	}
+	if m.HTML != "" {
+		html := textPart("text/html", m.HTML)
+		if body == nil {
+			body = html
+		} else {
+			alt := enmime.NewPart("multipart/alternative")
+			alt.AddChild(body)
+			alt.AddChild(html)
+			body = alt
+		}
+	}
+
+	root := body
+	if len(m.Attachments) > 0 {
+		root = enmime.NewPart("multipart/mixed")
+		root.AddChild(body)
+		for _, a := range m.Attachments {
+			p, err := attachmentPart(a)
+			if err != nil {
+				return nil, err
+			}
+			root.AddChild(p)
+		}
+	}
+	root.Header = msgHeader(m)
+	root.Header.Set("Mime-Version", "1.0")
+
+	var buf bytes.Buffer
+	if err := root.Encode(&buf); err != nil {
+		return nil, fmt.Errorf("outlook msg: encode: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func textPart(contentType, s string) *enmime.Part {
+	p := enmime.NewPart(contentType)
+	p.Charset = "utf-8"
+	p.Content = []byte(s)
+	return p
+}
+
+func attachmentPart(a outlook.Attachment) (*enmime.Part, error) {
+	if a.Message != nil {
+		inner, err := encodeMSG(a.Message)
+		if err != nil {
+			return nil, err
+		}
+		p := enmime.NewPart("message/rfc822")
+		p.Content = inner
+		p.FileName = a.Name + ".eml"
+		p.Disposition = "attachment"
+		return p, nil
+	}
+
+	ct := a.MIMEType
+	if ct == "" {
+		ct = mimeTypeByName(a.Name)
+	}
+	p := enmime.NewPart(ct)
+	p.Content = a.Data
+	p.FileName = a.Name
+	p.ContentID = a.ContentID
+	p.Disposition = "attachment"
+	if a.ContentID != "" && a.Hidden {
+		p.Disposition = "inline"
+	}
+	return p, nil
+}
+
+// msgHeader starts from the transport headers Outlook saved for received
+// mail and fills whatever is missing (always the case for drafts) from the
+// MAPI properties.
+func msgHeader(m *outlook.Message) textproto.MIMEHeader {
+	h := textproto.MIMEHeader{}
+	if m.Headers != "" {
+		r := textproto.NewReader(bufio.NewReader(strings.NewReader(strings.TrimRight(m.Headers, "\r\n") + "\r\n\r\n")))
+		// Keep what parsed before a malformed line.
+		if parsed, _ := r.ReadMIMEHeader(); parsed != nil {
+			h = parsed
+		}
+		for _, k := range mimeManaged {
+			h.Del(k)
+		}
+	}
+
+	set := func(k, v string) {
+		if v != "" && h.Get(k) == "" {
+			h.Set(k, v)
+		}
+	}
+	set("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	if m.SenderEmail != "" {
+		set("From", (&mail.Address{Name: m.SenderName, Address: m.SenderEmail}).String())
+	}
+	var to, cc, bcc []string
+	for _, r := range m.Recipients {
+		if r.Email == "" {
+			continue
+		}
+		addr := (&mail.Address{Name: r.Name, Address: r.Email}).String()
+		switch r.Type {
+		case outlook.RecipientCc:
+			cc = append(cc, addr)
+		case outlook.RecipientBcc:
+			bcc = append(bcc, addr)
+		default:
+			to = append(to, addr)
+		}
+	}
+	set("To", strings.Join(to, ", "))
+	set("Cc", strings.Join(cc, ", "))
+	set("Bcc", strings.Join(bcc, ", "))
+	if m.Date != nil {
+		set("Date", m.Date.Format(time.RFC1123Z))
+	}
+	set("Message-Id", m.MessageID)
+	set("In-Reply-To", m.InReplyTo)
+	set("References", m.References)
+	return h
+}
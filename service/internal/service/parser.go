@@ -3,21 +3,49 @@ package service
 import (
 	"bytes"
 	"context"
-	"mime"
 	"net/mail"
 	"strings"
 	"time"
 
 	"github.com/Zifeldev/emailback/service/internal/lang"
+	"github.com/Zifeldev/emailback/service/internal/links"
+	"github.com/Zifeldev/emailback/service/internal/mailauth"
+	"github.com/Zifeldev/emailback/service/internal/maildate"
+	"github.com/Zifeldev/emailback/service/internal/mailheader"
+	"github.com/Zifeldev/emailback/service/internal/mailinglist"
 	"github.com/Zifeldev/emailback/service/internal/metrics"
+	"github.com/Zifeldev/emailback/service/internal/outlook"
+	"github.com/Zifeldev/emailback/service/internal/pgp"
+	"github.com/Zifeldev/emailback/service/internal/received"
 	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/Zifeldev/emailback/service/internal/sanitize"
+	"github.com/Zifeldev/emailback/service/internal/smime"
+	"github.com/Zifeldev/emailback/service/internal/thread"
 	"github.com/google/uuid"
 	"github.com/jhillyerd/enmime"
 )
 
 type Options struct {
+	// IncludeHTML keeps the HTML body, stripped to the sanitizer's allowlist;
+	// cid: and remote image URLs are resolved when it is rendered.
 	IncludeHTML     bool
 	HTMLToTextLimit int
+	// DKIM resolves signing keys; nil disables DKIM verification.
+	DKIM mailauth.KeyResolver
+	// DNS serves SPF and DMARC lookups; nil disables both checks.
+	DNS mailauth.Resolver
+	// SMIME verifies and decrypts S/MIME layers; nil leaves them as attachments.
+	SMIME *smime.Processor
+	// PGP verifies and decrypts PGP/MIME and inline OpenPGP blocks; nil
+	// leaves them as they are.
+	PGP *pgp.Processor
+	// RepairCharset also re-decodes bodies whose declared Latin-1 charset
+	// reads better as Cyrillic, and UTF-8 that was decoded twice. Parts with
+	// no charset or a us-ascii one carrying 8-bit bytes are always guessed.
+	RepairCharset bool
+	// MaxDepth limits how many levels of attached message/rfc822 parts are
+	// parsed into child emails; 0 keeps them as plain attachments.
+	MaxDepth int
 }
 
 type Parser interface {
@@ -36,29 +64,58 @@ func NewEnmimeParser(opts Options, detector lang.Detector) *EnmimeParser {
 	return &EnmimeParser{opts: opts, detector: detector}
 }
 
-func (p *EnmimeParser) Parse(_ context.Context, raw []byte) (*repository.EmailEntity, error) {
+func (p *EnmimeParser) Parse(ctx context.Context, raw []byte) (*repository.EmailEntity, error) {
+	return p.parse(ctx, raw, nil, "", nil)
+}
+
+// parse handles one message. For an attached message, ancestors are the
+// Message-IDs of the emails it is nested in and path is its MIME part there.
+// A non-nil pv collects preview diagnostics and keeps the HTML body as
+// received; previews are left out of the metrics.
+func (p *EnmimeParser) parse(ctx context.Context, raw []byte, ancestors []string, path string, pv *Preview) (*repository.EmailEntity, error) {
 	start := time.Now()
-	env, err := enmime.ReadEnvelope(bytes.NewReader(raw))
+	record := pv == nil
+	// raw stays the message as received; mimeRaw is what gets parsed. Outlook
+	// .msg uploads are converted to MIME first, and their signatures cannot be
+	// checked against the rebuilt message.
+	mimeRaw := raw
+	converted := outlook.IsMSG(raw)
+	if converted {
+		var err error
+		if mimeRaw, err = convertMSG(raw); err != nil {
+			if record {
+				metrics.EmailsFailed.Inc()
+			}
+			return nil, err
+		}
+	}
+	// Signed and encrypted mail is parsed from its inner entity.
+	var smimeRes *smime.Result
+	if p.opts.SMIME != nil {
+		mimeRaw, smimeRes = p.opts.SMIME.Unwrap(mimeRaw)
+	}
+	var pgpRes *pgp.Result
+	if p.opts.PGP != nil {
+		mimeRaw, pgpRes = p.opts.PGP.Unwrap(mimeRaw)
+	}
+	env, err := enmime.ReadEnvelope(bytes.NewReader(mimeRaw))
 	if err != nil {
-		metrics.EmailsFailed.Inc()
+		if record {
+			metrics.EmailsFailed.Inc()
+		}
 		return nil, err
 	}
 
 
-	headers := make(map[string]string, 32)
-	if env.Root != nil {
-		for k, vals := range env.Root.Header {
-			if len(vals) == 0 {
-				continue
-			}
-			headers[strings.ToLower(k)] = strings.Join(vals, ", ")
-		}
-	}
+	headers := mailheader.Parse(mimeRaw)
 
 	// Message-ID
 	msgRaw := env.GetHeader("Message-ID")
 	msgID := strings.Trim(msgRaw, " <>")
-	if msgID == "" {
+	if msgID == "" && path != "" {
+		// Keeps re-parsing idempotent for attached messages without one.
+		msgID = ancestors[len(ancestors)-1] + "/" + path
+	} else if msgID == "" {
 		msgID = uuid.NewString()
 	}
 
@@ -66,7 +123,7 @@ func (p *EnmimeParser) Parse(_ context.Context, raw []byte) (*repository.EmailEn
 	subjRaw := env.GetHeader("Subject")
 	subject := subjRaw
 	if subjRaw != "" {
-		if dec, err := (&mime.WordDecoder{}).DecodeHeader(subjRaw); err == nil && dec != "" {
+		if dec, err := mailheader.WordDecoder.DecodeHeader(subjRaw); err == nil && dec != "" {
 			subject = dec
 		}
 	}
@@ -80,6 +137,14 @@ func (p *EnmimeParser) Parse(_ context.Context, raw []byte) (*repository.EmailEn
 	} else if a, err := mail.ParseAddress(from); err == nil {
 		from = a.Address
 	}
+	fromAddrs := addressList(env, "From")
+	var fromAddr, sender *repository.Address
+	if len(fromAddrs) > 0 {
+		fromAddr = &fromAddrs[0]
+	}
+	if s := addressList(env, "Sender"); len(s) > 0 {
+		sender = &s[0]
+	}
 
 	// To
 	var toList []string
@@ -93,22 +158,50 @@ func (p *EnmimeParser) Parse(_ context.Context, raw []byte) (*repository.EmailEn
 		}
 	}
 
-	// Date
-	var datePtr *time.Time
-	if dv := env.GetHeader("Date"); dv != "" {
-		if dt, err := mail.ParseDate(dv); err == nil {
-			datePtr = &dt
+	// Threading headers
+	inReplyTo := ""
+	if ids := thread.ParseMessageIDs(env.GetHeader("In-Reply-To")); len(ids) > 0 {
+		inReplyTo = ids[0]
+	}
+	references := thread.ParseMessageIDs(strings.Join(env.GetHeaderValues("References"), " "))
+
+	// Outlook "Rich Text" mail hides body and files in winmail.dat.
+	parts := extractAttachments(env)
+	attachments, winmail := expandTNEF(parts)
+	text, textCharset := fixCharset(env, env.Text, false, p.opts.RepairCharset)
+	html, htmlCharset := fixCharset(env, env.HTML, true, p.opts.RepairCharset)
+	if winmail != nil {
+		if strings.TrimSpace(text) == "" && strings.TrimSpace(html) == "" {
+			text, html = winmail.Body, winmail.HTML
+			textCharset, htmlCharset = nil, nil
 		}
+		if subject == "" {
+			subject = winmail.Subject
+		}
+	}
+
+	// Inline PGP blocks are replaced by their plaintext before cleaning.
+	if p.opts.PGP != nil {
+		text, pgpRes = p.opts.PGP.Inline(text, pgpRes)
 	}
 
 	// Body selection
-	body := strings.TrimSpace(env.Text)
-	if body == "" && env.HTML != "" {
-		body = env.HTML
+	body := strings.TrimSpace(text)
+	bodyCharset := textCharset
+	if body == "" && html != "" {
+		body = html
+		bodyCharset = htmlCharset
 	}
 
 	// Clean body text and detect language
-	clean := lang.CleanText(body)
+	var clean string
+	if pv != nil {
+		pv.MIMETree = mimeTree(env)
+		pv.Cleaning.BodySource = bodySource(body, text)
+		clean, pv.Cleaning.CleanReport = lang.CleanTextReport(body)
+	} else {
+		clean = lang.CleanText(body)
+	}
 
 	var langCode string
 	var langConf float64
@@ -126,38 +219,134 @@ func (p *EnmimeParser) Parse(_ context.Context, raw []byte) (*repository.EmailEn
 		"line_count":   lineCount(clean),
 		"subject_len":  len([]rune(subject)),
 		"headers_size": len(headers),
-		"attachments":  len(env.Attachments),
+		"attachments":  len(env.Attachments) + len(attachments) - len(parts),
 	}
 
+	var list *mailinglist.Info
+	if env.Root != nil {
+		list = mailinglist.Parse(env.Root.Header)
+	}
+	autoResponse := classifyAutoResponse(env, subject, body)
+
 	entity := &repository.EmailEntity{
 		ID:         uuid.NewString(),
 		MessageID:  msgID,
 		From:       from,
 		To:         toList,
 		Subject:    subject,
-		Date:       datePtr,
 		Text:       clean,
-		HTML:       pickHTML(env.HTML, p.opts.IncludeHTML),
+		HTML:       pickHTML(html, p.opts.IncludeHTML),
 		Language:   langCode,
 		Confidence: langConf,
 		Metrics:    mailMetrics,
 		Headers:    headers,
 		CreatedAt:  time.Now().UTC(),
 		RawSize:    len(raw),
+		InReplyTo:  inReplyTo,
+		References: references,
+		PartPath:   path,
+
+		FromAddress: fromAddr,
+		Sender:      sender,
+		ReplyTo:     addressList(env, "Reply-To"),
+		ToAddresses: addressList(env, "To"),
+		Cc:          addressList(env, "Cc"),
+		Bcc:         addressList(env, "Bcc"),
+
+		Attachments:    attachments,
+		Links:          links.Extract(text, html),
+		Events:         extractEvents(attachments),
+		DeliveryStatus: extractDeliveryStatus(env),
+		AutoResponse:   autoResponse,
+		Category:       classifyCategory(env, list, from, subject, autoResponse),
+		List:           list,
+		ParseWarnings:  parseWarnings(env),
+		Charset:        bodyCharset,
+		SMIME:          smimeRes,
+		PGP:            pgpRes,
+		Raw:            raw,
+	}
+
+	// Date, falling back to the last Received hop when the header is missing
+	// or cannot be a send time (the epoch, or later than delivery).
+	var receivedValues []string
+	if env.Root != nil {
+		receivedValues = env.Root.Header.Values("Received")
+	}
+	datePtr, offset := parseDate(env.GetHeader("Date"))
+	entity.Hops = received.Parse(receivedValues, datePtr)
+	latest := received.Latest(entity.Hops)
+	ref := time.Now()
+	if latest != nil {
+		ref = *latest
+	}
+	switch {
+	case datePtr != nil && maildate.Plausible(*datePtr, ref):
+		entity.Date, entity.DateTZOffset, entity.DateSource = datePtr, offset, repository.DateSourceHeader
+	case latest != nil:
+		entity.Hops = received.Parse(receivedValues, nil)
+		entity.Date, entity.DateSource = latest, repository.DateSourceReceived
+		datePtr = nil
+	default:
+		entity.DateSource = repository.DateSourceNone
+		datePtr = nil
+	}
+	if transit, ok := received.Transit(entity.Hops, datePtr); ok {
+		secs := transit.Seconds()
+		entity.TransitSeconds = &secs
+		if secs >= 0 && record {
+			metrics.DeliveryLatency.Observe(secs)
+		}
+	}
+	entity.OriginIP = received.OriginIP(entity.Hops)
+
+	// Attached messages were delivered somewhere else, earlier; checking them
+	// against their old Received origin says nothing and costs lookups.
+	if !converted && path == "" {
+		p.authenticate(ctx, raw, env, entity)
 	}
+	p.parseEmbedded(ctx, env, entity, ancestors, !record)
 
+	if pv != nil {
+		// Integrators compare it with the stored, sanitized body.
+		entity.HTML = html
+		return entity, nil
+	}
+	countWarnings(entity.ParseWarnings)
 	metrics.EmailsProcessed.Inc()
 	metrics.EmailProcessingDuration.Observe(time.Since(start).Seconds())
 	return entity, nil
 }
 
+// parseDate reads a Date header value and the sender's UTC offset in minutes.
+func parseDate(v string) (*time.Time, *int) {
+	t, ok := maildate.Parse(v)
+	if !ok {
+		return nil, nil
+	}
+	_, secs := t.Zone()
+	off := secs / 60
+	return &t, &off
+}
+
 func pickHTML(html string, include bool) string {
-	if include {
-		return html
+	if include && html != "" {
+		return sanitize.HTML(html, sanitize.Options{})
 	}
 	return ""
 }
 
+// bodySource names the part the cleaned body was taken from.
+func bodySource(body, text string) string {
+	switch {
+	case body == "":
+		return ""
+	case body == strings.TrimSpace(text):
+		return "text"
+	}
+	return "html"
+}
+
 func countWords(s string) int {
 	if s = strings.TrimSpace(s); s == "" {
 		return 0
@@ -1,10 +1,40 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"net"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/Zifeldev/emailback/service/internal/cfb/cfbtest"
+	"github.com/Zifeldev/emailback/service/internal/charset"
+	"github.com/Zifeldev/emailback/service/internal/links"
+	"github.com/Zifeldev/emailback/service/internal/mailauth"
+	"github.com/Zifeldev/emailback/service/internal/mailinglist"
+	"github.com/Zifeldev/emailback/service/internal/metrics"
+	"github.com/Zifeldev/emailback/service/internal/pgp"
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/Zifeldev/emailback/service/internal/smime"
+	"github.com/jhillyerd/enmime"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/smallstep/pkcs7"
+	"golang.org/x/text/encoding/charmap"
 )
 
 type mockDetector struct {
@@ -82,7 +112,7 @@ Hello world! This is a test.
 }
 
 func TestEnmimeParser_Parse_HTMLFallback(t *testing.T) {
-	html := `<html><body><p>Hello <b>World</b></p></body></html>`
+	html := `<html><body onload="x()"><p>Hello <b>World</b></p><script>alert(1)</script></body></html>`
 	raw := []byte(strings.ReplaceAll(`Subject: HTML Only
 From: alice@example.com
 To: bob@example.com
@@ -105,14 +135,91 @@ Content-Type: text/html; charset=UTF-8
 	if ent.HTML == "" {
 		t.Errorf("expected HTML to be included")
 	}
+	if strings.Contains(ent.HTML, "script") || strings.Contains(ent.HTML, "onload") || !strings.Contains(ent.HTML, "<b>World</b>") {
+		t.Errorf("expected sanitized HTML, got: %q", ent.HTML)
+	}
 	if !(strings.Contains(ent.Text, "Hello World") || strings.Contains(ent.Text, "Hello *World")) {
 		t.Errorf("expected cleaned text to contain 'Hello World' (or '*World'), got: %q", ent.Text)
 	}
 }
 
+func TestEnmimeParser_Parse_Links(t *testing.T) {
+	raw := []byte(strings.ReplaceAll(`From: security@mybank.com
+Subject: Verify your account
+Message-ID: <links@example.com>
+MIME-Version: 1.0
+Content-Type: multipart/alternative; boundary="b"
+
+--b
+Content-Type: text/plain; charset=UTF-8
+
+Verify at https://mybank.com.secure-login.ru/verify or read https://mybank.com/help.
+
+--b
+Content-Type: text/html; charset=UTF-8
+
+<p>Verify at <a href="https://mybank.com.secure-login.ru/verify">https://mybank.com/login</a></p>
+--b--
+`, "\n", "\r\n"))
+
+	ent, err := NewEnmimeParser(Options{}, mockDetector{}).Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if len(ent.Links) != 2 {
+		t.Fatalf("expected the html link and one text-only link, got %+v", ent.Links)
+	}
+	if l := ent.Links[0]; l.Source != links.SourceHTML || l.Domain != "mybank.com.secure-login.ru" || l.TextDomain != "mybank.com" || !l.Mismatch {
+		t.Fatalf("unexpected html link: %+v", l)
+	}
+	if l := ent.Links[1]; l.Source != links.SourceText || l.URL != "https://mybank.com/help" || l.Mismatch {
+		t.Fatalf("unexpected text link: %+v", l)
+	}
+	if strings.Contains(ent.Text, "https://") {
+		t.Fatalf("cleaned text should still drop urls: %q", ent.Text)
+	}
+}
+
+func TestEnmimeParser_Parse_Date(t *testing.T) {
+	hop := "Received: from relay.example.com by mx.example.org; Mon, 02 Jan 2006 15:05:05 +0000\r\n"
+	for _, tc := range []struct {
+		name, date string
+		want       string // RFC 3339
+		source     string
+		offset     *int
+	}{
+		{"lenient header keeps offset", "Date: Mon, 2 Jan 06 18:04 MSK\r\n", "2006-01-02T18:04:00+03:00", repository.DateSourceHeader, intPtr(180)},
+		{"missing falls back to received", "", "2006-01-02T15:05:05Z", repository.DateSourceReceived, nil},
+		{"epoch falls back to received", "Date: Thu, 1 Jan 1970 00:00:00 +0000\r\n", "2006-01-02T15:05:05Z", repository.DateSourceReceived, nil},
+		{"future falls back to received", "Date: Tue, 2 Jan 2046 15:04:05 +0000\r\n", "2006-01-02T15:05:05Z", repository.DateSourceReceived, nil},
+	} {
+		raw := []byte(hop + tc.date + "From: alice@example.com\r\nSubject: Date\r\nMessage-ID: <date@example.com>\r\n\r\nhello\r\n")
+		ent, err := NewEnmimeParser(Options{}, mockDetector{}).Parse(context.Background(), raw)
+		if err != nil {
+			t.Fatalf("%s: parse err: %v", tc.name, err)
+		}
+		if ent.Date == nil || ent.Date.Format(time.RFC3339) != tc.want || ent.DateSource != tc.source {
+			t.Fatalf("%s: date %v from %q", tc.name, ent.Date, ent.DateSource)
+		}
+		if (ent.DateTZOffset == nil) != (tc.offset == nil) || (tc.offset != nil && *ent.DateTZOffset != *tc.offset) {
+			t.Fatalf("%s: offset %v", tc.name, ent.DateTZOffset)
+		}
+	}
+
+	ent, err := NewEnmimeParser(Options{}, mockDetector{}).Parse(context.Background(), []byte("Date: soon\r\nSubject: x\r\n\r\nhi\r\n"))
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if ent.Date != nil || ent.DateSource != repository.DateSourceNone {
+		t.Fatalf("unparseable date without hops: %v %q", ent.Date, ent.DateSource)
+	}
+}
+
+func intPtr(n int) *int { return &n }
+
 func TestEnmimeParser_Parse_NoMessageID_SubjectDecode_ToMulti_NoDate_Attachments(t *testing.T) {
 	boundary := "mixedb"
-	encodedSubj := "=?UTF-8?B?0J/RgNC40LLQtdGCLCDQv9C+0LvRjNC30LDRgNCw?=" 
+	encodedSubj := "=?UTF-8?B?0J/RgNC40LLQtdGCLCDQv9C+0LvRjNC30LDRgNCw?="
 	raw := []byte(strings.ReplaceAll(
 		"From: Bob <bob@example.com>\n"+
 			"To: alice@example.com\n"+
@@ -156,3 +263,854 @@ func TestEnmimeParser_Parse_NoMessageID_SubjectDecode_ToMulti_NoDate_Attachments
 		t.Fatalf("expected line_count >=2")
 	}
 }
+
+func TestEnmimeParser_Parse_ExtractsAttachments(t *testing.T) {
+	raw := []byte(strings.ReplaceAll(
+		"From: Bob <bob@example.com>\n"+
+			"To: alice@example.com\n"+
+			"Subject: Invoice\n"+
+			"MIME-Version: 1.0\n"+
+			"Content-Type: multipart/mixed; boundary=\"b1\"\n"+
+			"\n"+
+			"--b1\n"+
+			"Content-Type: text/plain; charset=UTF-8\n\n"+
+			"See attached\n"+
+			"--b1\n"+
+			"Content-Type: application/pdf; name=\"invoice.pdf\"\n"+
+			"Content-Disposition: attachment; filename=\"invoice.pdf\"\n"+
+			"Content-Transfer-Encoding: base64\n\n"+
+			"JVBERi0xLjQK\n"+
+			"--b1--\n",
+		"\n", "\r\n"))
+
+	p := NewEnmimeParser(Options{}, mockDetector{})
+	ent, err := p.Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if len(ent.Attachments) != 1 {
+		t.Fatalf("expected 1 attachment, got %d", len(ent.Attachments))
+	}
+	a := ent.Attachments[0]
+	if a.Filename != "invoice.pdf" || a.ContentType != "application/pdf" {
+		t.Fatalf("unexpected attachment meta: %+v", a)
+	}
+	if a.SniffedType != "application/pdf" {
+		t.Fatalf("unexpected sniffed type %q", a.SniffedType)
+	}
+	if string(a.Data) != "%PDF-1.4\n" || a.Size != int64(len(a.Data)) {
+		t.Fatalf("unexpected payload %q size=%d", a.Data, a.Size)
+	}
+	if len(a.SHA256) != 64 {
+		t.Fatalf("expected hex sha256, got %q", a.SHA256)
+	}
+}
+
+// winmailDat builds a minimal TNEF stream with a subject, plain body and one
+// attached file.
+func winmailDat() []byte {
+	var b []byte
+	u16 := func(v uint16) { b = binary.LittleEndian.AppendUint16(b, v) }
+	u32 := func(v uint32) { b = binary.LittleEndian.AppendUint32(b, v) }
+	attr := func(level byte, id uint32, data string) {
+		b = append(b, level)
+		u32(id)
+		u32(uint32(len(data)))
+		b = append(b, data...)
+		var sum uint16
+		for i := 0; i < len(data); i++ {
+			sum += uint16(data[i])
+		}
+		u16(sum)
+	}
+	u32(0x223E9F78)
+	u16(1)
+	attr(1, 0x00018004, "Rich text report\x00")
+	attr(1, 0x0002800C, "Body from Outlook\x00")
+	attr(2, 0x00069002, strings.Repeat("\x00", 14))
+	attr(2, 0x00018010, "report.pdf\x00")
+	attr(2, 0x0006800F, "%PDF-1.4\n")
+	return b
+}
+
+func TestEnmimeParser_Parse_TNEF(t *testing.T) {
+	raw := []byte(strings.ReplaceAll(
+		"From: Bob <bob@example.com>\n"+
+			"To: alice@example.com\n"+
+			"MIME-Version: 1.0\n"+
+			"Content-Type: multipart/mixed; boundary=\"b1\"\n"+
+			"\n"+
+			"--b1\n"+
+			"Content-Type: application/ms-tnef; name=\"winmail.dat\"\n"+
+			"Content-Disposition: attachment; filename=\"winmail.dat\"\n"+
+			"Content-Transfer-Encoding: base64\n\n"+
+			base64.StdEncoding.EncodeToString(winmailDat())+"\n"+
+			"--b1--\n",
+		"\n", "\r\n"))
+
+	p := NewEnmimeParser(Options{}, mockDetector{})
+	ent, err := p.Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if ent.Subject != "Rich text report" || ent.Text != "Body from Outlook" {
+		t.Fatalf("unexpected subject/body: %q / %q", ent.Subject, ent.Text)
+	}
+	if len(ent.Attachments) != 1 {
+		t.Fatalf("expected winmail.dat to be replaced by 1 attachment, got %d", len(ent.Attachments))
+	}
+	a := ent.Attachments[0]
+	if a.Filename != "report.pdf" || a.ContentType != "application/pdf" || a.Disposition != "attachment" {
+		t.Fatalf("unexpected attachment meta: %+v", a)
+	}
+	if string(a.Data) != "%PDF-1.4\n" || a.SniffedType != "application/pdf" {
+		t.Fatalf("unexpected payload %q (%s)", a.Data, a.SniffedType)
+	}
+	if ent.Metrics["attachments"] != 1 {
+		t.Fatalf("unexpected attachments metric %v", ent.Metrics["attachments"])
+	}
+}
+
+// msgString is a PT_UNICODE property stream of an Outlook .msg file.
+func msgString(id uint16, s string) *cfbtest.Node {
+	var b []byte
+	for _, r := range s {
+		b = binary.LittleEndian.AppendUint16(b, uint16(r))
+	}
+	return cfbtest.Stream(fmt.Sprintf("__substg1.0_%04X001F", id), b)
+}
+
+func msgProps(headerSize int, props map[uint32]uint32) *cfbtest.Node {
+	b := make([]byte, headerSize)
+	for tag, v := range props {
+		b = binary.LittleEndian.AppendUint32(b, tag)
+		b = binary.LittleEndian.AppendUint32(b, 6)
+		b = binary.LittleEndian.AppendUint64(b, uint64(v))
+	}
+	return cfbtest.Stream("__properties_version1.0", b)
+}
+
+func TestEnmimeParser_Parse_OutlookMSG(t *testing.T) {
+	headers := "Received: from mx.example.com (mx.example.com [203.0.113.5]) by mail.example.org; Thu, 30 Oct 2025 18:00:05 +0000\r\n" +
+		"From: Alice <alice@example.com>\r\n" +
+		"To: bob@example.org\r\n" +
+		"Subject: =?UTF-8?Q?Gr=C3=BC=C3=9Fe?=\r\n" +
+		"Date: Thu, 30 Oct 2025 18:00:00 +0000\r\n" +
+		"Message-ID: <msg-1@example.com>\r\n" +
+		"Content-Type: multipart/alternative; boundary=\"gone\"\r\n"
+	msg := cfbtest.Build(
+		msgProps(32, nil),
+		msgString(0x0037, "Grüße"),
+		msgString(0x007D, headers),
+		msgString(0x1000, "Hello from Outlook"),
+		cfbtest.Storage("__attach_version1.0_#00000000",
+			msgProps(8, map[uint32]uint32{0x37050003: 1}),
+			msgString(0x3707, "notes.txt"),
+			cfbtest.Stream("__substg1.0_37010102", []byte("attached notes")),
+		),
+	)
+
+	p := NewEnmimeParser(Options{}, mockDetector{code: "en", conf: 0.9, ok: true})
+	ent, err := p.Parse(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if ent.MessageID != "msg-1@example.com" || ent.From != "alice@example.com" || ent.Subject != "Grüße" {
+		t.Fatalf("unexpected headers: id=%q from=%q subject=%q", ent.MessageID, ent.From, ent.Subject)
+	}
+	if len(ent.To) != 1 || ent.To[0] != "bob@example.org" {
+		t.Fatalf("unexpected to %v", ent.To)
+	}
+	if ent.Text != "Hello from Outlook" || ent.Language != "en" {
+		t.Fatalf("unexpected body/lang: %q %q", ent.Text, ent.Language)
+	}
+	if len(ent.Hops) != 1 || ent.Hops[0].IP != "203.0.113.5" {
+		t.Fatalf("transport headers not used: %+v", ent.Hops)
+	}
+	if len(ent.Attachments) != 1 || ent.Attachments[0].Filename != "notes.txt" || string(ent.Attachments[0].Data) != "attached notes" {
+		t.Fatalf("unexpected attachments %+v", ent.Attachments)
+	}
+	for _, h := range ent.Headers {
+		if strings.Contains(h.Raw, "boundary=\"gone\"") {
+			t.Fatalf("original MIME structure headers should be dropped")
+		}
+	}
+	if !bytes.Equal(ent.Raw, msg) || ent.RawSize != len(msg) {
+		t.Fatalf("raw must stay the uploaded .msg, got %d bytes", len(ent.Raw))
+	}
+}
+
+func TestEnmimeParser_Parse_OutlookDraft(t *testing.T) {
+	msg := cfbtest.Build(
+		msgProps(32, nil),
+		msgString(0x0037, "Draft"),
+		msgString(0x0C1A, "Alice"),
+		msgString(0x5D01, "alice@example.com"),
+		msgString(0x1000, "Not sent yet"),
+		cfbtest.Storage("__recip_version1.0_#00000000",
+			msgProps(8, map[uint32]uint32{0x0C150003: 2}),
+			msgString(0x3001, "Carol"),
+			msgString(0x39FE, "carol@example.com"),
+		),
+	)
+
+	p := NewEnmimeParser(Options{}, mockDetector{})
+	ent, err := p.Parse(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if ent.From != "alice@example.com" || ent.Subject != "Draft" || ent.Text != "Not sent yet" {
+		t.Fatalf("unexpected entity: from=%q subject=%q text=%q", ent.From, ent.Subject, ent.Text)
+	}
+	if len(ent.Cc) != 1 || ent.Cc[0].Address != "carol@example.com" || ent.Cc[0].Name != "Carol" {
+		t.Fatalf("unexpected cc %+v", ent.Cc)
+	}
+}
+
+func TestEnmimeParser_Parse_SMIMESigned(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(7),
+		Subject:               pkix.Name{CommonName: "Alice"},
+		EmailAddresses:        []string{"alice@example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	cert, _ := x509.ParseCertificate(der)
+
+	entity := "Content-Type: text/plain; charset=UTF-8\r\n\r\nPlease find the signed terms.\r\n"
+	sd, _ := pkcs7.NewSignedData([]byte(entity))
+	sd.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+	if err := sd.AddSigner(cert, key, pkcs7.SignerInfoConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	sd.Detach()
+	sig, _ := sd.Finish()
+
+	raw := []byte("From: Alice <alice@example.com>\r\n" +
+		"To: legal@example.org\r\n" +
+		"Subject: Terms\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/signed; protocol=\"application/pkcs7-signature\"; micalg=sha-256; boundary=\"s\"\r\n" +
+		"\r\n" +
+		"--s\r\n" + entity + "\r\n" +
+		"--s\r\n" +
+		"Content-Type: application/pkcs7-signature; name=smime.p7s\r\n" +
+		"Content-Transfer-Encoding: base64\r\n\r\n" +
+		base64.StdEncoding.EncodeToString(sig) + "\r\n" +
+		"--s--\r\n")
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	p := NewEnmimeParser(Options{SMIME: smime.NewProcessor(roots, nil)}, mockDetector{})
+	ent, err := p.Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if !strings.Contains(ent.Text, "Please find the signed terms") || len(ent.Attachments) != 0 {
+		t.Fatalf("inner entity not parsed: text=%q attachments=%d", ent.Text, len(ent.Attachments))
+	}
+	if ent.SMIME == nil || !ent.SMIME.Signed || len(ent.SMIME.Signers) != 1 {
+		t.Fatalf("unexpected smime result %+v", ent.SMIME)
+	}
+	if s := ent.SMIME.Signers[0]; s.Result != smime.ResultPass || s.Emails[0] != "alice@example.com" {
+		t.Fatalf("unexpected signer %+v", s)
+	}
+	if string(ent.Raw) != string(raw) {
+		t.Fatalf("raw must stay the message as received")
+	}
+
+	// Without a processor the signature stays an attachment.
+	ent, err = NewEnmimeParser(Options{}, mockDetector{}).Parse(context.Background(), raw)
+	if err != nil || ent.SMIME != nil || len(ent.Attachments) != 1 {
+		t.Fatalf("unexpected result without smime: %v %+v", err, ent.SMIME)
+	}
+}
+
+func TestEnmimeParser_Parse_PGPEncrypted(t *testing.T) {
+	key, err := openpgp.NewEntity("Security", "", "security@example.org", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypt := func(plain string) string {
+		var buf bytes.Buffer
+		aw, _ := armor.Encode(&buf, "PGP MESSAGE", nil)
+		w, err := openpgp.Encrypt(aw, []*openpgp.Entity{key}, nil, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(plain))
+		w.Close()
+		aw.Close()
+		return buf.String()
+	}
+
+	raw := []byte("From: Researcher <r@example.com>\r\n" +
+		"To: security@example.org\r\n" +
+		"Subject: Report\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/encrypted; protocol=\"application/pgp-encrypted\"; boundary=\"e\"\r\n" +
+		"\r\n" +
+		"--e\r\nContent-Type: application/pgp-encrypted\r\n\r\nVersion: 1\r\n" +
+		"--e\r\nContent-Type: application/octet-stream; name=encrypted.asc\r\n\r\n" +
+		encrypt("Content-Type: text/plain; charset=utf-8\r\n\r\nThe login form is vulnerable to injection\r\n") + "\r\n" +
+		"--e--\r\n")
+
+	p := NewEnmimeParser(Options{PGP: pgp.NewProcessor(openpgp.EntityList{key})}, mockDetector{})
+	ent, err := p.Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if !strings.Contains(ent.Text, "login form is vulnerable") || len(ent.Attachments) != 0 {
+		t.Fatalf("decrypted entity not parsed: text=%q attachments=%d", ent.Text, len(ent.Attachments))
+	}
+	if ent.PGP == nil || !ent.PGP.Encrypted || !ent.PGP.Decrypted || ent.PGP.Signed {
+		t.Fatalf("unexpected pgp result %+v", ent.PGP)
+	}
+
+	// The same report pasted into a plain text body.
+	inline := []byte("From: r@example.com\r\nSubject: Report\r\n\r\nHello,\r\n\r\n" +
+		encrypt("The login form is vulnerable to injection\n"))
+	ent, err = p.Parse(context.Background(), inline)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if !strings.Contains(ent.Text, "login form is vulnerable") || strings.Contains(ent.Text, "BEGIN PGP") {
+		t.Fatalf("inline block not decrypted: %q", ent.Text)
+	}
+	if ent.PGP == nil || !ent.PGP.Inline || !ent.PGP.Decrypted {
+		t.Fatalf("unexpected inline pgp result %+v", ent.PGP)
+	}
+}
+
+func TestEnmimeParser_Parse_CalendarInvite(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\r\n" +
+		"METHOD:REQUEST\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:standup-1\r\n" +
+		"SUMMARY:Standup\r\n" +
+		"ORGANIZER;CN=Jane:mailto:jane@example.com\r\n" +
+		"ATTENDEE;PARTSTAT=NEEDS-ACTION;RSVP=TRUE:mailto:bob@example.com\r\n" +
+		"DTSTART;TZID=Europe/Berlin:20240715T100000\r\n" +
+		"DTEND;TZID=Europe/Berlin:20240715T101500\r\n" +
+		"RRULE:FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	raw := []byte("From: jane@example.com\r\n" +
+		"To: bob@example.com\r\n" +
+		"Subject: Invitation: Standup\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=\"m\"\r\n\r\n" +
+		"--m\r\n" +
+		"Content-Type: multipart/alternative; boundary=\"a\"\r\n\r\n" +
+		"--a\r\nContent-Type: text/plain\r\n\r\nDaily standup\r\n" +
+		"--a\r\nContent-Type: text/calendar; method=REQUEST; charset=utf-8\r\n\r\n" + ics +
+		"--a--\r\n" +
+		"--m\r\n" +
+		"Content-Type: application/ics; name=invite.ics\r\n" +
+		"Content-Disposition: attachment; filename=invite.ics\r\n\r\n" + ics +
+		"--m--\r\n")
+
+	ent, err := NewEnmimeParser(Options{}, mockDetector{}).Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if len(ent.Events) != 1 {
+		t.Fatalf("expected one event, got %+v", ent.Events)
+	}
+	ev := ent.Events[0]
+	if ev.Method != "REQUEST" || ev.UID != "standup-1" || ev.Summary != "Standup" || ev.RRule == "" {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	if want := time.Date(2024, 7, 15, 8, 0, 0, 0, time.UTC); ev.Start == nil || !ev.Start.Equal(want) {
+		t.Fatalf("start = %v, want %v", ev.Start, want)
+	}
+	if ev.Organizer == nil || ev.Organizer.Email != "jane@example.com" || len(ev.Attendees) != 1 || ev.Attendees[0].PartStat != "NEEDS-ACTION" {
+		t.Fatalf("unexpected participants %+v %+v", ev.Organizer, ev.Attendees)
+	}
+}
+
+func TestEnmimeParser_Parse_AttachedMessages(t *testing.T) {
+	spam := "From: spammer@example.net\r\n" +
+		"To: victim@example.org\r\n" +
+		"Subject: You won\r\n" +
+		"Message-ID: <spam-1@example.net>\r\n\r\n" +
+		"Claim your prize\r\n"
+	// The abuse report carries the spam without a Message-ID of its own.
+	report := "From: abuse@example.org\r\n" +
+		"Subject: Abuse report\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=\"r\"\r\n\r\n" +
+		"--r\r\nContent-Type: text/plain\r\n\r\nSee attached spam\r\n" +
+		"--r\r\nContent-Type: message/rfc822\r\n\r\n" + spam +
+		"--r--\r\n"
+	raw := []byte("From: desk@example.com\r\n" +
+		"Subject: Fwd: Abuse report\r\n" +
+		"Message-ID: <fwd-1@example.com>\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=\"f\"\r\n\r\n" +
+		"--f\r\nContent-Type: text/plain\r\n\r\nFYI\r\n" +
+		"--f\r\nContent-Type: message/rfc822\r\nContent-Disposition: attachment\r\n\r\n" + report +
+		"--f--\r\n")
+
+	ent, err := NewEnmimeParser(Options{MaxDepth: 3}, mockDetector{}).Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if len(ent.Embedded) != 1 || len(ent.Attachments) != 1 {
+		t.Fatalf("expected one attached message kept as attachment, got %d/%d", len(ent.Embedded), len(ent.Attachments))
+	}
+	child := ent.Embedded[0]
+	if child.Subject != "Abuse report" || child.PartPath != "2" || child.MessageID != "fwd-1@example.com/2" {
+		t.Fatalf("unexpected child %q path=%q id=%q", child.Subject, child.PartPath, child.MessageID)
+	}
+	if len(child.Embedded) != 1 {
+		t.Fatalf("expected nested message, got %d", len(child.Embedded))
+	}
+	if gc := child.Embedded[0]; gc.MessageID != "spam-1@example.net" || gc.From != "spammer@example.net" || gc.PartPath != "2" {
+		t.Fatalf("unexpected grandchild %+v", gc)
+	}
+
+	ent, err = NewEnmimeParser(Options{MaxDepth: 1}, mockDetector{}).Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if len(ent.Embedded) != 1 || len(ent.Embedded[0].Embedded) != 0 {
+		t.Fatalf("depth limit not applied")
+	}
+	ent, err = NewEnmimeParser(Options{}, mockDetector{}).Parse(context.Background(), raw)
+	if err != nil || len(ent.Embedded) != 0 {
+		t.Fatalf("attached messages parsed without MaxDepth: %v", err)
+	}
+}
+
+func TestEnmimeParser_Parse_Bounce(t *testing.T) {
+	raw := []byte("From: MAILER-DAEMON@mail.example.com\r\n" +
+		"To: news@example.com\r\n" +
+		"Subject: Undelivered Mail Returned to Sender\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/report; report-type=delivery-status; boundary=\"b\"\r\n\r\n" +
+		"--b\r\nContent-Type: text/plain\r\n\r\nYour message could not be delivered.\r\n" +
+		"--b\r\nContent-Type: message/delivery-status\r\n\r\n" +
+		"Reporting-MTA: dns; mail.example.com\r\n\r\n" +
+		"Final-Recipient: rfc822; bob@example.org\r\n" +
+		"Action: failed\r\n" +
+		"Status: 5.1.1\r\n\r\n" +
+		"--b\r\nContent-Type: text/rfc822-headers\r\n\r\n" +
+		"Message-ID: <nl-42@example.com>\r\n\r\n" +
+		"--b--\r\n")
+
+	ent, err := NewEnmimeParser(Options{}, mockDetector{}).Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if len(ent.DeliveryStatus) != 1 {
+		t.Fatalf("expected one delivery status, got %+v", ent.DeliveryStatus)
+	}
+	ds := ent.DeliveryStatus[0]
+	if ds.Recipient != "bob@example.org" || ds.BounceType != "hard" || ds.OriginalMessageID != "nl-42@example.com" || ds.ReportingMTA != "mail.example.com" {
+		t.Fatalf("unexpected delivery status %+v", ds)
+	}
+}
+
+func TestClassifyAutoResponse(t *testing.T) {
+	cases := []struct {
+		name, headers, body, want string
+	}{
+		{"person", "Subject: Lunch?\r\n", "Are you free at noon?", repository.AutoResponseNone},
+		{"vacation subject", "Subject: Abwesenheitsnotiz: Angebot\r\n", "Danke für Ihre Nachricht.", repository.AutoResponseVacation},
+		{"vacation body", "Subject: Re: Angebot\r\nAuto-Submitted: auto-replied\r\nContent-Type: text/plain; charset=utf-8\r\n", "Я в отпуске до 15 июля.", repository.AutoResponseVacation},
+		{"ticket ack", "Subject: [#123] Received\r\nAuto-Submitted: auto-replied\r\n", "Your request has been received.", repository.AutoResponseAutoGenerated},
+		{"x-autoreply", "Subject: Re: hi\r\nX-Autoreply: yes\r\n", "Thanks, we got it.", repository.AutoResponseAutoGenerated},
+		{"explicit no", "Subject: Re: hi\r\nAuto-Submitted: no\r\n", "Sure.", repository.AutoResponseNone},
+		{"list", "Subject: [dev] release\r\nList-Id: <dev.lists.example.com>\r\nPrecedence: list\r\n", "Release is out.", repository.AutoResponseList},
+		{"bulk", "Subject: July newsletter\r\nPrecedence: bulk\r\n", "News.", repository.AutoResponseList},
+	}
+	for _, c := range cases {
+		env, err := enmime.ReadEnvelope(strings.NewReader("From: a@example.com\r\n" + c.headers + "\r\n" + c.body + "\r\n"))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got := classifyAutoResponse(env, env.GetHeader("Subject"), env.Text); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestClassifyCategory(t *testing.T) {
+	cases := []struct {
+		name, headers, want string
+	}{
+		{"person", "From: bob@example.org\r\nSubject: Lunch?\r\n", repository.CategoryPersonal},
+		{"newsletter", "From: news@acme.example\r\nSubject: This week at Acme\r\nList-Id: <news.acme.example>\r\nList-Unsubscribe: <https://acme.example/u>\r\n", repository.CategoryNewsletter},
+		{"receipt with unsubscribe", "From: shop@acme.example\r\nSubject: Your order #123 has shipped\r\nList-Unsubscribe: <https://acme.example/u>\r\n", repository.CategoryTransactional},
+		{"no-reply", "From: no-reply@bank.example\r\nSubject: New sign-in from Chrome\r\n", repository.CategoryTransactional},
+		{"esp", "From: hello@app.example\r\nSubject: Welcome aboard\r\nX-SES-Outgoing: 2024.07.15-1.2.3.4\r\n", repository.CategoryTransactional},
+		{"bulk", "From: team@app.example\r\nSubject: Product update\r\nPrecedence: bulk\r\n", repository.CategoryNewsletter},
+	}
+	for _, c := range cases {
+		env, err := enmime.ReadEnvelope(strings.NewReader(c.headers + "\r\nbody\r\n"))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		from := env.GetHeader("From")
+		list := mailinglist.Parse(env.Root.Header)
+		if got := classifyCategory(env, list, from, env.GetHeader("Subject"), repository.AutoResponseNone); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestEnmimeParser_Parse_ThreadingHeaders(t *testing.T) {
+	raw := []byte(strings.ReplaceAll(`Subject: Re: Plan
+From: bob@example.com
+To: alice@example.com
+Message-ID: <c@example.com>
+In-Reply-To: <b@example.com>
+References: <a@example.com>
+ <b@example.com>
+Content-Type: text/plain; charset=UTF-8
+
+ok
+`, "\n", "\r\n"))
+
+	p := NewEnmimeParser(Options{}, mockDetector{})
+	ent, err := p.Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if ent.InReplyTo != "b@example.com" {
+		t.Fatalf("unexpected in-reply-to %q", ent.InReplyTo)
+	}
+	if len(ent.References) != 2 || ent.References[0] != "a@example.com" || ent.References[1] != "b@example.com" {
+		t.Fatalf("unexpected references %v", ent.References)
+	}
+}
+
+func TestEnmimeParser_Parse_StructuredAddresses(t *testing.T) {
+	raw := []byte(strings.ReplaceAll(`Subject: Hi
+From: "Smith, Alice" <Alice@Example.COM>
+Sender: =?UTF-8?B?0JHQvtCx?= <bob@example.com>
+Reply-To: support@example.com
+To: Carol <carol@example.org>, broken@@, dave@example.org
+Cc: =?UTF-8?Q?Ren=C3=A9?= <rene@example.fr>
+Message-ID: <addr@example.com>
+Content-Type: text/plain; charset=UTF-8
+
+hello
+`, "\n", "\r\n"))
+
+	p := NewEnmimeParser(Options{}, mockDetector{})
+	ent, err := p.Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if ent.FromAddress == nil || ent.FromAddress.Name != "Smith, Alice" || ent.FromAddress.Domain != "example.com" || ent.FromAddress.LocalPart != "Alice" {
+		t.Fatalf("unexpected from: %+v", ent.FromAddress)
+	}
+	if ent.Sender == nil || ent.Sender.Name != "Боб" {
+		t.Fatalf("unexpected sender: %+v", ent.Sender)
+	}
+	if len(ent.ReplyTo) != 1 || ent.ReplyTo[0].Address != "support@example.com" {
+		t.Fatalf("unexpected reply-to: %+v", ent.ReplyTo)
+	}
+	if len(ent.ToAddresses) != 3 || ent.ToAddresses[0].Name != "Carol" || ent.ToAddresses[2].Address != "dave@example.org" {
+		t.Fatalf("unexpected to: %+v", ent.ToAddresses)
+	}
+	if len(ent.Cc) != 1 || ent.Cc[0].Name != "René" {
+		t.Fatalf("unexpected cc: %+v", ent.Cc)
+	}
+}
+
+func TestEnmimeParser_Parse_DKIM(t *testing.T) {
+	raw := []byte(strings.ReplaceAll(`DKIM-Signature: v=1; a=rsa-sha256; c=relaxed/relaxed; d=example.com; s=sel;
+	h=from:subject; bh=47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=; b=AAAA
+From: alice@example.com
+Subject: Signed
+Message-ID: <dkim@example.com>
+
+hello
+`, "\n", "\r\n"))
+
+	ent, err := NewEnmimeParser(Options{}, mockDetector{}).Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if ent.DKIM != nil {
+		t.Fatalf("dkim should be skipped without a resolver, got %+v", ent.DKIM)
+	}
+
+	p := NewEnmimeParser(Options{DKIM: mailauth.NewStaticKeyResolver(nil)}, mockDetector{})
+	ent, err = p.Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if len(ent.DKIM) != 1 || ent.DKIM[0].Domain != "example.com" || ent.DKIM[0].Result != mailauth.ResultPermError {
+		t.Fatalf("unexpected dkim results: %+v", ent.DKIM)
+	}
+}
+
+type fakeDNS map[string][]string
+
+func (f fakeDNS) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if v, ok := f[name]; ok {
+		return v, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+func (f fakeDNS) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+func (f fakeDNS) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+func (f fakeDNS) LookupAddr(_ context.Context, addr string) ([]string, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: addr, IsNotFound: true}
+}
+
+func TestEnmimeParser_Parse_SPFAndDMARC(t *testing.T) {
+	raw := []byte(strings.ReplaceAll(`Return-Path: <bounce@mail.example.com>
+Received: from relay.internal ([10.0.0.5]) by mx.local; Mon, 02 Jan 2006 15:04:07 +0000
+Received: from out.example.com (out.example.com [192.0.2.44]) by relay.internal with ESMTPS; Mon, 02 Jan 2006 15:04:06 +0000
+From: Alice <alice@example.com>
+Subject: Hi
+Message-ID: <spf@example.com>
+
+hello
+`, "\n", "\r\n"))
+	dns := fakeDNS{
+		"mail.example.com":   {"v=spf1 ip4:192.0.2.0/24 -all"},
+		"_dmarc.example.com": {"v=DMARC1; p=reject"},
+	}
+
+	ent, err := NewEnmimeParser(Options{DNS: dns}, mockDetector{}).Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if ent.SPF == nil || ent.SPF.Result != mailauth.ResultPass || ent.SPF.IP != "192.0.2.44" || ent.SPF.Domain != "mail.example.com" {
+		t.Fatalf("unexpected spf: %+v", ent.SPF)
+	}
+	if ent.DMARC == nil || ent.DMARC.Result != mailauth.ResultPass || !ent.DMARC.SPFAligned || ent.DMARC.Applied != mailauth.PolicyNone {
+		t.Fatalf("unexpected dmarc: %+v", ent.DMARC)
+	}
+
+	spoofed := []byte(strings.Replace(string(raw), "192.0.2.44", "203.0.113.9", 1))
+	ent, err = NewEnmimeParser(Options{DNS: dns}, mockDetector{}).Parse(context.Background(), spoofed)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if ent.SPF.Result != mailauth.ResultFail || ent.DMARC.Result != mailauth.ResultFail || ent.DMARC.Applied != mailauth.PolicyReject {
+		t.Fatalf("spoofed message should fail: spf=%+v dmarc=%+v", ent.SPF, ent.DMARC)
+	}
+
+	// An attached copy is not authenticated.
+	fwd := []byte("From: bob@example.org\r\nSubject: Fwd: Hi\r\nMessage-ID: <fwd@example.org>\r\n" +
+		"MIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=\"f\"\r\n\r\n" +
+		"--f\r\nContent-Type: message/rfc822\r\n\r\n" + string(raw) + "\r\n--f--\r\n")
+	ent, err = NewEnmimeParser(Options{DNS: dns, MaxDepth: 1}, mockDetector{}).Parse(context.Background(), fwd)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if ent.SPF == nil || len(ent.Embedded) != 1 || ent.Embedded[0].SPF != nil || ent.Embedded[0].DMARC != nil {
+		t.Fatalf("only the outer message should be checked: %+v %+v", ent.SPF, ent.Embedded)
+	}
+}
+
+func TestEnmimeParser_Parse_ReceivedHops(t *testing.T) {
+	raw := []byte(strings.ReplaceAll(`Received: from relay.example.com (relay.example.com [198.51.100.2]) by mx.example.org with ESMTPS id B2; Mon, 02 Jan 2006 15:05:05 +0000
+Received: from [10.1.2.3] (helo=laptop) by relay.example.com with ESMTPSA id A1; Mon, 02 Jan 2006 15:04:35 +0000
+From: alice@example.com
+Date: Mon, 02 Jan 2006 15:04:05 +0000
+Subject: Hops
+Message-ID: <hops@example.com>
+
+hello
+`, "\n", "\r\n"))
+
+	ent, err := NewEnmimeParser(Options{}, mockDetector{}).Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if len(ent.Hops) != 2 || ent.Hops[0].IP != "10.1.2.3" || ent.Hops[1].From != "relay.example.com" {
+		t.Fatalf("unexpected hops: %+v", ent.Hops)
+	}
+	if ent.Hops[1].Delay == nil || *ent.Hops[1].Delay != 30 {
+		t.Fatalf("unexpected hop delay: %+v", ent.Hops[1].Delay)
+	}
+	if ent.TransitSeconds == nil || *ent.TransitSeconds != 60 {
+		t.Fatalf("unexpected transit: %v", ent.TransitSeconds)
+	}
+	if ent.OriginIP != "198.51.100.2" {
+		t.Fatalf("origin ip should skip private hops, got %q", ent.OriginIP)
+	}
+}
+
+func TestEnmimeParser_Preview_RawHTMLAndNoMetrics(t *testing.T) {
+	raw := []byte("From: alice@example.com\r\nSubject: Hi\r\nMessage-ID: <pv-2@example.com>\r\n" +
+		"Received: from a.example.com (a.example.com [192.0.2.1]) by mx.example.org; Mon, 02 Jan 2006 15:04:09 +0000\r\n" +
+		"Date: Mon, 02 Jan 2006 15:04:05 +0000\r\n" +
+		"MIME-Version: 1.0\r\nContent-Type: text/html; charset=utf-8\r\nContent-Transfer-Encoding: base64\r\n\r\n" +
+		base64.StdEncoding.EncodeToString([]byte(`<p onclick="x()">Hi</p><script>alert(1)</script>`)) + "!\r\n")
+	p := NewEnmimeParser(Options{IncludeHTML: true}, nil)
+
+	warning := metrics.ParseWarnings.WithLabelValues("Malformed Base64", repository.SeverityWarning)
+	processed, latency, warnings := testutil.ToFloat64(metrics.EmailsProcessed), sampleCount(metrics.DeliveryLatency), testutil.ToFloat64(warning)
+	pv, err := p.Preview(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("Preview error: %v", err)
+	}
+	if pv.Email.HTML != `<p onclick="x()">Hi</p><script>alert(1)</script>` {
+		t.Fatalf("preview should return the html as received, got %q", pv.Email.HTML)
+	}
+	if w := pv.Email.ParseWarnings; len(w) == 0 || w[0].Type != "Malformed Base64" {
+		t.Fatalf("expected a base64 warning, got %+v", w)
+	}
+	if testutil.ToFloat64(metrics.EmailsProcessed) != processed || sampleCount(metrics.DeliveryLatency) != latency ||
+		testutil.ToFloat64(warning) != warnings {
+		t.Fatal("preview must not touch the metrics")
+	}
+
+	ent, err := p.Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if strings.Contains(ent.HTML, "script") {
+		t.Fatalf("parse should sanitize: %q", ent.HTML)
+	}
+	if testutil.ToFloat64(metrics.EmailsProcessed) != processed+1 || sampleCount(metrics.DeliveryLatency) != latency+1 ||
+		testutil.ToFloat64(warning) != warnings+1 {
+		t.Fatal("parse should be counted")
+	}
+}
+
+func sampleCount(h prometheus.Histogram) uint64 {
+	var m dto.Metric
+	_ = h.Write(&m)
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestEnmimeParser_Preview(t *testing.T) {
+	raw := []byte(strings.ReplaceAll(`From: alice@example.com
+To: bob@example.com
+Subject: Preview
+Message-ID: <preview-1@example.com>
+MIME-Version: 1.0
+Content-Type: multipart/alternative; boundary="b1"
+
+--b1
+Content-Type: text/plain; charset=utf-8
+
+Sounds good, see you Monday.
+
+On Mon, Jan 2, 2006 at 3:04 PM Bob wrote:
+> earlier message
+--b1
+Content-Type: text/html; charset=utf-8
+Content-Transfer-Encoding: base64
+
+PHA+VGhhbmtzIGZvciB0aGUgdXBkYXRlPC9wPg=!=
+--b1--
+`, "\n", "\r\n"))
+
+	p := NewEnmimeParser(Options{IncludeHTML: false}, nil)
+	pv, err := p.Preview(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("Preview error: %v", err)
+	}
+	if pv.Email == nil || pv.Email.MessageID != "preview-1@example.com" {
+		t.Fatalf("unexpected email %+v", pv.Email)
+	}
+	if pv.Email.HTML == "" {
+		t.Fatal("preview must include html even when IncludeHTML is off")
+	}
+	if pv.MIMETree == nil || pv.MIMETree.ContentType != "multipart/alternative" || len(pv.MIMETree.Children) != 2 {
+		t.Fatalf("unexpected mime tree %+v", pv.MIMETree)
+	}
+	if c := pv.MIMETree.Children[1]; c.PartID != "2" || c.ContentType != "text/html" || c.Charset != "utf-8" {
+		t.Fatalf("unexpected html part %+v", c)
+	}
+	if w := pv.Email.ParseWarnings; len(w) == 0 || w[0].PartPath != "2" || w[0].Severity != repository.SeverityWarning {
+		t.Fatalf("expected base64 warning on part 2, got %+v", w)
+	}
+	cl := pv.Cleaning
+	if cl.BodySource != "text" || cl.ReplyHeaders == 0 || cl.OutputChars >= cl.InputChars {
+		t.Fatalf("unexpected cleaning report %+v", cl)
+	}
+
+	ent, err := p.Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if ent.HTML != "" || ent.Text != pv.Email.Text {
+		t.Fatalf("Parse should match preview without html: %q vs %q", ent.Text, pv.Email.Text)
+	}
+}
+
+func TestEnmimeParser_Parse_HeaderFields(t *testing.T) {
+	raw := []byte(strings.ReplaceAll(`Received: from b.example by c.example; Mon, 02 Jan 2006 15:04:07 +0000
+Received: from a.example by b.example; Mon, 02 Jan 2006 15:04:06 +0000
+From: =?windows-1251?B?yOLg7Q==?= <ivan@example.com>
+To: bob@example.com
+Subject: =?koi8-r?B?8NLJ18XU?=
+Message-ID: <headers-1@example.com>
+Content-Type: text/plain; charset=utf-8
+
+hello
+`, "\n", "\r\n"))
+
+	ent, err := NewEnmimeParser(Options{}, nil).Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if len(ent.Headers) != 7 || ent.Headers[0].Name != "Received" || ent.Headers[1].Name != "Received" {
+		t.Fatalf("unexpected headers %+v", ent.Headers)
+	}
+	if ent.Headers[0].Value == ent.Headers[1].Value {
+		t.Fatal("repeated Received fields must not be merged")
+	}
+	if ent.Headers[2].Value != "Иван <ivan@example.com>" || ent.FromAddress == nil || ent.FromAddress.Name != "Иван" {
+		t.Fatalf("display name not decoded: %q %+v", ent.Headers[2].Value, ent.FromAddress)
+	}
+	if ent.Subject != "Привет" {
+		t.Fatalf("subject not decoded: %q", ent.Subject)
+	}
+}
+
+func TestEnmimeParser_Parse_CharsetRepair(t *testing.T) {
+	const russian = "Добрый день! Высылаю счёт за прошлый месяц, проверьте, пожалуйста"
+	body, _ := charmap.Windows1251.NewEncoder().String(russian)
+	raw := []byte("From: a@example.com\r\nMessage-ID: <cs-1@example.com>\r\n" +
+		"Content-Type: text/plain; charset=us-ascii\r\n\r\n" + body + "\r\n")
+
+	p := NewEnmimeParser(Options{}, mockDetector{code: "ru", conf: 0.9, ok: true})
+	ent, err := p.Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if ent.Text != russian {
+		t.Fatalf("text not repaired: %q", ent.Text)
+	}
+	if ent.Charset == nil || ent.Charset.Declared != "us-ascii" || ent.Charset.Chosen != "windows-1251" || ent.Charset.Repair != charset.RepairGuessed {
+		t.Fatalf("unexpected charset %+v", ent.Charset)
+	}
+
+	// Double-encoded UTF-8 is only undone in repair mode.
+	twice, _ := charmap.ISO8859_1.NewDecoder().String(russian)
+	raw = []byte("From: a@example.com\r\nMessage-ID: <cs-2@example.com>\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n\r\n" + twice + "\r\n")
+	if ent, _ := p.Parse(context.Background(), raw); ent.Text == russian {
+		t.Fatal("double-encoded text repaired without repair mode")
+	}
+	p = NewEnmimeParser(Options{RepairCharset: true}, nil)
+	ent, err = p.Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if ent.Text != russian || ent.Charset.Repair != charset.RepairDoubleEncoded {
+		t.Fatalf("double encoding not repaired: %q %+v", ent.Text, ent.Charset)
+	}
+}
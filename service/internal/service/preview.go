@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Zifeldev/emailback/service/internal/lang"
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/jhillyerd/enmime"
+)
+
+// Previewer parses a message without saving it and reports what the parser
+// saw along the way.
+type Previewer interface {
+	Preview(ctx context.Context, rawEmail []byte) (*Preview, error)
+}
+
+// Preview is a parsed email together with the diagnostics that are not
+// stored: the MIME structure and what cleaning removed. Email.HTML is the
+// HTML body as received, before sanitizing, whatever Options.IncludeHTML says.
+type Preview struct {
+	Email    *repository.EmailEntity `json:"email"`
+	MIMETree *MIMEPart               `json:"mime_tree,omitempty"`
+	Cleaning Cleaning                `json:"cleaning"`
+	// Embedded are the attached messages that would be stored as children.
+	Embedded []*repository.EmailEntity `json:"embedded,omitempty"`
+}
+
+// MIMEPart is one node of the MIME tree. PartID is enmime's dotted path,
+// empty for the root.
+type MIMEPart struct {
+	PartID      string      `json:"part_id,omitempty"`
+	ContentType string      `json:"content_type"`
+	Disposition string      `json:"disposition,omitempty"`
+	FileName    string      `json:"filename,omitempty"`
+	Charset     string      `json:"charset,omitempty"`
+	Size        int         `json:"size"`
+	Children    []*MIMEPart `json:"children,omitempty"`
+}
+
+// Cleaning reports which body was cleaned and what lang.CleanText removed.
+type Cleaning struct {
+	BodySource string `json:"body_source"` // text, html or empty
+	lang.CleanReport
+}
+
+// Preview parses raw like Parse, for inspection only.
+func (p *EnmimeParser) Preview(ctx context.Context, raw []byte) (*Preview, error) {
+	pv := &Preview{}
+	ent, err := p.parse(ctx, raw, nil, "", pv)
+	if err != nil {
+		return nil, err
+	}
+	pv.Email = ent
+	pv.Embedded = ent.Embedded
+	return pv, nil
+}
+
+// mimeTree mirrors the part tree of env.
+func mimeTree(env *enmime.Envelope) *MIMEPart {
+	if env.Root == nil {
+		return nil
+	}
+	var build func(p *enmime.Part) *MIMEPart
+	build = func(p *enmime.Part) *MIMEPart {
+		node := &MIMEPart{
+			PartID:      p.PartID,
+			ContentType: p.ContentType,
+			Disposition: p.Disposition,
+			FileName:    p.FileName,
+			Charset:     p.Charset,
+			Size:        len(p.Content),
+		}
+		for c := p.FirstChild; c != nil; c = c.NextSibling {
+			node.Children = append(node.Children, build(c))
+		}
+		return node
+	}
+	return build(env.Root)
+}
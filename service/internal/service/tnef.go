@@ -0,0 +1,64 @@
+package service
+
+import (
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/Zifeldev/emailback/service/internal/tnef"
+)
+
+// isTNEFAttachment matches winmail.dat parts by declared type, file name or
+// signature; senders are not consistent about any of them.
+func isTNEFAttachment(a repository.AttachmentEntity) bool {
+	switch strings.ToLower(a.ContentType) {
+	case "application/ms-tnef", "application/vnd.ms-tnef":
+		return true
+	}
+	if strings.EqualFold(a.Filename, "winmail.dat") {
+		return true
+	}
+	return tnef.IsTNEF(a.Data)
+}
+
+// expandTNEF replaces winmail.dat attachments with the files they carry and
+// returns the first decoded container so its body and subject can stand in
+// for an empty MIME body. Undecodable containers are kept as they are.
+func expandTNEF(atts []repository.AttachmentEntity) ([]repository.AttachmentEntity, *tnef.Message) {
+	var first *tnef.Message
+	out := atts[:0:0]
+	for _, a := range atts {
+		if !isTNEFAttachment(a) {
+			out = append(out, a)
+			continue
+		}
+		msg, err := tnef.Decode(a.Data)
+		if err != nil {
+			out = append(out, a)
+			continue
+		}
+		if first == nil {
+			first = msg
+		}
+		for _, ta := range msg.Attachments {
+			ct := ta.MIMEType
+			if ct == "" {
+				ct = mimeTypeByName(ta.Name)
+			}
+			out = append(out, newAttachment(ta.Name, ct, "attachment", ta.ContentID, ta.Data, a.CreatedAt))
+		}
+	}
+	return out, first
+}
+
+// mimeTypeByName guesses a type for extracted files that carry no MIME tag.
+func mimeTypeByName(name string) string {
+	if ct := mime.TypeByExtension(path.Ext(name)); ct != "" {
+		if i := strings.IndexByte(ct, ';'); i >= 0 {
+			ct = ct[:i]
+		}
+		return ct
+	}
+	return "application/octet-stream"
+}
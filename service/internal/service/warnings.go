@@ -0,0 +1,35 @@
+package service
+
+import (
+	"github.com/Zifeldev/emailback/service/internal/metrics"
+	"github.com/Zifeldev/emailback/service/internal/repository"
+	"github.com/jhillyerd/enmime"
+)
+
+// parseWarnings collects the errors enmime recorded on each part.
+// Envelope.Errors holds the same list without the part they belong to.
+func parseWarnings(env *enmime.Envelope) []repository.ParseWarning {
+	var out []repository.ParseWarning
+	var walk func(p *enmime.Part)
+	walk = func(p *enmime.Part) {
+		for ; p != nil; p = p.NextSibling {
+			for _, e := range p.Errors {
+				w := repository.ParseWarning{Type: e.Name, Severity: repository.SeverityWarning, PartPath: p.PartID, Detail: e.Detail}
+				if e.Severe {
+					w.Severity = repository.SeverityError
+				}
+				out = append(out, w)
+			}
+			walk(p.FirstChild)
+		}
+	}
+	walk(env.Root)
+	return out
+}
+
+// countWarnings adds the warnings of a stored message to the metrics by type.
+func countWarnings(ws []repository.ParseWarning) {
+	for _, w := range ws {
+		metrics.ParseWarnings.WithLabelValues(w.Type, w.Severity).Inc()
+	}
+}
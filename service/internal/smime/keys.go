@@ -0,0 +1,136 @@
+package smime
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// KeyPair is a recipient certificate with its private key.
+type KeyPair struct {
+	Certificate *x509.Certificate
+	PrivateKey  crypto.PrivateKey
+}
+
+// LoadKeys reads every PEM file in dir and pairs certificates with the
+// private keys that match them, wherever in the directory each was found.
+// A missing directory yields no keys.
+func LoadKeys(dir string) ([]KeyPair, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []*x509.Certificate
+	var keys []crypto.PrivateKey
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for {
+			var block *pem.Block
+			block, data = pem.Decode(data)
+			if block == nil {
+				break
+			}
+			switch block.Type {
+			case "CERTIFICATE":
+				c, err := x509.ParseCertificate(block.Bytes)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", path, err)
+				}
+				certs = append(certs, c)
+			case "PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY":
+				k, err := parsePrivateKey(block)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", path, err)
+				}
+				keys = append(keys, k)
+			}
+		}
+	}
+
+	var pairs []KeyPair
+	for _, c := range certs {
+		for _, k := range keys {
+			if pub, ok := k.(interface{ Public() crypto.PublicKey }); ok {
+				if eq, ok := pub.Public().(interface{ Equal(crypto.PublicKey) bool }); ok && eq.Equal(c.PublicKey) {
+					pairs = append(pairs, KeyPair{Certificate: c, PrivateKey: k})
+					break
+				}
+			}
+		}
+	}
+	return pairs, nil
+}
+
+func parsePrivateKey(b *pem.Block) (crypto.PrivateKey, error) {
+	switch b.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(b.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(b.Bytes)
+	}
+	k, err := x509.ParsePKCS8PrivateKey(b.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch k.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return k, nil
+	}
+	return nil, fmt.Errorf("unsupported private key type %T", k)
+}
+
+// LoadTrustStore builds the pool of trusted roots from a PEM bundle or a
+// directory of PEM files. An empty path means the system pool.
+func LoadTrustStore(path string) (*x509.CertPool, error) {
+	if path == "" {
+		return x509.SystemCertPool()
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = files[:0]
+		for _, e := range entries {
+			if !e.IsDir() {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+	}
+
+	pool := x509.NewCertPool()
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(data) && !info.IsDir() {
+			return nil, fmt.Errorf("%s: no certificates found", f)
+		}
+	}
+	return pool, nil
+}
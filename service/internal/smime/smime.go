@@ -0,0 +1,262 @@
+// Package smime verifies and decrypts S/MIME messages (RFC 8551): detached
+// multipart/signed and opaque or enveloped application/pkcs7-mime.
+package smime
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Zifeldev/emailback/service/internal/rawmime"
+	"github.com/smallstep/pkcs7"
+)
+
+// Signature results.
+const (
+	ResultPass      = "pass"      // signature valid, certificate chains to the trust store
+	ResultUntrusted = "untrusted" // signature valid, certificate not trusted
+	ResultExpired   = "expired"   // signature valid, certificate trusted only at the claimed signing time
+	ResultFail      = "fail"      // content was altered or the signature is malformed
+)
+
+// maxLayers bounds nesting such as signed-then-encrypted-then-signed.
+const maxLayers = 4
+
+// Signer describes one signature and the certificate that made it.
+type Signer struct {
+	Subject     string     `json:"subject"`
+	Issuer      string     `json:"issuer"`
+	Serial      string     `json:"serial"`
+	Emails      []string   `json:"emails,omitempty"`
+	Fingerprint string     `json:"fingerprint_sha256"`
+	NotBefore   time.Time  `json:"not_before"`
+	NotAfter    time.Time  `json:"not_after"`
+	SigningTime *time.Time `json:"signing_time,omitempty"`
+	Result      string     `json:"result"`
+	Reason      string     `json:"reason,omitempty"`
+}
+
+// Result summarises the S/MIME layers found on a message.
+type Result struct {
+	Signed    bool     `json:"signed"`
+	Encrypted bool     `json:"encrypted"`
+	Decrypted bool     `json:"decrypted"`
+	Signers   []Signer `json:"signers,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Processor unwraps S/MIME layers. Roots is the trust store for signer
+// certificates (nil means the system pool); Keys decrypt enveloped data.
+type Processor struct {
+	Roots *x509.CertPool
+	Keys  []KeyPair
+	// Now is the time certificates are validated at; tests override it.
+	Now func() time.Time
+}
+
+func NewProcessor(roots *x509.CertPool, keys []KeyPair) *Processor {
+	return &Processor{Roots: roots, Keys: keys, Now: time.Now}
+}
+
+// Unwrap returns the innermost message with the original outer headers and a
+// summary of the layers removed. Messages without S/MIME come back unchanged
+// with a nil result. A layer that cannot be opened stops unwrapping; the
+// message up to that point is returned and the reason recorded in the result.
+func (p *Processor) Unwrap(raw []byte) ([]byte, *Result) {
+	var res *Result
+	msg := rawmime.ToCRLF(raw)
+	for i := 0; i < maxLayers; i++ {
+		hdr, body, ok := rawmime.SplitEntity(msg)
+		if !ok {
+			break
+		}
+		ct, params := rawmime.MediaType(hdr)
+		var inner []byte
+		var err error
+		switch {
+		case ct == "multipart/signed" && isPKCS7Signature(params["protocol"]):
+			if res == nil {
+				res = &Result{}
+			}
+			res.Signed = true
+			inner, err = p.detached(body, params["boundary"], res)
+		case ct == "application/pkcs7-mime" || ct == "application/x-pkcs7-mime":
+			if res == nil {
+				res = &Result{}
+			}
+			inner, err = p.opaque(hdr, body, params["smime-type"], res)
+		default:
+			return msg, res
+		}
+		if err != nil {
+			res.Error = err.Error()
+			return msg, res
+		}
+		msg = rawmime.Rewrap(hdr, rawmime.ToCRLF(inner))
+	}
+	return msg, res
+}
+
+// detached verifies a multipart/signed body and returns the signed entity.
+func (p *Processor) detached(body []byte, boundary string, res *Result) ([]byte, error) {
+	parts := rawmime.SplitMultipart(body, boundary)
+	if len(parts) < 2 {
+		return nil, errors.New("smime: multipart/signed needs two parts")
+	}
+	sigHdr, sigBody, ok := rawmime.SplitEntity(parts[1])
+	if !ok {
+		return nil, errors.New("smime: malformed signature part")
+	}
+	der, err := rawmime.DecodeBody(sigHdr, sigBody)
+	if err != nil {
+		return nil, err
+	}
+	p7, err := pkcs7.Parse(der)
+	if err != nil {
+		return nil, fmt.Errorf("smime: signature: %w", err)
+	}
+	p7.Content = parts[0]
+	res.Signers = append(res.Signers, p.verify(p7)...)
+	return parts[0], nil
+}
+
+// opaque handles application/pkcs7-mime: signed-data carries the content
+// itself, enveloped-data needs one of our keys.
+func (p *Processor) opaque(hdr, body []byte, smimeType string, res *Result) ([]byte, error) {
+	der, err := rawmime.DecodeBody(hdr, body)
+	if err != nil {
+		return nil, err
+	}
+	p7, err := pkcs7.Parse(der)
+	if err != nil {
+		return nil, fmt.Errorf("smime: %w", err)
+	}
+	if len(p7.Signers) > 0 || strings.EqualFold(smimeType, "signed-data") {
+		res.Signed = true
+		res.Signers = append(res.Signers, p.verify(p7)...)
+		return p7.Content, nil
+	}
+
+	res.Encrypted = true
+	if len(p.Keys) == 0 {
+		return nil, errors.New("smime: encrypted message and no private keys configured")
+	}
+	for _, k := range p.Keys {
+		if plain, err := p7.Decrypt(k.Certificate, k.PrivateKey); err == nil {
+			res.Decrypted = true
+			return plain, nil
+		}
+	}
+	return nil, errors.New("smime: no private key matches the message recipients")
+}
+
+// verify checks the signature over p7.Content once and then the trust chain
+// of every signer certificate separately. Chains are validated at p.Now():
+// the signing time is an attribute the signer chose, so a chain that only
+// holds at that time is reported as expired rather than passed.
+func (p *Processor) verify(p7 *pkcs7.PKCS7) []Signer {
+	sigErr := p7.Verify()
+
+	var signingTime *time.Time
+	var t time.Time
+	if err := p7.UnmarshalSignedAttribute(pkcs7.OIDAttributeSigningTime, &t); err == nil {
+		signingTime = &t
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range p7.Certificates {
+		intermediates.AddCert(c)
+	}
+
+	var out []Signer
+	for _, si := range p7.Signers {
+		cert := findCert(p7.Certificates, si.IssuerAndSerialNumber.IssuerName.FullBytes, si.IssuerAndSerialNumber.SerialNumber.String())
+		if cert == nil {
+			out = append(out, Signer{Result: ResultFail, Reason: "signer certificate not included"})
+			continue
+		}
+		s := describe(cert)
+		s.SigningTime = signingTime
+		switch {
+		case sigErr != nil:
+			s.Result, s.Reason = ResultFail, sigErr.Error()
+		default:
+			opts := x509.VerifyOptions{
+				Roots:         p.Roots,
+				Intermediates: intermediates,
+				CurrentTime:   p.Now(),
+				KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection, x509.ExtKeyUsageAny},
+			}
+			_, err := cert.Verify(opts)
+			if err == nil {
+				s.Result = ResultPass
+				break
+			}
+			s.Result, s.Reason = ResultUntrusted, err.Error()
+			if signingTime != nil {
+				opts.CurrentTime = *signingTime
+				if _, err := cert.Verify(opts); err == nil {
+					s.Result = ResultExpired
+				}
+			}
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func findCert(certs []*x509.Certificate, issuer []byte, serial string) *x509.Certificate {
+	for _, c := range certs {
+		if c.SerialNumber.String() == serial && (len(issuer) == 0 || bytes.Equal(c.RawIssuer, issuer)) {
+			return c
+		}
+	}
+	return nil
+}
+
+var oidEmailAddress = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 1}
+
+func describe(c *x509.Certificate) Signer {
+	sum := sha256.Sum256(c.Raw)
+	s := Signer{
+		Subject:     c.Subject.String(),
+		Issuer:      c.Issuer.String(),
+		Serial:      c.SerialNumber.Text(16),
+		Emails:      append([]string(nil), c.EmailAddresses...),
+		Fingerprint: hex.EncodeToString(sum[:]),
+		NotBefore:   c.NotBefore.UTC(),
+		NotAfter:    c.NotAfter.UTC(),
+	}
+	// Older certificates carry the address in the subject DN only.
+	for _, n := range c.Subject.Names {
+		if n.Type.Equal(oidEmailAddress) {
+			if v, ok := n.Value.(string); ok && !containsFold(s.Emails, v) {
+				s.Emails = append(s.Emails, v)
+			}
+		}
+	}
+	return s
+}
+
+func isPKCS7Signature(protocol string) bool {
+	switch strings.ToLower(protocol) {
+	case "application/pkcs7-signature", "application/x-pkcs7-signature":
+		return true
+	}
+	return false
+}
+
+func containsFold(list []string, v string) bool {
+	for _, s := range list {
+		if strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
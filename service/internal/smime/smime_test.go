@@ -0,0 +1,262 @@
+package smime
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smallstep/pkcs7"
+)
+
+type testPKI struct {
+	caCert   *x509.Certificate
+	leaf     *x509.Certificate
+	leafKey  *rsa.PrivateKey
+	roots    *x509.CertPool
+	leafPEM  []byte
+	leafKPEM []byte
+}
+
+var (
+	pkiOnce sync.Once
+	pki     testPKI
+)
+
+func newPKI(t *testing.T) testPKI {
+	t.Helper()
+	pkiOnce.Do(func() {
+		caKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+		ca := &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: "Test CA"},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(24 * time.Hour),
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign,
+		}
+		der, _ := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+		pki.caCert, _ = x509.ParseCertificate(der)
+
+		pki.leafKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+		leaf := &x509.Certificate{
+			SerialNumber:   big.NewInt(42),
+			Subject:        pkix.Name{CommonName: "Alice"},
+			EmailAddresses: []string{"alice@example.com"},
+			NotBefore:      time.Now().Add(-time.Hour),
+			NotAfter:       time.Now().Add(24 * time.Hour),
+			KeyUsage:       x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+			ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection},
+		}
+		der, _ = x509.CreateCertificate(rand.Reader, leaf, pki.caCert, &pki.leafKey.PublicKey, caKey)
+		pki.leaf, _ = x509.ParseCertificate(der)
+		pki.roots = x509.NewCertPool()
+		pki.roots.AddCert(pki.caCert)
+		pki.leafPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+		pki.leafKPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(pki.leafKey)})
+	})
+	if pki.leaf == nil {
+		t.Fatal("failed to build test certificates")
+	}
+	return pki
+}
+
+func sign(t *testing.T, p testPKI, content []byte, detached bool) []byte {
+	t.Helper()
+	sd, err := pkcs7.NewSignedData(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+	if err := sd.AddSigner(p.leaf, p.leafKey, pkcs7.SignerInfoConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if detached {
+		sd.Detach()
+	}
+	der, err := sd.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func b64(b []byte) string {
+	s := base64.StdEncoding.EncodeToString(b)
+	var out strings.Builder
+	for len(s) > 76 {
+		out.WriteString(s[:76] + "\r\n")
+		s = s[76:]
+	}
+	out.WriteString(s)
+	return out.String()
+}
+
+const innerEntity = "Content-Type: text/plain; charset=UTF-8\r\n\r\nSigned contract attached.\r\n"
+
+func signedMessage(t *testing.T, p testPKI, signed string) []byte {
+	sig := sign(t, p, []byte(signed), true)
+	return []byte("From: Alice <alice@example.com>\r\n" +
+		"To: legal@example.org\r\n" +
+		"Subject: Contract\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/signed; protocol=\"application/pkcs7-signature\"; micalg=sha-256; boundary=\"sig\"\r\n" +
+		"\r\n" +
+		"This is an S/MIME signed message\r\n" +
+		"--sig\r\n" +
+		innerEntity +
+		"\r\n--sig\r\n" +
+		"Content-Type: application/pkcs7-signature; name=smime.p7s\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		b64(sig) + "\r\n" +
+		"--sig--\r\n")
+}
+
+func TestUnwrap_DetachedSignature(t *testing.T) {
+	p := newPKI(t)
+	raw := signedMessage(t, p, innerEntity)
+
+	out, res := NewProcessor(p.roots, nil).Unwrap(raw)
+	if res == nil || !res.Signed || res.Encrypted || res.Error != "" {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if len(res.Signers) != 1 {
+		t.Fatalf("want 1 signer, got %+v", res.Signers)
+	}
+	s := res.Signers[0]
+	if s.Result != ResultPass || s.Subject != "CN=Alice" || s.Issuer != "CN=Test CA" || s.Serial != "2a" {
+		t.Fatalf("unexpected signer %+v", s)
+	}
+	if len(s.Emails) != 1 || s.Emails[0] != "alice@example.com" || len(s.Fingerprint) != 64 {
+		t.Fatalf("unexpected signer details %+v", s)
+	}
+	if !bytes.HasPrefix(out, []byte("From: Alice <alice@example.com>\r\n")) ||
+		!bytes.Contains(out, []byte("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\nSigned contract attached.")) ||
+		bytes.Contains(out, []byte("multipart/signed")) {
+		t.Fatalf("unexpected unwrapped message:\n%s", out)
+	}
+}
+
+func TestUnwrap_TamperedAndUntrusted(t *testing.T) {
+	p := newPKI(t)
+	raw := signedMessage(t, p, innerEntity)
+
+	tampered := bytes.Replace(raw, []byte("Signed contract"), []byte("Forged contract"), 1)
+	_, res := NewProcessor(p.roots, nil).Unwrap(tampered)
+	if res == nil || len(res.Signers) != 1 || res.Signers[0].Result != ResultFail {
+		t.Fatalf("tampered content must fail: %+v", res)
+	}
+
+	_, res = NewProcessor(x509.NewCertPool(), nil).Unwrap(raw)
+	if res == nil || len(res.Signers) != 1 || res.Signers[0].Result != ResultUntrusted {
+		t.Fatalf("unknown issuer must be untrusted: %+v", res)
+	}
+
+	// Bare LF line endings are canonicalised before checking.
+	lf := bytes.ReplaceAll(raw, []byte("\r\n"), []byte("\n"))
+	if _, res := NewProcessor(p.roots, nil).Unwrap(lf); res == nil || res.Signers[0].Result != ResultPass {
+		t.Fatalf("LF message should verify: %+v", res)
+	}
+}
+
+func TestUnwrap_ValidatedNowNotAtSigningTime(t *testing.T) {
+	p := newPKI(t)
+	raw := signedMessage(t, p, innerEntity)
+
+	proc := NewProcessor(p.roots, nil)
+	proc.Now = func() time.Time { return time.Now().Add(48 * time.Hour) }
+	_, res := proc.Unwrap(raw)
+	if res == nil || len(res.Signers) != 1 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if s := res.Signers[0]; s.Result != ResultExpired || s.SigningTime == nil || !strings.Contains(s.Reason, "expired") {
+		t.Fatalf("certificate expired since signing must not pass: %+v", s)
+	}
+
+	_, res = NewProcessor(x509.NewCertPool(), nil).Unwrap(raw)
+	if res.Signers[0].Result != ResultUntrusted {
+		t.Fatalf("untrusted at any time: %+v", res.Signers[0])
+	}
+}
+
+func TestUnwrap_OpaqueSignedInsideEncrypted(t *testing.T) {
+	p := newPKI(t)
+	saved := pkcs7.ContentEncryptionAlgorithm
+	pkcs7.ContentEncryptionAlgorithm = pkcs7.EncryptionAlgorithmAES256CBC
+	defer func() { pkcs7.ContentEncryptionAlgorithm = saved }()
+
+	opaque := "Content-Type: application/pkcs7-mime; smime-type=signed-data; name=smime.p7m\r\n" +
+		"Content-Transfer-Encoding: base64\r\n\r\n" +
+		b64(sign(t, p, []byte(innerEntity), false)) + "\r\n"
+	env, err := pkcs7.Encrypt([]byte(opaque), []*x509.Certificate{p.leaf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := []byte("From: alice@example.com\r\n" +
+		"Subject: Secret\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: application/pkcs7-mime; smime-type=enveloped-data; name=smime.p7m\r\n" +
+		"Content-Transfer-Encoding: base64\r\n\r\n" +
+		b64(env) + "\r\n")
+
+	keys := []KeyPair{{Certificate: p.leaf, PrivateKey: p.leafKey}}
+	out, res := NewProcessor(p.roots, keys).Unwrap(raw)
+	if res == nil || !res.Encrypted || !res.Decrypted || !res.Signed || res.Error != "" {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if len(res.Signers) != 1 || res.Signers[0].Result != ResultPass {
+		t.Fatalf("unexpected signers %+v", res.Signers)
+	}
+	if !bytes.Contains(out, []byte("Subject: Secret\r\n")) || !bytes.HasSuffix(out, []byte("Signed contract attached.\r\n")) {
+		t.Fatalf("unexpected unwrapped message:\n%s", out)
+	}
+
+	out, res = NewProcessor(p.roots, nil).Unwrap(raw)
+	if res == nil || !res.Encrypted || res.Decrypted || res.Error == "" {
+		t.Fatalf("missing key must be reported: %+v", res)
+	}
+	if !bytes.Equal(out, raw) {
+		t.Fatalf("undecryptable message should be returned as is")
+	}
+}
+
+func TestUnwrap_PlainMessage(t *testing.T) {
+	raw := []byte("From: a@example.com\r\nContent-Type: text/plain\r\n\r\nhi\r\n")
+	out, res := NewProcessor(nil, nil).Unwrap(raw)
+	if res != nil || !bytes.Equal(out, raw) {
+		t.Fatalf("plain message changed: %+v", res)
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	p := newPKI(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "alice.crt"), p.leafPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "alice.key"), p.leafKPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadKeys(dir)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(keys) != 1 || keys[0].Certificate.SerialNumber.Int64() != 42 {
+		t.Fatalf("unexpected keys %+v", keys)
+	}
+	if keys, err := LoadKeys(filepath.Join(dir, "missing")); err != nil || keys != nil {
+		t.Fatalf("missing dir should yield no keys: %v %v", keys, err)
+	}
+}
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalStore_PutGetDelete(t *testing.T) {
+	s, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "attachments/ab/abcdef", strings.NewReader("payload")); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	rc, err := s.Get(ctx, "attachments/ab/abcdef")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	bs, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(bs) != "payload" {
+		t.Fatalf("unexpected payload %q", bs)
+	}
+
+	if err := s.Delete(ctx, "attachments/ab/abcdef"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := s.Get(ctx, "attachments/ab/abcdef"); err != ErrBlobNotFound {
+		t.Fatalf("expected ErrBlobNotFound, got %v", err)
+	}
+}
+
+func TestLocalStore_RejectsTraversal(t *testing.T) {
+	s, _ := NewLocalStore(t.TempDir())
+	if err := s.Put(context.Background(), "../escape", strings.NewReader("x")); err == nil {
+		t.Fatalf("expected error for traversal key")
+	}
+}
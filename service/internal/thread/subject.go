@@ -0,0 +1,61 @@
+package thread
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// Reply/forward markers used by common clients in several locales, optionally
+	// followed by a counter: "Re:", "RE[2]:", "Fwd:", "AW:", "WG:", "Ответ:", "Отв:".
+	reSubjectPrefix = regexp.MustCompile(`(?i)^\s*(?:re|fw|fwd|aw|wg|sv|vs|antw|ответ|отв|пересл)\s*(?:\[\d+\]|\(\d+\))?\s*:\s*`)
+	reListTag       = regexp.MustCompile(`^\s*\[[^\]]{1,40}\]\s*`)
+	reMessageID     = regexp.MustCompile(`<([^<>\s]+)>`)
+	reSpaces        = regexp.MustCompile(`\s+`)
+)
+
+// NormalizeSubject strips reply/forward prefixes and mailing-list tags and folds
+// whitespace and case, so that "Re: [team] Fwd: Plan" and "plan" compare equal.
+func NormalizeSubject(s string) string {
+	for {
+		prev := s
+		s = reSubjectPrefix.ReplaceAllString(s, "")
+		s = reListTag.ReplaceAllString(s, "")
+		if s == prev {
+			break
+		}
+	}
+	s = reSpaces.ReplaceAllString(strings.TrimSpace(s), " ")
+	return strings.ToLower(s)
+}
+
+// IsReplySubject reports whether the subject carries a reply or forward marker,
+// possibly behind a list tag.
+func IsReplySubject(s string) bool {
+	s = reListTag.ReplaceAllString(s, "")
+	return reSubjectPrefix.MatchString(s)
+}
+
+// ParseMessageIDs extracts message ids in header order from a References or
+// In-Reply-To value. Ids are returned without angle brackets. Values written by
+// broken clients without brackets are accepted when they look like a single id.
+func ParseMessageIDs(header string) []string {
+	matches := reMessageID.FindAllStringSubmatch(header, -1)
+	if len(matches) == 0 {
+		h := strings.TrimSpace(header)
+		if h != "" && !strings.ContainsAny(h, " \t,") && strings.Contains(h, "@") {
+			return []string{h}
+		}
+		return nil
+	}
+	out := make([]string, 0, len(matches))
+	seen := make(map[string]struct{}, len(matches))
+	for _, m := range matches {
+		if _, dup := seen[m[1]]; dup {
+			continue
+		}
+		seen[m[1]] = struct{}{}
+		out = append(out, m[1])
+	}
+	return out
+}
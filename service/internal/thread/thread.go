@@ -0,0 +1,264 @@
+package thread
+
+import (
+	"sort"
+	"time"
+)
+
+// Message is the subset of an email needed to place it in a conversation.
+type Message struct {
+	ID         string     `json:"id"`
+	MessageID  string     `json:"message_id"`
+	InReplyTo  string     `json:"in_reply_to,omitempty"`
+	References []string   `json:"references,omitempty"`
+	From       string     `json:"from,omitempty"`
+	Subject    string     `json:"subject"`
+	Date       *time.Time `json:"date,omitempty"`
+}
+
+// Node is one position in a conversation tree. Message is nil for a
+// placeholder that stands for a referenced message we never received.
+type Node struct {
+	Message  *Message `json:"message,omitempty"`
+	Children []*Node  `json:"children,omitempty"`
+
+	parent *Node
+	id     string
+}
+
+// Build arranges messages into conversation trees following the algorithm
+// described by Jamie Zawinski (https://www.jwz.org/doc/threading.html):
+// link by References/In-Reply-To, prune empty containers, group remaining
+// roots by normalized subject, and order siblings by date.
+func Build(msgs []*Message) []*Node {
+	table := make(map[string]*Node, len(msgs)*2)
+	get := func(id string) *Node {
+		if n, ok := table[id]; ok {
+			return n
+		}
+		n := &Node{id: id}
+		table[id] = n
+		return n
+	}
+
+	for _, m := range msgs {
+		id := m.MessageID
+		if id == "" {
+			id = "\x00" + m.ID
+		}
+		n := get(id)
+		if n.Message != nil {
+			// Duplicate Message-ID: keep both by giving the second a private slot.
+			n = get(id + "\x00" + m.ID)
+		}
+		n.Message = m
+
+		refs := references(m)
+		var prev *Node
+		for _, ref := range refs {
+			r := get(ref)
+			if prev != nil && r.parent == nil && !reachable(r, prev) {
+				link(prev, r)
+			}
+			prev = r
+		}
+		if n.parent != nil {
+			unlink(n)
+		}
+		if prev != nil && prev != n && !reachable(n, prev) {
+			link(prev, n)
+		}
+	}
+
+	var roots []*Node
+	for _, n := range table {
+		if n.parent == nil {
+			roots = append(roots, n)
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].id < roots[j].id })
+
+	roots = pruneList(roots, true)
+	roots = groupBySubject(roots)
+	sortTree(roots)
+	return roots
+}
+
+// Flatten returns the messages of the trees in depth-first reading order.
+func Flatten(roots []*Node) []*Message {
+	var out []*Message
+	var walk func(ns []*Node)
+	walk = func(ns []*Node) {
+		for _, n := range ns {
+			if n.Message != nil {
+				out = append(out, n.Message)
+			}
+			walk(n.Children)
+		}
+	}
+	walk(roots)
+	return out
+}
+
+func references(m *Message) []string {
+	refs := make([]string, 0, len(m.References)+1)
+	for _, r := range m.References {
+		if r != "" && r != m.MessageID {
+			refs = append(refs, r)
+		}
+	}
+	if m.InReplyTo != "" && m.InReplyTo != m.MessageID {
+		if len(refs) == 0 || refs[len(refs)-1] != m.InReplyTo {
+			refs = append(refs, m.InReplyTo)
+		}
+	}
+	return refs
+}
+
+// reachable reports whether target is n itself or one of its descendants.
+func reachable(n, target *Node) bool {
+	if n == target {
+		return true
+	}
+	for _, c := range n.Children {
+		if reachable(c, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func link(parent, child *Node) {
+	child.parent = parent
+	parent.Children = append(parent.Children, child)
+}
+
+func unlink(n *Node) {
+	p := n.parent
+	for i, c := range p.Children {
+		if c == n {
+			p.Children = append(p.Children[:i], p.Children[i+1:]...)
+			break
+		}
+	}
+	n.parent = nil
+}
+
+// pruneList drops empty leaf containers and replaces empty containers with
+// their children. At the root level an empty container is only dissolved when
+// it has a single child, otherwise it keeps unrelated replies together.
+func pruneList(ns []*Node, root bool) []*Node {
+	out := make([]*Node, 0, len(ns))
+	for _, n := range ns {
+		n.Children = pruneList(n.Children, false)
+		if n.Message != nil {
+			out = append(out, n)
+			continue
+		}
+		switch {
+		case len(n.Children) == 0:
+		case !root || len(n.Children) == 1:
+			for _, c := range n.Children {
+				c.parent = n.parent
+				out = append(out, c)
+			}
+		default:
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func nodeSubject(n *Node) (string, *Message) {
+	if n.Message != nil {
+		return n.Message.Subject, n.Message
+	}
+	for _, c := range n.Children {
+		if c.Message != nil {
+			return c.Message.Subject, c.Message
+		}
+	}
+	return "", nil
+}
+
+// groupBySubject merges root threads that share a normalized subject. This is
+// the fallback for clients that drop References and In-Reply-To.
+func groupBySubject(roots []*Node) []*Node {
+	bySubj := make(map[string]*Node, len(roots))
+	out := make([]*Node, 0, len(roots))
+	for _, n := range roots {
+		subj, _ := nodeSubject(n)
+		key := NormalizeSubject(subj)
+		if key == "" {
+			out = append(out, n)
+			continue
+		}
+		existing, ok := bySubj[key]
+		if !ok {
+			bySubj[key] = n
+			out = append(out, n)
+			continue
+		}
+		existingSubj, _ := nodeSubject(existing)
+		switch {
+		case existing.Message == nil && n.Message == nil:
+			for _, c := range n.Children {
+				link(existing, c)
+			}
+		case existing.Message == nil:
+			link(existing, n)
+		case n.Message == nil:
+			link(n, existing)
+			replace(out, existing, n)
+			bySubj[key] = n
+		case IsReplySubject(subj) && !IsReplySubject(existingSubj):
+			link(existing, n)
+		case !IsReplySubject(subj) && IsReplySubject(existingSubj):
+			link(n, existing)
+			replace(out, existing, n)
+			bySubj[key] = n
+		default:
+			holder := &Node{}
+			link(holder, existing)
+			link(holder, n)
+			replace(out, existing, holder)
+			bySubj[key] = holder
+		}
+	}
+	return out
+}
+
+func replace(ns []*Node, old, repl *Node) {
+	for i, n := range ns {
+		if n == old {
+			ns[i] = repl
+			return
+		}
+	}
+}
+
+func earliest(n *Node) time.Time {
+	var t time.Time
+	if n.Message != nil && n.Message.Date != nil {
+		t = *n.Message.Date
+	}
+	for _, c := range n.Children {
+		if ct := earliest(c); !ct.IsZero() && (t.IsZero() || ct.Before(t)) {
+			t = ct
+		}
+	}
+	return t
+}
+
+func sortTree(ns []*Node) {
+	sort.SliceStable(ns, func(i, j int) bool {
+		ti, tj := earliest(ns[i]), earliest(ns[j])
+		if ti.IsZero() != tj.IsZero() {
+			return !ti.IsZero()
+		}
+		return ti.Before(tj)
+	})
+	for _, n := range ns {
+		sortTree(n.Children)
+	}
+}
@@ -0,0 +1,89 @@
+package thread
+
+import (
+	"testing"
+	"time"
+)
+
+func at(min int) *time.Time {
+	t := time.Date(2025, 1, 1, 10, min, 0, 0, time.UTC)
+	return &t
+}
+
+func TestNormalizeSubject(t *testing.T) {
+	cases := map[string]string{
+		"Re: Fwd: Plan":             "plan",
+		"RE[2]: [team] Plan":        "plan",
+		"AW: WG:  Quarterly   plan": "quarterly plan",
+		"Ответ: Счёт":               "счёт",
+		"Plan":                      "plan",
+	}
+	for in, want := range cases {
+		if got := NormalizeSubject(in); got != want {
+			t.Errorf("NormalizeSubject(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if !IsReplySubject("[team] Re: x") || IsReplySubject("Regarding x") {
+		t.Errorf("IsReplySubject misclassified")
+	}
+}
+
+func TestParseMessageIDs(t *testing.T) {
+	got := ParseMessageIDs("<a@x> <b@x>\r\n <a@x>")
+	if len(got) != 2 || got[0] != "a@x" || got[1] != "b@x" {
+		t.Fatalf("unexpected ids: %v", got)
+	}
+	if got := ParseMessageIDs("bare@x"); len(got) != 1 || got[0] != "bare@x" {
+		t.Fatalf("expected bare id, got %v", got)
+	}
+	if got := ParseMessageIDs("not an id"); got != nil {
+		t.Fatalf("expected nil, got %v", got)
+	}
+}
+
+func TestBuild_ReferencesAndMissingParent(t *testing.T) {
+	msgs := []*Message{
+		{ID: "3", MessageID: "c@x", References: []string{"a@x", "b@x"}, Subject: "Re: Plan", Date: at(3)},
+		{ID: "1", MessageID: "a@x", Subject: "Plan", Date: at(1)},
+		// b@x was never received; c@x still hangs below a@x through it.
+		{ID: "4", MessageID: "d@x", InReplyTo: "a@x", Subject: "Re: Plan", Date: at(2)},
+	}
+	roots := Build(msgs)
+	if len(roots) != 1 || roots[0].Message.ID != "1" {
+		t.Fatalf("expected single root a@x, got %+v", roots)
+	}
+	order := Flatten(roots)
+	if len(order) != 3 || order[0].ID != "1" || order[1].ID != "4" || order[2].ID != "3" {
+		ids := []string{}
+		for _, m := range order {
+			ids = append(ids, m.ID)
+		}
+		t.Fatalf("unexpected order: %v", ids)
+	}
+}
+
+func TestBuild_SubjectFallback(t *testing.T) {
+	msgs := []*Message{
+		{ID: "2", MessageID: "r@x", Subject: "Re: Invoice 42", Date: at(5)},
+		{ID: "1", MessageID: "o@x", Subject: "Invoice 42", Date: at(1)},
+		{ID: "3", MessageID: "z@x", Subject: "Other", Date: at(2)},
+	}
+	roots := Build(msgs)
+	if len(roots) != 2 {
+		t.Fatalf("expected 2 roots, got %d", len(roots))
+	}
+	if roots[0].Message.ID != "1" || len(roots[0].Children) != 1 || roots[0].Children[0].Message.ID != "2" {
+		t.Fatalf("reply not attached to original by subject")
+	}
+}
+
+func TestBuild_NoLoops(t *testing.T) {
+	msgs := []*Message{
+		{ID: "1", MessageID: "a@x", References: []string{"b@x"}, Subject: "x"},
+		{ID: "2", MessageID: "b@x", References: []string{"a@x"}, Subject: "x"},
+	}
+	roots := Build(msgs)
+	if got := len(Flatten(roots)); got != 2 {
+		t.Fatalf("expected both messages once, got %d", got)
+	}
+}
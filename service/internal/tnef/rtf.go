@@ -0,0 +1,283 @@
+package tnef
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Compressed RTF (MS-OXRTFCP) stream types.
+const (
+	rtfCompressed   = 0x75465A4C // "LZFu"
+	rtfUncompressed = 0x414C454D // "MELA"
+)
+
+// rtfPrebuf is the dictionary every compressed RTF stream starts with.
+const rtfPrebuf = "{\\rtf1\\ansi\\mac\\deff0\\deftab720{\\fonttbl;}" +
+	"{\\f0\\fnil \\froman \\fswiss \\fmodern \\fscript \\fdecor MS Sans SerifSymbolArialTimes New RomanCourier" +
+	"{\\colortbl\\red0\\green0\\blue0\r\n\\par \\pard\\plain\\f0\\fs20\\b\\i\\u\\tab\\tx"
+
+var errBadRTF = errors.New("tnef: malformed compressed rtf")
+
+// DecompressRTF expands a PR_RTF_COMPRESSED value.
+func DecompressRTF(b []byte) ([]byte, error) {
+	if len(b) < 16 {
+		return nil, errBadRTF
+	}
+	compSize := binary.LittleEndian.Uint32(b[0:])
+	rawSize := binary.LittleEndian.Uint32(b[4:])
+	kind := binary.LittleEndian.Uint32(b[8:])
+	data := b[16:]
+	if int(compSize)-12 < len(data) && compSize >= 12 {
+		data = data[:compSize-12]
+	}
+
+	switch kind {
+	case rtfUncompressed:
+		if int(rawSize) < len(data) {
+			data = data[:rawSize]
+		}
+		return data, nil
+	case rtfCompressed:
+	default:
+		return nil, errBadRTF
+	}
+
+	const dictSize = 4096
+	dict := make([]byte, dictSize)
+	copy(dict, rtfPrebuf)
+	wpos := len(rtfPrebuf)
+	// rawSize comes from the sender; a literal expands at most 17-fold, so
+	// never reserve more than that, and stop once rawSize bytes are out.
+	limit := int(rawSize)
+	out := make([]byte, 0, min(limit, 17*len(data)))
+
+	for i := 0; i < len(data) && len(out) < limit; {
+		control := data[i]
+		i++
+		for bit := 0; bit < 8 && i < len(data) && len(out) < limit; bit++ {
+			if control&(1<<bit) == 0 {
+				c := data[i]
+				i++
+				out = append(out, c)
+				dict[wpos] = c
+				wpos = (wpos + 1) % dictSize
+				continue
+			}
+			if i+1 >= len(data) {
+				return nil, errBadRTF
+			}
+			ref := int(data[i])<<8 | int(data[i+1])
+			i += 2
+			offset, length := ref>>4, ref&0xF+2
+			if offset == wpos {
+				return out, nil
+			}
+			for k := 0; k < length && len(out) < limit; k++ {
+				c := dict[(offset+k)%dictSize]
+				out = append(out, c)
+				dict[wpos] = c
+				wpos = (wpos + 1) % dictSize
+			}
+		}
+	}
+	return out, nil
+}
+
+// RTFToText renders RTF as plain text, dropping formatting and hidden destinations.
+func RTFToText(rtf []byte) string {
+	var sb strings.Builder
+	walkRTF(rtf, func(ev rtfEvent) {
+		if ev.inHTMLTag || ev.skip {
+			return
+		}
+		sb.WriteString(ev.text)
+	})
+	return strings.TrimSpace(sb.String())
+}
+
+// RTFToHTML de-encapsulates HTML from RTF generated with \fromhtml1
+// (MS-OXRTFEX). It reports false for ordinary RTF.
+func RTFToHTML(rtf []byte) (string, bool) {
+	if !bytes.Contains(rtf[:min(len(rtf), 1024)], []byte(`\fromhtml`)) {
+		return "", false
+	}
+	var sb strings.Builder
+	walkRTF(rtf, func(ev rtfEvent) {
+		if ev.inHTMLRTF {
+			return
+		}
+		if ev.inHTMLTag || !ev.skip {
+			sb.WriteString(ev.text)
+		}
+	})
+	return sb.String(), true
+}
+
+type rtfEvent struct {
+	text      string
+	skip      bool // inside an ignorable destination
+	inHTMLTag bool // inside {\*\htmltag ...}
+	inHTMLRTF bool // between \htmlrtf and \htmlrtf0
+}
+
+type rtfState struct {
+	skip      bool
+	inHTMLTag bool
+	ucSkip    int
+}
+
+// skippedDestinations never contribute visible text.
+var skippedDestinations = map[string]bool{
+	"fonttbl": true, "colortbl": true, "stylesheet": true, "info": true, "pict": true,
+	"header": true, "footer": true, "object": true, "listtable": true, "listoverridetable": true,
+	"rsidtbl": true, "generator": true, "xmlnstbl": true, "themedata": true, "datastore": true,
+	"latentstyles": true, "mmathPr": true,
+}
+
+// walkRTF tokenizes rtf and emits text runs together with the state they were
+// found in. Control words are translated where they stand for text.
+func walkRTF(rtf []byte, emit func(rtfEvent)) {
+	stack := []rtfState{{ucSkip: 1}}
+	htmlrtf := false
+	pendingSkip := 0 // characters to drop after \uN
+	starDest := false
+
+	cur := func() *rtfState { return &stack[len(stack)-1] }
+	out := func(s string) {
+		if pendingSkip > 0 {
+			pendingSkip--
+			return
+		}
+		st := cur()
+		emit(rtfEvent{text: s, skip: st.skip, inHTMLTag: st.inHTMLTag, inHTMLRTF: htmlrtf && !st.inHTMLTag})
+	}
+
+	for i := 0; i < len(rtf); i++ {
+		c := rtf[i]
+		switch c {
+		case '{':
+			stack = append(stack, *cur())
+			starDest = false
+		case '}':
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case '\r', '\n':
+		case '\\':
+			if i+1 >= len(rtf) {
+				return
+			}
+			n := rtf[i+1]
+			switch {
+			case n == '\\' || n == '{' || n == '}':
+				out(string(n))
+				i++
+			case n == '*':
+				starDest = true
+				i++
+			case n == '\'':
+				if i+3 < len(rtf) {
+					if v, err := strconv.ParseUint(string(rtf[i+2:i+4]), 16, 8); err == nil {
+						out(string(cp1252(byte(v))))
+					}
+				}
+				i += 3
+			case n == '~':
+				out(" ")
+				i++
+			case n == '\r' || n == '\n':
+				out("\n")
+				i++
+			case isAlpha(n):
+				j := i + 1
+				for j < len(rtf) && isAlpha(rtf[j]) {
+					j++
+				}
+				word := string(rtf[i+1 : j])
+				k := j
+				if k < len(rtf) && (rtf[k] == '-' || isDigit(rtf[k])) {
+					k++
+					for k < len(rtf) && isDigit(rtf[k]) {
+						k++
+					}
+				}
+				param, hasParam := 0, k > j
+				if hasParam {
+					param, _ = strconv.Atoi(string(rtf[j:k]))
+				}
+				if k < len(rtf) && rtf[k] == ' ' {
+					k++
+				}
+				i = k - 1
+
+				st := cur()
+				switch {
+				case word == "htmltag" || word == "mhtmltag":
+					st.inHTMLTag = true
+					st.skip = false
+				case word == "htmlrtf":
+					htmlrtf = !hasParam || param != 0
+				case starDest || skippedDestinations[word]:
+					if !st.inHTMLTag {
+						st.skip = true
+					}
+				case word == "par" || word == "line":
+					out("\n")
+				case word == "tab":
+					out("\t")
+				case word == "uc":
+					st.ucSkip = param
+				case word == "u":
+					if param < 0 {
+						param += 65536
+					}
+					out(string(rune(param)))
+					pendingSkip = st.ucSkip
+				case word == "emdash":
+					out("—")
+				case word == "endash":
+					out("–")
+				case word == "bullet":
+					out("•")
+				case word == "lquote":
+					out("‘")
+				case word == "rquote":
+					out("’")
+				case word == "ldblquote":
+					out("“")
+				case word == "rdblquote":
+					out("”")
+				}
+				starDest = false
+			default:
+				i++
+			}
+		default:
+			j := i
+			for j < len(rtf) && rtf[j] != '\\' && rtf[j] != '{' && rtf[j] != '}' && rtf[j] != '\r' && rtf[j] != '\n' {
+				j++
+			}
+			if pendingSkip > 0 {
+				drop := min(pendingSkip, j-i)
+				pendingSkip -= drop
+				i += drop
+			}
+			if i < j {
+				out(string(rtf[i:j]))
+			}
+			i = j - 1
+		}
+	}
+}
+
+func isAlpha(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func cp1252(c byte) rune {
+	return charmap.Windows1252.DecodeByte(c)
+}
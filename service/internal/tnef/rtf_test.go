@@ -0,0 +1,72 @@
+package tnef
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestDecompressRTF(t *testing.T) {
+	// Example 1 from MS-OXRTFCP section 4.1.
+	in, _ := hex.DecodeString("2d0000002b0000004c5a4675f1c5c7a703000a00726370673132354232" +
+		"0af32068656c09002062770" + "5b06c647d0a800fa0")
+	got, err := DecompressRTF(in)
+	if err != nil {
+		t.Fatalf("decompress: %v", err)
+	}
+	if want := "{\\rtf1\\ansi\\ansicpg1252\\pard hello world}\r\n"; string(got) != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestDecompressRTF_LyingRawSize(t *testing.T) {
+	in, _ := hex.DecodeString("2d000000f0ffffff4c5a4675f1c5c7a703000a00726370673132354232" +
+		"0af32068656c09002062770" + "5b06c647d0a800fa0")
+	got, err := DecompressRTF(in)
+	if err != nil || !strings.HasSuffix(string(got), "hello world}\r\n") {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	if cap(got) > 17*len(in) {
+		t.Fatalf("reserved %d bytes for a %d byte stream", cap(got), len(in))
+	}
+
+	copy(in[4:], le32(6))
+	if got, err := DecompressRTF(in); err != nil || string(got) != "{\\rtf1" {
+		t.Fatalf("output should stop at rawSize, got %q, %v", got, err)
+	}
+}
+
+func TestDecompressRTF_Uncompressed(t *testing.T) {
+	body := "{\\rtf1 plain}"
+	in := append(le32(uint32(len(body)+12)), le32(uint32(len(body)))...)
+	in = append(in, []byte("MELA")...)
+	in = append(in, 0, 0, 0, 0)
+	in = append(in, body...)
+	got, err := DecompressRTF(in)
+	if err != nil || string(got) != body {
+		t.Fatalf("got %q, %v", got, err)
+	}
+}
+
+func TestRTFToText(t *testing.T) {
+	rtf := `{\rtf1\ansi{\fonttbl{\f0 Arial;}}{\colortbl;\red0\green0\blue0;}{\*\generator Riched20;}` +
+		`\pard Hello \b world\b0 !\par Caf\'e9 \'80 costs\tab 5\par}`
+	if got, want := RTFToText([]byte(rtf)), "Hello world!\nCafé € costs\t5"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestRTFToHTML(t *testing.T) {
+	rtf := `{\rtf1\ansi\fromhtml1 {\*\htmltag19 <html>}{\*\htmltag50 <body>}\htmlrtf {\b \htmlrtf0 ` +
+		`{\*\htmltag84 <b>}Bold\htmlrtf }\htmlrtf0 {\*\htmltag92 </b>} text{\*\htmltag58 </body>}{\*\htmltag27 </html>}}`
+	got, ok := RTFToHTML([]byte(rtf))
+	if !ok {
+		t.Fatalf("expected encapsulated html")
+	}
+	if !strings.Contains(got, "<b>Bold</b> text") || !strings.HasPrefix(got, "<html><body>") {
+		t.Fatalf("unexpected html %q", got)
+	}
+	if _, ok := RTFToHTML([]byte(`{\rtf1 plain}`)); ok {
+		t.Fatalf("plain rtf should not be treated as html")
+	}
+}
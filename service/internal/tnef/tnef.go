@@ -0,0 +1,405 @@
+// Package tnef decodes Microsoft TNEF (winmail.dat) containers as produced by
+// Outlook and Exchange for "Rich Text" messages.
+package tnef
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const signature = 0x223E9F78
+
+// Attribute levels.
+const (
+	levelMessage    = 0x01
+	levelAttachment = 0x02
+)
+
+// Attribute IDs (type in the high word, id in the low word).
+const (
+	attSubject        = 0x00018004
+	attMessageClass   = 0x00078008
+	attBody           = 0x0002800C
+	attAttachData     = 0x0006800F
+	attAttachTitle    = 0x00018010
+	attAttachRendData = 0x00069002
+	attMAPIProps      = 0x00069003
+	attAttachment     = 0x00069005
+)
+
+// MAPI property tags used here.
+const (
+	prSubject           = 0x0037
+	prBody              = 0x1000
+	prRTFCompressed     = 0x1009
+	prHTML              = 0x1013
+	prAttachDataBin     = 0x3701
+	prAttachFilename    = 0x3704
+	prAttachLongName    = 0x3707
+	prAttachMimeTag     = 0x370E
+	prAttachContentID   = 0x3712
+	prDisplayName       = 0x3001
+	ptFlagMultiValue    = 0x1000
+	namedPropertyIDBase = 0x8000
+)
+
+var (
+	ErrNotTNEF = errors.New("tnef: bad signature")
+	errShort   = errors.New("tnef: truncated data")
+)
+
+// Message is the decoded content of a TNEF stream.
+type Message struct {
+	MessageClass string
+	Subject      string
+	Body         string // plain text, when present
+	HTML         string // HTML body, stored or de-encapsulated from RTF
+	RTF          []byte // decompressed RTF body, when present
+	Attachments  []Attachment
+}
+
+type Attachment struct {
+	Name      string
+	MIMEType  string
+	ContentID string
+	Data      []byte
+}
+
+// IsTNEF reports whether data starts with the TNEF signature.
+func IsTNEF(data []byte) bool {
+	return len(data) >= 4 && binary.LittleEndian.Uint32(data) == signature
+}
+
+// Decode parses a complete TNEF stream.
+func Decode(data []byte) (*Message, error) {
+	if !IsTNEF(data) {
+		return nil, ErrNotTNEF
+	}
+	r := &reader{b: data[6:]} // signature + legacy key
+	msg := &Message{}
+	var att *Attachment
+
+	for r.len() > 0 {
+		level, err := r.u8()
+		if err != nil {
+			return nil, err
+		}
+		id, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		n, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		val, err := r.bytes(int(n))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := r.u16(); err != nil { // checksum
+			return nil, err
+		}
+
+		switch {
+		case id == attAttachRendData:
+			msg.Attachments = append(msg.Attachments, Attachment{})
+			att = &msg.Attachments[len(msg.Attachments)-1]
+		case level == levelAttachment && att != nil:
+			switch id {
+			case attAttachTitle:
+				if att.Name == "" {
+					att.Name = cString(val)
+				}
+			case attAttachData:
+				att.Data = val
+			case attAttachment:
+				props, err := decodeProps(val)
+				if err != nil {
+					return nil, err
+				}
+				applyAttachmentProps(att, props)
+			}
+		case level == levelMessage:
+			switch id {
+			case attSubject:
+				msg.Subject = cString(val)
+			case attMessageClass:
+				msg.MessageClass = cString(val)
+			case attBody:
+				msg.Body = cString(val)
+			case attMAPIProps:
+				props, err := decodeProps(val)
+				if err != nil {
+					return nil, err
+				}
+				if err := applyMessageProps(msg, props); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	// Attachments without payload are OLE renderings we cannot use.
+	kept := msg.Attachments[:0]
+	for _, a := range msg.Attachments {
+		if len(a.Data) > 0 {
+			kept = append(kept, a)
+		}
+	}
+	msg.Attachments = kept
+	return msg, nil
+}
+
+func applyMessageProps(msg *Message, props []property) error {
+	for _, p := range props {
+		switch p.id {
+		case prSubject:
+			if msg.Subject == "" {
+				msg.Subject = p.string()
+			}
+		case prBody:
+			if msg.Body == "" {
+				msg.Body = p.string()
+			}
+		case prHTML:
+			msg.HTML = p.string()
+		case prRTFCompressed:
+			rtf, err := DecompressRTF(p.data)
+			if err != nil {
+				return err
+			}
+			msg.RTF = rtf
+		}
+	}
+	if msg.HTML == "" && len(msg.RTF) > 0 {
+		if html, ok := RTFToHTML(msg.RTF); ok {
+			msg.HTML = html
+		}
+	}
+	if msg.Body == "" && len(msg.RTF) > 0 {
+		msg.Body = RTFToText(msg.RTF)
+	}
+	return nil
+}
+
+func applyAttachmentProps(att *Attachment, props []property) {
+	for _, p := range props {
+		switch p.id {
+		case prAttachLongName:
+			att.Name = p.string()
+		case prAttachFilename, prDisplayName:
+			if att.Name == "" {
+				att.Name = p.string()
+			}
+		case prAttachMimeTag:
+			att.MIMEType = p.string()
+		case prAttachContentID:
+			att.ContentID = p.string()
+		case prAttachDataBin:
+			if len(att.Data) == 0 {
+				att.Data = p.data
+			}
+		}
+	}
+}
+
+// property is one decoded MAPI property; only the first value of
+// multi-valued properties is kept.
+type property struct {
+	id   uint16
+	typ  uint16
+	data []byte
+}
+
+func (p property) string() string {
+	switch p.typ {
+	case 0x001F: // PT_UNICODE
+		return utf16String(p.data)
+	default:
+		return cString(p.data)
+	}
+}
+
+func decodeProps(b []byte) ([]property, error) {
+	r := &reader{b: b}
+	count, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	var out []property
+	for i := uint32(0); i < count; i++ {
+		typ, err := r.u16()
+		if err != nil {
+			return nil, err
+		}
+		id, err := r.u16()
+		if err != nil {
+			return nil, err
+		}
+		if id >= namedPropertyIDBase {
+			if _, err := r.bytes(16); err != nil { // GUID
+				return nil, err
+			}
+			kind, err := r.u32()
+			if err != nil {
+				return nil, err
+			}
+			if kind == 0 {
+				if _, err := r.u32(); err != nil {
+					return nil, err
+				}
+			} else {
+				n, err := r.u32()
+				if err != nil {
+					return nil, err
+				}
+				if _, err := r.padded(int(n)); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		values := uint32(1)
+		base := typ &^ ptFlagMultiValue
+		if typ&ptFlagMultiValue != 0 || isVariable(base) {
+			if values, err = r.u32(); err != nil {
+				return nil, err
+			}
+		}
+		var first []byte
+		for v := uint32(0); v < values; v++ {
+			var val []byte
+			if isVariable(base) {
+				n, err := r.u32()
+				if err != nil {
+					return nil, err
+				}
+				if val, err = r.padded(int(n)); err != nil {
+					return nil, err
+				}
+			} else {
+				size, ok := fixedSize(base)
+				if !ok {
+					return nil, fmt.Errorf("tnef: unsupported property type 0x%04x", base)
+				}
+				if val, err = r.bytes(size); err != nil {
+					return nil, err
+				}
+			}
+			if v == 0 {
+				first = val
+			}
+		}
+		out = append(out, property{id: id, typ: base, data: first})
+	}
+	return out, nil
+}
+
+func isVariable(t uint16) bool {
+	switch t {
+	case 0x001E, 0x001F, 0x0102, 0x000D: // STRING8, UNICODE, BINARY, OBJECT
+		return true
+	}
+	return false
+}
+
+func fixedSize(t uint16) (int, bool) {
+	switch t {
+	case 0x0001, 0x0002, 0x0003, 0x0004, 0x000A, 0x000B: // NULL, SHORT, LONG, FLOAT, ERROR, BOOLEAN (padded to 4)
+		return 4, true
+	case 0x0005, 0x0006, 0x0007, 0x0014, 0x0040: // DOUBLE, CURRENCY, APPTIME, I8, SYSTIME
+		return 8, true
+	case 0x0048: // CLSID
+		return 16, true
+	}
+	return 0, false
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return decodeANSI(b)
+}
+
+func utf16String(b []byte) string {
+	u := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		c := binary.LittleEndian.Uint16(b[i:])
+		if c == 0 {
+			break
+		}
+		u = append(u, c)
+	}
+	return string(utf16.Decode(u))
+}
+
+// decodeANSI treats 8-bit strings as Windows-1252 unless they are valid UTF-8.
+func decodeANSI(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	var sb strings.Builder
+	for _, c := range b {
+		sb.WriteRune(cp1252(c))
+	}
+	return sb.String()
+}
+
+type reader struct {
+	b []byte
+}
+
+func (r *reader) len() int { return len(r.b) }
+
+func (r *reader) bytes(n int) ([]byte, error) {
+	if n < 0 || n > len(r.b) {
+		return nil, errShort
+	}
+	v := r.b[:n:n]
+	r.b = r.b[n:]
+	return v, nil
+}
+
+// padded reads n bytes and skips the padding to the next 4-byte boundary.
+func (r *reader) padded(n int) ([]byte, error) {
+	v, err := r.bytes(n)
+	if err != nil {
+		return nil, err
+	}
+	if pad := (4 - n%4) % 4; pad > 0 {
+		if _, err := r.bytes(min(pad, len(r.b))); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+func (r *reader) u8() (byte, error) {
+	b, err := r.bytes(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *reader) u16() (uint16, error) {
+	b, err := r.bytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(b), nil
+}
+
+func (r *reader) u32() (uint32, error) {
+	b, err := r.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
@@ -0,0 +1,119 @@
+package tnef
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+func le16(v uint16) []byte { b := make([]byte, 2); binary.LittleEndian.PutUint16(b, v); return b }
+func le32(v uint32) []byte { b := make([]byte, 4); binary.LittleEndian.PutUint32(b, v); return b }
+
+type tnefBuilder struct{ bytes.Buffer }
+
+func newBuilder() *tnefBuilder {
+	b := &tnefBuilder{}
+	b.Write(le32(signature))
+	b.Write(le16(0x0001))
+	return b
+}
+
+func (b *tnefBuilder) attr(level byte, id uint32, data []byte) {
+	b.WriteByte(level)
+	b.Write(le32(id))
+	b.Write(le32(uint32(len(data))))
+	b.Write(data)
+	var sum uint16
+	for _, c := range data {
+		sum += uint16(c)
+	}
+	b.Write(le16(sum))
+}
+
+func padded(data []byte) []byte {
+	out := append(le32(uint32(len(data))), data...)
+	for len(out)%4 != 0 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+func unicodeProp(id uint16, s string) []byte {
+	u := utf16.Encode([]rune(s + "\x00"))
+	raw := make([]byte, 0, len(u)*2)
+	for _, c := range u {
+		raw = append(raw, le16(c)...)
+	}
+	out := append(le16(0x001F), le16(id)...)
+	out = append(out, le32(1)...)
+	return append(out, padded(raw)...)
+}
+
+func binaryProp(id uint16, data []byte) []byte {
+	out := append(le16(0x0102), le16(id)...)
+	out = append(out, le32(1)...)
+	return append(out, padded(data)...)
+}
+
+func props(ps ...[]byte) []byte {
+	out := le32(uint32(len(ps)))
+	for _, p := range ps {
+		out = append(out, p...)
+	}
+	return out
+}
+
+func TestDecode(t *testing.T) {
+	rtf := `{\rtf1\ansi\fromhtml1 {\*\htmltag19 <html>}Hi there{\*\htmltag27 </html>}}`
+	compressed := append(le32(uint32(len(rtf)+12)), le32(uint32(len(rtf)))...)
+	compressed = append(compressed, []byte("MELA")...)
+	compressed = append(compressed, le32(0)...)
+	compressed = append(compressed, rtf...)
+
+	b := newBuilder()
+	b.attr(levelMessage, attMessageClass, []byte("IPM.Microsoft Mail.Note\x00"))
+	b.attr(levelMessage, attSubject, []byte("Quarterly r\xe9port\x00"))
+	b.attr(levelMessage, attMAPIProps, props(
+		// a named property that must be skipped correctly
+		append(append(le16(0x0003), le16(0x8001)...), append(append(make([]byte, 16), le32(0)...), append(le32(0x1234), le32(7)...)...)...),
+		binaryProp(prRTFCompressed, compressed),
+	))
+	b.attr(levelAttachment, attAttachRendData, make([]byte, 14))
+	b.attr(levelAttachment, attAttachTitle, []byte("REPORT~1.PDF\x00"))
+	b.attr(levelAttachment, attAttachData, []byte("%PDF-1.4 data"))
+	b.attr(levelAttachment, attAttachment, props(
+		unicodeProp(prAttachLongName, "Quarterly report.pdf"),
+		unicodeProp(prAttachMimeTag, "application/pdf"),
+	))
+	b.attr(levelAttachment, attAttachRendData, make([]byte, 14)) // OLE object without data
+
+	msg, err := Decode(b.Bytes())
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if msg.Subject != "Quarterly réport" || msg.MessageClass != "IPM.Microsoft Mail.Note" {
+		t.Fatalf("unexpected message fields: %+v", msg)
+	}
+	if msg.HTML != "<html>Hi there</html>" || msg.Body != "Hi there" {
+		t.Fatalf("unexpected bodies: html=%q body=%q", msg.HTML, msg.Body)
+	}
+	if len(msg.Attachments) != 1 {
+		t.Fatalf("want 1 attachment, got %d", len(msg.Attachments))
+	}
+	a := msg.Attachments[0]
+	if a.Name != "Quarterly report.pdf" || a.MIMEType != "application/pdf" || string(a.Data) != "%PDF-1.4 data" {
+		t.Fatalf("unexpected attachment: %+v", a)
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	if _, err := Decode([]byte("not tnef")); err != ErrNotTNEF {
+		t.Fatalf("want ErrNotTNEF, got %v", err)
+	}
+	b := newBuilder()
+	b.attr(levelMessage, attSubject, []byte("x\x00"))
+	if _, err := Decode(b.Bytes()[:b.Len()-4]); err == nil {
+		t.Fatalf("expected truncation error")
+	}
+}